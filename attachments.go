@@ -0,0 +1,126 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package hessian
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// EncodeAttachments encodes dubbo attachments the same way as the rpc body does.
+func EncodeAttachments(m map[string]string) ([]byte, error) {
+	encoder := NewEncoder()
+	if err := encoder.Encode(m); err != nil {
+		return nil, perrors.WithStack(err)
+	}
+	return encoder.Buffer(), nil
+}
+
+// DecodeAttachments decodes dubbo attachments encoded by EncodeAttachments
+// or by the java side. A null attachments value is decoded as an empty map.
+func DecodeAttachments(buf []byte) (map[string]string, error) {
+	return decodeAttachments(NewDecoder(buf))
+}
+
+func decodeAttachments(decoder *Decoder) (map[string]string, error) {
+	attachments, err := decoder.Decode()
+	if err != nil {
+		return nil, perrors.WithStack(err)
+	}
+	return toAttachments(attachments)
+}
+
+// toAttachments converts a decoded hessian map into dubbo attachments.
+// Some dubbo versions put values other than strings into the attachments, such as a long timeout,
+// which are converted to their string form like java String.valueOf.
+func toAttachments(v interface{}) (map[string]string, error) {
+	if m, ok := v.(*OrderedMap); ok {
+		v = m.ToMap()
+	}
+	switch m := v.(type) {
+	case nil:
+		return make(map[string]string), nil
+	case map[string]string:
+		return m, nil
+	case map[interface{}]interface{}:
+		atta := make(map[string]string, len(m))
+		for k, v := range m {
+			key, ok := k.(string)
+			if !ok {
+				return nil, perrors.Errorf("get wrong attachment key: %+v", k)
+			}
+			atta[key] = attachmentValue(v)
+		}
+		return atta, nil
+	}
+	return nil, perrors.Errorf("get wrong attachments: %+v", v)
+}
+
+// attachmentValue returns the string form of the attachment value @v.
+func attachmentValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case int32:
+		return strconv.FormatInt(int64(value), 10)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return javaDoubleString(value)
+	case bool:
+		return strconv.FormatBool(value)
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprint(v)
+}
+
+// javaDoubleString returns the string form of double @f like java Double.toString, such as "1.0" for 1
+// and "1.0E21" for 1e21, which is the plain decimal if 1e-3 <= |f| < 1e7, or else the scientific notation.
+func javaDoubleString(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	if abs := math.Abs(f); f == 0 || (abs >= 1e-3 && abs < 1e7) {
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+
+	// such as "1.5E+21" and "1E-05" into "1.5E21" and "1.0E-5"
+	s := strconv.FormatFloat(f, 'E', -1, 64)
+	i := strings.IndexByte(s, 'E')
+	mantissa, exp := s[:i], s[i+1:]
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	n, _ := strconv.Atoi(exp)
+	return mantissa + "E" + strconv.Itoa(n)
+}
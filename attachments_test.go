@@ -0,0 +1,142 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package hessian
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachments(t *testing.T) {
+	atta := map[string]string{DUBBO_VERSION_KEY: "2.0.2", PATH_KEY: "test"}
+	buf, err := EncodeAttachments(atta)
+	assert.Nil(t, err)
+	decoded, err := DecodeAttachments(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, atta, decoded)
+
+	// null attachments
+	decoded, err = DecodeAttachments([]byte{BC_NULL})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{}, decoded)
+
+	// attachments of wrong type
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([]string{"a"}))
+	_, err = DecodeAttachments(e.Buffer())
+	assert.NotNil(t, err)
+
+	// the values other than strings are converted to strings
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(map[string]int32{"a": 1}))
+	decoded, err = DecodeAttachments(e.Buffer())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1"}, decoded)
+
+	// attachments in the body of a response
+	strObj := ""
+	rsp := NewResponse("ok", nil, atta)
+	resp, err := NewHessianCodec(nil).Write(Service{}, DubboHeader{
+		SerialID:       2,
+		Type:           PackageResponse,
+		ID:             1,
+		ResponseStatus: Response_OK,
+	}, rsp)
+	assert.Nil(t, err)
+
+	codecR := NewHessianCodec(bufio.NewReader(bytes.NewReader(resp)))
+	assert.Nil(t, codecR.ReadHeader(&DubboHeader{}))
+	decodedResponse := &Response{RspObj: &strObj}
+	assert.Nil(t, codecR.ReadBody(decodedResponse))
+	assert.Equal(t, "ok", strObj)
+	assert.Equal(t, atta, decodedResponse.Attachments)
+}
+
+func TestMixedAttachments(t *testing.T) {
+	// the attachments of a response from a dubbo 2.7 provider, which puts the timeout as a long
+	e := NewEncoder()
+	e.buffer = encByte(e.buffer, BC_MAP)
+	e.buffer = encString(e.buffer, "java.util.HashMap")
+	for _, kv := range [][2]interface{}{
+		{DUBBO_VERSION_KEY, "2.0.2"},
+		{TIMEOUT_KEY, int64(3000)},
+		{"retries", int32(2)},
+		{"async", false},
+		{"weight", 0.5},
+		{"ratio", 1.0},
+		{"limit", 1e21},
+		{"tag", nil},
+	} {
+		assert.Nil(t, e.Encode(kv[0]))
+		assert.Nil(t, e.Encode(kv[1]))
+	}
+	e.buffer = encByte(e.buffer, BC_END)
+	atta := e.Buffer()
+
+	e = NewEncoder()
+	header := []byte{0xda, 0xbb, 0x02, Response_OK, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0}
+	e.Append(header)
+	assert.Nil(t, e.Encode(RESPONSE_VALUE_WITH_ATTACHMENTS))
+	assert.Nil(t, e.Encode("ok"))
+	e.Append(atta)
+	frame := e.Buffer()
+	frame[15] = byte(len(frame) - HEADER_LENGTH)
+
+	codecR := NewHessianCodec(bufio.NewReader(bytes.NewReader(frame)))
+	assert.Nil(t, codecR.ReadHeader(&DubboHeader{}))
+	strObj := ""
+	decodedResponse := &Response{RspObj: &strObj}
+	assert.Nil(t, codecR.ReadBody(decodedResponse))
+	assert.Equal(t, "ok", strObj)
+	assert.Equal(t, map[string]string{
+		DUBBO_VERSION_KEY: "2.0.2",
+		TIMEOUT_KEY:       "3000",
+		"retries":         "2",
+		"async":           "false",
+		"weight":          "0.5",
+		"ratio":           "1.0",
+		"limit":           "1.0E21",
+		"tag":             "",
+	}, decodedResponse.Attachments)
+}
+
+func TestJavaDoubleString(t *testing.T) {
+	for _, c := range []struct {
+		f float64
+		s string
+	}{
+		{0, "0.0"},
+		{math.Copysign(0, -1), "-0.0"},
+		{1, "1.0"},
+		{-2.5, "-2.5"},
+		{0.001, "0.001"},
+		{0.0001, "1.0E-4"},
+		{1.5e-5, "1.5E-5"},
+		{1234567, "1234567.0"},
+		{1e7, "1.0E7"},
+		{1.25e21, "1.25E21"},
+		{math.MaxFloat64, "1.7976931348623157E308"},
+		{math.Inf(1), "Infinity"},
+		{math.Inf(-1), "-Infinity"},
+		{math.NaN(), "NaN"},
+	} {
+		assert.Equal(t, c.s, javaDoubleString(c.f), "%v", c.f)
+	}
+}
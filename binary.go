@@ -15,7 +15,10 @@
 package hessian
 
 import (
+	"bytes"
 	"io"
+	"reflect"
+	"unicode/utf8"
 )
 
 import (
@@ -90,7 +93,7 @@ func (d *Decoder) getBinaryLength(tag byte) (int, error) {
 	}
 
 	if tag >= BC_BINARY_SHORT && tag <= byte(0x37) { // [0x34, 0x37]
-		_, err = io.ReadFull(d.reader, buf[:1])
+		_, err = d.readFull(buf[:1])
 		if err != nil {
 			return 0, perrors.WithStack(err)
 		}
@@ -99,10 +102,10 @@ func (d *Decoder) getBinaryLength(tag byte) (int, error) {
 	}
 
 	if tag != BC_BINARY_CHUNK && tag != BC_BINARY {
-		return 0, perrors.Errorf("illegal binary tag:%d", tag)
+		return 0, d.tagErrorf("illegal binary tag:%#x", tag)
 	}
 
-	_, err = io.ReadFull(d.reader, buf[:2])
+	_, err = d.readFull(buf[:2])
 	if err != nil {
 		return 0, perrors.WithStack(err)
 	}
@@ -141,8 +144,14 @@ func (d *Decoder) decBinary(flag int32) ([]byte, error) {
 		if err != nil {
 			return nil, perrors.WithStack(err)
 		}
+		if d.maxBinaryLength > 0 && len(data)+length > d.maxBinaryLength {
+			return nil, perrors.Wrapf(ErrMaxBinaryLengthExceeded, "binary of %d bytes, max length %d", len(data)+length, d.maxBinaryLength)
+		}
+		if err = d.checkRemaining(length); err != nil {
+			return nil, err
+		}
 
-		_, err = io.ReadFull(d.reader, buf[:length])
+		_, err = d.readFull(buf[:length])
 		if err != nil {
 			return nil, perrors.WithStack(err)
 		}
@@ -160,3 +169,189 @@ func (d *Decoder) decBinary(flag int32) ([]byte, error) {
 	}
 	return data, nil
 }
+
+// SetBinaryStreamThreshold makes a binary longer than @threshold bytes decode into an io.Reader
+// instead of []byte, which reads the bytes from the underlying stream on demand. The binaries
+// no longer than @threshold are still decoded into []byte, and so are all of them if @threshold
+// is not positive, which is the default.
+// Once the decoder goes on decoding the following values, the unread part of the binary is
+// read into memory, so a large binary should be the last value, or be read before decoding more.
+func (d *Decoder) SetBinaryStreamThreshold(threshold int) {
+	d.binaryStreamThreshold = threshold
+}
+
+// decBinaryStream reads the chunks of the binary into memory until its length goes beyond
+// binaryStreamThreshold, and then the rest of it is left to an io.Reader.
+func (d *Decoder) decBinaryStream(tag byte) (interface{}, error) {
+	var data []byte
+
+	for {
+		length, err := d.getBinaryLength(tag)
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
+
+		if len(data)+length > d.binaryStreamThreshold {
+			r := &binaryReader{d: d, tag: tag, remain: length, buf: bytes.NewBuffer(data)}
+			d.binary = r
+			return r, nil
+		}
+
+		n := len(data)
+		data = append(data, make([]byte, length)...)
+		if _, err = d.readFull(data[n:]); err != nil {
+			return nil, perrors.WithStack(err)
+		}
+
+		if tag != BC_BINARY_CHUNK {
+			return data, nil
+		}
+
+		tag, err = d.readBufByte()
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
+	}
+}
+
+// binaryReader reads a binary from the stream of the decoder chunk by chunk.
+type binaryReader struct {
+	d      *Decoder // nil if the binary has been read to the end
+	tag    byte     // tag of the current chunk
+	remain int      // unread bytes of the current chunk
+	buf    *bytes.Buffer
+	err    error
+}
+
+func (r *binaryReader) Read(p []byte) (int, error) {
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+	if r.d == nil {
+		if r.err != nil {
+			return 0, r.err
+		}
+		return 0, io.EOF
+	}
+	return r.readChunks(p)
+}
+
+func (r *binaryReader) readChunks(p []byte) (int, error) {
+	d := r.d
+	// the chunks are read by the decoder itself
+	d.binary = nil
+
+	for r.remain == 0 {
+		if r.tag != BC_BINARY_CHUNK {
+			r.d = nil
+			return 0, io.EOF
+		}
+
+		tag, err := d.readBufByte()
+		if err == nil {
+			r.remain, err = d.getBinaryLength(tag)
+		}
+		if err != nil {
+			r.d = nil
+			r.err = perrors.WithStack(err)
+			return 0, r.err
+		}
+		r.tag = tag
+	}
+
+	if len(p) > r.remain {
+		p = p[:r.remain]
+	}
+	n, err := d.readFull(p)
+	r.remain -= n
+	if err != nil {
+		r.d = nil
+		r.err = perrors.WithStack(err)
+		return n, r.err
+	}
+
+	d.binary = r
+	return n, nil
+}
+
+// drain reads the rest of the binary into memory.
+func (r *binaryReader) drain() {
+	var buf [4096]byte
+	for r.d != nil {
+		n, _ := r.readChunks(buf[:])
+		r.buf.Write(buf[:n])
+	}
+}
+
+// drainBinary makes the decoder skip the streaming binary before reading the following values.
+func (d *Decoder) drainBinary() {
+	if d.binary != nil {
+		d.binary.drain()
+	}
+}
+
+// encBytesField encodes the string or *string field @field with tagOptionBytes as the binary of its UTF-8 bytes.
+func (e *Encoder) encBytesField(field reflect.Value) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		field = field.Elem()
+	}
+	if field.Kind() != reflect.String {
+		return perrors.Errorf("the field of tag option %s should be a string, but %s", tagOptionBytes, field.Type())
+	}
+	e.buffer = encBinary(e.buffer, []byte(field.String()))
+	return nil
+}
+
+// decBytesField decodes the binary of UTF-8 bytes into the string or *string field @field with tagOptionBytes.
+// A string is accepted too, and a null is the empty string, or a nil *string.
+func (d *Decoder) decBytesField(field reflect.Value) error {
+	typ := UnpackPtrType(field.Type())
+	if typ.Kind() != reflect.String {
+		return perrors.Errorf("the field of tag option %s should be a string, but %s", tagOptionBytes, field.Type())
+	}
+
+	var s string
+	tag := d.peekByte()
+	switch {
+	case tag == BC_NULL:
+		if _, err := d.readByte(); err != nil {
+			return perrors.WithStack(err)
+		}
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	case tag == BC_BINARY || tag == BC_BINARY_CHUNK || (tag >= BC_BINARY_DIRECT && tag <= 0x2f) ||
+		(tag >= BC_BINARY_SHORT && tag <= 0x37):
+		b, err := d.decBinary(TAG_READ)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(b) {
+			return perrors.Errorf("the %d bytes are not UTF-8, the first invalid byte at %d", len(b), invalidUTF8Offset(b))
+		}
+		s = string(b)
+	default:
+		var err error
+		if s, err = d.decString(TAG_READ); err != nil {
+			return err
+		}
+	}
+
+	SetValue(field, reflect.ValueOf(s).Convert(typ))
+	return nil
+}
+
+// invalidUTF8Offset returns the offset of the first byte of @b which is not UTF-8, or len(b) if all are.
+func invalidUTF8Offset(b []byte) int {
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return len(b)
+}
@@ -19,9 +19,14 @@ import (
 	"fmt"
 
 	// "fmt"
+	"io"
 	"testing"
 )
 
+import (
+	"github.com/stretchr/testify/assert"
+)
+
 func TestEncBinary(t *testing.T) {
 	var (
 		v   []byte
@@ -148,3 +153,121 @@ func TestBinaryEncode(t *testing.T) {
 	testJavaDecode(t, "argBinary_16", []byte(s16))
 	testJavaDecode(t, "argBinary_65536", []byte(s65560[:65536]))
 }
+
+func TestBinaryStream(t *testing.T) {
+	blob := make([]byte, 200000)
+	for i := range blob {
+		blob[i] = byte(i % 251)
+	}
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(blob[:1000]))
+	assert.Nil(t, e.Encode(blob[:70000])) // two chunks
+	assert.Nil(t, e.Encode(blob))
+	assert.Nil(t, e.Encode("end"))
+	assert.Nil(t, e.Encode([]interface{}{blob, "after"}))
+
+	d := NewDecoderFromReader(bytes.NewReader(e.Buffer()))
+	d.SetBinaryStreamThreshold(100000)
+
+	v, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, blob[:1000], v)
+	v, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, blob[:70000], v)
+
+	v, err = d.Decode()
+	assert.Nil(t, err)
+	r, ok := v.(io.Reader)
+	assert.True(t, ok)
+	head := make([]byte, 10)
+	_, err = io.ReadFull(r, head)
+	assert.Nil(t, err)
+	assert.Equal(t, blob[:10], head)
+
+	// the rest of the binary is kept for the reader
+	v, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "end", v)
+	rest, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, blob[10:], rest)
+
+	v, err = d.Decode()
+	assert.Nil(t, err)
+	list := v.([]interface{})
+	assert.Equal(t, "after", list[1])
+	data, err := io.ReadAll(list[0].(io.Reader))
+	assert.Nil(t, err)
+	assert.Equal(t, blob, data)
+
+	// the chunks are read from the stream on demand
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(blob))
+	d = NewDecoderFromReader(io.LimitReader(bytes.NewReader(e.Buffer()), 100000))
+	d.SetBinaryStreamThreshold(1)
+	v, err = d.Decode()
+	assert.Nil(t, err)
+	data, err = io.ReadAll(v.(io.Reader))
+	assert.NotNil(t, err)
+	assert.True(t, bytes.HasPrefix(blob, data))
+}
+
+type bytesText string
+
+type bytesFieldDTO struct {
+	Payload string     `hessian:"payload,bytes"`
+	Note    *string    `hessian:"note,bytes"`
+	Text    bytesText  `hessian:"text,bytes"`
+	Raw     []byte     `hessian:"raw"`
+	Missing *bytesText `hessian:"missing,bytes"`
+}
+
+func (bytesFieldDTO) JavaClassName() string {
+	return "test.BytesFieldDTO"
+}
+
+func TestBytesField(t *testing.T) {
+	RegisterPOJO(&bytesFieldDTO{})
+
+	note := "便条"
+	dto := &bytesFieldDTO{Payload: "héllo", Note: &note, Text: "", Raw: []byte("raw")}
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(dto))
+	// the strings are written as the binaries of their UTF-8 bytes
+	want := encBinary(append(encBinary(encBinary(nil, []byte("héllo")), []byte(note)), BC_BINARY_DIRECT), []byte("raw"))
+	assert.True(t, bytes.HasSuffix(e.Buffer(), append(want, BC_NULL)))
+
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, dto, res)
+
+	// a string or a null is accepted too
+	b := encTestClassInstance(nil, 0, "test.BytesFieldDTO", []string{"payload", "note", "text"}, "s", nil, []byte("t"))
+	res, err = NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &bytesFieldDTO{Payload: "s", Text: "t"}, res)
+
+	// a compact long is a number rather than a short binary
+	b = encTestClassInstance(nil, 0, "test.BytesFieldDTO", []string{"payload"}, int64(100000))
+	assert.Equal(t, byte(0x3d), b[len(b)-3])
+	res, err = NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &bytesFieldDTO{Payload: "100000"}, res)
+
+	b = encTestClassInstance(nil, 0, "test.BytesFieldDTO", []string{"payload"}, []byte{'o', 'k', 0xff, 0xfe})
+	_, err = NewDecoder(b).Decode()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the 4 bytes are not UTF-8, the first invalid byte at 2")
+
+	type wrongBytesField struct {
+		Count int32 `hessian:"count,bytes"`
+	}
+	_, err = RegisterPOJOMapping("test.WrongBytesField", wrongBytesField{}, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, NewEncoder().Encode(&wrongBytesField{Count: 1}))
+	b = encTestClassInstance(nil, 0, "test.WrongBytesField", []string{"count"}, []byte("1"))
+	_, err = NewDecoder(b).Decode()
+	assert.NotNil(t, err)
+}
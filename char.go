@@ -0,0 +1,73 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// JavaChar is a java char or java.lang.Character, which is sent as a string of the char.
+// A go rune is an int32 and is sent as a java int, so use JavaChar to send a char instead.
+// A code point above the BMP, which is not a java char, is sent as a string of its surrogate pair.
+type JavaChar rune
+
+func (c JavaChar) String() string {
+	return string(rune(c))
+}
+
+// encChar writes the char @c as a string. A lone surrogate is written as it is in the same way
+// as java, which can not be a go string.
+func encChar(b []byte, c JavaChar) []byte {
+	if utf16.IsSurrogate(rune(c)) {
+		return encSurrogate(encByte(b, BC_STRING_DIRECT+1), rune(c))
+	}
+	return encString(b, string(rune(c)))
+}
+
+// isStringTag checks whether @tag starts a string.
+func isStringTag(tag byte) bool {
+	return tag <= STRING_DIRECT_MAX || (tag >= 0x30 && tag <= 0x33) || tag == BC_STRING || tag == BC_STRING_CHUNK
+}
+
+// decChar decodes a java char, which is a string of one char, into its code point. A string of
+// a surrogate pair is also accepted as the code point above the BMP, and null is the zero char.
+func (d *Decoder) decChar() (rune, error) {
+	if d.peekByte() == BC_NULL {
+		_, err := d.readByte()
+		return 0, perrors.WithStack(err)
+	}
+	s, err := d.decString(TAG_READ)
+	if err != nil {
+		return 0, perrors.WithStack(err)
+	}
+	return stringChar(s)
+}
+
+// stringChar gets the only code point of @s.
+func stringChar(s string) (rune, error) {
+	if s == "" {
+		return 0, nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) {
+		return 0, perrors.Errorf("string %q is not a char", s)
+	}
+	return r, nil
+}
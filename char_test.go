@@ -0,0 +1,90 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type charHolder struct {
+	Initial JavaChar
+	Symbol  rune
+	Code    uint16
+}
+
+func (charHolder) JavaClassName() string {
+	return "test.model.CharHolder"
+}
+
+func TestJavaChar(t *testing.T) {
+	for _, c := range []struct {
+		char JavaChar
+		wire []byte
+	}{
+		{'a', []byte{0x01, 'a'}},
+		{'中', []byte{0x01, 0xe4, 0xb8, 0xad}},
+		// a surrogate pair, which is two java chars
+		{'😀', []byte{0x02, 0xed, 0xa0, 0xbd, 0xed, 0xb8, 0x80}},
+	} {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(c.char))
+		assert.Equal(t, c.wire, e.Buffer())
+
+		// a char is decoded as a string at the top level
+		v, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, c.char.String(), v)
+
+		var r rune
+		assert.Nil(t, ReflectResponse(v, &r))
+		assert.Equal(t, rune(c.char), r)
+	}
+
+	// a lone surrogate is written like java
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(JavaChar(0xd83d)))
+	assert.Equal(t, []byte{0x01, 0xed, 0xa0, 0xbd}, e.Buffer())
+
+	RegisterPOJO(&charHolder{})
+	holder := &charHolder{Initial: 'D', Symbol: '😀', Code: '中'}
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(holder))
+	// the fields of a go rune and uint16 are sent as numbers
+	v, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, holder, v)
+
+	// the chars sent by java into the fields of rune and uint16
+	b := encString(encInt32(encString([]byte{BC_OBJECT_DEF}, "test.model.CharHolder"), 3), "initial")
+	for _, name := range []string{"symbol", "code"} {
+		b = encString(b, name)
+	}
+	b = append(b, BC_OBJECT_DIRECT)
+	for _, s := range []string{"D", "😀", "中"} {
+		b = encString(b, s)
+	}
+	v, err = NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, holder, v)
+
+	// not a char
+	b = append(b[:len(b)-4], 0x02, 'a', 'b')
+	_, err = NewDecoder(b).Decode()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,76 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+	"sync"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// ClassNameResolver names the go structs which are neither registered nor POJO for the encoders,
+// such as by mapping the go package path to the java package. It is consulted by an Encoder set
+// by SetClassNameResolver, and the registered types and the POJOs take precedence over it.
+// The class definition of every resolved type is cached, so one resolver should be shared by
+// the encoders. A resolved type is not registered, which should be registered to be decoded.
+type ClassNameResolver struct {
+	resolve func(typ reflect.Type) (javaName string, fields []string, ok bool)
+	cache   sync.Map // reflect.Type -> resolvedClass
+}
+
+type resolvedClass struct {
+	cls classInfo
+	ok  bool
+	err error
+}
+
+// NewClassNameResolver returns a ClassNameResolver which gets the java class name and the field
+// names of go struct @typ by @resolve, where the fields are bound like RegisterPOJOMapping and
+// a nil @fields means all the fields in the declaration order. @typ is not resolved if @ok is false.
+// @resolve is called once per type, which may be called concurrently.
+func NewClassNameResolver(resolve func(typ reflect.Type) (javaName string, fields []string, ok bool)) *ClassNameResolver {
+	return &ClassNameResolver{resolve: resolve}
+}
+
+// classInfo gets the cached class definition of go struct @typ.
+func (r *ClassNameResolver) classInfo(typ reflect.Type) (classInfo, bool, error) {
+	if c, ok := r.cache.Load(typ); ok {
+		resolved := c.(resolvedClass)
+		return resolved.cls, resolved.ok, resolved.err
+	}
+
+	var resolved resolvedClass
+	javaName, fields, ok := r.resolve(typ)
+	switch {
+	case !ok:
+	case javaName == "":
+		resolved.err = perrors.Errorf("the java class name of %s resolved is empty", typ)
+	default:
+		resolved.cls, resolved.err = newClassInfo(javaName, typ, fields)
+		resolved.ok = resolved.err == nil
+	}
+	r.cache.Store(typ, resolved)
+
+	return resolved.cls, resolved.ok, resolved.err
+}
+
+// SetClassNameResolver sets the resolver of the java class names of the go structs which
+// are neither registered nor POJO. A nil @r removes the resolver.
+func (e *Encoder) SetClassNameResolver(r *ClassNameResolver) {
+	e.classNameResolver = r
+}
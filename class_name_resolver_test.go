@@ -0,0 +1,88 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type resolvedAddress struct {
+	City string
+	Zip  string
+}
+
+type resolvedUser struct {
+	Name    string
+	Age     int32
+	Address *resolvedAddress
+}
+
+type unresolvedThing struct {
+	ID int64
+}
+
+func TestClassNameResolver(t *testing.T) {
+	calls := map[reflect.Type]int{}
+	resolver := NewClassNameResolver(func(typ reflect.Type) (string, []string, bool) {
+		calls[typ]++
+		if typ == reflect.TypeOf(unresolvedThing{}) {
+			return "", nil, false
+		}
+		// the go package path is mapped to the java package
+		pkg := strings.Replace(typ.PkgPath(), "github.com/apache/", "org.apache.", 1)
+		pkg = strings.NewReplacer("/", ".", "-", "_").Replace(pkg)
+		if typ == reflect.TypeOf(resolvedAddress{}) {
+			return pkg + ".Address", []string{"zip", "city"}, true
+		}
+		return pkg + ".User", nil, true
+	})
+
+	user := &resolvedUser{Name: "Alice", Age: 18, Address: &resolvedAddress{City: "Hangzhou", Zip: "310000"}}
+	for i := 0; i < 2; i++ {
+		e := NewEncoder()
+		e.SetClassNameResolver(resolver)
+		assert.Nil(t, e.Encode([]interface{}{user, &Department{Name: "dev"}, *user.Address}))
+
+		d := NewDecoder(e.Buffer())
+		d.SetGenericMode(true)
+		v, err := d.Decode()
+		assert.Nil(t, err)
+		list := v.([]interface{})
+		o := list[0].(*GenericObject)
+		assert.Equal(t, "org.apache.dubbo_go_hessian2.User", o.ClassName)
+		assert.Equal(t, []string{"name", "age", "address"}, o.fieldNames)
+		address := o.Fields["address"].(*GenericObject)
+		assert.Equal(t, "org.apache.dubbo_go_hessian2.Address", address.ClassName)
+		assert.Equal(t, []string{"zip", "city"}, address.fieldNames)
+		assert.Equal(t, "310000", address.Fields["zip"])
+		// a registered POJO is not resolved
+		assert.Equal(t, Department{}.JavaClassName(), list[1].(*GenericObject).ClassName)
+		assert.Equal(t, address.Fields, list[2].(*GenericObject).Fields)
+	}
+	// resolved once per type
+	assert.Equal(t, map[reflect.Type]int{reflect.TypeOf(resolvedUser{}): 1, reflect.TypeOf(resolvedAddress{}): 1}, calls)
+
+	e := NewEncoder()
+	e.SetClassNameResolver(resolver)
+	assert.NotNil(t, e.Encode(&unresolvedThing{ID: 1}))
+	// without a resolver
+	assert.NotNil(t, NewEncoder().Encode(user))
+}
@@ -288,8 +288,12 @@ func SetValue(dest, v reflect.Value) {
 	// check whether the v is a ref holder
 	if v.IsValid() {
 		if h, ok := v.Interface().(*_refHolder); ok {
+			// the destination is set again when the list changes, such as it is converted
 			h.add(dest)
-			return
+			if !h.value.IsValid() || !h.value.Type().AssignableTo(UnpackPtrType(dest.Type())) {
+				return
+			}
+			v = h.value
 		}
 	}
 	//temporary process, only handle the same type of situation
@@ -342,6 +346,10 @@ func SetValue(dest, v reflect.Value) {
 	if dest.Kind() == reflect.Ptr {
 		dest = UnpackPtrValue(dest)
 		v = UnpackPtrValue(v)
+		if dest.Type() == v.Type() {
+			dest.Set(v)
+			return
+		}
 	}
 
 	kind := dest.Kind()
@@ -360,6 +368,45 @@ func SetValue(dest, v reflect.Value) {
 	dest.Set(v)
 }
 
+// canSetValue reports whether SetValue can set @v to a destination of @destTyp,
+// so that a decoded value of another type, such as of a corrupt input, is an error rather than a panic.
+func canSetValue(destTyp reflect.Type, v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	if _, ok := v.Interface().(*_refHolder); ok {
+		return true
+	}
+
+	// a nil pointer is kept as a pointer by UnpackPtrValue, which can only be set to a pointer or an interface
+	if kind := destTyp.Kind(); kind != reflect.Ptr && kind != reflect.Interface && UnpackPtrValue(v).Kind() == reflect.Ptr {
+		return false
+	}
+	elemTyp := UnpackPtrType(destTyp)
+	if UnpackPtrType(v.Type()).AssignableTo(elemTyp) {
+		return true
+	}
+	item := UnpackPtrValue(v).Interface()
+	switch {
+	case validateFloatKind(elemTyp.Kind()):
+		switch item.(type) {
+		case float64, float32:
+			return true
+		}
+	case validateIntKind(elemTyp.Kind()):
+		switch item.(type) {
+		case int64, int32, int, int16, int8:
+			return true
+		}
+	case validateUintKind(elemTyp.Kind()):
+		switch item.(type) {
+		case uint64, int64, int32, uint32:
+			return true
+		}
+	}
+	return false
+}
+
 // AddrEqual compares addrs
 func AddrEqual(x, y interface{}) bool {
 	if x == nil || y == nil {
@@ -389,10 +436,25 @@ func SetSlice(dest reflect.Value, objects interface{}) error {
 	elemKind := destTyp.Elem().Kind()
 	if elemKind == reflect.Uint8 {
 		// for binary
-		dest.Set(EnsureRawValue(objects))
+		v := EnsureRawValue(objects)
+		if destTyp.Kind() == reflect.Array && v.Kind() == reflect.Slice {
+			if v.Len() != destTyp.Len() {
+				return perrors.Errorf("can not assign %d bytes to %v", v.Len(), destTyp)
+			}
+			reflect.Copy(dest, v)
+			return nil
+		}
+		dest.Set(v)
 		return nil
 	}
 
+	// a ref to a decoded list
+	if v, ok := objects.(reflect.Value); ok && v.IsValid() {
+		if ref, ok := v.Interface().(*_refHolder); ok {
+			objects = ref
+		}
+	}
+
 	if ref, ok := objects.(*_refHolder); ok {
 		v, err := ConvertSliceValueType(destTyp, ref.value)
 		if err != nil {
@@ -430,6 +492,10 @@ func ConvertSliceValueType(destTyp reflect.Type, v reflect.Value) (reflect.Value
 		return _zeroValue, perrors.Errorf("expect slice type, but get %v, objects: %v", k, v)
 	}
 
+	// a list is only assigned to an array of the same length
+	if destTyp.Kind() == reflect.Array && v.Len() != destTyp.Len() {
+		return _zeroValue, perrors.Errorf("can not assign a list of %d elements to %v", v.Len(), destTyp)
+	}
 	if v.Len() <= 0 {
 		return _zeroValue, nil
 	}
@@ -440,7 +506,12 @@ func ConvertSliceValueType(destTyp reflect.Type, v reflect.Value) (reflect.Value
 	elemIntType := validateIntKind(elemKind)
 	elemUintType := validateUintKind(elemKind)
 
-	sl := reflect.MakeSlice(destTyp, v.Len(), v.Len())
+	var sl reflect.Value
+	if destTyp.Kind() == reflect.Array {
+		sl = reflect.New(destTyp).Elem()
+	} else {
+		sl = reflect.MakeSlice(destTyp, v.Len(), v.Len())
+	}
 	var itemValue reflect.Value
 	for i := 0; i < v.Len(); i++ {
 		item := v.Index(i).Interface()
@@ -453,6 +524,9 @@ func ConvertSliceValueType(destTyp reflect.Type, v reflect.Value) (reflect.Value
 		if !elemPtrType && itemValue.Kind() == reflect.Ptr {
 			itemValue = UnpackPtrValue(itemValue)
 		}
+		if !canSetValue(destTyp.Elem(), itemValue) {
+			return _zeroValue, perrors.Errorf("can not assign %v to the element of %v", itemValue.Type(), destTyp)
+		}
 
 		switch {
 		case elemFloatType:
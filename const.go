@@ -200,11 +200,18 @@ const (
 	FLAG_EVENT   = byte(0x20) // for heartbeat
 	SERIAL_MASK  = 0x1f
 
+	// the serialization id of hessian2 in dubbo
+	SERIAL_ID_HESSIAN2 = byte(0x02)
+
 	DUBBO_VERSION                          = "2.5.4"
 	DUBBO_VERSION_KEY                      = "dubbo"
 	DEFAULT_DUBBO_PROTOCOL_VERSION         = "2.0.2" // Dubbo RPC protocol version, for compatibility, it must not be between 2.0.10 ~ 2.6.2
 	LOWEST_VERSION_FOR_RESPONSE_ATTACHMENT = 2000200
-	DEFAULT_LEN                            = 8388608 // 8 * 1024 * 1024 default body max length
+	DEFAULT_LEN                            = 8388608  // 8 * 1024 * 1024 default body max length
+	DEFAULT_MAX_DECODE_DEPTH               = 512      // default max nesting depth of the lists, maps and objects
+	DEFAULT_MAX_DECODE_ELEMENTS            = 16777216 // 16 * 1024 * 1024 default max elements of a decoded value
+	DEFAULT_MAX_DECODE_STRING_LENGTH       = 67108864 // 64 * 1024 * 1024 default max chars of a decoded string
+	DEFAULT_MAX_DECODE_BINARY_LENGTH       = 67108864 // 64 * 1024 * 1024 default max bytes of a decoded binary
 )
 
 // regular
@@ -230,6 +237,16 @@ var (
 	ErrBodyNotEnough   = perrors.New("body buffer too short")
 	ErrJavaException   = perrors.New("got java exception")
 	ErrIllegalPackage  = perrors.New("illegal package!")
+	ErrPayloadTooLarge = perrors.New("payload too large")
+	ErrUintOverflow    = perrors.New("unsigned integer overflows java long")
+	ErrIntOverflow     = perrors.New("integer overflows go field")
+	// ErrUnknownJavaEnum is the cause of the error returned when the name of a registered java enum
+	// is unknown to its go type. The enum name string is returned together with the error. Inside
+	// a list, a map or an object there is no error, the element keeps the name string, and a struct
+	// field of the enum type is set to InvalidJavaEnum, so the decoding goes on.
+	ErrUnknownJavaEnum = perrors.New("unknown java enum name")
+	// ErrUnsupportedSerialization is returned by DecodeHeader for a package of a serialization other than hessian2.
+	ErrUnsupportedSerialization = perrors.New("unsupported serialization")
 )
 
 // DescRegex ...
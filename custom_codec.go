@@ -0,0 +1,78 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+)
+
+// Codec is implemented by a go type which encodes and decodes itself, like json.Marshaler and
+// json.Unmarshaler, such as a type sent as a string of a java format. It takes the place of
+// the POJO registry and the serializers for the type.
+//
+// EncodeHessian writes exactly one hessian value by the encoder, usually by calling e.Encode with
+// another value, such as the string form. It should not call e.Encode with the receiver itself.
+//
+// DecodeHessian reads exactly the one value written by EncodeHessian, usually by calling d.Decode once
+// and converting the result. Reading less or more than the value, such as peeking at its bytes
+// without decoding them, misplaces the following values. A null is passed to DecodeHessian, except
+// for a field of a pointer type, which is left nil by a null without calling it.
+//
+// The encoder calls EncodeHessian for any value of the type, and for a struct field whose pointer
+// implements it. The decoder knows the type only for the fields of a struct, so it calls
+// DecodeHessian for the struct fields. A top level value, a list element or a map value is decoded
+// by calling DecodeHessian with the decoder directly, or by the DecodeHessian of a parent type.
+type Codec interface {
+	EncodeHessian(e *Encoder) error
+	DecodeHessian(d *Decoder) error
+}
+
+var codecType = reflect.TypeOf((*Codec)(nil)).Elem()
+
+// fieldCodec returns the Codec of a struct field @field, which is of a type implementing Codec,
+// or whose pointer implements it.
+func fieldCodec(field reflect.Value) (Codec, bool) {
+	if field.Kind() == reflect.Interface || field.Type().Implements(codecType) ||
+		!reflect.PtrTo(field.Type()).Implements(codecType) {
+		return nil, false
+	}
+	if !field.CanAddr() {
+		// a field of a struct passed by value
+		p := reflect.New(field.Type())
+		p.Elem().Set(field)
+		field = p.Elem()
+	}
+	return field.Addr().Interface().(Codec), true
+}
+
+// decCodecField decodes the struct field @field by its DecodeHessian, which returns false
+// if the type of the field does not implement Codec.
+func (d *Decoder) decCodecField(field reflect.Value) (bool, error) {
+	if field.Kind() == reflect.Ptr && field.Type().Implements(codecType) {
+		// a null leaves the pointer nil
+		if d.peekByte() == BC_NULL {
+			_, err := d.readByte()
+			return true, err
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return true, field.Interface().(Codec).DecodeHessian(d)
+	}
+	if field.Kind() != reflect.Interface && reflect.PtrTo(field.Type()).Implements(codecType) {
+		return true, field.Addr().Interface().(Codec).DecodeHessian(d)
+	}
+	return false, nil
+}
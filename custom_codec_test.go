@@ -0,0 +1,107 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// dottedVersion is sent as a string such as "1.2"
+type dottedVersion struct {
+	Major int
+	Minor int
+}
+
+func (v dottedVersion) JavaClassName() string {
+	return "test.DottedVersion"
+}
+
+func (v *dottedVersion) EncodeHessian(e *Encoder) error {
+	return e.Encode(fmt.Sprintf("%d.%d", v.Major, v.Minor))
+}
+
+func (v *dottedVersion) DecodeHessian(d *Decoder) error {
+	s, err := d.Decode()
+	if err != nil {
+		return err
+	}
+	str, ok := s.(string)
+	if !ok {
+		return perrors.Errorf("version %v is not a string", s)
+	}
+	_, err = fmt.Sscanf(str, "%d.%d", &v.Major, &v.Minor)
+	return err
+}
+
+type versionHolder struct {
+	Name    string         `hessian:"name"`
+	Version dottedVersion  `hessian:"version"`
+	Latest  *dottedVersion `hessian:"latest"`
+	Count   int32          `hessian:"count"`
+}
+
+func (versionHolder) JavaClassName() string {
+	return "test.VersionHolder"
+}
+
+func TestCodec(t *testing.T) {
+	// the codec takes the place of the registered POJO
+	RegisterPOJO(&dottedVersion{})
+	RegisterPOJO(&versionHolder{})
+
+	in := &versionHolder{Name: "app", Version: dottedVersion{Major: 1, Minor: 2}, Latest: &dottedVersion{Major: 3, Minor: 4}, Count: 5}
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(in))
+	assert.True(t, bytes.Contains(e.Buffer(), encString(nil, "1.2")))
+	assert.True(t, bytes.Contains(e.Buffer(), encString(nil, "3.4")))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, in, res)
+
+	// a struct passed by value and a nil pointer field
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(versionHolder{Version: dottedVersion{Major: 1}}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &versionHolder{Version: dottedVersion{Major: 1}}, res)
+
+	// a top level value is decoded by calling DecodeHessian directly
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&dottedVersion{Major: 6, Minor: 7}))
+	assert.Equal(t, encString(nil, "6.7"), e.Buffer())
+	var v dottedVersion
+	assert.Nil(t, v.DecodeHessian(NewDecoder(e.Buffer())))
+	assert.Equal(t, dottedVersion{Major: 6, Minor: 7}, v)
+
+	e = NewEncoder()
+	assert.Nil(t, e.Encode((*dottedVersion)(nil)))
+	assert.Equal(t, []byte{BC_NULL}, e.Buffer())
+
+	// the error of DecodeHessian
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&GenericObject{ClassName: "test.VersionHolder", Fields: map[string]interface{}{
+		"name": "app", "version": int32(1), "latest": nil, "count": int32(0),
+	}, fieldNames: []string{"name", "version", "latest", "count"}}))
+	_, err = NewDecoder(e.Buffer()).Decode()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "DecodeHessian field name:version")
+}
@@ -70,7 +70,10 @@ func (d *Decoder) decDate(flag int32) (time.Time, error) {
 	if flag != TAG_READ {
 		tag = byte(flag)
 	} else {
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return t, perrors.WithStack(err)
+		}
 	}
 
 	switch {
@@ -78,30 +81,68 @@ func (d *Decoder) decDate(flag int32) (time.Time, error) {
 		return ZeroDate, nil
 	case tag == BC_DATE: //'d': //date
 		s = buf[:8]
-		l, err = d.next(s)
+		l, err = d.readFull(s)
 		if err != nil {
 			return t, err
 		}
 		if l != 8 {
-			return t, ErrNotEnoughBuf
+			return t, d.offsetError(ErrShortBuffer)
 		}
 		i64 = UnpackInt64(s)
-		return time.Unix(i64/1000, i64%1000*10e5), nil
+		return time.Unix(i64/1000, i64%1000*10e5).In(d.location), nil
 		// return time.Unix(i64/1000, i64*100), nil
 
 	case tag == BC_DATE_MINUTE:
 		s = buf[:4]
-		l, err = d.next(s)
+		l, err = d.readFull(s)
 		if err != nil {
 			return t, err
 		}
 		if l != 4 {
-			return t, ErrNotEnoughBuf
+			return t, d.offsetError(ErrShortBuffer)
 		}
 		i64 = int64(UnpackInt32(s))
-		return time.Unix(i64*60, 0), nil
+		return time.Unix(i64*60, 0).In(d.location), nil
 
 	default:
-		return t, perrors.Errorf("decDate Invalid type: %v", tag)
+		return t, d.tagErrorf("decDate Invalid type: %#x", tag)
+	}
+}
+
+// decTime decodes a date, or an object decoded into time.Time such as java.sql.Timestamp.
+func (d *Decoder) decTime() (time.Time, error) {
+	if b := d.peek(1); len(b) == 0 || b[0] == BC_NULL || b[0] == BC_DATE || b[0] == BC_DATE_MINUTE {
+		return d.decDate(TAG_READ)
+	}
+
+	// the time.Time field gets the time.Time of a java.sql or java.time object in the epoch millis mode too
+	epochMillis := d.epochMillis
+	d.epochMillis = false
+	v, err := d.Decode()
+	d.epochMillis = epochMillis
+	if err != nil {
+		return ZeroDate, perrors.WithStack(err)
 	}
+	switch t := v.(type) {
+	case nil:
+		return ZeroDate, nil
+	case time.Time:
+		return t, nil
+	}
+	return ZeroDate, perrors.Errorf("can not decode %T into time.Time", v)
+}
+
+// decodedTime gets the value which the decoder returns for the time @t, which is its epoch millis
+// in the epoch millis mode, see SetEpochMillis.
+func (d *Decoder) decodedTime(t time.Time) interface{} {
+	if d.epochMillis {
+		return unixMillis(t)
+	}
+	return t
+}
+
+// unixMillis returns the epoch millis of @t like Time.UnixMilli of go1.17, which does not overflow
+// for the dates far from 1970 as UnixNano does.
+func unixMillis(t time.Time) int64 {
+	return t.Unix()*1000 + int64(t.Nanosecond()/1e6)
 }
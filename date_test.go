@@ -22,8 +22,14 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+import (
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+	"github.com/apache/dubbo-go-hessian2/java_sql"
+)
+
 func init() {
 	RegisterPOJO(&DateDemo{})
+	RegisterPOJO(&epochMillisEvent{})
 }
 
 type DateDemo struct {
@@ -125,11 +131,11 @@ func TestEncDateNull(t *testing.T) {
 	res, _ = d.Decode()
 	assert.Equal(t, ZeroDate, res.(*DateDemo).Date)
 	assert.Equal(t, 2, len(res.(*DateDemo).Dates))
-	assert.Equal(t, tz.Local().String(), (*res.(*DateDemo).Dates[0]).String())
+	assert.Equal(t, tz.UTC().String(), (*res.(*DateDemo).Dates[0]).String())
 	assert.Equal(t, &ZeroDate, res.(*DateDemo).NilDate)
 	assert.Equal(t, ZeroDate, *res.(*DateDemo).Date1)
-	assert.Equal(t, tz.Local().String(), (*res.(*DateDemo).Date2).String())
-	assert.Equal(t, tz.Local().String(), (*(*res.(*DateDemo).Date3)).String())
+	assert.Equal(t, tz.UTC().String(), (*res.(*DateDemo).Date2).String())
+	assert.Equal(t, tz.UTC().String(), (*(*res.(*DateDemo).Date3)).String())
 
 }
 
@@ -153,3 +159,93 @@ func doTestDateNull(t *testing.T, method string) {
 		assert.Equal(t, &ZeroDate, r.(*DateDemo).Date1)
 	})
 }
+
+func TestDecDateLocation(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.FixedZone("UTC+8", 8*3600))
+	minute := time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(ts))
+	assert.Nil(t, e.Encode(minute))
+
+	// UTC by default
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, time.UTC, res.(time.Time).Location())
+	assert.True(t, ts.Equal(res.(time.Time)))
+
+	// only the location differs, the instant is the same
+	loc := time.FixedZone("UTC-5", -5*3600)
+	d := NewDecoder(e.Buffer())
+	d.SetLocation(loc)
+	for _, expected := range []time.Time{ts, minute} {
+		res, err = d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, loc, res.(time.Time).Location())
+		assert.True(t, expected.Equal(res.(time.Time)), "%v", res)
+	}
+}
+
+type epochMillisEvent struct {
+	Created time.Time
+	Updated int64
+}
+
+func (epochMillisEvent) JavaClassName() string {
+	return "test.EpochMillisEvent"
+}
+
+func TestDecodeEpochMillis(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.FixedZone("UTC+8", 8*3600))
+	minute := time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(ts))
+	assert.Nil(t, e.Encode(minute))
+	assert.Nil(t, e.Encode(java_sql.NewTimestamp(ts)))
+	assert.Nil(t, e.Encode(java8_time.NewInstant(ts)))
+	assert.Nil(t, e.Encode(java8_time.NewZonedDateTime(ts.UTC())))
+	assert.Nil(t, e.Encode([]time.Time{ts, minute}))
+	assert.Nil(t, e.Encode(&epochMillisEvent{Created: ts, Updated: 7}))
+	e.Append([]byte{BC_NULL})
+
+	d := NewDecoder(e.Buffer())
+	d.SetEpochMillis(true)
+	for _, expected := range []time.Time{ts, minute, ts, ts, ts} {
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, expected.UnixNano()/1e6, res)
+	}
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{ts.UnixNano() / 1e6, minute.UnixNano() / 1e6}, res)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	event := res.(*epochMillisEvent)
+	assert.True(t, ts.Equal(event.Created))
+	assert.Equal(t, int64(7), event.Updated)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+
+	// a java.util.Date field of a java object into an int64 field
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&GenericObject{ClassName: "test.EpochMillisEvent", Fields: map[string]interface{}{
+		"created": ts,
+		"updated": minute,
+	}}))
+	d = NewDecoder(e.Buffer())
+	d.SetEpochMillis(true)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, minute.UnixNano()/1e6, res.(*epochMillisEvent).Updated)
+
+	// the epoch millis into an int64 out parameter
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(ts))
+	d = NewDecoder(e.Buffer())
+	d.SetEpochMillis(true)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	var out int64
+	assert.Nil(t, ReflectResponse(res, &out))
+	assert.Equal(t, ts.UnixNano()/1e6, out)
+}
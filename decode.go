@@ -17,8 +17,12 @@ package hessian
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 import (
@@ -27,57 +31,431 @@ import (
 
 // Decoder struct
 type Decoder struct {
-	reader *bufio.Reader
-	refs   []interface{}
+	reader  *bufio.Reader
+	counter *readCounter  // counts the bytes read by reader from its source
+	buf     *bytes.Reader // the source of reader if the decoder is created by NewDecoder
+	refs    []interface{}
 	// record type refs, both list and map need it
 	// todo: map
 	typeRefs      *TypeRefs
 	classInfoList []classInfo
+	generic       bool // decode every object into a *GenericObject
+
+	maxDepth    int // max nesting depth of the lists, maps and objects
+	maxElements int // max elements of a top level value, including list elements, map entries and object fields
+	depth       int
+	elements    int
+
+	maxStringLength int // max chars of a string, including all of its chunks
+	maxBinaryLength int // max bytes of a binary kept in memory, including all of its chunks
+
+	binaryStreamThreshold int           // a binary longer than it is decoded into an io.Reader
+	binary                *binaryReader // the streaming binary which has not been read to the end
+
+	location *time.Location // the location of the decoded dates
+
+	capturing int    // the depth of the RawValue being captured
+	raw       []byte // the bytes read while capturing
+
+	strict bool     // return an UnknownClassError for an unregistered java class
+	path   []string // the names of the fields being decoded, only tracked in strict mode
+
+	refListener func(RefEvent) // diagnostic listener of the refs defined and used
+
+	int64Mode bool // decode every int into int64
+
+	epochMillis bool // decode the dates into int64 epoch millis
+
+	classNameRewriter func(string) string // rewrites the class names read from the wire
+
+	v1 bool // decode the hessian 1.0 wire format, see NewDecoderV1
+
+	skipNull bool // leave the fields and the map entries bound to nulls untouched
+
+	typedPOJOLists bool // decode the lists of a registered POJO into typed slices
+
+	observing     bool   // the top level value is being observed, or is not to be observed
+	observedClass string // the java class of the top level value being observed
 }
 
 // Error part
 var (
-	ErrNotEnoughBuf    = perrors.Errorf("not enough buf")
+	// ErrShortBuffer is returned when the decoder needs more bytes than the input has, such as
+	// a partial frame, which means more data is needed instead of the data is corrupt.
+	// io.EOF is returned instead if the input ends right before a top level value.
+	// The errors returned by the decoder add the offset of the input to ErrShortBuffer and
+	// ErrIllegalRefIndex, compare their perrors.Cause with them.
+	ErrShortBuffer = perrors.New("short buffer")
+	// ErrNotEnoughBuf is the same as ErrShortBuffer.
+	//
+	// Deprecated: use ErrShortBuffer.
+	ErrNotEnoughBuf    = ErrShortBuffer
 	ErrIllegalRefIndex = perrors.Errorf("illegal ref index")
+
+	ErrMaxDepthExceeded        = perrors.New("max decode depth exceeded")
+	ErrMaxElementsExceeded     = perrors.New("max decode elements exceeded")
+	ErrMaxStringLengthExceeded = perrors.New("max decode string length exceeded")
+	ErrMaxBinaryLengthExceeded = perrors.New("max decode binary length exceeded")
+
+	// ErrDecodePanic is returned instead of panicking when the decoder panics on a malformed input,
+	// which is a bug of the decoder to be reported. The decoder should not be used after it.
+	ErrDecodePanic = perrors.New("decode panic")
 )
 
 // NewDecoder generate a decoder instance
 func NewDecoder(b []byte) *Decoder {
-	return &Decoder{reader: bufio.NewReader(bytes.NewReader(b)), typeRefs: &TypeRefs{records: map[string]bool{}}}
+	buf := bytes.NewReader(b)
+	d := NewDecoderFromReader(buf)
+	d.buf = buf
+	return d
+}
+
+// NewDecoderFromReader generate a decoder instance which pulls bytes from @r on demand
+// while walking the object graph, so the whole frame needn't be in memory before decoding.
+func NewDecoderFromReader(r io.Reader) *Decoder {
+	counter := &readCounter{r: r}
+	return &Decoder{
+		reader:          bufio.NewReader(counter),
+		counter:         counter,
+		typeRefs:        &TypeRefs{records: map[string]bool{}},
+		maxDepth:        DEFAULT_MAX_DECODE_DEPTH,
+		maxElements:     DEFAULT_MAX_DECODE_ELEMENTS,
+		maxStringLength: DEFAULT_MAX_DECODE_STRING_LENGTH,
+		maxBinaryLength: DEFAULT_MAX_DECODE_BINARY_LENGTH,
+		location:        time.UTC,
+	}
+}
+
+// Reset discards the state of the decoder and makes it decode @b from the beginning,
+// so that one decoder can be reused to decode many frames without allocating a new one
+// for every frame. The ref table, the type refs and the class definitions of the previous
+// frame are cleared, while the settings such as the max depth are kept.
+func (d *Decoder) Reset(b []byte) {
+	if d.buf == nil {
+		d.buf = bytes.NewReader(b)
+	} else {
+		d.buf.Reset(b)
+	}
+	d.counter.r, d.counter.n = d.buf, 0
+	d.reader.Reset(d.counter)
+
+	d.resetTables()
+	d.depth = 0
+	d.elements = 0
+	d.binary = nil
+	d.capturing = 0
+	d.raw = d.raw[:0]
+	d.path = d.path[:0]
+}
+
+// resetTables clears the ref table, the type refs and the class definitions, which are numbered from the beginning of a stream.
+func (d *Decoder) resetTables() {
+	for i := range d.refs {
+		d.refs[i] = nil
+	}
+	d.refs = d.refs[:0]
+	d.typeRefs.typeRefs = d.typeRefs.typeRefs[:0]
+	d.typeRefs.typeNames = d.typeRefs.typeNames[:0]
+	for name := range d.typeRefs.records {
+		delete(d.typeRefs.records, name)
+	}
+	for i := range d.classInfoList {
+		d.classInfoList[i] = classInfo{}
+	}
+	d.classInfoList = d.classInfoList[:0]
+}
+
+// Offset returns the number of the input bytes the decoder has consumed, which is the offset of the next
+// top level value after a Decode, such as to locate a value in a file of many. The unread bytes of a binary
+// streamed by SetBinaryStreamThreshold are not counted until they are read.
+func (d *Decoder) Offset() int {
+	return int(d.counter.n) - d.reader.Buffered()
+}
+
+// SetMaxDepth sets the max nesting depth of the lists, maps and objects, which is
+// DEFAULT_MAX_DECODE_DEPTH by default. There is no limit if @depth is not positive.
+func (d *Decoder) SetMaxDepth(depth int) {
+	d.maxDepth = depth
+}
+
+// SetMaxElements sets the max total number of the list elements, map entries and object fields
+// of a top level value, which is DEFAULT_MAX_DECODE_ELEMENTS by default.
+// There is no limit if @elements is not positive.
+func (d *Decoder) SetMaxElements(elements int) {
+	d.maxElements = elements
+}
+
+// SetMaxStringLength sets the max length of a string in java chars, counting all of its chunks, which is
+// DEFAULT_MAX_DECODE_STRING_LENGTH by default. There is no limit if @length is not positive.
+func (d *Decoder) SetMaxStringLength(length int) {
+	d.maxStringLength = length
+}
+
+// SetMaxBinaryLength sets the max length of a binary in bytes, counting all of its chunks, which is
+// DEFAULT_MAX_DECODE_BINARY_LENGTH by default. There is no limit if @length is not positive.
+// A binary streamed by SetBinaryStreamThreshold is not limited, for it is not kept in memory.
+func (d *Decoder) SetMaxBinaryLength(length int) {
+	d.maxBinaryLength = length
+}
+
+// SetLocation sets the location of the time.Time decoded from the dates, which is time.UTC
+// by default so that the result doesn't depend on the local zone of the process.
+// The location only changes how the time is displayed, the instant is the same.
+func (d *Decoder) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	d.location = loc
+}
+
+// SetStrict sets whether the decoder returns an *UnknownClassError for the java class of an object,
+// a typed map or a typed list which is neither registered as POJO nor has a Serializer or a DecodeHook,
+// instead of decoding it leniently, such as into a map or a *GenericObject. The java collection classes
+// of the packages java.* and javax.* and the immutable collections of guava are always known. It is off by default.
+func (d *Decoder) SetStrict(strict bool) {
+	d.strict = strict
+}
+
+// SetInt64Mode sets whether the decoder decodes every int into int64, so that a generic consumer gets
+// the same type for the numbers written as either int or long. It applies to the ints in the lists, maps
+// and objects too, and a java int[] is decoded into []int64. It is off by default, which decodes an int
+// into int32 and a long into int64.
+func (d *Decoder) SetInt64Mode(int64Mode bool) {
+	d.int64Mode = int64Mode
+}
+
+// SetEpochMillis sets whether the decoder decodes the dates into their int64 milliseconds since the epoch
+// instead of time.Time, so that the decoded values carry no zone. It applies to java.util.Date, either compact
+// or not, java.sql.Date, java.sql.Timestamp, java.time.Instant and java.time.ZonedDateTime, whose sub-millisecond
+// nanos are truncated, and to the typed lists of java.util.Date too, which are decoded into []int64. The zone-less
+// java.time.LocalDate and java.time.LocalDateTime are left as they are. A time.Time field of a POJO still gets
+// the time.Time, and an int64 field accepts a java.util.Date as its epoch millis. It is off by default.
+func (d *Decoder) SetEpochMillis(epochMillis bool) {
+	d.epochMillis = epochMillis
+}
+
+// SetSkipNull sets whether the decoder leaves a struct field untouched when its value is null, instead of
+// overwriting it, such as for the partial updates whose nulls mean no change. It applies to the fields of any
+// type, so that a string field keeps its value rather than getting the string "null", and a number field
+// rather than failing, and to the go maps bound to the maps, which leave out the keys of the null values
+// instead of holding the zero values for them. The values owned by the caller keep their fields for the nulls
+// too: the instances of a POJOFactory are not zeroed, and Decoder.ReflectResponse binds a map into the struct
+// of its @out instead of a zero one. It is off by default.
+func (d *Decoder) SetSkipNull(skip bool) {
+	d.skipNull = skip
+}
+
+// SetTypedPOJOLists sets whether the decoder decodes a java collection whose elements are all of the same
+// registered POJO, such as a java.util.ArrayList<com.foo.Bar>, into a typed slice like []*Bar instead of
+// a []interface{}. A null or another element keeps the []interface{}. It is off by default, and either way
+// ReflectResponse assigns the list to a *[]*Bar out parameter.
+func (d *Decoder) SetTypedPOJOLists(typed bool) {
+	d.typedPOJOLists = typed
+}
+
+// SetRefListener sets a listener which is called when the decoder defines a ref for an object,
+// a list or a map, and when a ref tag refers to it, so that a diagnostic tool can rebuild
+// the back-reference graph of a payload. It is only for debugging and nil by default.
+func (d *Decoder) SetRefListener(listener func(RefEvent)) {
+	d.refListener = listener
+}
+
+// SetClassNameRewriter sets a function which rewrites every java class name read from the wire before it is
+// looked up, such as to decode com.old.Foo as com.new.Foo which is registered. It applies to the classes of
+// the objects at any depth and to the types of the typed lists and maps, which are rewritten before the unknown
+// class falls back, so a rewrite can rescue a class which is not registered. The element class of a typed list
+// like "[com.old.Foo" is rewritten without the brackets. It is nil by default, which keeps the names.
+func (d *Decoder) SetClassNameRewriter(rewriter func(string) string) {
+	d.classNameRewriter = rewriter
+}
+
+// rewriteClassName rewrites the java class name @name read from the wire by the ClassNameRewriter.
+func (d *Decoder) rewriteClassName(name string) string {
+	if d.classNameRewriter == nil || name == "" {
+		return name
+	}
+	elem := strings.TrimLeft(name, "[")
+	return name[:len(name)-len(elem)] + d.classNameRewriter(elem)
+}
+
+// enterContainer is called before decoding the elements of a list, map or object,
+// and leaveContainer should be called after them if it returns no error.
+func (d *Decoder) enterContainer() error {
+	if d.depth == 0 {
+		d.elements = 0
+	}
+	if d.maxDepth > 0 && d.depth >= d.maxDepth {
+		return perrors.Wrapf(ErrMaxDepthExceeded, "max depth %d", d.maxDepth)
+	}
+	d.depth++
+	return nil
+}
+
+func (d *Decoder) leaveContainer() {
+	d.depth--
+}
+
+// addElements counts @n elements of the value being decoded, which should be called
+// before allocating the memory for them.
+func (d *Decoder) addElements(n int) error {
+	d.elements += n
+	if d.maxElements > 0 && (n < 0 || d.elements > d.maxElements) {
+		return perrors.Wrapf(ErrMaxElementsExceeded, "max elements %d", d.maxElements)
+	}
+	return nil
 }
 
 /////////////////////////////////////////
 // utilities
 /////////////////////////////////////////
 
-// peek a byte
+// all the reading of Decoder should go through the following functions,
+// no matter the bytes come from a buffer or a stream.
+// They return ErrShortBuffer if the input ends before the bytes to read.
+
+// shortBuffer converts the EOF errors of reading into ErrShortBuffer.
+func (d *Decoder) shortBuffer(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return d.offsetError(ErrShortBuffer)
+	}
+	return err
+}
+
+// offsetError is an error of decoding at an offset of the input, whose Cause is the error.
+type offsetError struct {
+	err    error
+	offset int
+}
+
+func (e *offsetError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.err, e.offset)
+}
+
+func (e *offsetError) Cause() error {
+	return e.err
+}
+
+func (e *offsetError) Unwrap() error {
+	return e.err
+}
+
+// offsetError adds the current offset of the input to @err, such as "short buffer at offset 137".
+func (d *Decoder) offsetError(err error) error {
+	return &offsetError{err: err, offset: d.Offset()}
+}
+
+// tagErrorf returns the error of the illegal tag which has just been read, whose message ends with the offset
+// of the tag in the input, such as "invalid type tag 0x4f at offset 137", to be located in a hex dump.
+func (d *Decoder) tagErrorf(format string, args ...interface{}) error {
+	return perrors.Errorf(format+" at offset %d", append(args, d.Offset()-1)...)
+}
+
+// peek a byte, which is 0 at the end of the input, so that the following reading returns ErrShortBuffer
 func (d *Decoder) peekByte() byte {
-	return d.peek(1)[0]
+	b := d.peek(1)
+	if len(b) == 0 {
+		return 0
+	}
+	return b[0]
 }
 
-// get the buffer length
-func (d *Decoder) len() int {
-	d.peek(1) // peek one byte to get the buffer length
-	return d.reader.Buffered()
+// checkRemaining returns ErrShortBuffer if the input of a decoder created by NewDecoder has less than
+// @n bytes left, so that a declared length is checked before allocating the memory for it.
+func (d *Decoder) checkRemaining(n int) error {
+	if d.buf != nil && d.reader.Buffered()+d.buf.Len() < n {
+		return d.offsetError(ErrShortBuffer)
+	}
+	return nil
 }
 
 // read a byte from Decoder, advance the ptr
 func (d *Decoder) readByte() (byte, error) {
-	return d.reader.ReadByte()
+	d.drainBinary()
+	b, err := d.reader.ReadByte()
+	if err == nil && d.capturing > 0 {
+		d.raw = append(d.raw, b)
+	}
+	return b, d.shortBuffer(err)
 }
 
 // unread a byte
 func (d *Decoder) unreadByte() error {
-	return d.reader.UnreadByte()
+	err := d.reader.UnreadByte()
+	if err == nil && d.capturing > 0 && len(d.raw) > 0 {
+		d.raw = d.raw[:len(d.raw)-1]
+	}
+	return err
+}
+
+// read exactly len(b) bytes, and return the length of b.
+// The bytes are copied from the buffer of the reader, so that @b doesn't escape to the heap,
+// and the callers can read into an array on the stack without allocating.
+func (d *Decoder) readFull(b []byte) (int, error) {
+	d.drainBinary()
+	n := 0
+	for n < len(b) {
+		size := len(b) - n
+		if size > d.reader.Size() {
+			size = d.reader.Size()
+		}
+		p, err := d.reader.Peek(size)
+		copied := copy(b[n:], p)
+		if d.capturing > 0 {
+			d.raw = append(d.raw, p[:copied]...)
+		}
+		_, _ = d.reader.Discard(copied)
+		n += copied
+		if err != nil && n < len(b) {
+			return n, d.shortBuffer(err)
+		}
+	}
+	return n, nil
+}
+
+// read a utf8 rune
+// Only the bytes the rune needs are peeked, one at a time as its lead byte tells, so that reading
+// the last rune of a frame does not wait for more bytes from a stream, such as an open connection.
+func (d *Decoder) readRune() (rune, int, error) {
+	d.drainBinary()
+	p, err := d.reader.Peek(1)
+	if err != nil {
+		return 0, 0, d.shortBuffer(err)
+	}
+	for k := 2; k <= utf8SeqLen(p[0]); k++ {
+		if p, err = d.reader.Peek(k); err != nil {
+			// a partial rune at the end of the input
+			return 0, 0, d.shortBuffer(err)
+		}
+		if p[k-1]&0xc0 != 0x80 {
+			// not a continuation byte, the lead byte is decoded as utf8.RuneError
+			break
+		}
+	}
+	r, n := utf8.DecodeRune(p)
+	if d.capturing > 0 {
+		// capture the original bytes, which are not utf8.RuneError for an invalid rune
+		d.raw = append(d.raw, p[:n]...)
+	}
+	_, _ = d.reader.Discard(n)
+	return r, n, nil
 }
 
-// read byte arr, and return the length of b
-func (d *Decoder) next(b []byte) (int, error) {
-	return d.reader.Read(b)
+// utf8SeqLen returns the length of the utf8 sequence led by byte @b, which is 1 for an invalid lead byte.
+func utf8SeqLen(b byte) int {
+	switch {
+	case b >= 0xc2 && b <= 0xdf:
+		return 2
+	case b >= 0xe0 && b <= 0xef:
+		return 3
+	case b >= 0xf0 && b <= 0xf4:
+		return 4
+	}
+	return 1
 }
 
 // peek n bytes, will not advance the read ptr
 func (d *Decoder) peek(n int) []byte {
+	d.drainBinary()
 	b, _ := d.reader.Peek(n)
 	return b
 }
@@ -95,7 +473,7 @@ func (d *Decoder) nextRune(s []rune) []rune {
 	n = len(s)
 	s = s[:0]
 	for i = 0; i < n; i++ {
-		if r, ri, err = d.reader.ReadRune(); err == nil && ri > 0 {
+		if r, ri, err = d.readRune(); err == nil && ri > 0 {
 			s = append(s, r)
 		}
 	}
@@ -115,19 +493,31 @@ func (d *Decoder) decType() (string, error) {
 	)
 
 	buf = arr[:1]
-	if _, err = io.ReadFull(d.reader, buf); err != nil {
+	if _, err = d.readFull(buf); err != nil {
 		return "", perrors.WithStack(err)
 	}
 	tag = buf[0]
 	if (tag >= BC_STRING_DIRECT && tag <= STRING_DIRECT_MAX) ||
 		(tag >= 0x30 && tag <= 0x33) || (tag == BC_STRING) || (tag == BC_STRING_CHUNK) {
-		return d.decString(int32(tag))
+		name, err := d.decString(int32(tag))
+		if err == nil {
+			name = d.rewriteClassName(name)
+			d.typeRefs.appendTypeRefs(name, nil)
+			d.observeClass(name)
+		}
+		return name, err
 	}
 
 	if idx, err = d.decInt32(int32(tag)); err != nil {
 		return "", perrors.WithStack(err)
 	}
 
+	// a type ref refers to a type name which has been read
+	if name, ok := d.typeRefs.getName(int(idx)); ok {
+		d.observeClass(name)
+		return name, nil
+	}
+
 	typ, _, err = d.getStructDefByIndex(int(idx))
 	if err == nil {
 		return typ.String(), nil
@@ -137,19 +527,50 @@ func (d *Decoder) decType() (string, error) {
 }
 
 // Decode parse hessian data, and ensure the reflection value unpacked
+//
+// The top level values decoded one after another are a hessian stream like the values written by one encoder,
+// in which the refs, the list types and the class definitions are numbered from the beginning of the stream,
+// so a value can refer to the ones defined by the values before it. io.EOF is returned at the end of the input.
 func (d *Decoder) Decode() (interface{}, error) {
 	return EnsureInterface(d.DecodeValue())
 }
 
+// DecodeNext decodes the next of the independent values concatenated in the input, such as the records of
+// an append-only log which are written by their own encoders. Unlike Decode, the refs, the list types and
+// the class definitions of the values before are cleared, as each value numbers its own from zero.
+// It returns io.EOF at the end of the input, so the values can be decoded in a loop until io.EOF,
+// and Offset tells where the next value starts.
+func (d *Decoder) DecodeNext() (interface{}, error) {
+	d.resetTables()
+	return d.Decode()
+}
+
 // DecodeValue parse hessian data, the return value maybe a reflection value when it's a map, list, object, or ref.
-func (d *Decoder) DecodeValue() (interface{}, error) {
-	var (
-		err error
-		tag byte
-	)
+// Any input either decodes or returns an error, ErrDecodePanic if the decoder panics on it.
+func (d *Decoder) DecodeValue() (_ interface{}, err error) {
+	if d.depth == 0 {
+		defer d.recoverPanic(&err)
+	}
+	if !d.observing {
+		if o := getObserver(); o != nil {
+			return d.observeDecodeValue(o)
+		}
+	}
+
+	var tag byte
 
+	if d.depth == 0 {
+		d.path = d.path[:0]
+	}
+	if d.v1 {
+		return d.decV1Value()
+	}
 	tag, err = d.readByte()
-	if err == io.EOF {
+	if err != nil {
+		// the input ends between the top level values
+		if perrors.Cause(err) == ErrShortBuffer && d.depth == 0 {
+			return nil, io.EOF
+		}
 		return nil, err
 	}
 
@@ -172,14 +593,22 @@ func (d *Decoder) DecodeValue() (interface{}, error) {
 
 	case (0x80 <= tag && tag <= 0xbf) || (0xc0 <= tag && tag <= 0xcf) ||
 		(0xd0 <= tag && tag <= 0xd7) || tag == BC_INT: //'I': //int
-		return d.decInt32(int32(tag))
+		i, err := d.decInt32(int32(tag))
+		if d.int64Mode && err == nil {
+			return int64(i), nil
+		}
+		return i, err
 
 	case (tag >= 0xd8 && tag <= 0xef) || (tag >= 0xf0 && tag <= 0xff) ||
 		(tag >= 0x38 && tag <= 0x3f) || (tag == BC_LONG_INT) || (tag == BC_LONG): //'L': //long
 		return d.decInt64(int32(tag))
 
 	case (tag == BC_DATE_MINUTE) || (tag == BC_DATE): //'d': //date
-		return d.decDate(int32(tag))
+		t, err := d.decDate(int32(tag))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodedTime(t), nil
 
 	case (tag == BC_DOUBLE_ZERO) || (tag == BC_DOUBLE_ONE) || (tag == BC_DOUBLE_BYTE) ||
 		(tag == BC_DOUBLE_SHORT) || (tag == BC_DOUBLE_MILL) || (tag == BC_DOUBLE): //'D': //double
@@ -194,6 +623,9 @@ func (d *Decoder) DecodeValue() (interface{}, error) {
 		// case 'B', 'b': //binary
 	case (tag == BC_BINARY) || (tag == BC_BINARY_CHUNK) || (tag >= 0x20 && tag <= 0x2f) ||
 		(tag >= BC_BINARY_SHORT && tag <= 0x3f):
+		if d.binaryStreamThreshold > 0 {
+			return d.decBinaryStream(tag)
+		}
 		return d.decBinary(int32(tag))
 
 	// case 'V': //list
@@ -210,7 +642,15 @@ func (d *Decoder) DecodeValue() (interface{}, error) {
 		return d.decObject(int32(tag))
 
 	default:
-		return nil, perrors.Errorf("Invalid type: %v,>>%v<<<", string(tag), d.peek(d.len()))
+		return nil, d.tagErrorf("invalid type tag %#x", tag)
+	}
+}
+
+// recoverPanic converts a panic of decoding a top level value into ErrDecodePanic.
+func (d *Decoder) recoverPanic(err *error) {
+	if p := recover(); p != nil {
+		d.depth = 0
+		*err = perrors.Wrapf(ErrDecodePanic, "%v", p)
 	}
 }
 
@@ -218,8 +658,9 @@ func (d *Decoder) DecodeValue() (interface{}, error) {
 // typeRefs
 /////////////////////////////////////////
 type TypeRefs struct {
-	typeRefs []reflect.Type
-	records  map[string]bool // record if existing for type
+	typeRefs  []reflect.Type
+	typeNames []string
+	records   map[string]bool // record if existing for type
 }
 
 // appendTypeRefs add list or map type ref
@@ -229,8 +670,17 @@ func (t *TypeRefs) appendTypeRefs(name string, p reflect.Type) {
 	}
 	t.records[name] = true
 	t.typeRefs = append(t.typeRefs, p)
+	t.typeNames = append(t.typeNames, name)
 }
 
 func (t *TypeRefs) Get(index int) reflect.Type {
 	return t.typeRefs[index]
 }
+
+// getName gets the name of the type ref @index
+func (t *TypeRefs) getName(index int) (string, bool) {
+	if index < 0 || index >= len(t.typeNames) {
+		return "", false
+	}
+	return t.typeNames[index], true
+}
@@ -0,0 +1,180 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"encoding/hex"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+	"github.com/apache/dubbo-go-hessian2/java_exception"
+)
+
+// fuzzSeeds returns the encoded values to be mutated by the fuzzer.
+func fuzzSeeds() [][]byte {
+	RegisterPOJO(&Case{})
+
+	list := []interface{}{int32(1), "x"}
+	values := []interface{}{
+		nil, true, int32(1), int64(1) << 40, 1.5, "abc", []byte{1, 2}, time.Unix(1, 0),
+		[]interface{}{int32(1), "x", map[string]interface{}{"a": int32(1)}},
+		map[interface{}]interface{}{int32(1): "a"},
+		[]interface{}{list, list},
+		[]int32{1, 2}, []int64{1}, []float64{1}, []string{"a"},
+		&Case{A: "a", B: 1}, []*Case{{}, {}}, map[string]*Case{"a": {}},
+		java_exception.NewThrowable("x"),
+		&java8_time.LocalDate{Year: 2020, Month: 1, Day: 1},
+		big.NewInt(12345),
+	}
+
+	seeds := make([][]byte, 0, len(values)+1)
+	for _, v := range values {
+		e := NewEncoder()
+		if err := e.Encode(v); err != nil {
+			panic(err)
+		}
+		seeds = append(seeds, e.Buffer())
+	}
+	// the class definitions and the refs span the top level values
+	e := NewEncoder()
+	_ = e.Encode(&Case{})
+	_ = e.Encode([]*Case{{}})
+	return append(seeds, e.Buffer())
+}
+
+// decodeFuzzInput decodes @b in every mode of the decoder, and returns ErrDecodePanic if any of them panics.
+func decodeFuzzInput(b []byte) error {
+	decoders := []func() *Decoder{
+		func() *Decoder { return NewDecoder(b) },
+		func() *Decoder {
+			d := NewDecoder(b)
+			d.SetGenericMode(true)
+			return d
+		},
+		func() *Decoder {
+			d := NewDecoder(b)
+			d.SetStrict(true)
+			d.SetInt64Mode(true)
+			d.SetEpochMillis(true)
+			return d
+		},
+		func() *Decoder {
+			d := NewDecoder(b)
+			d.SetBinaryStreamThreshold(1)
+			return d
+		},
+		func() *Decoder { return NewDecoderV1(b) },
+	}
+	for _, newDecoder := range decoders {
+		d := newDecoder()
+		for i := 0; i < 3; i++ {
+			_, err := d.Decode()
+			if perrors.Cause(err) == ErrDecodePanic {
+				return err
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func TestDecodeRandomBytes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	seeds := fuzzSeeds()
+	for i := 0; i < 20000; i++ {
+		var b []byte
+		if i%4 == 0 {
+			b = make([]byte, r.Intn(32))
+			r.Read(b)
+		} else {
+			// mutate a few bytes of a valid input, which goes deeper than the random bytes
+			b = append([]byte(nil), seeds[r.Intn(len(seeds))]...)
+			for j := 0; j < 1+r.Intn(3) && len(b) > 0; j++ {
+				b[r.Intn(len(b))] = byte(r.Intn(256))
+			}
+		}
+		if err := decodeFuzzInput(b); err != nil {
+			t.Fatalf("decode %x: %+v", b, err)
+		}
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	RegisterPOJO(&Case{})
+
+	for _, input := range []string{
+		// a class definition of a negative field num
+		"4398c1e34599d116f8d2fd93b2aed55b7d44",
+		// an int in the typed list of com.test.case
+		"730d636f6d2e746573742e6361736592016101626000e0",
+		// a string in the variable typed list of com.test.case
+		"560d636f6d2e746573742e636173659201610162600088",
+		// a long for the cause of a java.lang.Throwable
+		"43136a6176612e6c616e672e5468726f7761626c65951073657269616c56657273696f6e5549440d64657461696c4d6573736167651473" +
+			"757070726573736564457863657074696f6e730a737461636b547261630b05636175736560e0017856145b6a6176612e6c616e672e5468" +
+			"726f7761626c6590561c5b6a6176612e6c616e672e537461636b5472616365456c656d656e7490e5",
+	} {
+		b, _ := hex.DecodeString(input)
+		_, err := NewDecoder(b).Decode()
+		assert.Error(t, err, input)
+		assert.NotEqual(t, ErrDecodePanic, perrors.Cause(err), input)
+	}
+}
+
+type panicPOJO struct{}
+
+func (panicPOJO) JavaClassName() string {
+	return "test.PanicPOJO"
+}
+
+// panicSerializer panics like a buggy serializer
+type panicSerializer struct {
+	objectSerializer
+}
+
+func (panicSerializer) EncObject(*Encoder, POJO) error {
+	return nil
+}
+
+func (panicSerializer) DecInstance(*Decoder, reflect.Type, ClassInfo) (interface{}, error) {
+	panic("broken serializer")
+}
+
+func TestDecodePanic(t *testing.T) {
+	RegisterPOJO(panicPOJO{})
+	SetSerializer(panicPOJO{}.JavaClassName(), panicSerializer{})
+	defer delete(serializerMap, panicPOJO{}.JavaClassName())
+
+	b := []byte{BC_OBJECT_DEF, byte(len(panicPOJO{}.JavaClassName()))}
+	b = append(b, panicPOJO{}.JavaClassName()...)
+	b = append(b, BC_INT_ZERO, BC_OBJECT_DIRECT)
+
+	_, err := NewDecoder(b).Decode()
+	assert.Equal(t, ErrDecodePanic, perrors.Cause(err))
+	assert.Contains(t, err.Error(), "broken serializer")
+}
@@ -0,0 +1,207 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// DecodeHook constructs a go value from the decoded fields of an instance of java class @javaName.
+type DecodeHook func(javaName string, fields map[string]interface{}) (interface{}, error)
+
+type decodeHookEntry struct {
+	prefix string
+	hook   DecodeHook
+}
+
+var decodeHooks = struct {
+	sync.RWMutex
+	entries []decodeHookEntry // sorted by prefix length in descending order
+}{}
+
+// RegisterDecodeHook registers @hook for the java classes whose names start with @prefix, such as
+// "com.mycompany.dto.". An empty prefix matches every class. A hook is only used for the classes
+// which have not been registered as POJO, and the hook of the longest matching prefix wins.
+// Registering a prefix again replaces its hook.
+func RegisterDecodeHook(prefix string, hook DecodeHook) {
+	decodeHooks.Lock()
+	defer decodeHooks.Unlock()
+
+	for i := range decodeHooks.entries {
+		if decodeHooks.entries[i].prefix == prefix {
+			decodeHooks.entries[i].hook = hook
+			return
+		}
+	}
+	decodeHooks.entries = append(decodeHooks.entries, decodeHookEntry{prefix: prefix, hook: hook})
+	sort.SliceStable(decodeHooks.entries, func(i, j int) bool {
+		return len(decodeHooks.entries[i].prefix) > len(decodeHooks.entries[j].prefix)
+	})
+}
+
+// UnregisterDecodeHook removes the hook of @prefix.
+func UnregisterDecodeHook(prefix string) {
+	decodeHooks.Lock()
+	defer decodeHooks.Unlock()
+
+	for i := range decodeHooks.entries {
+		if decodeHooks.entries[i].prefix == prefix {
+			decodeHooks.entries = append(decodeHooks.entries[:i], decodeHooks.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// getDecodeHook gets the hook of the longest prefix matching java class @javaName.
+func getDecodeHook(javaName string) (DecodeHook, bool) {
+	decodeHooks.RLock()
+	defer decodeHooks.RUnlock()
+
+	for _, entry := range decodeHooks.entries {
+		if strings.HasPrefix(javaName, entry.prefix) {
+			return entry.hook, true
+		}
+	}
+	return nil, false
+}
+
+var unknownClassCallback = struct {
+	sync.RWMutex
+	fn func(className string)
+}{}
+
+// OnUnknownClass sets @fn to be called with the name of every java class which is neither
+// registered as POJO nor has a Serializer, once per class for each Decoder when the class
+// definition is read. It is only for observation, such as logging the classes to be registered,
+// and does not change the decoding result. A nil @fn removes the callback.
+func OnUnknownClass(fn func(className string)) {
+	unknownClassCallback.Lock()
+	defer unknownClassCallback.Unlock()
+
+	unknownClassCallback.fn = fn
+}
+
+// checkUnknownClass calls the OnUnknownClass callback if java class @javaName is unknown
+// and the decoder has not met it before.
+func (d *Decoder) checkUnknownClass(javaName string) {
+	unknownClassCallback.RLock()
+	fn := unknownClassCallback.fn
+	unknownClassCallback.RUnlock()
+	if fn == nil {
+		return
+	}
+
+	if _, ok := getStructInfo(javaName); ok {
+		return
+	}
+	if _, ok := GetSerializer(javaName); ok {
+		return
+	}
+	for _, cls := range d.classInfoList {
+		if cls.javaName == javaName {
+			return
+		}
+	}
+	fn(javaName)
+}
+
+// UnknownClassError is returned by a strict decoder for a java class which is neither registered
+// as POJO nor has a Serializer or a DecodeHook. Path is the path of the go struct fields being
+// decoded when the class is met, such as "order.buyer", which is empty at the top level.
+type UnknownClassError struct {
+	ClassName string
+	Path      string
+}
+
+func (e *UnknownClassError) Error() string {
+	if e.Path == "" {
+		return "unregistered java class " + e.ClassName
+	}
+	return e.Path + ": unregistered java class " + e.ClassName
+}
+
+// knownClass checks whether java class @javaName is registered or has a Serializer or a DecodeHook.
+func knownClass(javaName string) bool {
+	if _, ok := getStructInfo(javaName); ok {
+		return true
+	}
+	if _, ok := GetSerializer(javaName); ok {
+		return true
+	}
+	_, ok := getDecodeHook(javaName)
+	return ok
+}
+
+// checkStrictClass returns an *UnknownClassError in strict mode if java class @javaName is unknown.
+func (d *Decoder) checkStrictClass(javaName string) error {
+	if !d.strict || knownClass(javaName) {
+		return nil
+	}
+	return &UnknownClassError{ClassName: javaName, Path: strings.Join(d.path, ".")}
+}
+
+// checkStrictType is checkStrictClass for the type @typeName of a typed map or list, such as "[com.foo.Bar".
+// The hessian type names like "[int", the java classes of java.* and javax.* and the collections of guava are known.
+func (d *Decoder) checkStrictType(typeName string) error {
+	if !d.strict {
+		return nil
+	}
+	javaName := strings.TrimLeft(typeName, "[")
+	if !strings.Contains(javaName, ".") || strings.HasPrefix(javaName, "java.") || strings.HasPrefix(javaName, "javax.") {
+		return nil
+	}
+	if jdkListTypes[javaName] || jdkMapTypes[javaName] || orderedMapJavaTypes[javaName] {
+		return nil
+	}
+	return d.checkStrictClass(javaName)
+}
+
+// decInstanceByHook reads all the fields of an instance of class @cls and passes them to @hook.
+func (d *Decoder) decInstanceByHook(hook DecodeHook, cls classInfo) (interface{}, error) {
+	if err := d.enterContainer(); err != nil {
+		return nil, err
+	}
+	defer d.leaveContainer()
+	if err := d.addElements(len(cls.fieldNameList)); err != nil {
+		return nil, err
+	}
+
+	// hold the ref index of the instance, which refers to the value returned by the hook
+	refIndex := len(d.refs)
+	d.appendRefs(nil)
+
+	fields := make(map[string]interface{}, len(cls.fieldNameList))
+	for _, fieldName := range cls.fieldNameList {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, perrors.Wrapf(err, "failed to decode field %s of %s", fieldName, cls.javaName)
+		}
+		fields[fieldName] = v
+	}
+
+	v, err := hook(cls.javaName, fields)
+	if err != nil {
+		return nil, perrors.Wrapf(err, "decode hook of %s", cls.javaName)
+	}
+	d.refs[refIndex] = v
+
+	return v, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// encode a class definition of @javaName with @fields, which is the @idx class definition in the stream
+func encTestClassInstance(b []byte, idx int, javaName string, fields []string, values ...interface{}) []byte {
+	e := NewEncoder()
+	e.Append(b)
+	if fields != nil {
+		e.Append([]byte{BC_OBJECT_DEF})
+		e.Append(encString(nil, javaName))
+		e.Append(encInt32(nil, int32(len(fields))))
+		for _, f := range fields {
+			e.Append(encString(nil, f))
+		}
+	}
+	e.Append([]byte{BC_OBJECT_DIRECT + byte(idx)})
+	for _, v := range values {
+		e.Encode(v)
+	}
+	return e.Buffer()
+}
+
+func TestDecodeHook(t *testing.T) {
+	type user struct {
+		Name string
+		Age  int32
+	}
+
+	RegisterDecodeHook("com.mycompany.dto.", func(javaName string, fields map[string]interface{}) (interface{}, error) {
+		return fields, nil
+	})
+	RegisterDecodeHook("com.mycompany.dto.user.", func(javaName string, fields map[string]interface{}) (interface{}, error) {
+		return &user{Name: fields["name"].(string), Age: fields["age"].(int32)}, nil
+	})
+	defer UnregisterDecodeHook("com.mycompany.dto.")
+	defer UnregisterDecodeHook("com.mycompany.dto.user.")
+
+	b := encTestClassInstance(nil, 0, "com.mycompany.dto.user.User", []string{"name", "age"}, "tom", int32(18))
+	b = encTestClassInstance(b, 1, "com.mycompany.dto.Order", []string{"id"}, int64(7))
+	b = encTestClassInstance(b, 0, "", nil, "ann", int32(20))
+	b = append(b, BC_REF, 0x90) // ref to the first user
+
+	d := NewDecoder(b)
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &user{Name: "tom", Age: 18}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"id": int64(7)}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &user{Name: "ann", Age: 20}, res)
+
+	first := res
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &user{Name: "tom", Age: 18}, res)
+	assert.False(t, first == res)
+
+	// no hook matches
+	_, err = NewDecoder(encTestClassInstance(nil, 0, "com.other.Foo", []string{"id"}, int64(7))).Decode()
+	assert.NotNil(t, err)
+}
+
+func TestDecodeHookNotForRegisteredPOJO(t *testing.T) {
+	RegisterPOJO(&Case{})
+	RegisterDecodeHook("", func(javaName string, fields map[string]interface{}) (interface{}, error) {
+		return javaName, nil
+	})
+	defer UnregisterDecodeHook("")
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&Case{A: "a", B: 1}))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &Case{A: "a", B: 1}, res)
+
+	res, err = NewDecoder(encTestClassInstance(nil, 0, "com.other.Foo", []string{"id"}, int64(7))).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "com.other.Foo", res)
+}
+
+func TestOnUnknownClass(t *testing.T) {
+	b := encTestClassInstance(nil, 0, "com.mycompany.dto.Unknown", []string{"id"}, int64(1))
+	b = encTestClassInstance(b, 1, "com.mycompany.dto.Unknown", []string{"id", "name"}, int64(2), "a")
+	b = encTestClassInstance(b, 2, "com.mycompany.dto.Other", []string{"id"}, int64(3))
+	b = encTestClassInstance(b, 3, "java.util.UUID", []string{"mostSigBits", "leastSigBits"}, int64(1), int64(2))
+	b = encTestClassInstance(b, 0, "com.mycompany.dto.Unknown", nil, int64(4))
+
+	decodeAll := func() []interface{} {
+		var values []interface{}
+		d := NewDecoder(b)
+		d.SetGenericMode(true)
+		for {
+			v, err := d.Decode()
+			if err != nil {
+				break
+			}
+			values = append(values, v)
+		}
+		return values
+	}
+
+	want := decodeAll()
+	var classes []string
+	OnUnknownClass(func(className string) {
+		classes = append(classes, className)
+	})
+	defer OnUnknownClass(nil)
+
+	assert.Equal(t, want, decodeAll())
+	assert.Equal(t, []string{"com.mycompany.dto.Unknown", "com.mycompany.dto.Other"}, classes)
+	assert.Equal(t, 5, len(want))
+
+	// once per class for each decoder
+	decodeAll()
+	assert.Equal(t, 4, len(classes))
+}
+
+type strictOrder struct {
+	ID    int64
+	Buyer interface{}
+}
+
+func (strictOrder) JavaClassName() string {
+	return "test.strict.Order"
+}
+
+func TestStrictMode(t *testing.T) {
+	RegisterPOJO(&strictOrder{})
+	b := encTestClassInstance(nil, 0, "test.strict.Order", []string{"iD", "buyer"}, int64(1))
+	b = encTestClassInstance(b, 1, "com.mycompany.dto.Buyer", []string{"name"}, "Alice")
+
+	for _, generic := range []bool{false, true} {
+		d := NewDecoder(b)
+		d.SetGenericMode(generic)
+		d.SetStrict(true)
+		_, err := d.Decode()
+		unknown, ok := perrors.Cause(err).(*UnknownClassError)
+		if assert.True(t, ok, "%v", err) {
+			assert.Equal(t, &UnknownClassError{ClassName: "com.mycompany.dto.Buyer", Path: "buyer"}, unknown)
+			assert.Equal(t, "buyer: unregistered java class com.mycompany.dto.Buyer", unknown.Error())
+		}
+	}
+
+	// lenient by default
+	d := NewDecoder(b)
+	d.SetGenericMode(true)
+	v, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "com.mycompany.dto.Buyer", v.(*GenericObject).Fields["buyer"].(*GenericObject).ClassName)
+
+	typedMap := func(typ string) []byte {
+		return append(encString(encString(encString([]byte{BC_MAP}, typ), "k"), "v"), BC_END)
+	}
+	typedList := func(typ string) []byte {
+		return encInt32(encString([]byte{BC_LIST_FIXED}, typ), 0)
+	}
+	for _, c := range []struct {
+		data    []byte
+		unknown string
+	}{
+		{typedMap("com.mycompany.dto.Attrs"), "com.mycompany.dto.Attrs"},
+		{typedList("[com.mycompany.dto.Item"), "com.mycompany.dto.Item"},
+		{typedMap("java.util.HashMap"), ""},
+		{typedList("java.util.ArrayList"), ""},
+		{typedList("[int"), ""},
+		{typedList("[test.strict.Order"), ""},
+	} {
+		d := NewDecoder(c.data)
+		d.SetStrict(true)
+		_, err := d.Decode()
+		if c.unknown == "" {
+			assert.Nil(t, err)
+			continue
+		}
+		assert.Equal(t, &UnknownClassError{ClassName: c.unknown}, perrors.Cause(err))
+	}
+}
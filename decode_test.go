@@ -20,11 +20,23 @@
 package hessian
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 )
 
 const (
@@ -113,3 +125,478 @@ func testDecodeFrameworkFunc(t *testing.T, method string, expected func(interfac
 	}
 	expected(r)
 }
+
+func TestNewDecoderFromReader(t *testing.T) {
+	now := time.Unix(1566715510, 123e6)
+	values := []interface{}{
+		int32(-0x40000), int64(0x7fffffffff), 3.1415926, -0.001, now, true,
+		strings.Repeat("hessian-中文-", CHUNK_SIZE/4), bytes.Repeat([]byte{0x1f}, CHUNK_SIZE+7),
+		map[interface{}]interface{}{"k": []interface{}{"v", int32(1)}},
+		&Case{A: "a", B: 1},
+	}
+
+	e := NewEncoder()
+	for _, v := range values {
+		assert.Nil(t, e.Encode(v))
+	}
+
+	// a stream which only returns one byte per read
+	d := NewDecoderFromReader(iotest.OneByteReader(bytes.NewReader(e.Buffer())))
+	for _, v := range values {
+		decoded, err := d.Decode()
+		assert.Nil(t, err)
+		if tm, ok := v.(time.Time); ok {
+			assert.True(t, tm.Equal(decoded.(time.Time)))
+			continue
+		}
+		if c, ok := v.(*Case); ok {
+			assert.Equal(t, c.A, decoded.(*Case).A)
+			continue
+		}
+		assert.Equal(t, v, decoded)
+	}
+}
+
+func TestDecoderLimits(t *testing.T) {
+	// nested lists deeper than the default max depth
+	deep := bytes.Repeat([]byte{BC_LIST_DIRECT_UNTYPED + 1}, DEFAULT_MAX_DECODE_DEPTH+1)
+	deep = append(deep, BC_NULL)
+	_, err := NewDecoder(deep).Decode()
+	assert.Equal(t, ErrMaxDepthExceeded, perrors.Cause(err))
+
+	d := NewDecoder(deep)
+	d.SetMaxDepth(0)
+	_, err = d.Decode()
+	assert.Nil(t, err)
+
+	// a list declaring a huge length is rejected before the allocation
+	huge := append([]byte{BC_LIST_FIXED_UNTYPED}, encInt32(nil, 0x7fffffff)...)
+	_, err = NewDecoder(huge).Decode()
+	assert.Equal(t, ErrMaxElementsExceeded, perrors.Cause(err))
+
+	// the elements of all the nested values are counted
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{map[string]string{"a": "a", "b": "b"}, []int32{1, 2}}))
+	d = NewDecoder(e.Buffer())
+	d.SetMaxElements(5)
+	_, err = d.Decode()
+	assert.Equal(t, ErrMaxElementsExceeded, perrors.Cause(err))
+
+	d = NewDecoder(e.Buffer())
+	d.SetMaxElements(6)
+	_, err = d.Decode()
+	assert.Nil(t, err)
+
+	// the count starts again for every top level value
+	e = NewEncoder()
+	assert.Nil(t, e.Encode([]int32{1, 2}))
+	assert.Nil(t, e.Encode([]int32{1, 2}))
+	d = NewDecoder(e.Buffer())
+	d.SetMaxElements(2)
+	for i := 0; i < 2; i++ {
+		_, err = d.Decode()
+		assert.Nil(t, err)
+	}
+}
+
+func TestDecoderScalarLimits(t *testing.T) {
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(strings.Repeat("dubbo", 20000))) // chunked
+	assert.Nil(t, e.Encode(make([]byte, 100000)))
+	d := NewDecoder(e.Buffer())
+	d.SetMaxStringLength(99999)
+	_, err := d.Decode()
+	assert.Equal(t, ErrMaxStringLengthExceeded, perrors.Cause(err))
+	assert.Contains(t, err.Error(), "max length 99999")
+
+	d = NewDecoder(e.Buffer())
+	d.SetMaxStringLength(100000)
+	d.SetMaxBinaryLength(99999)
+	s, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, 100000, len(s.(string)))
+	_, err = d.Decode()
+	assert.Equal(t, ErrMaxBinaryLengthExceeded, perrors.Cause(err))
+
+	d = NewDecoder(e.Buffer())
+	d.SetMaxStringLength(0)
+	d.SetMaxBinaryLength(0)
+	for i := 0; i < 2; i++ {
+		_, err = d.Decode()
+		assert.Nil(t, err)
+	}
+
+	// the chunks declaring more bytes than the input has are rejected before reading them
+	chunks := []byte{BC_STRING_CHUNK, 0xff, 0xff, BC_STRING_CHUNK, 0xff, 0xff}
+	_, err = NewDecoder(chunks).Decode()
+	assert.Equal(t, ErrShortBuffer, perrors.Cause(err))
+	_, err = NewDecoder([]byte{BC_BINARY_CHUNK, 0xff, 0xff}).Decode()
+	assert.Equal(t, ErrShortBuffer, perrors.Cause(err))
+}
+
+type int64ModeHolder struct {
+	Count int32       `hessian:"count"`
+	Codes []int32     `hessian:"codes"`
+	Extra interface{} `hessian:"extra"`
+}
+
+func (int64ModeHolder) JavaClassName() string {
+	return "test.Int64ModeHolder"
+}
+
+func TestInt64Mode(t *testing.T) {
+	values := []interface{}{
+		int32(1),
+		int64(1),
+		[]interface{}{int32(2), int64(3)},
+		map[interface{}]interface{}{int32(4): int32(5)},
+		[][]int32{{6}},
+		&int64ModeHolder{Count: 7, Codes: []int32{8}, Extra: int32(9)},
+	}
+	e := NewEncoder()
+	for _, v := range values {
+		assert.Nil(t, e.Encode(v))
+	}
+
+	d := NewDecoder(e.Buffer())
+	for _, v := range values {
+		decoded, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, v, decoded)
+	}
+
+	d = NewDecoder(e.Buffer())
+	d.SetInt64Mode(true)
+	for _, want := range []interface{}{
+		int64(1),
+		int64(1),
+		[]interface{}{int64(2), int64(3)},
+		map[interface{}]interface{}{int64(4): int64(5)},
+		[][]int64{{6}},
+		// the typed fields keep their types
+		&int64ModeHolder{Count: 7, Codes: []int32{8}, Extra: int64(9)},
+	} {
+		decoded, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, want, decoded)
+	}
+}
+
+type skipNullHolder struct {
+	Name   string            `hessian:"name"`
+	Count  int32             `hessian:"count"`
+	Labels map[string]string `hessian:"labels"`
+	Case   *Case             `hessian:"case"`
+}
+
+func (skipNullHolder) JavaClassName() string {
+	return "test.SkipNullHolder"
+}
+
+func TestSkipNull(t *testing.T) {
+	RegisterPOJO(&skipNullHolder{})
+
+	obj := NewGenericObject("test.SkipNullHolder")
+	obj.Fields["name"] = nil
+	obj.Fields["count"] = nil
+	obj.Fields["labels"] = map[string]interface{}{"a": nil, "b": "x"}
+	obj.Fields["case"] = nil
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(obj))
+
+	// a null int32 field fails by default
+	_, err := NewDecoder(e.Buffer()).Decode()
+	assert.Error(t, err)
+
+	// and the map holds the zero value of a null
+	obj.Fields["count"] = int32(1)
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(obj))
+	decoded, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "", "b": "x"}, decoded.(*skipNullHolder).Labels)
+
+	obj.Fields["count"] = nil
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(obj))
+	d := NewDecoder(e.Buffer())
+	d.SetSkipNull(true)
+	decoded, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &skipNullHolder{Labels: map[string]string{"b": "x"}}, decoded)
+}
+
+func TestSkipNullKeepsDefaults(t *testing.T) {
+	RegisterPOJO(&skipNullHolder{})
+
+	obj := NewGenericObject("test.SkipNullHolder")
+	obj.Fields["name"] = nil
+	obj.Fields["count"] = int32(2)
+	obj.Fields["labels"] = nil
+	obj.Fields["case"] = nil
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(obj))
+
+	// the defaults preset by a factory survive the nulls
+	assert.Nil(t, RegisterPOJOFactory("test.SkipNullHolder", func() interface{} {
+		return &skipNullHolder{Name: "default", Count: 1}
+	}))
+	defer RegisterPOJOFactory("test.SkipNullHolder", nil)
+
+	// a null string field gets the string "null" by default
+	decoded, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &skipNullHolder{Name: "null", Count: 2}, decoded)
+
+	d := NewDecoder(e.Buffer())
+	d.SetSkipNull(true)
+	decoded, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &skipNullHolder{Name: "default", Count: 2}, decoded)
+
+	// and so do the defaults of the out of ReflectResponse
+	m := map[interface{}]interface{}{"name": nil, "count": int32(2)}
+	out := &skipNullHolder{Name: "default", Count: 1}
+	assert.Nil(t, ReflectResponse(m, out))
+	assert.Equal(t, &skipNullHolder{Count: 2}, out)
+
+	out = &skipNullHolder{Name: "default", Count: 1}
+	assert.Nil(t, d.ReflectResponse(m, out))
+	assert.Equal(t, &skipNullHolder{Name: "default", Count: 2}, out)
+}
+
+func TestDecodeNext(t *testing.T) {
+	RegisterPOJO(&Case{})
+	RegisterPOJO(&int64ModeHolder{})
+
+	list := []interface{}{"x"}
+	values := []interface{}{
+		&Case{A: "a", B: 1},
+		// the class definition #0 and the ref #0 of its own
+		&int64ModeHolder{Count: 2, Codes: []int32{3}},
+		[]interface{}{list, list},
+	}
+	var (
+		data    []byte
+		offsets []int
+	)
+	for _, v := range values {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(v))
+		data = append(data, e.Buffer()...)
+		offsets = append(offsets, len(data))
+	}
+
+	d := NewDecoder(data)
+	var decoded []interface{}
+	for {
+		v, err := d.DecodeNext()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		decoded = append(decoded, v)
+		assert.Equal(t, offsets[len(decoded)-1], d.Offset())
+	}
+	assert.Equal(t, values, decoded)
+
+	// the second value refers to the class definition #0 of the first one in a stream
+	d = NewDecoder(data)
+	_, err := d.Decode()
+	assert.Nil(t, err)
+	v, err := d.Decode()
+	assert.False(t, err == nil && reflect.DeepEqual(values[1], v))
+}
+
+func TestDecodeErrorOffset(t *testing.T) {
+	e := NewEncoder()
+	assert.Nil(t, e.Encode("first"))
+	assert.Nil(t, e.Encode([]interface{}{"abc", int32(1000), true}))
+	data := e.Buffer()
+	// replace the int of 3 bytes by the reserved tag 0x40
+	offset := bytes.IndexByte(data, 'c') + 1
+	data[offset] = 0x40
+
+	for _, d := range []*Decoder{NewDecoder(data), NewDecoderFromReader(iotest.OneByteReader(bytes.NewReader(data)))} {
+		_, err := d.Decode()
+		assert.Nil(t, err)
+		_, err = d.Decode()
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("invalid type tag 0x40 at offset %d", offset))
+	}
+
+	// the tag of a field read by its type
+	RegisterPOJO(&Case{})
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&Case{A: "a", B: 1}))
+	data = e.Buffer()
+	data[len(data)-1] = 0x40
+	_, err := NewDecoder(data).Decode()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("tag:0x40 at offset %d", len(data)-1))
+
+	// the chunk of a string
+	data = append([]byte{BC_STRING_CHUNK, 0, 1, 'a'}, BC_TRUE)
+	_, err = NewDecoder(data).Decode()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "illegal string chunk tag 0x54 at offset 4")
+
+	// the end of the input, the refs and the class indexes out of the input read
+	_, err = NewDecoder([]byte{BC_INT, 0, 0}).Decode()
+	assert.Equal(t, ErrShortBuffer, perrors.Cause(err))
+	assert.Contains(t, err.Error(), "short buffer at offset 3")
+	_, err = NewDecoder([]byte{BC_LIST_DIRECT_UNTYPED + 2, BC_TRUE, BC_REF, 0x91}).Decode()
+	assert.Equal(t, ErrIllegalRefIndex, perrors.Cause(err))
+	assert.Contains(t, err.Error(), "illegal ref index at offset 4")
+	_, err = NewDecoder([]byte{BC_LIST_DIRECT_UNTYPED + 1, BC_OBJECT_DIRECT + 2}).Decode()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "illegal class index @idx 2 at offset 2")
+}
+
+type rewrittenItem struct {
+	Name string
+}
+
+func (rewrittenItem) JavaClassName() string {
+	return "com.new.Item"
+}
+
+type rewrittenOrder struct {
+	ID    int32
+	Item  *rewrittenItem
+	Items []*rewrittenItem
+}
+
+func (rewrittenOrder) JavaClassName() string {
+	return "com.new.Order"
+}
+
+func TestClassNameRewriter(t *testing.T) {
+	RegisterPOJOs(&rewrittenItem{}, &rewrittenOrder{})
+
+	// the old classes are not registered
+	e := NewEncoder()
+	encTestInstance(e, encTestClassDef(e, "com.old.Order", "iD", "item", "items"))
+	assert.Nil(t, e.Encode(int32(1)))
+	assert.Nil(t, e.Encode(&GenericObject{ClassName: "com.old.Item", Fields: map[string]interface{}{"name": "a"}}))
+	encTestListHead(e, "[com.old.Item", 1)
+	assert.Nil(t, e.Encode(&GenericObject{ClassName: "com.old.Item", Fields: map[string]interface{}{"name": "b"}}))
+	b := e.Buffer()
+
+	d := NewDecoder(b)
+	d.SetStrict(true)
+	_, err := d.Decode()
+	var unknown *UnknownClassError
+	assert.True(t, errors.As(err, &unknown))
+
+	var names []string
+	d = NewDecoder(b)
+	d.SetStrict(true)
+	d.SetClassNameRewriter(func(name string) string {
+		names = append(names, name)
+		return strings.Replace(name, "com.old.", "com.new.", 1)
+	})
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &rewrittenOrder{ID: 1, Item: &rewrittenItem{Name: "a"}, Items: []*rewrittenItem{{Name: "b"}}}, res)
+	assert.Equal(t, []string{"com.old.Order", "com.old.Item", "com.old.Item"}, names)
+}
+
+func TestShortBuffer(t *testing.T) {
+	RegisterPOJO(&Case{})
+	values := []interface{}{
+		int32(1 << 20),
+		int64(1 << 40),
+		3.25,
+		time.Unix(1600000000, 0),
+		"hello, 世界",
+		strings.Repeat("dubbo", 20000), // chunked
+		make([]byte, 1100),
+		[]interface{}{"a", int32(1), nil},
+		[]string{"a", "b"},
+		map[interface{}]interface{}{"k": "v"},
+		&Case{A: "a", B: 1},
+	}
+	for _, v := range values {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(v))
+		b := e.Buffer()
+
+		for n := 1; n < len(b); n++ {
+			if len(b) > 2000 && n > 10 && n < len(b)-10 {
+				continue
+			}
+			_, err := NewDecoder(b[:n]).Decode()
+			assert.Equal(t, ErrShortBuffer, perrors.Cause(err), "%T truncated to %d bytes: %v", v, n, err)
+		}
+	}
+
+	// the input ends between the top level values
+	d := NewDecoder(nil)
+	_, err := d.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoderReset(t *testing.T) {
+	RegisterPOJO(&Case{})
+	c := &Case{A: "a", B: 1}
+
+	frame := func() []byte {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode([]interface{}{c, c}))
+		return e.Buffer()
+	}
+
+	d := NewDecoder(frame())
+	d.SetMaxDepth(4)
+	for i := 0; i < 3; i++ {
+		// every frame starts the class definitions and refs from 0
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{c, c}, res)
+
+		_, err = d.Decode()
+		assert.Equal(t, io.EOF, err)
+
+		d.Reset(frame())
+	}
+	assert.Equal(t, 4, d.maxDepth)
+
+	// a decoder reading from a stream can be reset to decode a buffer
+	d = NewDecoderFromReader(bytes.NewReader(frame()))
+	d.Reset(frame())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{c, c}, res)
+}
+
+func benchmarkDecodeFrames(b *testing.B, reuse bool) {
+	RegisterPOJO(&Case{})
+	e := NewEncoder()
+	if err := e.Encode([]interface{}{&Case{A: "a", B: 1}, "dubbo-go", int64(1)}); err != nil {
+		b.Fatal(err)
+	}
+	frame := e.Buffer()
+
+	d := NewDecoder(frame)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if reuse {
+			d.Reset(frame)
+		} else {
+			d = NewDecoder(frame)
+		}
+		if _, err := d.Decode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewDecoderPerFrame(b *testing.B) {
+	benchmarkDecodeFrames(b, false)
+}
+
+func BenchmarkReuseDecoder(b *testing.B) {
+	benchmarkDecodeFrames(b, true)
+}
@@ -15,14 +15,23 @@
 package hessian
 
 import (
-	"encoding/binary"
 	"math"
+	"strconv"
 )
 
 import (
 	perrors "github.com/pkg/errors"
 )
 
+// decimalFloat32 gets the double of the shortest decimal form of @v, which is narrowed back into @v.
+func decimalFloat32(v float32) float64 {
+	if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+		return float64(v)
+	}
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+	return f
+}
+
 /////////////////////////////////////////
 // Double
 /////////////////////////////////////////
@@ -34,9 +43,10 @@ import (
 // ::= x5d b0                # byte cast to double (-128.0 to 127.0)
 // ::= x5e b1 b0             # short cast to double
 // ::= x5f b3 b2 b1 b0       # 32-bit float cast to double
+//
+// NaN, the infinities and -0.0 are written in 'D' bit by bit, since they are no short values.
 func encFloat(b []byte, v float64) []byte {
-	fv := float64(int64(v))
-	if fv == v {
+	if v >= -0x8000 && v < 0x8000 && v == math.Trunc(v) && !(v == 0 && math.Signbit(v)) {
 		iv := int64(v)
 		switch iv {
 		case 0:
@@ -79,11 +89,43 @@ func (d *Decoder) decDouble(flag int32) (interface{}, error) {
 	if flag != TAG_READ {
 		tag = byte(flag)
 	} else {
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
+	}
+	if tag == BC_LONG_INT {
+		return d.decInt32(TAG_READ)
+	}
+
+	f, err := d.decFloat64(int32(tag))
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// decFloat64 decodes a double like decDouble, but returns a float64 without boxing it,
+// which is used to decode the elements of a double[].
+func (d *Decoder) decFloat64(flag int32) (float64, error) {
+	var (
+		err error
+		tag byte
+		buf [8]byte
+	)
+
+	if flag != TAG_READ {
+		tag = byte(flag)
+	} else {
+		tag, err = d.readByte()
+		if err != nil {
+			return 0, perrors.WithStack(err)
+		}
 	}
 	switch tag {
 	case BC_LONG_INT:
-		return d.decInt32(TAG_READ)
+		i32, err := d.decInt32(TAG_READ)
+		return float64(i32), err
 
 	case BC_DOUBLE_ZERO:
 		return float64(0), nil
@@ -92,25 +134,21 @@ func (d *Decoder) decDouble(flag int32) (interface{}, error) {
 		return float64(1), nil
 
 	case BC_DOUBLE_BYTE:
-		var i8 int8
-		err = binary.Read(d.reader, binary.BigEndian, &i8)
-		return float64(i8), perrors.WithStack(err)
+		b, err := d.readByte()
+		return float64(int8(b)), perrors.WithStack(err)
 
 	case BC_DOUBLE_SHORT:
-		var i16 int16
-		err = binary.Read(d.reader, binary.BigEndian, &i16)
-		return float64(i16), perrors.WithStack(err)
+		_, err = d.readFull(buf[:2])
+		return float64(UnpackInt16(buf[:2])), perrors.WithStack(err)
 
 	case BC_DOUBLE_MILL:
-		var i32 int32
-		err = binary.Read(d.reader, binary.BigEndian, &i32)
-		return float64(i32) / 1000, perrors.WithStack(err)
+		_, err = d.readFull(buf[:4])
+		return float64(UnpackInt32(buf[:4])) / 1000, perrors.WithStack(err)
 
 	case BC_DOUBLE:
-		var f64 float64
-		err = binary.Read(d.reader, binary.BigEndian, &f64)
-		return f64, perrors.WithStack(err)
+		_, err = d.readFull(buf[:8])
+		return UnpackFloat64(buf[:8]), perrors.WithStack(err)
 	}
 
-	return nil, perrors.Errorf("decDouble parse double wrong tag:%d-%#x", int(tag), tag)
+	return 0, d.tagErrorf("decDouble parse double wrong tag:%d-%#x", int(tag), tag)
 }
@@ -15,9 +15,14 @@
 package hessian
 
 import (
+	"math"
 	"testing"
 )
 
+import (
+	"github.com/stretchr/testify/assert"
+)
+
 func TestEncDouble(t *testing.T) {
 	var (
 		v   float64
@@ -70,3 +75,80 @@ func TestDoubleEncode(t *testing.T) {
 	testJavaDecode(t, "argDouble_m129_0", -129.0)
 	testJavaDecode(t, "argDouble_m32768_0", -32768.0)
 }
+
+func TestDoubleSpecialValues(t *testing.T) {
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Copysign(0, -1),
+		math.Float64frombits(0x7ff8000000000123), math.MaxFloat64, math.SmallestNonzeroFloat64, 1 << 62} {
+		bits := math.Float64bits(v)
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(v))
+		assert.Equal(t, []byte{BC_DOUBLE, byte(bits >> 56), byte(bits >> 48), byte(bits >> 40), byte(bits >> 32),
+			byte(bits >> 24), byte(bits >> 16), byte(bits >> 8), byte(bits)}, e.Buffer(), "%v", v)
+
+		res, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, bits, math.Float64bits(res.(float64)), "%v", v)
+
+		e = NewEncoder()
+		assert.Nil(t, e.Encode([]float64{v}))
+		var out []float64
+		res, err = NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Nil(t, ReflectResponse(res, &out))
+		assert.Equal(t, bits, math.Float64bits(out[0]), "%v", v)
+	}
+
+	// the bytes of Hessian2Output.writeDouble of java
+	for wire, bits := range map[string]uint64{
+		"\x44\x7f\xf8\x00\x00\x00\x00\x00\x00": 0x7ff8000000000000, // Double.NaN
+		"\x44\x7f\xf0\x00\x00\x00\x00\x00\x00": 0x7ff0000000000000, // Double.POSITIVE_INFINITY
+		"\x44\xff\xf0\x00\x00\x00\x00\x00\x00": 0xfff0000000000000, // Double.NEGATIVE_INFINITY
+		"\x5b":                                 0,                  // -0.0 is written as 0.0 by java
+	} {
+		res, err := NewDecoder([]byte(wire)).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, bits, math.Float64bits(res.(float64)))
+	}
+
+	// a short value is still short
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(-128.0))
+	assert.Equal(t, []byte{BC_DOUBLE_BYTE, 0x80}, e.Buffer())
+}
+
+func TestEncodeFloat32AsJavaFloat(t *testing.T) {
+	// widened exactly by default
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(float32(0.1)))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, float64(float32(0.1)), res)
+
+	e = NewEncoder()
+	e.SetFloat32AsJavaFloat(true)
+	values := []float32{0.1, 3.14159, -2.5e-8, 16777216, math.MaxFloat32, math.SmallestNonzeroFloat32, float32(math.Inf(-1))}
+	for _, v := range values {
+		assert.Nil(t, e.Encode(v))
+	}
+	assert.Nil(t, e.Encode([]float32{0.7}))
+	d := NewDecoder(e.Buffer())
+	for _, v := range values {
+		res, err = d.Decode()
+		assert.Nil(t, err)
+		// the value a java float narrows the double into
+		assert.Equal(t, v, float32(res.(float64)))
+	}
+	assert.Equal(t, 0.1, decimalFloat32(0.1))
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{0.7}, res)
+}
+
+func TestFloat32JavaDecode(t *testing.T) {
+	e := NewEncoder()
+	e.SetFloat32AsJavaFloat(true)
+	assert.Nil(t, e.Encode(float32(0.1)))
+	r, err := javaDecodeBytes("customArgFloat32", e.Buffer())
+	assert.Nil(t, err)
+	assert.Equal(t, "true", r)
+}
@@ -15,7 +15,12 @@
 package hessian
 
 import (
+	"io"
+	"math/big"
+	"net/url"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -24,15 +29,88 @@ import (
 	perrors "github.com/pkg/errors"
 )
 
+import (
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+)
+
 // nil bool int8 int32 int64 float32 float64 time.Time
 // string []byte []interface{} map[interface{}]interface{}
 // array object struct
+//
+// java has no unsigned types, so go integers are encoded as:
+// int8 int16 int32 uint8 uint16 -> int
+// int int64 uint32              -> long
+// uint uint64                   -> long, which is an error if the value is beyond math.MaxInt64
 
 // Encoder struct
 type Encoder struct {
 	classInfoList []classInfo
 	buffer        []byte
-	refMap        map[unsafe.Pointer]_refElem
+	refMap        map[unsafe.Pointer][]_refElem // objects, lists and maps encoded at the address
+	refCount      int                           // number of the objects, lists and maps encoded
+	typeRefs      map[string]int                // the type names of the lists and maps written, by their refs
+	pooledBuffer  *[]byte                       // the buffer got from encoderBufferPool, which is put back by Release
+	writer        io.Writer                     // the writer of EncodeTo
+	writeErr      error                         // the error of writing to the writer
+	flushed       int                           // the bytes written to the writer
+	observing     bool                          // the top level value is being observed
+
+	nilCollection      int32              // the nilCollection policy of the nil slices and maps
+	structAsMap        bool               // encode the go structs which have no java class as maps
+	float32AsJavaFloat bool               // encode float32 as the double of its shortest decimal form
+	sortMapKeys        bool               // encode the entries of the go maps in the order of their keys
+	classNameResolver  *ClassNameResolver // names the go structs which are neither registered nor POJO
+
+	omittedFieldsDefs map[omittedFieldsKey]int // the class definitions written by omitEmptyFields
+
+	listForm  ListForm                  // the form of the lists of the slices and arrays
+	listForms map[reflect.Type]ListForm // the forms of the lists of the slice types set by their own
+}
+
+// the policies of encoding the nil slices and maps
+const (
+	// a nil slice is an empty list, and a nil or empty map is null unless its keys are interface{}
+	nilCollectionCompatible int32 = iota
+	// a nil slice or map is null, and an empty one is an empty list or map
+	nilCollectionNull
+	// a nil or empty slice or map is an empty list or map
+	nilCollectionEmpty
+)
+
+// the default nil collection policy of new encoders, accessed atomically
+var encodeNilCollection = nilCollectionCompatible
+
+// SetEncodeNilCollectionAsNull for customize how the encoders created afterwards encode a nil slice
+// or a nil map. They are encoded as null if @asNull is true, otherwise as an empty list or map.
+// Once it is set, an empty but non-nil slice or map is always encoded as an empty list or map.
+// Without it, an encoder keeps the compatible way, which encodes a nil slice as an empty list, and
+// a nil or empty map as null unless it is a map[interface{}]interface{}.
+func SetEncodeNilCollectionAsNull(asNull bool) {
+	atomic.StoreInt32(&encodeNilCollection, nilCollectionPolicy(asNull))
+}
+
+func nilCollectionPolicy(asNull bool) int32 {
+	if asNull {
+		return nilCollectionNull
+	}
+	return nilCollectionEmpty
+}
+
+const (
+	// the initial buffer size of an encoder got from encoderBufferPool
+	encoderPoolBufferSize = 1024
+	// a buffer larger than this size is not put back to encoderBufferPool, so that
+	// a few large packages can not hold on too much memory.
+	encoderPoolMaxBufferSize = 1 << 20
+	// the buffered bytes are written to the writer of EncodeTo once they exceed this size
+	encoderFlushSize = 4096
+)
+
+var encoderBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, encoderPoolBufferSize)
+		return &b
+	},
 }
 
 // NewEncoder generate an encoder instance
@@ -40,27 +118,167 @@ func NewEncoder() *Encoder {
 	var buffer = make([]byte, 64)
 
 	return &Encoder{
-		buffer: buffer[:0],
-		refMap: make(map[unsafe.Pointer]_refElem, 7),
+		buffer:        buffer[:0],
+		refMap:        make(map[unsafe.Pointer][]_refElem, 7),
+		nilCollection: atomic.LoadInt32(&encodeNilCollection),
 	}
 }
 
-// Buffer returns byte buffer
+// NewPooledEncoder generate an encoder instance whose buffer is got from a sync.Pool.
+// Call Release to put the buffer back after the encoded bytes have been consumed, such as
+// written to the connection.
+func NewPooledEncoder() *Encoder {
+	b := encoderBufferPool.Get().(*[]byte)
+
+	return &Encoder{
+		buffer:        (*b)[:0],
+		refMap:        make(map[unsafe.Pointer][]_refElem, 7),
+		pooledBuffer:  b,
+		nilCollection: atomic.LoadInt32(&encodeNilCollection),
+	}
+}
+
+// SetNilCollectionAsNull sets whether the encoder encodes a nil slice or a nil map as null,
+// otherwise as an empty list or map. See SetEncodeNilCollectionAsNull.
+func (e *Encoder) SetNilCollectionAsNull(asNull bool) {
+	e.nilCollection = nilCollectionPolicy(asNull)
+}
+
+// SetStructAsMap sets whether the encoder encodes a go struct which is neither a POJO nor registered as a map
+// of its field names, such as for a java service expecting a Map<String, Object>. The field names are the same
+// as the ones of a POJO, such as the names in the hessian tags. A struct whose java class is named by the
+// ClassNameResolver is still an object. It is off by default, and such a struct is an error.
+func (e *Encoder) SetStructAsMap(asMap bool) {
+	e.structAsMap = asMap
+}
+
+// SetFloat32AsJavaFloat sets whether the encoder encodes a float32 for a java float. Hessian 2 has no
+// float, and the 32-bit form x5f is read as thousandths by the java implementation, so a float32 is
+// always written as a double. By default it is widened exactly, such as 0.1 written as 0.10000000149011612.
+// If set, it is written as the double of its shortest decimal form, such as 0.1, which java narrows into
+// the same float, and which a java double or Object receives without the spurious trailing digits.
+func (e *Encoder) SetFloat32AsJavaFloat(asFloat bool) {
+	e.float32AsJavaFloat = asFloat
+}
+
+// SetSortMapKeys sets whether the encoder encodes the entries of a go map in the order of their keys,
+// so that the equal maps are encoded into the same bytes, such as for hashing the encoded bytes.
+// The string keys are ordered lexicographically and the number keys numerically. The keys of
+// map[interface{}]interface{} are grouped by kind, the bools, the numbers, the strings and then
+// the others, such as the objects which are ordered by their type and printed form, and the distinct
+// pointers printed alike by their addresses. It is off by default, as the sorting costs, and the
+// entries are in the random order of iterating the map.
+func (e *Encoder) SetSortMapKeys(sortKeys bool) {
+	e.sortMapKeys = sortKeys
+}
+
+// Buffer returns byte buffer.
+// The returned slice shares memory with the encoder. If the encoder is got from NewPooledEncoder,
+// the slice is only valid until Release is called. Copy it if you intend to hold it longer.
 func (e *Encoder) Buffer() []byte {
 	return e.buffer[:]
 }
 
+// Release puts the buffer of an encoder got from NewPooledEncoder back to the pool, and
+// any slice returned by Buffer before must not be used anymore. The encoder is reset and can
+// still be used, but its buffer will be allocated normally.
+func (e *Encoder) Release() {
+	if e.pooledBuffer != nil && cap(e.buffer) <= encoderPoolMaxBufferSize {
+		// store the grown buffer back through the pointer got from the pool, which needs no allocation
+		*e.pooledBuffer = e.buffer[:0]
+		encoderBufferPool.Put(e.pooledBuffer)
+	}
+	e.pooledBuffer = nil
+	e.buffer = nil
+	e.classInfoList = nil
+	e.omittedFieldsDefs = nil
+	e.refMap = make(map[unsafe.Pointer][]_refElem, 7)
+	e.refCount = 0
+	e.typeRefs = nil
+}
+
+// encType writes the type name of a typed list or map. A name is written once per encoder,
+// and the later lists and maps of the same type refer to it by its index in the type table:
+//
+//	type ::= string
+//	     ::= int
+func (e *Encoder) encType(name string) {
+	if idx, ok := e.typeRefs[name]; ok {
+		e.buffer = encInt32(e.buffer, int32(idx))
+		return
+	}
+	if e.typeRefs == nil {
+		e.typeRefs = make(map[string]int, 7)
+	}
+	e.typeRefs[name] = len(e.typeRefs)
+	e.buffer = encString(e.buffer, name)
+}
+
 // Append byte arr to encoder buffer
 func (e *Encoder) Append(buf []byte) {
 	e.buffer = append(e.buffer, buf[:]...)
 }
 
+// EncodeTo encodes @v like Encode, but the encoded bytes are written to @w while they are produced
+// instead of being kept in the buffer, so a big value can be encoded without holding its whole
+// serialized form. The bytes in the buffer before, such as appended by Append, are written first.
+// The refs and class definitions are still kept by the encoder, so the values encoded one after
+// another make up one hessian stream, which is broken if an error is returned.
+//
+// A dubbo package has the body length in its header, which is back-patched after the body is
+// encoded, so the packages are still encoded in memory. Streaming a package body needs its length
+// in advance, such as by encoding the body to a counting writer at first.
+func (e *Encoder) EncodeTo(w io.Writer, v interface{}) error {
+	e.writer = w
+	defer func() {
+		e.writer = nil
+		e.writeErr = nil
+	}()
+
+	if err := e.Encode(v); err != nil {
+		return err
+	}
+	e.flush(0)
+	return e.writeErr
+}
+
+// flush writes the buffer to the writer of EncodeTo if it is at least @size bytes.
+func (e *Encoder) flush(size int) {
+	if e.writeErr != nil || len(e.buffer) == 0 || len(e.buffer) < size {
+		return
+	}
+	if _, err := e.writer.Write(e.buffer); err != nil {
+		e.writeErr = perrors.WithStack(err)
+	}
+	e.flushed += len(e.buffer)
+	e.buffer = e.buffer[:0]
+}
+
 // Encode If @v can not be encoded, the return value is nil. At present only struct may can not be encoded.
 func (e *Encoder) Encode(v interface{}) error {
+	if !e.observing {
+		if o := getObserver(); o != nil {
+			return e.observeEncode(o, v)
+		}
+	}
+	if e.writer != nil {
+		if e.writeErr != nil {
+			return e.writeErr
+		}
+		defer e.flush(encoderFlushSize)
+	}
+
 	if v == nil {
 		e.buffer = encNull(e.buffer)
 		return nil
 	}
+	if c, ok := v.(Codec); ok {
+		if vv := reflect.ValueOf(v); vv.Kind() == reflect.Ptr && vv.IsNil() {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		return c.EncodeHessian(e)
+	}
 
 	switch val := v.(type) {
 	case nil:
@@ -93,12 +311,21 @@ func (e *Encoder) Encode(v interface{}) error {
 		// when decode
 		e.buffer = encInt64(e.buffer, int64(val))
 	case uint:
-		e.buffer = encInt64(e.buffer, int64(val))
+		return e.encUint64(uint64(val))
 
 	case int64:
 		e.buffer = encInt64(e.buffer, val)
 	case uint64:
-		e.buffer = encInt64(e.buffer, int64(val))
+		return e.encUint64(val)
+
+	case time.Duration:
+		return e.Encode(java8_time.NewDuration(val))
+	case *time.Duration:
+		if val == nil {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		return e.Encode(java8_time.NewDuration(*val))
 
 	case time.Time:
 		if ZeroDate == val {
@@ -109,6 +336,10 @@ func (e *Encoder) Encode(v interface{}) error {
 		}
 
 	case float32:
+		if e.float32AsJavaFloat {
+			e.buffer = encFloat(e.buffer, decimalFloat32(val))
+			break
+		}
 		e.buffer = encFloat(e.buffer, float64(val))
 
 	case float64:
@@ -117,14 +348,68 @@ func (e *Encoder) Encode(v interface{}) error {
 	case string:
 		e.buffer = encString(e.buffer, val)
 
+	case JavaChar:
+		e.buffer = encChar(e.buffer, val)
+	case *JavaChar:
+		if val == nil {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		e.buffer = encChar(e.buffer, *val)
+
 	case []byte:
 		e.buffer = encBinary(e.buffer, val)
 
+	case *big.Int:
+		return e.encBigInteger(val)
+	case big.Int:
+		return e.encBigInteger(&val)
+	case *url.URL:
+		return e.encURI(val)
+	case url.URL:
+		return e.encURI(&val)
+
 	case map[interface{}]interface{}:
 		return e.encUntypedMap(val)
+	case []MapEntry:
+		return e.encMapEntries(val)
+
+	case *OrderedMap:
+		return e.encOrderedMap(val)
+	case OrderedMap:
+		return e.encOrderedMap(&val)
+	case *Set:
+		return e.encSet(val)
+	case Set:
+		return e.encSet(&val)
+	case *GenericObject:
+		return e.encGenericObject(val)
+	case GenericObject:
+		return e.encGenericObject(&val)
+	case *RawValue:
+		if val == nil {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		return e.AppendRaw(val.Data)
+	case RawValue:
+		return e.AppendRaw(val.Data)
 
 	default:
 		t := UnpackPtrType(reflect.TypeOf(v))
+		if vv := reflect.ValueOf(v); vv.Kind() == reflect.Ptr && t.Kind() != reflect.Bool {
+			// a pointer at any depth is encoded as the pointer to the value, and a nil one as null
+			for !vv.IsNil() && vv.Elem().Kind() == reflect.Ptr {
+				vv = vv.Elem()
+			}
+			if vv.IsNil() {
+				e.buffer = encNull(e.buffer)
+				return nil
+			}
+			if vv.Type() != reflect.TypeOf(v) {
+				return e.Encode(vv.Interface())
+			}
+		}
 		switch t.Kind() {
 		case reflect.Struct:
 			vv := reflect.ValueOf(v)
@@ -145,6 +430,17 @@ func (e *Encoder) Encode(v interface{}) error {
 				}
 				return e.encObject(p)
 			}
+			if _, ok := checkPOJORegistry(t.String()); ok {
+				return e.encObject(v)
+			}
+			if e.classNameResolver != nil {
+				if _, resolved, err := e.classNameResolver.classInfo(t); err != nil || resolved || !e.structAsMap {
+					return e.encObject(v)
+				}
+			}
+			if e.structAsMap {
+				return e.encStructAsMap(v)
+			}
 
 			return perrors.Errorf("struct type not Support! %s[%v] is not a instance of POJO!", t.String(), v)
 		case reflect.Slice, reflect.Array:
@@ -152,7 +448,10 @@ func (e *Encoder) Encode(v interface{}) error {
 		case reflect.Map: // the type must be map[string]int
 			return e.encMap(v)
 		case reflect.Bool:
-			vv := v.(*bool)
+			vv, ok := v.(*bool)
+			if !ok {
+				return e.encIndirectValue(v)
+			}
 			if vv != nil {
 				e.buffer = encBool(e.buffer, *vv)
 			} else {
@@ -162,9 +461,45 @@ func (e *Encoder) Encode(v interface{}) error {
 			if p, ok := v.(POJOEnum); ok { // JavaEnum
 				return e.encObject(p)
 			}
-			return perrors.Errorf("type not supported! %s", t.Kind().String())
+			return e.encIndirectValue(v)
 		}
 	}
 
 	return nil
 }
+
+// basicKindTypes are the types which the values of the named types of the kinds are encoded as.
+var basicKindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.String:  reflect.TypeOf(""),
+}
+
+// encIndirectValue encodes @v which is a pointer or a value of a named type of a basic kind, such as a
+// *string or a `type Status string` held by a map[string]interface{}, as the value it points to
+// or as the value of the basic type.
+func (e *Encoder) encIndirectValue(v interface{}) error {
+	vv := reflect.ValueOf(v)
+	if vv.Kind() == reflect.Ptr {
+		if vv.IsNil() {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		return e.Encode(vv.Elem().Interface())
+	}
+	if typ, ok := basicKindTypes[vv.Kind()]; ok {
+		return e.Encode(vv.Convert(typ).Interface())
+	}
+	return perrors.Errorf("type not supported! %s", vv.Kind().String())
+}
@@ -16,7 +16,10 @@ package hessian
 
 import (
 	"bytes"
+	"errors"
 	"os/exec"
+	"reflect"
+	"sync/atomic"
 	"testing"
 )
 
@@ -40,7 +43,11 @@ func javaDecodeValidate(method string, target interface{}) (string, error) {
 	if e != nil {
 		return "", e
 	}
+	return javaDecodeBytes(method, b)
+}
 
+// javaDecodeBytes gets the reply of the java test @method reading the encoded bytes @b.
+func javaDecodeBytes(method string, b []byte) (string, error) {
 	genHessianJar()
 	cmd := exec.Command("java", "-jar", hessianJar, method)
 
@@ -67,3 +74,293 @@ func testJavaDecode(t *testing.T, method string, target interface{}) {
 		t.Errorf("%s: encode %v to bytes wrongly", method, target)
 	}
 }
+
+// encTestClassDef writes the definition of the class @javaName with the fields @fieldNames, which java may
+// declare unlike the go struct, and records it like the encoder does. It returns the index of the definition.
+func encTestClassDef(e *Encoder, javaName string, fieldNames ...string) int {
+	e.buffer = encInt32(encString(encByte(e.buffer, BC_OBJECT_DEF), javaName), int32(len(fieldNames)))
+	for _, name := range fieldNames {
+		e.buffer = encString(e.buffer, name)
+	}
+	e.classInfoList = append(e.classInfoList, classInfo{javaName: javaName, fieldNameList: fieldNames})
+	return len(e.classInfoList) - 1
+}
+
+// encTestInstance writes the head of an instance of the class definition @idx, whose fields are encoded next,
+// and counts its ref so that the refs encoded next point to the right values.
+func encTestInstance(e *Encoder, idx int) {
+	if idx <= int(OBJECT_DIRECT_MAX) {
+		e.buffer = encByte(e.buffer, BC_OBJECT_DIRECT+byte(idx))
+	} else {
+		e.buffer = encInt32(encByte(e.buffer, BC_OBJECT), int32(idx))
+	}
+	e.refCount++
+}
+
+// encTestListHead writes the head of the fixed length list of the type @typ, whose @n elements are encoded next,
+// and counts its ref.
+func encTestListHead(e *Encoder, typ string, n int) {
+	e.buffer = encInt32(encString(encByte(e.buffer, BC_LIST_FIXED), typ), int32(n))
+	e.refCount++
+}
+
+func TestPooledEncoder(t *testing.T) {
+	e := NewEncoder()
+	if err := e.Encode([]interface{}{"hello", int64(1), map[string]string{"a": "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{}, e.Buffer()...)
+
+	for i := 0; i < 3; i++ {
+		pe := NewPooledEncoder()
+		if err := pe.Encode([]interface{}{"hello", int64(1), map[string]string{"a": "b"}}); err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(want, pe.Buffer(), t)
+		pe.Release()
+
+		// a released encoder is reset and still can be used
+		if err := pe.Encode([]interface{}{"hello", int64(1), map[string]string{"a": "b"}}); err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(want, pe.Buffer(), t)
+	}
+}
+
+func benchmarkEncoder(b *testing.B, newEncoder func() *Encoder) {
+	body := make([]interface{}, 0, 64)
+	for i := 0; i < 64; i++ {
+		body = append(body, map[string]interface{}{"name": "dubbo-go", "id": int64(i), "tags": []string{"a", "b"}})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := newEncoder()
+		if err := e.Encode(body); err != nil {
+			b.Fatal(err)
+		}
+		e.Release()
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	benchmarkEncoder(b, NewEncoder)
+}
+
+func BenchmarkPooledEncoder(b *testing.B) {
+	benchmarkEncoder(b, NewPooledEncoder)
+}
+
+func TestEncodeNilCollection(t *testing.T) {
+	var (
+		nilMap        map[string]int
+		nilUntypedMap map[interface{}]interface{}
+		nilSlice      []string
+		nilList       []interface{}
+	)
+	emptyMap := encByte(encByte(nil, BC_MAP_UNTYPED), BC_END)
+
+	// the compatible default encodes a nil slice as an empty list, and a nil or empty map as null
+	// unless its keys are interface{}
+	for _, c := range []struct {
+		v    interface{}
+		want []byte
+	}{
+		{nilMap, []byte{BC_NULL}},
+		{map[string]int{}, []byte{BC_NULL}},
+		{nilUntypedMap, emptyMap},
+		{map[interface{}]interface{}{}, emptyMap},
+	} {
+		e := NewEncoder()
+		if err := e.Encode(c.v); err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(c.want, e.Buffer(), t)
+	}
+	e := NewEncoder()
+	if err := e.Encode(nilSlice); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := NewDecoder(e.Buffer()).Decode(); err != nil || res == nil || reflect.ValueOf(res).Len() != 0 {
+		t.Errorf("nil slice should be encoded as empty list, but get %v, %v", res, err)
+	}
+
+	for _, asNull := range []bool{false, true} {
+		for _, v := range []interface{}{nilMap, nilUntypedMap, nilSlice, nilList} {
+			e := NewEncoder()
+			e.SetNilCollectionAsNull(asNull)
+			if err := e.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+			if asNull {
+				assertEqual([]byte{BC_NULL}, e.Buffer(), t)
+				continue
+			}
+
+			res, err := NewDecoder(e.Buffer()).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.ValueOf(res).Len() != 0 {
+				t.Errorf("%T should be encoded as empty collection, but get %v", v, res)
+			}
+		}
+
+		// empty collections are always encoded as empty ones
+		for _, v := range []interface{}{map[string]int{}, map[interface{}]interface{}{}} {
+			e := NewEncoder()
+			e.SetNilCollectionAsNull(asNull)
+			if err := e.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+			assertEqual(emptyMap, e.Buffer(), t)
+		}
+		for _, v := range []interface{}{[]string{}, []interface{}{}} {
+			e := NewEncoder()
+			e.SetNilCollectionAsNull(asNull)
+			if err := e.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+			res, err := NewDecoder(e.Buffer()).Decode()
+			if err != nil || res == nil || reflect.ValueOf(res).Len() != 0 {
+				t.Errorf("%T should be encoded as empty list, but get %v, %v", v, res, err)
+			}
+		}
+	}
+
+	// the package level policy is the default of new encoders
+	SetEncodeNilCollectionAsNull(true)
+	defer atomic.StoreInt32(&encodeNilCollection, nilCollectionCompatible)
+	e = NewEncoder()
+	if err := e.Encode(nilSlice); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual([]byte{BC_NULL}, e.Buffer(), t)
+}
+
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestEncodeTo(t *testing.T) {
+	body := make([]interface{}, 0, 1024)
+	for i := 0; i < 1024; i++ {
+		body = append(body, []interface{}{"dubbo-go", int64(i)})
+	}
+
+	e := NewEncoder()
+	if err := e.Encode(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Encode("end"); err != nil {
+		t.Fatal(err)
+	}
+	want := e.Buffer()
+
+	w := &countingWriter{}
+	se := NewEncoder()
+	if err := se.EncodeTo(w, body); err != nil {
+		t.Fatal(err)
+	}
+	if len(se.Buffer()) != 0 {
+		t.Fatalf("the buffer should be empty, but get %d bytes", len(se.Buffer()))
+	}
+	if w.writes < 2 {
+		t.Fatalf("the bytes should be written while encoding, but get %d writes", w.writes)
+	}
+	if err := se.EncodeTo(w, "end"); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(want, w.Bytes(), t)
+
+	if err := NewEncoder().EncodeTo(failingWriter{}, body); err == nil {
+		t.Fatal("the error of the writer should be returned")
+	}
+}
+
+type typeRefItem struct {
+	ID   int32
+	Name string
+}
+
+func (typeRefItem) JavaClassName() string {
+	return "test.TypeRefItem"
+}
+
+func TestEncodeTypeRefs(t *testing.T) {
+	const n = 10000
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		items = append(items, &typeRefItem{ID: int32(i), Name: "item"})
+	}
+	e := NewEncoder()
+	if err := e.Encode(items); err != nil {
+		t.Fatal(err)
+	}
+	b := e.Buffer()
+	if c := bytes.Count(b, []byte("test.TypeRefItem")); c != 1 {
+		t.Fatalf("the class should be defined once, but get %d definitions", c)
+	}
+	// every object is the direct class ref, the short int and the short string
+	if len(b) > 40+n*9 {
+		t.Fatalf("%d objects should be encoded in %d bytes at most, but get %d", n, 40+n*9, len(b))
+	}
+	got, err := NewDecoder(b).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := got.([]interface{}); len(list) != n || !reflect.DeepEqual(list[n-1], &typeRefItem{ID: n - 1, Name: "item"}) {
+		t.Fatalf("decode the objects wrongly: %v", list[len(list)-1])
+	}
+
+	lists := make([][]int32, 0, n)
+	for i := 0; i < n; i++ {
+		lists = append(lists, []int32{int32(i)})
+	}
+	e = NewEncoder()
+	if err = e.Encode(lists); err != nil {
+		t.Fatal(err)
+	}
+	b = e.Buffer()
+	if c := bytes.Count(b, []byte("\x04[int")); c != 1 {
+		t.Fatalf("the list type should be written once, but get %d", c)
+	}
+	if got, err = NewDecoder(b).Decode(); err != nil {
+		t.Fatal(err)
+	}
+	ary := got.([][]int32)
+	if len(ary) != n || !reflect.DeepEqual(ary[n-1], []int32{n - 1}) {
+		t.Fatalf("decode the lists wrongly: %v", ary[len(ary)-1])
+	}
+
+	m1, m2 := NewOrderedMap("java.util.TreeMap"), NewOrderedMap("java.util.TreeMap")
+	m1.Put("a", int32(1))
+	m2.Put("b", int32(2))
+	e = NewEncoder()
+	if err = e.Encode([]interface{}{m1, m2}); err != nil {
+		t.Fatal(err)
+	}
+	b = e.Buffer()
+	if c := bytes.Count(b, []byte("java.util.TreeMap")); c != 1 {
+		t.Fatalf("the map type should be written once, but get %d", c)
+	}
+	if got, err = NewDecoder(b).Decode(); err != nil {
+		t.Fatal(err)
+	}
+	if maps := got.([]interface{}); len(maps) != 2 || !reflect.DeepEqual(maps[1].(*OrderedMap).ToMap(), map[interface{}]interface{}{"b": int32(2)}) {
+		t.Fatalf("decode the maps wrongly: %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package hessian
+
+import (
+	"reflect"
+	"sort"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// GenericObject is an instance of java class ClassName, which is decoded by a decoder in generic mode.
+// It is encoded back as an instance of ClassName whose fields are in the order of the decoded
+// class definition, the fields that are not in the class definition are appended in name order.
+type GenericObject struct {
+	ClassName  string
+	Fields     map[string]interface{}
+	fieldNames []string // field order of the decoded class definition
+}
+
+// NewGenericObject creates an instance of java class @className without any field.
+func NewGenericObject(className string) *GenericObject {
+	return &GenericObject{ClassName: className, Fields: make(map[string]interface{})}
+}
+
+// fieldList gets the field names in encoding order.
+func (o *GenericObject) fieldList() []string {
+	names := make([]string, 0, len(o.Fields))
+	known := make(map[string]bool, len(o.fieldNames))
+	for _, name := range o.fieldNames {
+		if _, ok := o.Fields[name]; ok && !known[name] {
+			known[name] = true
+			names = append(names, name)
+		}
+	}
+	var others []string
+	for name := range o.Fields {
+		if !known[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+
+	return append(names, others...)
+}
+
+// SetGenericMode sets whether the decoder decodes every java object into a *GenericObject,
+// no matter if its class has been registered. The classes having a Serializer, such as
+// java.math.BigDecimal, are still decoded by their Serializer if registered.
+func (d *Decoder) SetGenericMode(generic bool) {
+	d.generic = generic
+}
+
+// decGenericObject reads the fields of an instance of class definition @idx.
+func (d *Decoder) decGenericObject(idx int) (interface{}, error) {
+	if idx < 0 || idx >= len(d.classInfoList) {
+		return nil, perrors.Errorf("illegal class index @idx %d at offset %d", idx, d.Offset())
+	}
+
+	cls := d.classInfoList[idx]
+	if err := d.enterContainer(); err != nil {
+		return nil, err
+	}
+	defer d.leaveContainer()
+	if err := d.addElements(len(cls.fieldNameList)); err != nil {
+		return nil, err
+	}
+
+	o := NewGenericObject(cls.javaName)
+	o.fieldNames = cls.fieldNameList
+	d.appendRefs(o)
+
+	pathDepth := len(d.path)
+	defer func() { d.path = d.path[:pathDepth] }()
+	for _, fieldName := range cls.fieldNameList {
+		if d.strict {
+			d.path = append(d.path[:pathDepth], fieldName)
+		}
+		v, err := d.Decode()
+		if err != nil {
+			return nil, perrors.Wrapf(err, "failed to decode field %s of %s", fieldName, cls.javaName)
+		}
+		o.Fields[fieldName] = v
+	}
+
+	return o, nil
+}
+
+func (e *Encoder) encGenericObject(o *GenericObject) error {
+	if o == nil {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
+
+	// check ref
+	if n, ok := e.checkRefMap(reflect.ValueOf(o)); ok {
+		e.buffer = encRef(e.buffer, n)
+		return nil
+	}
+
+	fieldList := o.fieldList()
+
+	// write object definition
+	idx := -1
+	for i := range e.classInfoList {
+		if e.classInfoList[i].javaName == o.ClassName && reflect.DeepEqual(e.classInfoList[i].fieldNameList, fieldList) {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		cls := classInfo{javaName: o.ClassName, fieldNameList: fieldList}
+		cls.buffer = encByte(cls.buffer, BC_OBJECT_DEF)
+		cls.buffer = encString(cls.buffer, o.ClassName)
+		cls.buffer = encInt32(cls.buffer, int32(len(fieldList)))
+		for _, fieldName := range fieldList {
+			cls.buffer = encString(cls.buffer, fieldName)
+		}
+
+		idx = len(e.classInfoList)
+		e.classInfoList = append(e.classInfoList, cls)
+		e.buffer = append(e.buffer, cls.buffer...)
+	}
+
+	// write object instance
+	if idx <= int(OBJECT_DIRECT_MAX) {
+		e.buffer = encByte(e.buffer, byte(idx)+BC_OBJECT_DIRECT)
+	} else {
+		e.buffer = encByte(e.buffer, BC_OBJECT)
+		e.buffer = encInt32(e.buffer, int32(idx))
+	}
+	for _, fieldName := range fieldList {
+		if err := e.Encode(o.Fields[fieldName]); err != nil {
+			return perrors.Wrapf(err, "failed to encode field %s of %s", fieldName, o.ClassName)
+		}
+	}
+
+	return nil
+}
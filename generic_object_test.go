@@ -0,0 +1,88 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenericObject(t *testing.T) {
+	RegisterPOJO(&Case{})
+	c := &Case{A: "a", B: 1}
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{c, c}))
+	d := NewDecoder(e.Buffer())
+	d.SetGenericMode(true)
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	list, ok := res.([]interface{})
+	if !ok {
+		assert.FailNow(t, "invalid decoded list", "expect []interface{}, but get %T", res)
+	}
+	o, ok := list[0].(*GenericObject)
+	if !ok {
+		assert.FailNow(t, "invalid decoded object", "expect *GenericObject, but get %T", list[0])
+	}
+	assert.Equal(t, "com.test.case", o.ClassName)
+	assert.Equal(t, map[string]interface{}{"a": "a", "b": int64(1)}, o.Fields)
+	// the ref refers to the same generic object
+	assert.True(t, o == list[1])
+
+	// a typed list of a registered class holds the generic objects too
+	e = NewEncoder()
+	assert.Nil(t, e.Encode([]*Case{c}))
+	d = NewDecoder(e.Buffer())
+	d.SetGenericMode(true)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.IsType(t, &GenericObject{}, res.([]interface{})[0])
+
+	// a generic object is encoded back as an instance of its java class
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(o))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, c, res)
+
+	// an unregistered class
+	buf := encTestClassInstance(nil, 0, "test.Unregistered", []string{"name", "age"}, "x", int32(3))
+	d = NewDecoder(buf)
+	d.SetGenericMode(true)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	o = res.(*GenericObject)
+	assert.Equal(t, "test.Unregistered", o.ClassName)
+	assert.Equal(t, map[string]interface{}{"name": "x", "age": int32(3)}, o.Fields)
+
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(o))
+	assert.Equal(t, buf, e.Buffer())
+
+	// the fields added to a generic object are appended in name order
+	o = NewGenericObject("test.Added")
+	o.Fields["b"] = "b"
+	o.Fields["a"] = "a"
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(o))
+	d = NewDecoder(e.Buffer())
+	d.SetGenericMode(true)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, res.(*GenericObject).fieldNames)
+}
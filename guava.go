@@ -0,0 +1,54 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+// The immutable collections of guava are written by hessian as the lists and maps of their classes.
+// The lists and sets are decoded into slices like the jdk lists, ImmutableSortedMap into an *OrderedMap
+// like java.util.TreeMap, and the other maps into go maps.
+func init() {
+	for _, name := range []string{
+		"ImmutableList",
+		"RegularImmutableList",
+		"SingletonImmutableList",
+		"ImmutableList$SubList",
+		"ImmutableList$ReverseImmutableList",
+		"RegularImmutableAsList",
+		"ImmutableSortedAsList",
+		"ImmutableSet",
+		"RegularImmutableSet",
+		"SingletonImmutableSet",
+		"JdkBackedImmutableSet",
+		"ImmutableEnumSet",
+		"ImmutableSortedSet",
+		"RegularImmutableSortedSet",
+		"ImmutableMapKeySet",
+		"ImmutableMapValues",
+	} {
+		jdkListTypes["com.google.common.collect."+name] = true
+	}
+	for _, name := range []string{
+		"ImmutableMap",
+		"RegularImmutableMap",
+		"JdkBackedImmutableMap",
+		"ImmutableEnumMap",
+		"ImmutableBiMap",
+		"RegularImmutableBiMap",
+		"SingletonImmutableBiMap",
+		"JdkBackedImmutableBiMap",
+	} {
+		jdkMapTypes["com.google.common.collect."+name] = true
+	}
+	orderedMapJavaTypes["com.google.common.collect.ImmutableSortedMap"] = true
+}
@@ -0,0 +1,68 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuavaCollections(t *testing.T) {
+	// an ImmutableMap written by the MapSerializer of hessian
+	e := NewEncoder()
+	e.buffer = encByte(e.buffer, BC_MAP)
+	e.buffer = encString(e.buffer, "com.google.common.collect.RegularImmutableMap")
+	for _, kv := range [][2]interface{}{{"a", int32(1)}, {"b", int32(2)}} {
+		assert.Nil(t, e.Encode(kv[0]))
+		assert.Nil(t, e.Encode(kv[1]))
+	}
+	e.buffer = encByte(e.buffer, BC_END)
+	// an ImmutableList and an ImmutableSet written by the CollectionSerializer
+	for _, typ := range []string{"com.google.common.collect.RegularImmutableList", "com.google.common.collect.SingletonImmutableSet"} {
+		e.buffer = encByte(e.buffer, BC_LIST_FIXED)
+		e.buffer = encString(e.buffer, typ)
+		e.buffer = encInt32(e.buffer, 1)
+		assert.Nil(t, e.Encode("x"))
+	}
+	// an ImmutableSortedMap keeps its order
+	e.buffer = encByte(e.buffer, BC_MAP)
+	e.buffer = encString(e.buffer, "com.google.common.collect.ImmutableSortedMap")
+	assert.Nil(t, e.Encode("k"))
+	assert.Nil(t, e.Encode("v"))
+	e.buffer = encByte(e.buffer, BC_END)
+
+	d := NewDecoder(e.Buffer())
+	d.SetStrict(true)
+	m, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{"a": int32(1), "b": int32(2)}, m)
+	var out map[string]int32
+	assert.Nil(t, ReflectResponse(m, &out))
+	assert.Equal(t, map[string]int32{"a": 1, "b": 2}, out)
+
+	for i := 0; i < 2; i++ {
+		list, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{"x"}, list)
+	}
+
+	sorted, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "com.google.common.collect.ImmutableSortedMap", sorted.(*OrderedMap).JavaType)
+	assert.Equal(t, []interface{}{"k"}, sorted.(*OrderedMap).Keys())
+}
@@ -17,6 +17,7 @@ package hessian
 import (
 	"bufio"
 	"encoding/binary"
+	"sync/atomic"
 	"time"
 )
 
@@ -58,11 +59,23 @@ type Service struct {
 
 // HessianCodec defines hessian codec
 type HessianCodec struct {
-	pkgType PackageType
-	reader  *bufio.Reader
-	bodyLen int
+	pkgType   PackageType
+	rspStatus byte
+	reader    *bufio.Reader
+	bodyLen   int
 }
 
+// max length of a dubbo package, including its header, accessed atomically
+var maxPayloadSize int64 = DEFAULT_LEN
+
+// SetMaxPayloadSize for customize the max length of a dubbo package including its header, which is
+// DEFAULT_LEN(8M) by default. It takes effect to both packing a package and reading a package header,
+// and is safe to be called while the packages are being packed and read.
+func SetMaxPayloadSize(n int) { atomic.StoreInt64(&maxPayloadSize, int64(n)) }
+
+// getMaxPayloadSize gets the max length of a dubbo package set by SetMaxPayloadSize.
+func getMaxPayloadSize() int { return int(atomic.LoadInt64(&maxPayloadSize)) }
+
 // NewHessianCodec generate a new hessian codec instance
 func NewHessianCodec(reader *bufio.Reader) *HessianCodec {
 	return &HessianCodec{
@@ -91,6 +104,30 @@ func (h *HessianCodec) Write(service Service, header DubboHeader, body interface
 	// unreachable return nil, nil
 }
 
+// EncodeHeartbeatRequest packs a two way heartbeat request of id @id like the ExchangeCodec of dubbo,
+// whose body is a null written by serialization @serialID, such as 2 for hessian2.
+func EncodeHeartbeatRequest(id int64, serialID byte) ([]byte, error) {
+	return packHeartbeat(DubboRequestHeartbeatHeader, id, serialID)
+}
+
+// EncodeHeartbeatResponse packs the heartbeat response of the heartbeat request @id like the ExchangeCodec
+// of dubbo, whose status is Response_OK and body is a null written by serialization @serialID.
+func EncodeHeartbeatResponse(id int64, serialID byte) ([]byte, error) {
+	header := DubboResponseHeartbeatHeader
+	header[3] = Response_OK
+	return packHeartbeat(header, id, serialID)
+}
+
+func packHeartbeat(header [HEADER_LENGTH]byte, id int64, serialID byte) ([]byte, error) {
+	if serialID == Zero || serialID&^SERIAL_MASK != 0 {
+		return nil, perrors.Errorf("illegal serialization ID:%v", serialID)
+	}
+	header[2] |= serialID
+	binary.BigEndian.PutUint64(header[4:], uint64(id))
+	binary.BigEndian.PutUint32(header[12:], 1)
+	return encNull(header[:]), nil
+}
+
 // ReadHeader uses hessian codec to read dubbo header
 func (h *HessianCodec) ReadHeader(header *DubboHeader) error {
 
@@ -109,9 +146,45 @@ func (h *HessianCodec) ReadHeader(header *DubboHeader) error {
 	}
 
 	//// read header
+	if err = parseHeader(buf, header); err != nil {
+		return err
+	}
 
-	if buf[0] != MAGIC_HIGH && buf[1] != MAGIC_LOW {
-		return ErrIllegalPackage
+	h.pkgType = header.Type
+	h.rspStatus = header.ResponseStatus
+	h.bodyLen = header.BodyLen
+
+	if h.reader.Buffered() < h.bodyLen {
+		return ErrBodyNotEnough
+	}
+
+	return perrors.WithStack(err)
+
+}
+
+// DecodeHeader parses the dubbo header in the first HEADER_LENGTH bytes of @buf without decoding the body,
+// such as for a proxy to route or drop the packages cheaply. The type of the package, such as a heartbeat,
+// and the length of the body which follows the header are in the returned header. It returns
+// ErrHeaderNotEnough if @buf is shorter than the header, an error of ErrIllegalPackage if the magic
+// number does not match, and an error of ErrUnsupportedSerialization if the serialization is not hessian2.
+func DecodeHeader(buf []byte) (DubboHeader, error) {
+	var header DubboHeader
+	if len(buf) < HEADER_LENGTH {
+		return header, ErrHeaderNotEnough
+	}
+	if err := parseHeader(buf, &header); err != nil {
+		return header, err
+	}
+	if header.SerialID != SERIAL_ID_HESSIAN2 {
+		return header, perrors.Wrapf(ErrUnsupportedSerialization, "serialization ID:%v", header.SerialID)
+	}
+	return header, nil
+}
+
+// parseHeader parses the dubbo header @buf of HEADER_LENGTH bytes into @header.
+func parseHeader(buf []byte, header *DubboHeader) error {
+	if buf[0] != MAGIC_HIGH || buf[1] != MAGIC_LOW {
+		return perrors.Wrapf(ErrIllegalPackage, "magic %#02x%02x", buf[0], buf[1])
 	}
 
 	// Header{serialization id(5 bit), event, two way, req/response}
@@ -146,16 +219,10 @@ func (h *HessianCodec) ReadHeader(header *DubboHeader) error {
 	if header.BodyLen < 0 {
 		return ErrIllegalPackage
 	}
-
-	h.pkgType = header.Type
-	h.bodyLen = header.BodyLen
-
-	if h.reader.Buffered() < h.bodyLen {
-		return ErrBodyNotEnough
+	if limit := getMaxPayloadSize(); header.BodyLen+HEADER_LENGTH > limit {
+		return perrors.Wrapf(ErrPayloadTooLarge, "Data length %d too large, max payload %d", header.BodyLen+HEADER_LENGTH, limit)
 	}
-
-	return perrors.WithStack(err)
-
+	return nil
 }
 
 // ReadBody uses hessian codec to read response body
@@ -175,17 +242,7 @@ func (h *HessianCodec) ReadBody(rspObj interface{}) error {
 
 	switch h.pkgType & 0x2f {
 	case PackageResponse | PackageHeartbeat | PackageResponse_Exception, PackageResponse | PackageResponse_Exception:
-		decoder := NewDecoder(buf[:])
-		exception, err := decoder.Decode()
-		if err != nil {
-			return perrors.WithStack(err)
-		}
-		rsp, ok := rspObj.(*Response)
-		if !ok {
-			return perrors.Errorf("java exception:%s", exception.(string))
-		}
-		rsp.Exception = perrors.Errorf("java exception:%s", exception.(string))
-		return nil
+		return unpackErrorResponseBody(buf, h.rspStatus, rspObj)
 	case PackageRequest | PackageHeartbeat, PackageResponse | PackageHeartbeat:
 	case PackageRequest:
 		if rspObj != nil {
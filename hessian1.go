@@ -0,0 +1,453 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// the tags of hessian 1.0 which differ from hessian 2.0
+const (
+	v1Date        = byte('d') // 'd' b64, the milliseconds since the epoch
+	v1StringChunk = byte('s') // 's' b16 utf8, a non-final chunk of a string
+	v1XML         = byte('X') // 'X' b16 utf8, the final chunk of an xml
+	v1XMLChunk    = byte('x') // 'x' b16 utf8, a non-final chunk of an xml
+	v1BinaryChunk = byte('b') // 'b' b16 bytes, a non-final chunk of a binary
+	v1Type        = byte('t') // 't' b16 bytes, the type of a list or map
+	v1Length      = byte('l') // 'l' b32, the length of a list
+	v1End         = byte('z') // the end of a list or map
+	v1Ref         = byte('R') // 'R' b32, a ref to a list or map
+	v1Remote      = byte('r') // 'r' type url, a remote object
+)
+
+// NewDecoderV1 generate a decoder instance of the hessian 1.0 wire format, such as for a legacy peer.
+// The encoder only writes hessian 2.0, and the decoders of NewDecoder only read hessian 2.0.
+//
+// The values of hessian 1.0 are decoded into the same go types as their hessian 2.0 counterparts,
+// and the settings of the decoder, such as the max depth, the strict mode and the int64 mode, apply.
+// An object of hessian 1.0 is a typed map of its class, which is decoded into the registered POJO by
+// the keys as the field names, or by the DecodeHook of the class, or into a *GenericObject in generic
+// mode, and the other typed maps into go maps or *OrderedMap like hessian 2.0.
+//
+// The features of hessian 1.0 which are not supported:
+//   - the remote objects 'r', and the envelopes of calls, replies, faults and headers, since only values are decoded
+//   - the Serializers, such as of java.math.BigDecimal and java.sql.Timestamp, whose classes are decoded as their
+//     registered POJO without the conversion
+//   - the streaming of big binaries by SetBinaryStreamThreshold, and RawValue fields, which are decoded as
+//     hessian 2.0 values
+func NewDecoderV1(b []byte) *Decoder {
+	d := NewDecoder(b)
+	d.v1 = true
+	return d
+}
+
+// decV1Value decodes a hessian 1.0 value.
+func (d *Decoder) decV1Value() (interface{}, error) {
+	tag, err := d.readByte()
+	if err != nil {
+		// the input ends between the top level values
+		if perrors.Cause(err) == ErrShortBuffer && d.depth == 0 {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	switch tag {
+	case v1End:
+		// the end of a list or map, like BC_END
+		return nil, io.EOF
+	case BC_NULL:
+		return nil, nil
+	case BC_TRUE:
+		return true, nil
+	case BC_FALSE:
+		return false, nil
+	case BC_INT:
+		// 'I' b32 is the same as hessian 2.0
+		i, err := d.decInt32(int32(tag))
+		if d.int64Mode && err == nil {
+			return int64(i), nil
+		}
+		return i, err
+	case BC_LONG:
+		return d.decInt64(int32(tag))
+	case BC_DOUBLE:
+		return d.decDouble(int32(tag))
+	case v1Date:
+		// the same as the 64-bit date of hessian 2.0
+		t, err := d.decDate(int32(BC_DATE))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodedTime(t), nil
+	case BC_STRING, v1StringChunk, v1XML, v1XMLChunk:
+		return d.decV1String(tag)
+	case BC_BINARY, v1BinaryChunk:
+		return d.decV1Binary(tag)
+	case BC_LIST_FIXED:
+		return d.decV1List()
+	case BC_MAP:
+		return d.decV1Map()
+	case v1Ref:
+		var buf [4]byte
+		if _, err = d.readFull(buf[:]); err != nil {
+			return nil, perrors.WithStack(err)
+		}
+		return d.refAt(UnpackInt32(buf[:]))
+	case v1Remote:
+		return nil, perrors.New("hessian 1.0 remote object is not supported")
+	default:
+		return nil, d.tagErrorf("illegal hessian 1.0 tag:%#x", tag)
+	}
+}
+
+// decV1String decodes the chunks of a hessian 1.0 string or xml whose first tag is @tag.
+// A chunk is like the final chunk 'S' of hessian 2.0.
+func (d *Decoder) decV1String(tag byte) (string, error) {
+	var (
+		sb    strings.Builder
+		chars int
+	)
+	for {
+		s, err := d.decString(int32(BC_STRING))
+		if err != nil {
+			return "", perrors.WithStack(err)
+		}
+		if chars += utf8.RuneCountInString(s); d.maxStringLength > 0 && chars > d.maxStringLength {
+			return "", perrors.Wrapf(ErrMaxStringLengthExceeded, "string of %d chars, max length %d", chars, d.maxStringLength)
+		}
+		sb.WriteString(s)
+		if tag == BC_STRING || tag == v1XML {
+			return sb.String(), nil
+		}
+
+		if tag, err = d.readByte(); err != nil {
+			return "", perrors.WithStack(err)
+		}
+		if tag != BC_STRING && tag != v1StringChunk && tag != v1XML && tag != v1XMLChunk {
+			return "", d.tagErrorf("illegal hessian 1.0 string chunk tag:%#x", tag)
+		}
+	}
+}
+
+// decV1Binary decodes the chunks of a hessian 1.0 binary whose first tag is @tag.
+// A chunk is like the final chunk 'B' of hessian 2.0.
+func (d *Decoder) decV1Binary(tag byte) ([]byte, error) {
+	var data []byte
+	for {
+		b, err := d.decBinary(int32(BC_BINARY))
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
+		if d.maxBinaryLength > 0 && len(data)+len(b) > d.maxBinaryLength {
+			return nil, perrors.Wrapf(ErrMaxBinaryLengthExceeded, "binary of %d bytes, max length %d", len(data)+len(b), d.maxBinaryLength)
+		}
+		data = append(data, b...)
+		if tag == BC_BINARY {
+			return data, nil
+		}
+
+		if tag, err = d.readByte(); err != nil {
+			return nil, perrors.WithStack(err)
+		}
+		if tag != BC_BINARY && tag != v1BinaryChunk {
+			return nil, d.tagErrorf("illegal hessian 1.0 binary chunk tag:%#x", tag)
+		}
+	}
+}
+
+// decV1Type reads the optional type 't' of a hessian 1.0 list or map, which is empty if absent.
+func (d *Decoder) decV1Type() (string, error) {
+	if d.peekByte() != v1Type {
+		return "", nil
+	}
+	var buf [2]byte
+	if _, err := d.readByte(); err != nil {
+		return "", perrors.WithStack(err)
+	}
+	if _, err := d.readFull(buf[:]); err != nil {
+		return "", perrors.WithStack(err)
+	}
+	length := int(buf[0])<<8 + int(buf[1])
+	if err := d.checkRemaining(length); err != nil {
+		return "", err
+	}
+	name := make([]byte, length)
+	if _, err := d.readFull(name); err != nil {
+		return "", perrors.WithStack(err)
+	}
+	if length == 0 {
+		return "", nil
+	}
+
+	typ := d.rewriteClassName(string(name))
+	d.observeClass(typ)
+	return typ, d.checkStrictType(typ)
+}
+
+// decV1List decodes a hessian 1.0 list, 'V' type? length? value* 'z'.
+func (d *Decoder) decV1List() (interface{}, error) {
+	typ, err := d.decV1Type()
+	if err != nil {
+		return nil, err
+	}
+	if d.peekByte() == v1Length {
+		// the length is only a hint, the list ends at 'z'
+		var buf [5]byte
+		if _, err = d.readFull(buf[:]); err != nil {
+			return nil, perrors.WithStack(err)
+		}
+	}
+
+	if err = d.enterContainer(); err != nil {
+		return nil, err
+	}
+	defer d.leaveContainer()
+
+	arrType := getListType(typ)
+	if d.int64Mode {
+		arrType = int64ListType(arrType)
+	}
+	if d.epochMillis {
+		arrType = epochMillisListType(arrType)
+	}
+	// the objects are decoded into *GenericObject in generic mode
+	if d.generic && arrType != nil && arrType.Elem().Kind() == reflect.Ptr && arrType.Elem().Elem().Kind() == reflect.Struct {
+		arrType = nil
+	}
+	if arrType == nil {
+		arrType = reflect.TypeOf([]interface{}{})
+	}
+
+	aryValue := reflect.MakeSlice(arrType, 0, 0)
+	holder := d.appendRefs(aryValue)
+	for d.peekByte() != v1End {
+		if err = d.addElements(1); err != nil {
+			return nil, err
+		}
+		it, err := d.DecodeValue()
+		if err != nil {
+			if err == io.EOF {
+				return nil, d.offsetError(ErrShortBuffer)
+			}
+			return nil, perrors.WithStack(err)
+		}
+		if it != nil {
+			aryValue = reflect.Append(aryValue, EnsureRawValue(it))
+		} else {
+			aryValue = reflect.Append(aryValue, reflect.Zero(arrType.Elem()))
+		}
+		holder.change(aryValue)
+	}
+	if _, err = d.readByte(); err != nil {
+		return nil, perrors.WithStack(err)
+	}
+
+	if arrType.Elem().Kind() == reflect.Interface {
+		// the list type is a java collection class, such as java.util.LinkedList
+		if v, ok := d.narrowPOJOList(aryValue); ok {
+			holder.change(v)
+		}
+	}
+	return holder, nil
+}
+
+// decV1Map decodes a hessian 1.0 map, 'M' type? (key value)* 'z', which is an object if its type is a class.
+func (d *Decoder) decV1Map() (interface{}, error) {
+	typ, err := d.decV1Type()
+	if err != nil {
+		return nil, err
+	}
+	if err = d.enterContainer(); err != nil {
+		return nil, err
+	}
+	defer d.leaveContainer()
+
+	info, registered := getStructInfo(typ)
+	switch {
+	case typ != "" && registered && info.typ.Implements(javaEnumType):
+		refIndex := len(d.refs)
+		d.appendRefs(nil)
+		fields, err := d.readV1Fields(typ)
+		if err != nil {
+			return nil, err
+		}
+		name, _ := fields["name"].(string)
+		enumValue := info.inst.(POJOEnum).EnumValue(name)
+		if enumValue == InvalidJavaEnum {
+			d.refs[refIndex] = name
+			return name, perrors.Wrapf(ErrUnknownJavaEnum, "%s.%s", typ, name)
+		}
+		var typedValue interface{} = enumValue
+		if v := reflect.ValueOf(enumValue); v.Type().ConvertibleTo(info.typ) {
+			typedValue = v.Convert(info.typ).Interface()
+		}
+		d.refs[refIndex] = typedValue
+		return typedValue, nil
+
+	case typ != "" && registered && !d.generic:
+		inst := reflect.New(info.typ).Interface()
+		d.appendRefs(inst)
+		fields, err := d.readV1Fields(typ)
+		if err != nil {
+			return nil, err
+		}
+		if err = copyMapToStruct(reflect.ValueOf(fields), reflect.ValueOf(inst), "", false); err != nil {
+			return nil, perrors.Wrapf(err, "hessian 1.0 object %s", typ)
+		}
+		return inst, nil
+
+	case !isV1MapType(typ) && d.generic:
+		o := NewGenericObject(typ)
+		d.appendRefs(o)
+		err = d.readV1Entries(func(k, v interface{}) {
+			name, _ := k.(string)
+			o.fieldNames = append(o.fieldNames, name)
+			o.Fields[name] = v
+		})
+		if err != nil {
+			return nil, err
+		}
+		return o, nil
+
+	case !isV1MapType(typ):
+		if hook, ok := getDecodeHook(typ); ok {
+			refIndex := len(d.refs)
+			d.appendRefs(nil)
+			fields, err := d.readV1Fields(typ)
+			if err != nil {
+				return nil, err
+			}
+			v, err := hook(typ, fields)
+			if err != nil {
+				return nil, perrors.Wrapf(err, "decode hook of %s", typ)
+			}
+			d.refs[refIndex] = v
+			return v, nil
+		}
+		// an unregistered class is decoded into a map like a typed map of hessian 2.0
+		d.checkUnknownClass(typ)
+
+	case orderedMapJavaTypes[typ]:
+		// the entries are kept in order as []MapEntry if a key can not be the key of a go map, like decOrderedMap
+		m := NewOrderedMap(typ)
+		refIndex := len(d.refs)
+		d.appendRefs(m)
+		var entries []MapEntry
+		err = d.readV1Entries(func(k, v interface{}) {
+			if entries == nil && !hashable(k) {
+				entries = make([]MapEntry, 0, m.Len()+1)
+				m.Range(func(key, value interface{}) bool {
+					entries = append(entries, MapEntry{Key: key, Value: value})
+					return true
+				})
+			}
+			if entries != nil {
+				entries = append(entries, MapEntry{Key: k, Value: v})
+				return
+			}
+			m.Put(k, v)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if entries != nil {
+			d.refs[refIndex] = entries
+			return entries, nil
+		}
+		return m, nil
+	}
+
+	m := make(map[interface{}]interface{})
+	refIndex := len(d.refs)
+	d.appendRefs(m)
+	var entries []MapEntry
+	err = d.readV1Entries(func(k, v interface{}) {
+		if entries == nil && !hashable(k) {
+			for mk, mv := range m {
+				entries = append(entries, MapEntry{Key: mk, Value: mv})
+			}
+		}
+		if entries != nil {
+			entries = append(entries, MapEntry{Key: k, Value: v})
+			return
+		}
+		m[k] = v
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entries != nil {
+		d.refs[refIndex] = entries
+		return entries, nil
+	}
+	return m, nil
+}
+
+// isV1MapType returns whether the type @typ of a hessian 1.0 map is a java map rather than a class.
+func isV1MapType(typ string) bool {
+	return typ == "" || jdkMapTypes[typ] || orderedMapJavaTypes[typ] || !strings.Contains(typ, ".") ||
+		strings.HasPrefix(typ, "java.util.")
+}
+
+// readV1Fields reads the fields of a hessian 1.0 object of class @typ until 'z', whose keys are the field names.
+func (d *Decoder) readV1Fields(typ string) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	var keyErr error
+	err := d.readV1Entries(func(k, v interface{}) {
+		name, ok := k.(string)
+		if !ok && keyErr == nil {
+			keyErr = perrors.Errorf("the field name of hessian 1.0 object %s should be a string, got %T", typ, k)
+		}
+		fields[name] = v
+	})
+	if err == nil {
+		err = keyErr
+	}
+	return fields, err
+}
+
+// readV1Entries reads the entries of a hessian 1.0 map until 'z' and passes them to @put.
+func (d *Decoder) readV1Entries(put func(k, v interface{})) error {
+	for d.peekByte() != v1End {
+		if err := d.addElements(1); err != nil {
+			return err
+		}
+		k, err := d.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return d.offsetError(ErrShortBuffer)
+			}
+			return err
+		}
+		v, err := d.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return d.offsetError(ErrShortBuffer)
+			}
+			return err
+		}
+		put(k, v)
+	}
+	_, err := d.readByte()
+	return perrors.WithStack(err)
+}
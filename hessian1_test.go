@@ -0,0 +1,154 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// v1Chunk writes a hessian 1.0 chunk of @tag with the 16-bit length @n of @data.
+func v1Chunk(tag byte, n int, data string) []byte {
+	return append([]byte{tag, byte(n >> 8), byte(n)}, data...)
+}
+
+func v1Str(s string) []byte {
+	return v1Chunk(BC_STRING, len([]rune(s)), s)
+}
+
+func v1Int(i int32) []byte {
+	return []byte{BC_INT, byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
+}
+
+func concatBytes(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestDecoderV1(t *testing.T) {
+	when := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	millis := when.UnixNano() / 1e6
+	date := []byte{v1Date}
+	for i := 7; i >= 0; i-- {
+		date = append(date, byte(millis>>(8*i)))
+	}
+
+	data := concatBytes(
+		[]byte{BC_NULL, BC_TRUE, BC_FALSE},
+		v1Int(-2),
+		[]byte{BC_LONG, 0, 0, 0, 1, 0, 0, 0, 0},
+		[]byte{BC_DOUBLE, 0x40, 0x0c, 0, 0, 0, 0, 0, 0},
+		date,
+		// the chunks of a string, an xml and a binary
+		v1Chunk(v1StringChunk, 3, "hel"), v1Str("lo, 世界"),
+		v1Chunk(v1XMLChunk, 2, "<a"), v1Chunk(v1XML, 2, "/>"),
+		v1Chunk(v1BinaryChunk, 2, "\x01\x02"), v1Chunk(BC_BINARY, 1, "\x03"),
+		// a typed list of int with its length
+		[]byte{BC_LIST_FIXED, v1Type, 0, 4}, []byte("[int"), []byte{v1Length, 0, 0, 0, 2}, v1Int(1), v1Int(2), []byte{v1End},
+	)
+	d := NewDecoderV1(data)
+	for _, expected := range []interface{}{
+		nil, true, false, int32(-2), int64(1) << 32, 3.5, when, "hello, 世界", "<a/>", []byte{1, 2, 3}, []int32{1, 2},
+	} {
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, res)
+	}
+	_, err := d.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoderV1Objects(t *testing.T) {
+	RegisterPOJO(&Case{})
+	RegisterPOJO(&colorBox{})
+	RegisterJavaEnum(testColorRed)
+
+	object := func(class string, fields ...[]byte) []byte {
+		b := append([]byte{BC_MAP, v1Type, 0, byte(len(class))}, class...)
+		return append(concatBytes(append([][]byte{b}, fields...)...), v1End)
+	}
+	data := concatBytes(
+		// an untyped list of the same object twice, the list is ref 0 and the object ref 1
+		[]byte{BC_LIST_FIXED, v1Length, 0, 0, 0, 2},
+		object("com.test.case", v1Str("a"), v1Str("x"), v1Str("b"), v1Int(7)),
+		[]byte{'R', 0, 0, 0, 1, v1End},
+		// an object with an enum field
+		object("test.model.ColorBox", v1Str("color"), object("test.model.Color", v1Str("name"), v1Str("GREEN")),
+			v1Str("label"), v1Str("box")),
+		// an untyped map, a sorted map and an unregistered class
+		[]byte{BC_MAP}, v1Str("k"), v1Int(1), []byte{v1End},
+		object("java.util.TreeMap", v1Str("b"), v1Int(2), v1Str("a"), v1Int(1)),
+		object("com.legacy.Unknown", v1Str("id"), v1Int(3)),
+	)
+
+	d := NewDecoderV1(data)
+	d.SetTypedPOJOLists(true)
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	cases := res.([]*Case)
+	assert.Equal(t, []*Case{{A: "x", B: 7}, {A: "x", B: 7}}, cases)
+	assert.True(t, cases[0] == cases[1])
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &colorBox{Color: testColorGreen, Label: "box"}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{"k": int32(1)}, res)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"b", "a"}, res.(*OrderedMap).Keys())
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{"id": int32(3)}, res)
+
+	// the unregistered class in generic mode
+	d = NewDecoderV1(object("com.legacy.Unknown", v1Str("id"), v1Int(3)))
+	d.SetGenericMode(true)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "com.legacy.Unknown", res.(*GenericObject).ClassName)
+	assert.Equal(t, int32(3), res.(*GenericObject).Fields["id"])
+
+	// a sorted map whose second key is a list is kept in order as map entries
+	res, err = NewDecoderV1(object("java.util.TreeMap", v1Str("a"), v1Int(1),
+		[]byte{BC_LIST_FIXED}, v1Int(2), []byte{v1End}, v1Int(3))).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []MapEntry{{Key: "a", Value: int32(1)}, {Key: []interface{}{int32(2)}, Value: int32(3)}}, res)
+
+	// a list of the same empty map twice, V l 0002 M z R 00000001 z
+	res, err = NewDecoderV1([]byte{'V', 'l', 0, 0, 0, 2, 'M', 'z', 'R', 0, 0, 0, 1, 'z'}).Decode()
+	assert.Nil(t, err)
+	list := res.([]interface{})
+	assert.Equal(t, []interface{}{map[interface{}]interface{}{}, map[interface{}]interface{}{}}, list)
+	assert.Equal(t, reflect.ValueOf(list[0]).Pointer(), reflect.ValueOf(list[1]).Pointer())
+
+	// the unsupported remote object, and a list without its end
+	_, err = NewDecoderV1([]byte{v1Remote}).Decode()
+	assert.NotNil(t, err)
+	_, err = NewDecoderV1(concatBytes([]byte{BC_LIST_FIXED}, v1Int(1))).Decode()
+	assert.Equal(t, ErrShortBuffer, perrors.Cause(err))
+}
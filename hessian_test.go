@@ -18,11 +18,13 @@ import (
 	"bufio"
 	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
 import (
+	perrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -137,6 +139,32 @@ func TestResponse(t *testing.T) {
 	})
 }
 
+func TestResponseRemoteError(t *testing.T) {
+	for _, status := range []byte{Response_SERVICE_ERROR, Response_SERVER_TIMEOUT, Response_BAD_REQUEST} {
+		strObj := ""
+		decodedResponse := &Response{RspObj: &strObj}
+		doTestResponse(t, PackageResponse, status, "remote error", decodedResponse, func() {
+			remoteErr, ok := decodedResponse.Exception.(*DubboRemoteError)
+			if !ok {
+				assert.FailNow(t, "invalid exception", "expect *DubboRemoteError, but get %T", decodedResponse.Exception)
+			}
+			assert.Equal(t, status, remoteErr.Status)
+			assert.Equal(t, "remote error", remoteErr.Message)
+			assert.Equal(t, "java exception:remote error", remoteErr.Error())
+		})
+	}
+
+	// the error is returned if the body is not read into a *Response
+	resp, err := doTestHessianEncodeHeader(t, PackageResponse, Response_SERVICE_ERROR, "remote error")
+	assert.Nil(t, err)
+	codecR := NewHessianCodec(bufio.NewReader(bytes.NewReader(resp)))
+	assert.Nil(t, codecR.ReadHeader(&DubboHeader{}))
+	err = codecR.ReadBody(new(string))
+	remoteErr, ok := err.(*DubboRemoteError)
+	assert.True(t, ok)
+	assert.Equal(t, Response_SERVICE_ERROR, remoteErr.Status)
+}
+
 func doTestRequest(t *testing.T, packageType PackageType, responseStatus byte, body interface{}) {
 	resp, err := doTestHessianEncodeHeader(t, packageType, responseStatus, body)
 
@@ -167,3 +195,99 @@ func TestRequest(t *testing.T) {
 	doTestRequest(t, PackageRequest, Zero, []interface{}{"a", 3, true, []*Case{{A: "a", B: 3}}})
 	doTestRequest(t, PackageRequest, Zero, []interface{}{map[string][]*Case{"key": {{A: "a", B: 3}}}})
 }
+
+func BenchmarkPackResponse(b *testing.B) {
+	RegisterPOJO(&Case{})
+	body := make([]*Case, 0, 64)
+	for i := 0; i < 64; i++ {
+		body = append(body, &Case{A: "dubbo-go", B: i})
+	}
+	header := DubboHeader{SerialID: 2, Type: PackageResponse, ID: 1, ResponseStatus: Response_OK}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := packResponse(header, body); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	defer SetMaxPayloadSize(DEFAULT_LEN)
+
+	body := strings.Repeat("a", 1024)
+	SetMaxPayloadSize(512)
+	_, err := packResponse(DubboHeader{SerialID: 2, Type: PackageResponse, ID: 1, ResponseStatus: Response_OK}, body)
+	assert.Equal(t, ErrPayloadTooLarge, perrors.Cause(err))
+
+	SetMaxPayloadSize(2048)
+	resp, err := packResponse(DubboHeader{SerialID: 2, Type: PackageResponse, ID: 1, ResponseStatus: Response_OK}, body)
+	assert.Nil(t, err)
+
+	// an oversized incoming package is rejected by its header
+	SetMaxPayloadSize(512)
+	codecR := NewHessianCodec(bufio.NewReader(bytes.NewReader(resp)))
+	err = codecR.ReadHeader(&DubboHeader{})
+	assert.Equal(t, ErrPayloadTooLarge, perrors.Cause(err))
+}
+
+func TestEncodeHeartbeat(t *testing.T) {
+	// the frames written by the ExchangeCodec of dubbo for the heartbeat of id 7 in hessian2
+	req, err := EncodeHeartbeatRequest(7, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xda, 0xbb, 0xe2, 0, 0, 0, 0, 0, 0, 0, 0, 7, 0, 0, 0, 1, 'N'}, req)
+	rsp, err := EncodeHeartbeatResponse(7, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xda, 0xbb, 0x22, 0x14, 0, 0, 0, 0, 0, 0, 0, 7, 0, 0, 0, 1, 'N'}, rsp)
+
+	for _, frame := range [][]byte{req, rsp} {
+		codec := NewHessianCodec(bufio.NewReader(bytes.NewReader(frame)))
+		var header DubboHeader
+		assert.Nil(t, codec.ReadHeader(&header))
+		assert.Equal(t, int64(7), header.ID)
+		assert.NotZero(t, header.Type&PackageHeartbeat)
+		assert.Nil(t, codec.ReadBody(nil))
+	}
+
+	_, err = EncodeHeartbeatResponse(7, 0)
+	assert.NotNil(t, err)
+	_, err = EncodeHeartbeatRequest(7, 0x20)
+	assert.NotNil(t, err)
+}
+
+func TestDecodeHeader(t *testing.T) {
+	req, err := doTestHessianEncodeHeader(t, PackageRequest, Zero, []interface{}{"a"})
+	assert.Nil(t, err)
+	header, err := DecodeHeader(req)
+	assert.Nil(t, err)
+	assert.Equal(t, SERIAL_ID_HESSIAN2, header.SerialID)
+	assert.Equal(t, PackageRequest, header.Type)
+	assert.Zero(t, header.Type&PackageHeartbeat)
+	assert.Equal(t, int64(1), header.ID)
+	assert.Equal(t, len(req)-HEADER_LENGTH, header.BodyLen)
+
+	heartbeat, err := EncodeHeartbeatResponse(7, 2)
+	assert.Nil(t, err)
+	header, err = DecodeHeader(heartbeat[:HEADER_LENGTH])
+	assert.Nil(t, err)
+	assert.NotZero(t, header.Type&PackageHeartbeat)
+	assert.NotZero(t, header.Type&PackageResponse)
+	assert.Equal(t, int64(7), header.ID)
+	assert.Equal(t, 1, header.BodyLen)
+
+	_, err = DecodeHeader(heartbeat[:HEADER_LENGTH-1])
+	assert.Equal(t, ErrHeaderNotEnough, err)
+
+	illegal := append([]byte{}, heartbeat...)
+	illegal[1] = 0xbc
+	_, err = DecodeHeader(illegal)
+	assert.Equal(t, ErrIllegalPackage, perrors.Cause(err))
+
+	// the serialization id 6 is fastjson
+	other, err := EncodeHeartbeatRequest(7, 6)
+	assert.Nil(t, err)
+	header, err = DecodeHeader(other)
+	assert.Equal(t, ErrUnsupportedSerialization, perrors.Cause(err))
+	assert.Equal(t, byte(6), header.SerialID)
+}
@@ -16,7 +16,6 @@ package hessian
 
 import (
 	"encoding/binary"
-	"io"
 )
 
 import (
@@ -63,7 +62,10 @@ func (d *Decoder) decInt32(flag int32) (int32, error) {
 	if flag != TAG_READ {
 		tag = byte(flag)
 	} else {
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return 0, perrors.WithStack(err)
+		}
 	}
 
 	switch {
@@ -73,7 +75,7 @@ func (d *Decoder) decInt32(flag int32) (int32, error) {
 
 	case tag >= 0xc0 && tag <= 0xcf:
 		buf := []byte{tag - BC_INT_BYTE_ZERO, 0}
-		_, err = io.ReadFull(d.reader, buf[1:])
+		_, err = d.readFull(buf[1:])
 		if err != nil {
 			return 0, perrors.WithStack(err)
 		}
@@ -87,7 +89,7 @@ func (d *Decoder) decInt32(flag int32) (int32, error) {
 		if buf[1]&0x80 != 0 {
 			buf[0] = 0xff
 		}
-		_, err = io.ReadFull(d.reader, buf[2:])
+		_, err = d.readFull(buf[2:])
 		if err != nil {
 			return 0, perrors.WithStack(err)
 		}
@@ -95,11 +97,11 @@ func (d *Decoder) decInt32(flag int32) (int32, error) {
 		return int32(u32), nil
 
 	case tag == BC_INT:
-		var i32 int32
-		err = binary.Read(d.reader, binary.BigEndian, &i32)
-		return i32, perrors.WithStack(err)
+		var buf [4]byte
+		_, err = d.readFull(buf[:])
+		return UnpackInt32(buf[:]), perrors.WithStack(err)
 
 	default:
-		return 0, perrors.Errorf("decInt32 integer wrong tag:%#x", tag)
+		return 0, d.tagErrorf("decInt32 integer wrong tag:%#x", tag)
 	}
 }
@@ -15,6 +15,7 @@
 package hessian
 
 import (
+	"math"
 	"testing"
 )
 
@@ -118,3 +119,56 @@ func TestIntEncode(t *testing.T) {
 	testJavaDecode(t, "argInt_m16", int32(-16))
 	testJavaDecode(t, "argInt_m17", int32(-17))
 }
+
+// the encoded sizes follow Hessian2Output.writeInt and writeLong of java
+func TestEncIntegerCompactSize(t *testing.T) {
+	for _, c := range []struct {
+		v    int32
+		size int
+	}{
+		{-0x10, 1}, {0x2f, 1},
+		{-0x11, 2}, {0x30, 2}, {-0x800, 2}, {0x7ff, 2},
+		{-0x801, 3}, {0x800, 3}, {-0x40000, 3}, {0x3ffff, 3},
+		{-0x40001, 5}, {0x40000, 5}, {math.MinInt32, 5}, {math.MaxInt32, 5},
+	} {
+		b := encInt32(nil, c.v)
+		if len(b) != c.size {
+			t.Errorf("int %#x is encoded into %d bytes, expect %d", c.v, len(b), c.size)
+		}
+		if res, err := NewDecoder(b).Decode(); err != nil || res != c.v {
+			t.Errorf("Decode(%#x) = %v, %v", c.v, res, err)
+		}
+	}
+
+	for _, c := range []struct {
+		v    int64
+		size int
+	}{
+		{-0x08, 1}, {0x0f, 1},
+		{-0x09, 2}, {0x10, 2}, {-0x800, 2}, {0x7ff, 2},
+		{-0x801, 3}, {0x800, 3}, {-0x40000, 3}, {0x3ffff, 3},
+		{-0x40001, 5}, {0x40000, 5}, {math.MinInt32, 5}, {math.MaxInt32, 5},
+		{math.MinInt32 - 1, 9}, {math.MaxInt32 + 1, 9}, {math.MinInt64, 9}, {math.MaxInt64, 9},
+	} {
+		b := encInt64(nil, c.v)
+		if len(b) != c.size {
+			t.Errorf("long %#x is encoded into %d bytes, expect %d", c.v, len(b), c.size)
+		}
+		if res, err := NewDecoder(b).Decode(); err != nil || res != c.v {
+			t.Errorf("Decode(%#x) = %v, %v", c.v, res, err)
+		}
+	}
+
+	// a list of small ints takes a byte per element
+	list := make([]int32, 1000)
+	for i := range list {
+		list[i] = int32(i%64 - 16)
+	}
+	e := NewEncoder()
+	if err := e.Encode(list); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Buffer()) > len(list)+16 {
+		t.Errorf("a list of %d small ints is encoded into %d bytes", len(list), len(e.Buffer()))
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+)
+
+func init() {
+	RegisterPOJO(&java8_time.LocalDate{})
+	RegisterPOJO(&java8_time.LocalTime{})
+	RegisterPOJO(&java8_time.LocalDateTime{})
+	RegisterPOJO(&java8_time.Duration{})
+	RegisterPOJO(&java8_time.Instant{})
+	RegisterPOJO(&java8_time.ZoneOffset{})
+	RegisterPOJO(&java8_time.ZonedDateTime{})
+	SetSerializer(java8_time.Duration{}.JavaClassName(), DurationSerializer{})
+	SetSerializer(java8_time.Instant{}.JavaClassName(), InstantSerializer{})
+	SetSerializer(java8_time.ZonedDateTime{}.JavaClassName(), ZonedDateTimeSerializer{})
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// DurationSerializer decodes java.time.Duration into time.Duration, and
+// a duration out of the range of time.Duration is an error.
+type DurationSerializer struct {
+	objectSerializer
+}
+
+func (DurationSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		duration, ok := v.(*java8_time.Duration)
+		if !ok {
+			return nil, perrors.Errorf("result type %T is not java8_time.Duration", v)
+		}
+		result, err := duration.ToDuration()
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
+		return result, nil
+	})
+}
+
+// InstantSerializer decodes java.time.Instant into time.Time in UTC, keeping the nanos.
+// Encode a java8_time.Instant for a java.time.Instant, since a time.Time is sent as java.util.Date.
+type InstantSerializer struct {
+	objectSerializer
+}
+
+func (InstantSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		instant, ok := v.(*java8_time.Instant)
+		if !ok {
+			return nil, perrors.Errorf("result type %T is not java8_time.Instant", v)
+		}
+		return d.decodedTime(instant.ToTime()), nil
+	})
+}
+
+// ZonedDateTimeSerializer decodes java.time.ZonedDateTime into time.Time in the location of its zone id,
+// and an unknown zone id is an error. Encode a java8_time.ZonedDateTime for a java.time.ZonedDateTime.
+type ZonedDateTimeSerializer struct {
+	objectSerializer
+}
+
+func (ZonedDateTimeSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		zoned, ok := v.(*java8_time.ZonedDateTime)
+		if !ok {
+			return nil, perrors.Errorf("result type %T is not java8_time.ZonedDateTime", v)
+		}
+		t, err := zoned.ToTime()
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
+		return d.decodedTime(t), nil
+	})
+}
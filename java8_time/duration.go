@@ -0,0 +1,63 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package java8_time
+
+import (
+	"math"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// Duration is java.time.Duration, which is sent by dubbo as DurationHandle
+type Duration struct {
+	Seconds int64 `hessian:"seconds"`
+	Nanos   int32 `hessian:"nanos"`
+}
+
+// NewDuration returns the java duration of @d, whose nanos is never negative like java
+func NewDuration(d time.Duration) Duration {
+	seconds := int64(d / time.Second)
+	nanos := int32(d % time.Second)
+	if nanos < 0 {
+		seconds--
+		nanos += int32(time.Second)
+	}
+	return Duration{Seconds: seconds, Nanos: nanos}
+}
+
+// ToDuration returns the go duration, or an error if it is out of the range of time.Duration,
+// which is about 292 years
+func (d Duration) ToDuration() (time.Duration, error) {
+	seconds, nanos := d.Seconds, int64(d.Nanos)
+	if seconds < 0 && nanos > 0 {
+		// avoid the overflow of the min duration
+		seconds++
+		nanos -= int64(time.Second)
+	}
+	if seconds > math.MaxInt64/int64(time.Second) || seconds < math.MinInt64/int64(time.Second) {
+		return 0, perrors.Errorf("java duration %ds %dns overflows time.Duration", d.Seconds, d.Nanos)
+	}
+	ns := seconds * int64(time.Second)
+	if (nanos > 0 && ns > math.MaxInt64-nanos) || (nanos < 0 && ns < math.MinInt64-nanos) {
+		return 0, perrors.Errorf("java duration %ds %dns overflows time.Duration", d.Seconds, d.Nanos)
+	}
+	return time.Duration(ns + nanos), nil
+}
+
+func (Duration) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.java8.DurationHandle"
+}
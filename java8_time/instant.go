@@ -0,0 +1,39 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java8_time
+
+import (
+	"time"
+)
+
+// Instant is java.time.Instant, which is sent by dubbo as InstantHandle
+type Instant struct {
+	Seconds int64 `hessian:"seconds"`
+	Nanos   int32 `hessian:"nanos"`
+}
+
+// NewInstant returns the java instant of @t, whose nanos is never negative like java
+func NewInstant(t time.Time) Instant {
+	return Instant{Seconds: t.Unix(), Nanos: int32(t.Nanosecond())}
+}
+
+// ToTime returns the instant in UTC
+func (i Instant) ToTime() time.Time {
+	return time.Unix(i.Seconds, int64(i.Nanos)).UTC()
+}
+
+func (Instant) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.java8.InstantHandle"
+}
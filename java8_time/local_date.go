@@ -0,0 +1,41 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java8_time
+
+import (
+	"time"
+)
+
+// LocalDate is java.time.LocalDate, which is sent by dubbo as LocalDateHandle
+type LocalDate struct {
+	Year  int32 `hessian:"year"`
+	Month int32 `hessian:"month"`
+	Day   int32 `hessian:"day"`
+}
+
+// NewLocalDate returns the date of @t in its own location
+func NewLocalDate(t time.Time) LocalDate {
+	year, month, day := t.Date()
+	return LocalDate{Year: int32(year), Month: int32(month), Day: int32(day)}
+}
+
+// ToTime returns the midnight of the date in UTC, no zone offset is applied
+func (d LocalDate) ToTime() time.Time {
+	return time.Date(int(d.Year), time.Month(d.Month), int(d.Day), 0, 0, 0, 0, time.UTC)
+}
+
+func (LocalDate) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.java8.LocalDateHandle"
+}
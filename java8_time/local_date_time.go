@@ -0,0 +1,41 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java8_time
+
+import (
+	"time"
+)
+
+// LocalDateTime is java.time.LocalDateTime, which is sent by dubbo as LocalDateTimeHandle.
+// It has no zone, so both NewLocalDateTime and ToTime keep the wall clock as it is.
+type LocalDateTime struct {
+	Date LocalDate `hessian:"date"`
+	Time LocalTime `hessian:"time"`
+}
+
+// NewLocalDateTime returns the date and wall clock of @t in its own location
+func NewLocalDateTime(t time.Time) LocalDateTime {
+	return LocalDateTime{Date: NewLocalDate(t), Time: NewLocalTime(t)}
+}
+
+// ToTime returns the date time in UTC, no zone offset is applied
+func (t LocalDateTime) ToTime() time.Time {
+	return time.Date(int(t.Date.Year), time.Month(t.Date.Month), int(t.Date.Day),
+		int(t.Time.Hour), int(t.Time.Minute), int(t.Time.Second), int(t.Time.Nano), time.UTC)
+}
+
+func (LocalDateTime) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.java8.LocalDateTimeHandle"
+}
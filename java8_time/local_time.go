@@ -0,0 +1,42 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java8_time
+
+import (
+	"time"
+)
+
+// LocalTime is java.time.LocalTime, which is sent by dubbo as LocalTimeHandle
+type LocalTime struct {
+	Hour   int32 `hessian:"hour"`
+	Minute int32 `hessian:"minute"`
+	Second int32 `hessian:"second"`
+	Nano   int32 `hessian:"nano"`
+}
+
+// NewLocalTime returns the wall clock of @t in its own location
+func NewLocalTime(t time.Time) LocalTime {
+	hour, minute, second := t.Clock()
+	return LocalTime{Hour: int32(hour), Minute: int32(minute), Second: int32(second), Nano: int32(t.Nanosecond())}
+}
+
+// ToTime returns the time of day on 0000-01-01 in UTC, no zone offset is applied
+func (t LocalTime) ToTime() time.Time {
+	return time.Date(0, time.January, 1, int(t.Hour), int(t.Minute), int(t.Second), int(t.Nano), time.UTC)
+}
+
+func (LocalTime) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.java8.LocalTimeHandle"
+}
@@ -0,0 +1,108 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java8_time
+
+import (
+	"fmt"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// ZoneOffset is java.time.ZoneOffset, which is sent by dubbo as ZoneOffsetHandle
+type ZoneOffset struct {
+	Seconds int32 `hessian:"seconds"`
+}
+
+func (ZoneOffset) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.java8.ZoneOffsetHandle"
+}
+
+// ZonedDateTime is java.time.ZonedDateTime, which is sent by dubbo as ZonedDateTimeHandle.
+// ZoneID is a region id like Asia/Kolkata, or an offset id like +05:30 or Z.
+type ZonedDateTime struct {
+	DateTime LocalDateTime `hessian:"dateTime"`
+	Offset   ZoneOffset    `hessian:"offset"`
+	ZoneID   string        `hessian:"zoneId"`
+}
+
+// NewZonedDateTime returns the java zoned date time of @t in its own location. The zone id is the name
+// of the location, or the offset id if the location is time.Local or has no name.
+func NewZonedDateTime(t time.Time) ZonedDateTime {
+	_, offset := t.Zone()
+	zoneID := t.Location().String()
+	if t.Location() == time.Local || zoneID == "" {
+		zoneID = offsetID(offset)
+	}
+	return ZonedDateTime{DateTime: NewLocalDateTime(t), Offset: ZoneOffset{Seconds: int32(offset)}, ZoneID: zoneID}
+}
+
+// ToTime returns the instant of the date time in the location of its zone id, which is loaded by
+// time.LoadLocation. It is an error if the zone id is unknown.
+func (t ZonedDateTime) ToTime() (time.Time, error) {
+	loc, err := zoneLocation(t.ZoneID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// the offset tells the instant of a wall clock which is ambiguous in the zone, such as in a DST overlap
+	instant := t.DateTime.ToTime().Add(-time.Duration(t.Offset.Seconds) * time.Second)
+	return instant.In(loc), nil
+}
+
+func (ZonedDateTime) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.java8.ZonedDateTimeHandle"
+}
+
+// offsetID returns the java id of the zone offset @seconds, like +05:30, or Z for zero
+func offsetID(seconds int) string {
+	if seconds == 0 {
+		return "Z"
+	}
+	sign := '+'
+	if seconds < 0 {
+		sign, seconds = '-', -seconds
+	}
+	id := fmt.Sprintf("%c%02d:%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		id += fmt.Sprintf(":%02d", seconds%60)
+	}
+	return id
+}
+
+// zoneLocation returns the location of the java zone id @id
+func zoneLocation(id string) (*time.Location, error) {
+	if id == "Z" {
+		return time.UTC, nil
+	}
+	if len(id) > 0 && (id[0] == '+' || id[0] == '-') {
+		var hour, minute, second int
+		n, _ := fmt.Sscanf(id[1:], "%d:%d:%d", &hour, &minute, &second)
+		if n < 2 {
+			return nil, perrors.Errorf("unknown java zone id %q", id)
+		}
+		offset := hour*3600 + minute*60 + second
+		if id[0] == '-' {
+			offset = -offset
+		}
+		return time.FixedZone(id, offset), nil
+	}
+	loc, err := time.LoadLocation(id)
+	if err != nil || id == "" || id == "Local" {
+		return nil, perrors.Errorf("unknown java zone id %q", id)
+	}
+	return loc, nil
+}
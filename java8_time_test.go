@@ -0,0 +1,158 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+)
+
+func doTestJava8Time(t *testing.T, v interface{}) interface{} {
+	e := NewEncoder()
+	err := e.Encode(v)
+	assert.Nil(t, err)
+
+	decoded, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	return decoded
+}
+
+func TestJava8LocalDateTime(t *testing.T) {
+	// a zone far from UTC must not shift the wall clock
+	loc := time.FixedZone("UTC+13", 13*3600)
+	now := time.Date(2019, time.August, 31, 23, 30, 15, 123456789, loc)
+
+	date := java8_time.NewLocalDate(now)
+	assert.Equal(t, &date, doTestJava8Time(t, date))
+	assert.Equal(t, time.Date(2019, time.August, 31, 0, 0, 0, 0, time.UTC), date.ToTime())
+
+	clock := java8_time.NewLocalTime(now)
+	assert.Equal(t, &clock, doTestJava8Time(t, clock))
+	assert.Equal(t, int32(123456789), clock.Nano)
+
+	dateTime := java8_time.NewLocalDateTime(now)
+	decoded := doTestJava8Time(t, &dateTime)
+	assert.Equal(t, &dateTime, decoded)
+	assert.Equal(t, time.Date(2019, time.August, 31, 23, 30, 15, 123456789, time.UTC),
+		decoded.(*java8_time.LocalDateTime).ToTime())
+}
+
+type durationHolder struct {
+	Timeout time.Duration
+	Retry   *time.Duration
+}
+
+func (durationHolder) JavaClassName() string {
+	return "test.DurationHolder"
+}
+
+func TestJava8Duration(t *testing.T) {
+	for _, d := range []time.Duration{0, 1500 * time.Millisecond, -1500 * time.Millisecond, math.MaxInt64, math.MinInt64} {
+		assert.Equal(t, d, doTestJava8Time(t, d))
+
+		var out time.Duration
+		assert.Nil(t, ReflectResponse(doTestJava8Time(t, &d), &out))
+		assert.Equal(t, d, out)
+	}
+
+	// java keeps the nanos of a negative duration positive
+	assert.Equal(t, java8_time.Duration{Seconds: -2, Nanos: 500000000}, java8_time.NewDuration(-1500*time.Millisecond))
+
+	retry := time.Second
+	holder := &durationHolder{Timeout: 3 * time.Second, Retry: &retry}
+	assert.Equal(t, holder, doTestJava8Time(t, holder))
+
+	// out of the range of time.Duration
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(java8_time.Duration{Seconds: math.MaxInt64 / int64(time.Second), Nanos: 999999999}))
+	_, err := NewDecoder(e.Buffer()).Decode()
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "overflows time.Duration"), err.Error())
+}
+
+type instantHolder struct {
+	At time.Time `hessian:"at"`
+}
+
+func (instantHolder) JavaClassName() string {
+	return "test.InstantHolder"
+}
+
+func TestJava8Instant(t *testing.T) {
+	epoch := java8_time.NewInstant(time.Unix(0, 0))
+	assert.Equal(t, java8_time.Instant{}, epoch)
+	assert.Equal(t, time.Unix(0, 0).UTC(), doTestJava8Time(t, epoch))
+
+	// the nanos of an instant before the epoch are positive like java
+	before := time.Date(1969, time.December, 31, 23, 59, 59, 500000000, time.UTC)
+	assert.Equal(t, java8_time.Instant{Seconds: -1, Nanos: 500000000}, java8_time.NewInstant(before))
+	assert.Equal(t, before, doTestJava8Time(t, java8_time.NewInstant(before)))
+
+	loc := time.FixedZone("UTC+8", 8*3600)
+	at := time.Date(2023, time.March, 1, 8, 0, 0, 123456789, loc)
+	RegisterPOJO(&instantHolder{})
+	e := NewEncoder()
+	encTestInstance(e, encTestClassDef(e, "test.InstantHolder", "at"))
+	assert.Nil(t, e.Encode(java8_time.NewInstant(at)))
+	decoded, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &instantHolder{At: at.UTC()}, decoded)
+}
+
+func TestJava8ZonedDateTime(t *testing.T) {
+	for _, zone := range []string{"America/New_York", "Asia/Kolkata", "UTC"} {
+		loc, err := time.LoadLocation(zone)
+		assert.Nil(t, err)
+		at := time.Date(2023, time.July, 4, 12, 30, 15, 999, loc)
+
+		zoned := java8_time.NewZonedDateTime(at)
+		assert.Equal(t, zone, zoned.ZoneID)
+		decoded := doTestJava8Time(t, zoned)
+		assert.True(t, at.Equal(decoded.(time.Time)))
+		assert.Equal(t, loc, decoded.(time.Time).Location())
+		assert.Equal(t, at.Format(time.RFC3339Nano), decoded.(time.Time).Format(time.RFC3339Nano))
+	}
+
+	// a zone of half an hour offset
+	zoned := java8_time.NewZonedDateTime(time.Date(2023, time.July, 4, 12, 0, 0, 0, time.FixedZone("", 5*3600+1800)))
+	assert.Equal(t, "+05:30", zoned.ZoneID)
+	assert.Equal(t, int32(19800), zoned.Offset.Seconds)
+	decoded := doTestJava8Time(t, zoned).(time.Time)
+	assert.Equal(t, "2023-07-04T12:00:00+05:30", decoded.Format(time.RFC3339))
+
+	// the offset picks the instant of a wall clock repeated by the end of DST
+	ny, _ := time.LoadLocation("America/New_York")
+	second := time.Date(2023, time.November, 5, 6, 30, 0, 0, time.UTC).In(ny)
+	assert.Equal(t, "01:30:00-05:00", second.Format("15:04:05Z07:00"))
+	decoded = doTestJava8Time(t, java8_time.NewZonedDateTime(second)).(time.Time)
+	assert.True(t, second.Equal(decoded))
+
+	zoned.ZoneID = "Mars/Olympus_Mons"
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(zoned))
+	_, err := NewDecoder(e.Buffer()).Decode()
+	assert.EqualError(t, perrors.Cause(err), `unknown java zone id "Mars/Olympus_Mons"`)
+}
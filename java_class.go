@@ -0,0 +1,73 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+func init() {
+	SetSerializer(JavaClass{}.JavaClassName(), JavaClassSerializer{})
+}
+
+// JavaClass is a java.lang.Class, which is sent as its fully-qualified name.
+// The name is kept as it is, so array classes look like "[Ljava.lang.String;"
+// and primitive classes like "int".
+type JavaClass struct {
+	Name string `hessian:"name"`
+}
+
+// NewJavaClass returns the JavaClass of java class @name.
+func NewJavaClass(name string) *JavaClass {
+	return &JavaClass{Name: name}
+}
+
+func (JavaClass) JavaClassName() string {
+	return "java.lang.Class"
+}
+
+func (c JavaClass) String() string {
+	return c.Name
+}
+
+// JavaClassSerializer decodes java.lang.Class into *JavaClass.
+type JavaClassSerializer struct {
+	objectSerializer
+}
+
+func (JavaClassSerializer) DecInstance(d *Decoder, _ reflect.Type, cls ClassInfo) (interface{}, error) {
+	result := &JavaClass{}
+	d.appendRefs(result)
+	for _, fieldName := range cls.fieldNameList {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, perrors.Wrapf(err, "failed to decode field %s of java.lang.Class", fieldName)
+		}
+		if fieldName != "name" || v == nil {
+			continue
+		}
+		name, ok := v.(string)
+		if !ok {
+			return nil, perrors.Errorf("java.lang.Class name should be a string, got %T", v)
+		}
+		result.Name = name
+	}
+
+	return result, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type classHolder struct {
+	Type    *JavaClass
+	Element JavaClass
+}
+
+func (classHolder) JavaClassName() string {
+	return "test.ClassHolder"
+}
+
+func TestJavaClass(t *testing.T) {
+	for _, name := range []string{"java.lang.String", "[Ljava.lang.String;", "[[I", "int"} {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(NewJavaClass(name)))
+		// the same layout as com.caucho.hessian.io.ClassSerializer
+		want := encString(encInt32(encString([]byte{BC_OBJECT_DEF}, "java.lang.Class"), 1), "name")
+		want = encString(append(want, BC_OBJECT_DIRECT), name)
+		assert.Equal(t, want, e.Buffer())
+
+		v, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, NewJavaClass(name), v)
+	}
+
+	RegisterPOJO(&classHolder{})
+	class := NewJavaClass("[Ljava.lang.Object;")
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{class, class, &classHolder{Type: class, Element: JavaClass{Name: "long"}}}))
+	v, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	list := v.([]interface{})
+	assert.Equal(t, class, list[0])
+	assert.True(t, list[0] == list[1])
+	holder := list[2].(*classHolder)
+	assert.Equal(t, class, holder.Type)
+	assert.Equal(t, "long", holder.Element.String())
+}
+
+func TestJavaClassMaxDepth(t *testing.T) {
+	// a malformed java.lang.Class whose name is another class
+	nested := func(n int) []byte {
+		b := encString(encInt32(encString([]byte{BC_OBJECT_DEF}, "java.lang.Class"), 1), "name")
+		for i := 0; i < n; i++ {
+			b = append(b, BC_OBJECT_DIRECT)
+		}
+		return encString(b, "java.lang.String")
+	}
+
+	d := NewDecoder(nested(200))
+	d.SetMaxDepth(10)
+	_, err := d.Decode()
+	assert.Equal(t, ErrMaxDepthExceeded, perrors.Cause(err))
+
+	_, err = NewDecoder(nested(4 << 20)).Decode()
+	assert.Equal(t, ErrMaxDepthExceeded, perrors.Cause(err))
+}
@@ -0,0 +1,68 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+// The wrappers of java.util.Collections, such as Collections$UnmodifiableMap and Collections$SynchronizedList,
+// are written by hessian as the lists and maps of their classes. The lists, sets and collections are decoded
+// into slices like the jdk lists, the sorted and navigable maps into an *OrderedMap like java.util.TreeMap,
+// and the other maps into go maps. An *OrderedMap of a wrapper class is encoded as java.util.TreeMap,
+// since java can not instantiate the wrapper to decode it.
+func init() {
+	for _, name := range []string{
+		"UnmodifiableCollection",
+		"UnmodifiableSet",
+		"UnmodifiableSortedSet",
+		"UnmodifiableNavigableSet",
+		"SynchronizedCollection",
+		"SynchronizedSet",
+		"SynchronizedSortedSet",
+		"SynchronizedNavigableSet",
+		"CheckedCollection",
+		"CheckedQueue",
+		"CheckedSet",
+		"CheckedSortedSet",
+		"CheckedNavigableSet",
+		"EmptySet",
+		"SingletonSet",
+		"SetFromMap",
+		"AsLIFOQueue",
+		"CopiesList",
+	} {
+		jdkListTypes["java.util.Collections$"+name] = true
+	}
+	for _, name := range []string{
+		"UnmodifiableMap",
+		"SynchronizedMap",
+		"CheckedMap",
+		"EmptyMap",
+		"SingletonMap",
+	} {
+		jdkMapTypes["java.util.Collections$"+name] = true
+	}
+	for _, name := range []string{
+		"UnmodifiableSortedMap",
+		"UnmodifiableNavigableMap",
+		"SynchronizedSortedMap",
+		"SynchronizedNavigableMap",
+		"CheckedSortedMap",
+		"CheckedNavigableMap",
+	} {
+		orderedMapJavaTypes["java.util.Collections$"+name] = true
+		javaCollectionWrappers["java.util.Collections$"+name] = "java.util.TreeMap"
+	}
+}
+
+// javaCollectionWrappers maps the wrapper classes of java.util.Collections to the classes they are encoded as.
+var javaCollectionWrappers = map[string]string{}
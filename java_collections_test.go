@@ -0,0 +1,76 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJavaCollectionsWrappers(t *testing.T) {
+	// a Collections$UnmodifiableMap written by the MapSerializer of hessian
+	e := NewEncoder()
+	e.buffer = encByte(e.buffer, BC_MAP)
+	e.buffer = encString(e.buffer, "java.util.Collections$UnmodifiableMap")
+	for _, kv := range [][2]interface{}{{"a", int64(1)}, {"b", int64(2)}} {
+		assert.Nil(t, e.Encode(kv[0]))
+		assert.Nil(t, e.Encode(kv[1]))
+	}
+	e.buffer = encByte(e.buffer, BC_END)
+	// a Collections$SynchronizedList written by the CollectionSerializer
+	e.buffer = encByte(e.buffer, BC_LIST_FIXED)
+	e.buffer = encString(e.buffer, "java.util.Collections$SynchronizedList")
+	e.buffer = encInt32(e.buffer, 2)
+	assert.Nil(t, e.Encode("x"))
+	assert.Nil(t, e.Encode("y"))
+	// a Collections$UnmodifiableSortedMap keeps its order
+	e.buffer = encByte(e.buffer, BC_MAP)
+	e.buffer = encString(e.buffer, "java.util.Collections$UnmodifiableSortedMap")
+	for _, k := range []string{"b", "a"} {
+		assert.Nil(t, e.Encode(k))
+		assert.Nil(t, e.Encode(k))
+	}
+	e.buffer = encByte(e.buffer, BC_END)
+
+	d := NewDecoder(e.Buffer())
+	d.SetStrict(true)
+	m, err := d.Decode()
+	assert.Nil(t, err)
+	var out map[string]int64
+	assert.Nil(t, ReflectResponse(m, &out))
+	assert.Equal(t, map[string]int64{"a": 1, "b": 2}, out)
+
+	list, err := d.Decode()
+	assert.Nil(t, err)
+	var strs []string
+	assert.Nil(t, ReflectResponse(list, &strs))
+	assert.Equal(t, []string{"x", "y"}, strs)
+
+	sorted, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"b", "a"}, sorted.(*OrderedMap).Keys())
+
+	// java can not instantiate the wrapper, so it is encoded as its implementation
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(sorted))
+	assert.NotContains(t, string(e.Buffer()), "Collections")
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "java.util.TreeMap", res.(*OrderedMap).JavaType)
+	assert.Equal(t, []interface{}{"b", "a"}, res.(*OrderedMap).Keys())
+}
@@ -0,0 +1,216 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+func init() {
+	RegisterPOJO(&regularEnumSet{})
+	RegisterPOJO(&jumboEnumSet{})
+	RegisterPOJO(&enumSetHandler{})
+	SetSerializer(regularEnumSet{}.JavaClassName(), EnumSetSerializer{})
+	SetSerializer(jumboEnumSet{}.JavaClassName(), EnumSetSerializer{})
+	SetSerializer(enumSetHandler{}.JavaClassName(), EnumSetSerializer{})
+
+	// an EnumSet written by a CollectionSerializer is a list of its constants
+	for _, name := range []string{"java.util.EnumSet", regularEnumSet{}.JavaClassName(), jumboEnumSet{}.JavaClassName()} {
+		jdkListTypes[name] = true
+		enumSetJavaTypes[name] = true
+	}
+}
+
+// enumSetJavaTypes are the java classes of a list which is narrowed to a slice of its enum type.
+var enumSetJavaTypes = map[string]bool{}
+
+const enumMapJavaType = "java.util.EnumMap"
+
+// regularEnumSet is the form of java.util.RegularEnumSet written field by field, in which the bit i
+// of elements stands for the constant of ordinal i. Only jdk 8 writes elementType and universe.
+type regularEnumSet struct {
+	Elements    int64         `hessian:"elements"`
+	ElementType *JavaClass    `hessian:"elementType"`
+	Universe    []interface{} `hessian:"universe"`
+}
+
+func (regularEnumSet) JavaClassName() string {
+	return "java.util.RegularEnumSet"
+}
+
+// jumboEnumSet is the form of java.util.JumboEnumSet for an enum of more than 64 constants.
+type jumboEnumSet struct {
+	Elements    []int64       `hessian:"elements"`
+	Size        int32         `hessian:"size"`
+	ElementType *JavaClass    `hessian:"elementType"`
+	Universe    []interface{} `hessian:"universe"`
+}
+
+func (jumboEnumSet) JavaClassName() string {
+	return "java.util.JumboEnumSet"
+}
+
+// enumSetHandler is the form of an EnumSet written by the EnumSetSerializer of hessian-lite.
+type enumSetHandler struct {
+	Type    *JavaClass    `hessian:"type"`
+	Objects []interface{} `hessian:"objects"`
+}
+
+func (enumSetHandler) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.EnumSetHandler"
+}
+
+// EnumSetSerializer decodes a java.util.EnumSet into a slice of the registered go enum type, such as []Color,
+// in the order of the ordinals. The bits of the set are resolved against the universe of the enum if it is
+// on the wire, otherwise the go enum value of a constant should be its java ordinal, like an iota.
+type EnumSetSerializer struct {
+	objectSerializer
+}
+
+func (EnumSetSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		return enumSetConstants(v, cls)
+	})
+}
+
+// enumSetConstants converts a decoded java.util.EnumSet @v of class @cls to the slice of its constants.
+func enumSetConstants(v interface{}, cls ClassInfo) (interface{}, error) {
+	var (
+		enumType  *JavaClass
+		universe  []interface{}
+		elements  []int64
+		constants []interface{}
+	)
+	switch set := v.(type) {
+	case *regularEnumSet:
+		enumType, universe, elements = set.ElementType, set.Universe, []int64{set.Elements}
+	case *jumboEnumSet:
+		enumType, universe, elements = set.ElementType, set.Universe, set.Elements
+	case *enumSetHandler:
+		enumType, constants = set.Type, set.Objects
+	default:
+		return nil, perrors.Errorf("result type %T is not a java enum set", v)
+	}
+
+	var goType reflect.Type
+	if enumType != nil {
+		if info, ok := getStructInfo(enumType.Name); ok && info.typ.Implements(javaEnumType) {
+			goType = info.typ
+		}
+	}
+	if constants == nil {
+		for i, bits := range elements {
+			for bit := 0; bit < 64; bit++ {
+				if bits&(1<<uint(bit)) == 0 {
+					continue
+				}
+				ordinal := i*64 + bit
+				switch {
+				case ordinal < len(universe):
+					constants = append(constants, universe[ordinal])
+				case goType != nil && reflect.TypeOf(JavaEnum(0)).ConvertibleTo(goType):
+					constants = append(constants, reflect.ValueOf(JavaEnum(ordinal)).Convert(goType).Interface())
+				default:
+					return nil, perrors.Errorf("can not resolve the ordinal %d of %s, the enum class is unknown", ordinal, cls.javaName)
+				}
+			}
+		}
+	}
+
+	result := reflect.ValueOf(make([]interface{}, len(constants)))
+	for i, c := range constants {
+		if c != nil {
+			result.Index(i).Set(reflect.ValueOf(c))
+		}
+	}
+	if goType != nil {
+		result = enumSlice(result, goType)
+	} else if sl, ok := narrowEnumList(result); ok {
+		result = sl
+	}
+	return result.Interface(), nil
+}
+
+// enumSlice converts the enum constants @v to a slice of @goType, keeping @v if some constant is not of @goType.
+func enumSlice(v reflect.Value, goType reflect.Type) reflect.Value {
+	sl := reflect.MakeSlice(reflect.SliceOf(goType), v.Len(), v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		if elem.IsNil() || elem.Elem().Type() != goType {
+			return v
+		}
+		sl.Index(i).Set(elem.Elem())
+	}
+	return sl
+}
+
+// narrowEnumList converts a decoded []interface{} whose elements are all constants of the same
+// registered go enum type to a slice of that type, like []Color.
+func narrowEnumList(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Interface || v.Len() == 0 {
+		return v, false
+	}
+	if v.Index(0).IsNil() || !v.Index(0).Elem().Type().Implements(javaEnumType) {
+		return v, false
+	}
+	sl := enumSlice(v, v.Index(0).Elem().Type())
+	return sl, sl.Type() != v.Type()
+}
+
+// decEnumMap decodes a java.util.EnumMap into a map keyed by the registered go enum type of its keys,
+// like map[Color]interface{}. It is a map[interface{}]interface{} if the keys are not constants of
+// the same go enum type.
+func (d *Decoder) decEnumMap() (interface{}, error) {
+	m := make(map[interface{}]interface{})
+	d.appendRefs(m)
+	refIndex := len(d.refs) - 1
+	v, err := d.readMapEntries(m)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(map[interface{}]interface{}); !ok || len(m) == 0 {
+		return v, nil
+	}
+
+	var keyType reflect.Type
+	for k := range m {
+		if k == nil {
+			return m, nil
+		}
+		t := reflect.TypeOf(k)
+		if keyType == nil && t.Implements(javaEnumType) {
+			keyType = t
+		}
+		if t != keyType {
+			return m, nil
+		}
+	}
+
+	result := reflect.MakeMapWithSize(reflect.MapOf(keyType, reflect.TypeOf(m).Elem()), len(m))
+	for k, v := range m {
+		value := reflect.Zero(result.Type().Elem())
+		if v != nil {
+			value = reflect.ValueOf(v)
+		}
+		result.SetMapIndex(reflect.ValueOf(k), value)
+	}
+	d.refs[refIndex] = result.Interface()
+
+	return result.Interface(), nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type permission JavaEnum
+
+const (
+	permissionRead permission = iota
+	permissionWrite
+	permissionDelete
+	permissionShare
+	permissionAdmin
+)
+
+var permissionNames = []string{"READ", "WRITE", "DELETE", "SHARE", "ADMIN"}
+
+func (permission) JavaClassName() string {
+	return "test.model.Permission"
+}
+
+func (p permission) String() string {
+	return permissionNames[p]
+}
+
+func (permission) EnumValue(s string) JavaEnum {
+	for i, name := range permissionNames {
+		if name == s {
+			return JavaEnum(i)
+		}
+	}
+	return InvalidJavaEnum
+}
+
+type permissionHolder struct {
+	Granted []permission
+	Limits  map[permission]int32
+}
+
+func (permissionHolder) JavaClassName() string {
+	return "test.model.PermissionHolder"
+}
+
+func TestEnumSet(t *testing.T) {
+	RegisterJavaEnum(permissionRead)
+	expected := []permission{permissionWrite, permissionDelete, permissionAdmin}
+	universe := []interface{}{permissionRead, permissionWrite, permissionDelete, permissionShare, permissionAdmin}
+	enumClass := NewJavaClass(permissionRead.JavaClassName())
+
+	for _, set := range []POJO{
+		// jdk 8 writes the universe of the enum
+		&regularEnumSet{Elements: 0x16, ElementType: enumClass, Universe: universe},
+		// the bits are resolved by the ordinals of the registered enum
+		&regularEnumSet{Elements: 0x16, ElementType: enumClass},
+		&jumboEnumSet{Elements: []int64{0x16}, Size: 3, ElementType: enumClass},
+		&enumSetHandler{Type: enumClass, Objects: []interface{}{permissionWrite, permissionDelete, permissionAdmin}},
+	} {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode([]interface{}{set, set}))
+		v, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{expected, expected}, v, "%T", set)
+	}
+
+	// written as a collection
+	e := NewEncoder()
+	e.buffer = encString(append(e.buffer, BC_LIST_FIXED), "java.util.RegularEnumSet")
+	e.buffer = encInt32(e.buffer, 3)
+	for _, p := range expected {
+		assert.Nil(t, e.Encode(p))
+	}
+	v, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, v)
+
+	// the enum class is unknown
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&regularEnumSet{Elements: 0x16}))
+	_, err = NewDecoder(e.Buffer()).Decode()
+	assert.NotNil(t, err)
+}
+
+func TestEnumMap(t *testing.T) {
+	RegisterJavaEnum(permissionRead)
+	RegisterPOJO(&permissionHolder{})
+
+	// writes an EnumMap of java.util.EnumMap like a MapSerializer
+	writeEnumMap := func(e *Encoder) {
+		e.buffer = encString(append(e.buffer, BC_MAP), "java.util.EnumMap")
+		assert.Nil(t, e.Encode(permissionRead))
+		assert.Nil(t, e.Encode(int32(10)))
+		assert.Nil(t, e.Encode(permissionShare))
+		assert.Nil(t, e.Encode(int32(2)))
+		e.buffer = append(e.buffer, BC_END)
+	}
+
+	e := NewEncoder()
+	writeEnumMap(e)
+	v, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[permission]interface{}{permissionRead: int32(10), permissionShare: int32(2)}, v)
+
+	// the fields of a go struct
+	e = NewEncoder()
+	encTestInstance(e, encTestClassDef(e, "test.model.PermissionHolder", "granted", "limits"))
+	assert.Nil(t, e.Encode(&regularEnumSet{Elements: 0x16, ElementType: NewJavaClass(permissionRead.JavaClassName())}))
+	writeEnumMap(e)
+	v, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &permissionHolder{
+		Granted: []permission{permissionWrite, permissionDelete, permissionAdmin},
+		Limits:  map[permission]int32{permissionRead: 10, permissionShare: 2},
+	}, v)
+}
@@ -14,10 +14,60 @@
 
 package hessian
 
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
 import (
 	"github.com/apache/dubbo-go-hessian2/java_exception"
 )
 
+// javaExceptions maps the go error types to the java exception class names
+var javaExceptions = struct {
+	sync.RWMutex
+	names map[reflect.Type]string
+}{names: make(map[reflect.Type]string)}
+
+// RegisterJavaException maps the go error type of @prototype to the java exception class @javaClassName,
+// so that the exception of a response of the type, or wrapping an error of the type, is encoded
+// as an instance of the java class with the error message, and the java client catches the right type.
+// The java class should have the field layout of java.lang.Throwable.
+// The other errors which are not java_exception.Throwabler are encoded as java.lang.Throwable.
+func RegisterJavaException(prototype error, javaClassName string) {
+	javaExceptions.Lock()
+	javaExceptions.names[reflect.TypeOf(prototype)] = javaClassName
+	javaExceptions.Unlock()
+}
+
+// toJavaException converts @err to the object encoded as a java exception.
+func toJavaException(err error) interface{} {
+	if t, ok := err.(java_exception.Throwabler); ok {
+		return t
+	}
+
+	javaExceptions.RLock()
+	defer javaExceptions.RUnlock()
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if name, ok := javaExceptions.names[reflect.TypeOf(e)]; ok {
+			o := NewGenericObject(name)
+			o.fieldNames = []string{"serialVersionUID", "detailMessage", "suppressedExceptions", "stackTrace", "cause"}
+			o.Fields["serialVersionUID"] = int64(0)
+			o.Fields["detailMessage"] = err.Error()
+			o.Fields["suppressedExceptions"] = []java_exception.Throwabler{}
+			o.Fields["stackTrace"] = []java_exception.StackTraceElement{}
+			o.Fields["cause"] = nil
+			return o
+		}
+	}
+	return java_exception.NewThrowable(err.Error())
+}
+
 func init() {
 	RegisterPOJO(&java_exception.Method{})
 	RegisterPOJO(&java_exception.Class{})
@@ -26,6 +76,7 @@ func init() {
 	RegisterPOJO(&java_exception.IOException{})
 	RegisterPOJO(&java_exception.RuntimeException{})
 	RegisterPOJO(&java_exception.StackTraceElement{})
+	SetSerializer(java_exception.StackTraceElement{}.JavaClassName(), StackTraceElementSerializer{})
 	RegisterPOJO(&java_exception.ClassCastException{})
 	RegisterPOJO(&java_exception.ArrayStoreException{})
 	RegisterPOJO(&java_exception.IllegalStateException{})
@@ -111,3 +162,51 @@ func init() {
 	RegisterPOJO(&java_exception.IncompleteAnnotationException{})
 	RegisterPOJO(&java_exception.AnnotationTypeMismatchException{})
 }
+
+// StackTraceElementSerializer decodes java.lang.StackTraceElement into *java_exception.StackTraceElement,
+// whose null strings, such as the file name of a native method, are left empty instead of "null".
+type StackTraceElementSerializer struct {
+	objectSerializer
+}
+
+func (StackTraceElementSerializer) DecInstance(d *Decoder, _ reflect.Type, cls ClassInfo) (interface{}, error) {
+	result := &java_exception.StackTraceElement{}
+	d.appendRefs(result)
+	strs := map[string]*string{
+		"declaringClass":  &result.DeclaringClass,
+		"methodName":      &result.MethodName,
+		"fileName":        &result.FileName,
+		"classLoaderName": &result.ClassLoaderName,
+		"moduleName":      &result.ModuleName,
+		"moduleVersion":   &result.ModuleVersion,
+	}
+	for _, fieldName := range cls.fieldNameList {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, perrors.Wrapf(err, "failed to decode field %s of java.lang.StackTraceElement", fieldName)
+		}
+		if v == nil {
+			continue
+		}
+		if fieldName == "lineNumber" {
+			switch n := v.(type) {
+			case int32:
+				result.LineNumber = int(n)
+			case int64:
+				result.LineNumber = int(n)
+			default:
+				return nil, perrors.Errorf("java.lang.StackTraceElement lineNumber should be an int, got %T", v)
+			}
+			continue
+		}
+		if p, ok := strs[fieldName]; ok {
+			str, ok := v.(string)
+			if !ok {
+				return nil, perrors.Errorf("java.lang.StackTraceElement %s should be a string, got %T", fieldName, v)
+			}
+			*p = str
+		}
+	}
+
+	return result, nil
+}
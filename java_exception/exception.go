@@ -14,6 +14,11 @@
 
 package java_exception
 
+import (
+	"reflect"
+	"strconv"
+)
+
 ////////////////////////////
 // Throwable interface
 ////////////////////////////
@@ -23,6 +28,35 @@ type Throwabler interface {
 	JavaClassName() string
 }
 
+// StackTracer is implemented by the exceptions which tell their java stack trace frames by themselves.
+type StackTracer interface {
+	GetStackTrace() []StackTraceElement
+}
+
+// StackTraceOf returns the java stack trace frames of the exception @e. It asks a StackTracer,
+// and otherwise reads the StackTrace field which every exception of this package has.
+func StackTraceOf(e Throwabler) []StackTraceElement {
+	if st, ok := e.(StackTracer); ok {
+		return st.GetStackTrace()
+	}
+	v := reflect.ValueOf(e)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName("StackTrace")
+	if !f.IsValid() {
+		return nil
+	}
+	trace, _ := f.Interface().([]StackTraceElement)
+	return trace
+}
+
 ////////////////////////////
 // Throwable
 ////////////////////////////
@@ -75,11 +109,39 @@ func (Exception) JavaClassName() string {
 // StackTraceElement
 ////////////////////////////
 
+// StackTraceElement is a frame of a java stack trace. The file name of a native method or of a class
+// compiled without debug info is null in java, which is decoded into the empty string, and the empty
+// optional fields are left out of the encoded object, so that java gets them as null again.
 type StackTraceElement struct {
-	DeclaringClass string
-	MethodName     string
-	FileName       string
-	LineNumber     int
+	DeclaringClass  string
+	MethodName      string
+	FileName        string `hessian:",omitempty"`
+	LineNumber      int    // -1 if unknown, -2 for a native method
+	ClassLoaderName string `hessian:",omitempty"` // since java 9
+	ModuleName      string `hessian:",omitempty"` // since java 9
+	ModuleVersion   string `hessian:",omitempty"` // since java 9
+}
+
+// IsNativeMethod returns whether the frame is of a native method.
+func (e StackTraceElement) IsNativeMethod() bool {
+	return e.LineNumber == -2
+}
+
+// String formats the frame like java.lang.StackTraceElement#toString of java 8,
+// such as "test.Main.main(Main.java:9)" and "java.lang.Thread.sleep(Native Method)".
+func (e StackTraceElement) String() string {
+	var source string
+	switch {
+	case e.IsNativeMethod():
+		source = "Native Method"
+	case e.FileName != "" && e.LineNumber >= 0:
+		source = e.FileName + ":" + strconv.Itoa(e.LineNumber)
+	case e.FileName != "":
+		source = e.FileName
+	default:
+		source = "Unknown Source"
+	}
+	return e.DeclaringClass + "." + e.MethodName + "(" + source + ")"
 }
 
 func (StackTraceElement) JavaClassName() string {
@@ -15,11 +15,20 @@
 package hessian
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+)
 
+import (
+	perrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
+import (
+	"github.com/apache/dubbo-go-hessian2/java_exception"
+)
+
 func TestException(t *testing.T) {
 	doTestException(t, "throw_throwable", "exception")
 	doTestException(t, "throw_exception", "exception")
@@ -117,3 +126,178 @@ func doTestException(t *testing.T, method, content string) {
 		assert.Equal(t, content, r.(error).Error())
 	})
 }
+
+func TestExceptionStackTraceAndCause(t *testing.T) {
+	root := java_exception.NewIOException("io broken")
+	root.StackTrace = []java_exception.StackTraceElement{
+		{DeclaringClass: "test.Reader", MethodName: "read", FileName: "Reader.java", LineNumber: 3},
+	}
+	// a java Throwable without cause refers to itself
+	root.Cause = root
+
+	ex := java_exception.NewRuntimeException("outer")
+	ex.StackTrace = []java_exception.StackTraceElement{
+		{DeclaringClass: "test.Service", MethodName: "call", FileName: "Service.java", LineNumber: 7, ModuleName: "test"},
+		{DeclaringClass: "test.Main", MethodName: "main", FileName: "Main.java", LineNumber: 9},
+	}
+	ex.Cause = root
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(ex))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+
+	decoded, ok := res.(java_exception.Throwabler)
+	if !assert.True(t, ok, "decoded %T", res) {
+		return
+	}
+	assert.Equal(t, "outer", decoded.Error())
+	assert.Equal(t, ex.StackTrace, java_exception.StackTraceOf(decoded))
+
+	cause, ok := res.(*java_exception.RuntimeException).Cause.(java_exception.IOException)
+	if !assert.True(t, ok, "cause %T", res.(*java_exception.RuntimeException).Cause) {
+		return
+	}
+	assert.Equal(t, "io broken", cause.Error())
+	assert.Equal(t, root.StackTrace, java_exception.StackTraceOf(cause))
+	assert.Nil(t, cause.Cause)
+
+	// encode the decoded exception again
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(res))
+	res2, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, res, res2)
+}
+
+type validationError struct {
+	field string
+}
+
+func (e validationError) Error() string {
+	return e.field + " is invalid"
+}
+
+// validationException has the field layout of java.lang.Throwable
+type validationException struct {
+	SerialVersionUID     int64
+	DetailMessage        string
+	SuppressedExceptions []java_exception.Throwabler
+	StackTrace           []java_exception.StackTraceElement
+	Cause                java_exception.Throwabler
+}
+
+func (e validationException) Error() string {
+	return e.DetailMessage
+}
+
+func (validationException) JavaClassName() string {
+	return "com.mycompany.ValidationException"
+}
+
+func TestRegisterJavaException(t *testing.T) {
+	RegisterPOJO(&validationException{})
+	RegisterJavaException(validationError{}, "com.mycompany.ValidationException")
+
+	decoded := &Response{}
+	body := &Response{Exception: fmt.Errorf("check: %w", validationError{field: "name"})}
+	doTestResponse(t, PackageResponse, Response_OK, body, decoded, func() {
+		ex, ok := decoded.Exception.(*validationException)
+		assert.True(t, ok, "%T", decoded.Exception)
+		assert.Equal(t, "check: name is invalid", ex.DetailMessage)
+		assert.Empty(t, ex.StackTrace)
+	})
+
+	// the errors which are not registered are still sent as java.lang.Throwable
+	decoded = &Response{}
+	body = &Response{Exception: errors.New("unknown")}
+	doTestResponse(t, PackageResponse, Response_OK, body, decoded, func() {
+		ex, ok := decoded.Exception.(*java_exception.Throwable)
+		assert.True(t, ok, "%T", decoded.Exception)
+		assert.Equal(t, "unknown", ex.Error())
+	})
+}
+
+func TestStackTraceElementMaxDepth(t *testing.T) {
+	// a malformed java.lang.StackTraceElement whose declaring class is another element
+	nested := func(n int) []byte {
+		b := encString(encInt32(encString([]byte{BC_OBJECT_DEF}, "java.lang.StackTraceElement"), 1), "declaringClass")
+		for i := 0; i < n; i++ {
+			b = append(b, BC_OBJECT_DIRECT)
+		}
+		return encString(b, "test.Main")
+	}
+
+	d := NewDecoder(nested(200))
+	d.SetMaxDepth(10)
+	_, err := d.Decode()
+	assert.Equal(t, ErrMaxDepthExceeded, perrors.Cause(err))
+
+	_, err = NewDecoder(nested(4 << 20)).Decode()
+	assert.Equal(t, ErrMaxDepthExceeded, perrors.Cause(err))
+}
+
+func TestStackTraceElements(t *testing.T) {
+	// a java StackTraceElement[] whose native frame has no file name
+	e := NewEncoder()
+	encTestListHead(e, "[java.lang.StackTraceElement", 3)
+	for _, fields := range []map[string]interface{}{
+		{"declaringClass": "java.lang.Thread", "methodName": "sleep", "fileName": nil, "lineNumber": int32(-2)},
+		{"declaringClass": "test.Generated", "methodName": "run", "fileName": nil, "lineNumber": int32(-1)},
+		{"declaringClass": "test.Main", "methodName": "main", "fileName": "Main.java", "lineNumber": int32(9)},
+	} {
+		assert.Nil(t, e.Encode(&GenericObject{ClassName: "java.lang.StackTraceElement", Fields: fields}))
+	}
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	elements, ok := res.([]*java_exception.StackTraceElement)
+	if !assert.True(t, ok, "decoded %T", res) {
+		return
+	}
+	assert.Equal(t, 3, len(elements))
+	assert.Equal(t, "", elements[0].FileName)
+	assert.True(t, elements[0].IsNativeMethod())
+	assert.Equal(t, "java.lang.Thread.sleep(Native Method)", elements[0].String())
+	assert.Equal(t, "test.Generated.run(Unknown Source)", elements[1].String())
+	assert.Equal(t, "test.Main.main(Main.java:9)", elements[2].String())
+
+	// the empty file name is encoded as absent, which java reads as null
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(*elements[0]))
+	assert.NotContains(t, string(e.Buffer()), "fileName")
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, elements[0], res)
+
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(elements))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, elements, res)
+}
+
+// tracedException tells its stack trace by itself
+type tracedException struct {
+	frames []java_exception.StackTraceElement
+}
+
+func (e tracedException) Error() string {
+	return "traced"
+}
+
+func (tracedException) JavaClassName() string {
+	return "com.mycompany.TracedException"
+}
+
+func (e tracedException) GetStackTrace() []java_exception.StackTraceElement {
+	return e.frames
+}
+
+func TestStackTraceOf(t *testing.T) {
+	frames := []java_exception.StackTraceElement{{DeclaringClass: "test.Main", MethodName: "main", LineNumber: 3}}
+
+	assert.Equal(t, frames, java_exception.StackTraceOf(tracedException{frames: frames}))
+	assert.Equal(t, frames, java_exception.StackTraceOf(&validationException{StackTrace: frames}))
+	assert.Equal(t, frames, java_exception.StackTraceOf(java_exception.IllegalStateException{StackTrace: frames}))
+	assert.Nil(t, java_exception.StackTraceOf((*java_exception.Throwable)(nil)))
+}
@@ -0,0 +1,88 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"encoding/binary"
+	"math/big"
+	"reflect"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+func init() {
+	RegisterPOJO(&bigIntegerHandle{})
+	SetSerializer(bigIntegerHandle{}.JavaClassName(), BigIntegerSerializer{})
+}
+
+// bigIntegerHandle is the form of java.math.BigInteger on the wire, which are its serializable fields
+// written by the JavaSerializer of hessian. The magnitude is the big-endian ints of the absolute value,
+// and the other fields are the caches of java, whose zero values mean they are not computed yet.
+type bigIntegerHandle struct {
+	Signum             int32   `hessian:"signum"`
+	Mag                []int32 `hessian:"mag"`
+	BitCount           int32   `hessian:"bitCount"`
+	BitLength          int32   `hessian:"bitLength"`
+	LowestSetBit       int32   `hessian:"lowestSetBit"`
+	FirstNonzeroIntNum int32   `hessian:"firstNonzeroIntNum"`
+}
+
+func (bigIntegerHandle) JavaClassName() string {
+	return "java.math.BigInteger"
+}
+
+// encBigInteger encodes the *big.Int @i as a java.math.BigInteger.
+func (e *Encoder) encBigInteger(i *big.Int) error {
+	if i == nil {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
+
+	b := i.Bytes()
+	if pad := len(b) % 4; pad != 0 {
+		b = append(make([]byte, 4-pad), b...)
+	}
+	mag := make([]int32, len(b)/4)
+	for j := range mag {
+		mag[j] = int32(binary.BigEndian.Uint32(b[j*4:]))
+	}
+	return e.encObject(&bigIntegerHandle{Signum: int32(i.Sign()), Mag: mag})
+}
+
+// BigIntegerSerializer decodes java.math.BigInteger into *big.Int. A *big.Int is encoded as a java.math.BigInteger.
+type BigIntegerSerializer struct {
+	objectSerializer
+}
+
+func (BigIntegerSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		handle, ok := v.(*bigIntegerHandle)
+		if !ok {
+			return nil, perrors.Errorf("result type %T is not java.math.BigInteger", v)
+		}
+
+		b := make([]byte, len(handle.Mag)*4)
+		for j, word := range handle.Mag {
+			binary.BigEndian.PutUint32(b[j*4:], uint32(word))
+		}
+		result := new(big.Int).SetBytes(b)
+		if handle.Signum < 0 {
+			result.Neg(result)
+		}
+		return result, nil
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"math/big"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type bigIntegerAccount struct {
+	ID      *big.Int
+	Balance *big.Int
+}
+
+func (bigIntegerAccount) JavaClassName() string {
+	return "test.model.Account"
+}
+
+func TestJavaBigInteger(t *testing.T) {
+	huge, _ := new(big.Int).SetString("1267650600228229401496703205377", 10) // 2^100 + 1
+	negative, _ := new(big.Int).SetString("-12345678901234567890123", 10)
+	for _, i := range []*big.Int{huge, negative, big.NewInt(0), big.NewInt(-1), big.NewInt(1 << 32)} {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(i))
+		res, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, i.Cmp(res.(*big.Int)), "%v != %v", i, res)
+
+		var out *big.Int
+		assert.Nil(t, ReflectResponse(res, &out))
+		assert.Equal(t, 0, i.Cmp(out))
+		var value big.Int
+		assert.Nil(t, ReflectResponse(res, &value))
+		assert.Equal(t, 0, i.Cmp(&value))
+	}
+
+	// a BigInteger written by the JavaSerializer of hessian, -(2^32 + 5)
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&GenericObject{ClassName: "java.math.BigInteger", Fields: map[string]interface{}{
+		"signum":             int32(-1),
+		"mag":                []int32{1, 5},
+		"bitCount":           int32(0),
+		"bitLength":          int32(0),
+		"lowestSetBit":       int32(-2),
+		"firstNonzeroIntNum": int32(-2),
+	}}))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "-4294967301", res.(*big.Int).String())
+
+	// the magnitude of the encoded value
+	wire := NewGenericObject("java.math.BigInteger")
+	wire.fieldNames = []string{"signum", "mag", "bitCount", "bitLength", "lowestSetBit", "firstNonzeroIntNum"}
+	for _, name := range wire.fieldNames {
+		wire.Fields[name] = int32(0)
+	}
+	wire.Fields["signum"] = int32(-1)
+	wire.Fields["mag"] = []int32{1, 5}
+	expected := NewEncoder()
+	assert.Nil(t, expected.Encode(wire))
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(res))
+	assert.Equal(t, expected.Buffer(), e.Buffer())
+
+	// the fields of the struct
+	RegisterPOJO(&bigIntegerAccount{})
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&bigIntegerAccount{ID: huge}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, huge.Cmp(res.(*bigIntegerAccount).ID))
+	assert.Nil(t, res.(*bigIntegerAccount).Balance)
+}
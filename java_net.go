@@ -0,0 +1,107 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+func init() {
+	RegisterPOJO(&uriHandle{})
+	RegisterPOJO(&urlHandle{})
+	SetSerializer(uriHandle{}.JavaClassName(), URISerializer{})
+	SetSerializer(urlHandle{}.JavaClassName(), URISerializer{})
+}
+
+// uriHandle is the form of java.net.URI on the wire.
+type uriHandle struct {
+	Value string `hessian:"string"`
+}
+
+func (uriHandle) JavaClassName() string {
+	return "java.net.URI"
+}
+
+// urlHandle is the form of java.net.URL on the wire, which are its serializable fields.
+type urlHandle struct {
+	Protocol  string `hessian:"protocol"`
+	Host      string `hessian:"host"`
+	Port      int32  `hessian:"port"`
+	File      string `hessian:"file"`
+	Authority string `hessian:"authority"`
+	Ref       string `hessian:"ref"`
+	HashCode  int32  `hessian:"hashCode"`
+}
+
+func (urlHandle) JavaClassName() string {
+	return "java.net.URL"
+}
+
+// String gets the external form of the java.net.URL, like URL.toExternalForm.
+func (h *urlHandle) String() string {
+	var buf strings.Builder
+	buf.WriteString(h.Protocol)
+	buf.WriteString(":")
+	if h.Authority != "" {
+		buf.WriteString("//")
+		buf.WriteString(h.Authority)
+	}
+	buf.WriteString(h.File)
+	if h.Ref != "" {
+		buf.WriteString("#")
+		buf.WriteString(h.Ref)
+	}
+	return buf.String()
+}
+
+// encURI encodes the *url.URL @u as a java.net.URI.
+func (e *Encoder) encURI(u *url.URL) error {
+	if u == nil {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
+	return e.encObject(&uriHandle{Value: u.String()})
+}
+
+// URISerializer decodes java.net.URI and java.net.URL into *url.URL, and a malformed one is an error.
+// A *url.URL is encoded as a java.net.URI.
+type URISerializer struct {
+	objectSerializer
+}
+
+func (URISerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		var s string
+		switch handle := v.(type) {
+		case *uriHandle:
+			s = handle.Value
+		case *urlHandle:
+			s = handle.String()
+		default:
+			return nil, perrors.Errorf("result type %T is not a java uri or url", v)
+		}
+		result, err := url.Parse(s)
+		if err != nil {
+			return nil, perrors.Wrapf(err, "malformed %s", cls.javaName)
+		}
+		return result, nil
+	})
+}
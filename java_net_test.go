@@ -0,0 +1,85 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"net/url"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type uriLink struct {
+	Self *url.URL
+	Next *url.URL
+}
+
+func (uriLink) JavaClassName() string {
+	return "test.model.Link"
+}
+
+func TestJavaURI(t *testing.T) {
+	for _, s := range []string{"https://user@example.com:8443/a/b?x=1&y=2#top", "../docs/index.html", "mailto:a@example.com"} {
+		u, err := url.Parse(s)
+		assert.Nil(t, err)
+
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(u))
+		assert.Contains(t, string(e.Buffer()), "java.net.URI")
+		res, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, u, res)
+	}
+
+	// the fields of the struct
+	RegisterPOJO(&uriLink{})
+	self, _ := url.Parse("/items?page=1")
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&uriLink{Self: self}))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &uriLink{Self: self}, res)
+
+	// a malformed uri
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&uriHandle{Value: "http://[::1"}))
+	_, err = NewDecoder(e.Buffer()).Decode()
+	assert.EqualError(t, err, `malformed java.net.URI: parse "http://[::1": missing ']' in host`)
+}
+
+func TestJavaURL(t *testing.T) {
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&urlHandle{
+		Protocol: "http", Host: "example.com", Port: 8080, File: "/search?q=go&n=10",
+		Authority: "example.com:8080", Ref: "results", HashCode: -1,
+	}))
+	assert.Nil(t, e.Encode(&urlHandle{Protocol: "file", Port: -1, File: "/tmp/a.txt", HashCode: -1}))
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	u := res.(*url.URL)
+	assert.Equal(t, "example.com:8080", u.Host)
+	assert.Equal(t, "/search", u.Path)
+	assert.Equal(t, "go", u.Query().Get("q"))
+	assert.Equal(t, "results", u.Fragment)
+	assert.Equal(t, "http://example.com:8080/search?q=go&n=10#results", u.String())
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "file:/tmp/a.txt", res.(*url.URL).String())
+}
@@ -0,0 +1,93 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package hessian
+
+import (
+	"reflect"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java_sql"
+)
+
+func init() {
+	RegisterPOJO(&java_sql.Timestamp{})
+	RegisterPOJO(&java_sql.Date{})
+	SetSerializer("java.sql.Timestamp", SqlDateSerializer{})
+	SetSerializer("java.sql.Date", SqlDateSerializer{})
+}
+
+// SqlDateSerializer decodes java.sql.Timestamp and java.sql.Date into time.Time.
+// The nanoseconds of a Timestamp are kept, and a Date is at the midnight of its day.
+type SqlDateSerializer struct {
+	objectSerializer
+}
+
+func (SqlDateSerializer) EncObject(e *Encoder, v POJO) error {
+	switch t := v.(type) {
+	case java_sql.Timestamp:
+		if t.Nanos == 0 {
+			t.Nanos = int32(t.Value.Nanosecond())
+		}
+		return e.encObject(&t)
+	case *java_sql.Timestamp:
+		if t == nil {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		return SqlDateSerializer{}.EncObject(e, *t)
+	case java_sql.Date:
+		return e.encObject(java_sql.NewDate(t.Value))
+	case *java_sql.Date:
+		if t == nil {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		return e.encObject(java_sql.NewDate(t.Value))
+	}
+	return e.encObject(v)
+}
+
+func (SqlDateSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		var t time.Time
+		switch sqlDate := v.(type) {
+		case *java_sql.Timestamp:
+			if !hasField(cls.classInfo, "nanos") {
+				// sent by java, which only has the milliseconds
+				sqlDate.Nanos = int32(sqlDate.Value.Nanosecond())
+			}
+			t = sqlDate.Time()
+		case *java_sql.Date:
+			t = sqlDate.Time()
+		default:
+			return nil, perrors.Errorf("result type %T is not a java.sql date", v)
+		}
+		return d.decodedTime(t), nil
+	})
+}
+
+func hasField(cls classInfo, name string) bool {
+	for _, fieldName := range cls.fieldNameList {
+		if fieldName == name {
+			return true
+		}
+	}
+	return false
+}
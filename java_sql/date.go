@@ -0,0 +1,45 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package java_sql
+
+import (
+	"time"
+)
+
+// Date is java.sql.Date, which is a date without the time of the day.
+type Date struct {
+	Value time.Time `hessian:"value"`
+}
+
+// NewDate creates a Date of the day of @t.
+func NewDate(t time.Time) *Date {
+	return &Date{Value: midnight(t)}
+}
+
+// Time returns the midnight of the day of the Date.
+func (d Date) Time() time.Time {
+	return midnight(d.Value)
+}
+
+func (Date) JavaClassName() string {
+	return "java.sql.Date"
+}
+
+func midnight(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
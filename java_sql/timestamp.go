@@ -0,0 +1,42 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package java_sql
+
+import (
+	"time"
+)
+
+// Timestamp is java.sql.Timestamp. Besides the milliseconds time in Value, which is the only
+// field java reads, Nanos keeps the nanoseconds of the second like java.sql.Timestamp does.
+type Timestamp struct {
+	Value time.Time `hessian:"value"`
+	Nanos int32     `hessian:"nanos"`
+}
+
+// NewTimestamp creates a Timestamp of @t.
+func NewTimestamp(t time.Time) *Timestamp {
+	return &Timestamp{Value: t, Nanos: int32(t.Nanosecond())}
+}
+
+// Time returns the time of the Timestamp with its nanoseconds.
+func (t Timestamp) Time() time.Time {
+	if t.Value.IsZero() {
+		return t.Value
+	}
+	return t.Value.Truncate(time.Second).Add(time.Duration(t.Nanos))
+}
+
+func (Timestamp) JavaClassName() string {
+	return "java.sql.Timestamp"
+}
@@ -0,0 +1,92 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package hessian
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java_sql"
+)
+
+type sqlTimeHolder struct {
+	CreatedAt time.Time
+	Birthday  *time.Time
+}
+
+func (sqlTimeHolder) JavaClassName() string {
+	return "test.SqlTimeHolder"
+}
+
+func TestSqlTimestamp(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.Local)
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(java_sql.NewTimestamp(ts)))
+	assert.Nil(t, e.Encode(java_sql.Timestamp{Value: ts}))
+	d := NewDecoder(e.Buffer())
+	for i := 0; i < 2; i++ {
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.True(t, ts.Equal(res.(time.Time)), "%v != %v", ts, res)
+	}
+
+	// java only sends the milliseconds
+	buf := encTestClassInstance(nil, 0, "java.sql.Timestamp", []string{"value"}, ts)
+	res, err := NewDecoder(buf).Decode()
+	assert.Nil(t, err)
+	assert.True(t, ts.Truncate(time.Millisecond).Equal(res.(time.Time)), "%v", res)
+}
+
+func TestSqlDate(t *testing.T) {
+	// the day is in the location of the decoder
+	day := time.Date(2020, 1, 2, 13, 4, 5, 0, time.UTC)
+	midnight := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(java_sql.Date{Value: day}))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.True(t, midnight.Equal(res.(time.Time)), "%v", res)
+
+	buf := encTestClassInstance(nil, 0, "java.sql.Date", []string{"value"}, day)
+	res, err = NewDecoder(buf).Decode()
+	assert.Nil(t, err)
+	assert.True(t, midnight.Equal(res.(time.Time)), "%v", res)
+}
+
+func TestSqlDateField(t *testing.T) {
+	RegisterPOJO(&sqlTimeHolder{})
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.Local)
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	// send the fields as java does
+	o := NewGenericObject("test.SqlTimeHolder")
+	o.Fields["createdAt"] = java_sql.NewTimestamp(ts)
+	o.Fields["birthday"] = java_sql.NewDate(day)
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(o))
+
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	holder := res.(*sqlTimeHolder)
+	assert.True(t, ts.Equal(holder.CreatedAt), "%v", holder.CreatedAt)
+	assert.True(t, day.Equal(*holder.Birthday), "%v", holder.Birthday)
+}
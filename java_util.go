@@ -0,0 +1,182 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java_util"
+)
+
+func init() {
+	RegisterPOJO(&java_util.Optional{})
+	RegisterPOJO(&java_util.UUID{})
+	RegisterPOJO(&java_util.BitSet{})
+	RegisterPOJO(&java_util.Currency{})
+	RegisterPOJO(&localeHandle{})
+	RegisterPOJO(&java_util.AtomicInteger{})
+	RegisterPOJO(&java_util.AtomicLong{})
+	RegisterPOJO(&java_util.Pattern{})
+	RegisterPOJO(&java_util.TimeZone{})
+	RegisterPOJO(&java_util.Calendar{})
+	_, _ = RegisterPOJOWithAliases(&calendarHandle{}, calendarHandle{}.JavaClassName(), "com.caucho.hessian.io.CalendarHandle")
+	SetSerializer("java.util.Optional", OptionalSerializer{})
+	SetSerializer(java_util.Locale{}.JavaClassName(), LocaleSerializer{})
+	SetSerializer(java_util.AtomicInteger{}.JavaClassName(), AtomicSerializer{})
+	SetSerializer(java_util.AtomicLong{}.JavaClassName(), AtomicSerializer{})
+	SetSerializer(java_util.Calendar{}.JavaClassName(), CalendarSerializer{})
+	SetSerializer(calendarHandle{}.JavaClassName(), CalendarSerializer{})
+	SetSerializer("com.caucho.hessian.io.CalendarHandle", CalendarSerializer{})
+	SetStringForm(&java_util.UUID{}, stringerForm)
+	SetStringForm(&java_util.Currency{}, stringerForm)
+	SetStringForm(&java_util.Locale{}, stringerForm)
+}
+
+func stringerForm(v interface{}) string {
+	return v.(fmt.Stringer).String()
+}
+
+var javaPropertiesType = java_util.Properties{}.JavaClassName()
+
+// decProperties reads the entries of a java.util.Properties, whose type has been read, into a map[string]string.
+// The map holding any entry which is not of strings, such as put into the Hashtable by java, is returned as it is.
+func (d *Decoder) decProperties() (interface{}, error) {
+	m := make(map[interface{}]interface{})
+	refIndex := len(d.refs)
+	d.appendRefs(m)
+	v, err := d.readMapEntries(m)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(map[interface{}]interface{}); !ok {
+		return v, nil
+	}
+
+	props := make(map[string]string, len(m))
+	for k, v := range m {
+		key, ok := k.(string)
+		if !ok {
+			return m, nil
+		}
+		if props[key], ok = v.(string); !ok {
+			return m, nil
+		}
+	}
+	d.refs[refIndex] = props
+	return props, nil
+}
+
+// localeHandle is the form of java.util.Locale on the wire.
+type localeHandle struct {
+	Value string `hessian:"value"`
+}
+
+func (localeHandle) JavaClassName() string {
+	return java_util.Locale{}.JavaClassName()
+}
+
+// LocaleSerializer sends java_util.Locale as its locale string like hessian,
+// and decodes it into *java_util.Locale.
+type LocaleSerializer struct {
+	objectSerializer
+}
+
+func (LocaleSerializer) EncObject(e *Encoder, v POJO) error {
+	switch l := v.(type) {
+	case java_util.Locale:
+		return e.encObject(&localeHandle{Value: l.String()})
+	case *java_util.Locale:
+		if l == nil {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		return e.encObject(&localeHandle{Value: l.String()})
+	}
+	return e.encObject(v)
+}
+
+func (LocaleSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		handle, ok := v.(*localeHandle)
+		if !ok {
+			return nil, perrors.Errorf("result type %T is not a locale handle", v)
+		}
+		return java_util.ParseLocale(handle.Value), nil
+	})
+}
+
+// AtomicSerializer unwraps a decoded java.util.concurrent.atomic.AtomicInteger or AtomicLong
+// into its int32 or int64 value.
+type AtomicSerializer struct {
+	objectSerializer
+}
+
+func (AtomicSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		switch atomic := v.(type) {
+		case *java_util.AtomicInteger:
+			return atomic.Value, nil
+		case *java_util.AtomicLong:
+			return atomic.Value, nil
+		}
+		return nil, perrors.Errorf("result type %T is not a java atomic number", v)
+	})
+}
+
+// calendarHandle is the form hessian of java writes a java.util.Calendar in, which only keeps the
+// class and the time of the calendar.
+type calendarHandle struct {
+	Type *JavaClass `hessian:"type"`
+	Date time.Time  `hessian:"date"`
+}
+
+func (calendarHandle) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.CalendarHandle"
+}
+
+// CalendarSerializer decodes java.util.GregorianCalendar into time.Time of the instant of its time
+// in the location of its zone. java_util.NewCalendar makes the calendar to send of a time.Time.
+// The calendar handle written by hessian of java has no zone, so its time is in the location of the decoder.
+type CalendarSerializer struct {
+	objectSerializer
+}
+
+func (CalendarSerializer) EncObject(e *Encoder, v POJO) error {
+	if c, ok := v.(*java_util.Calendar); ok && c == nil {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
+	return e.encObject(v)
+}
+
+func (CalendarSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return decInstanceAs(d, typ, cls, func(v interface{}) (interface{}, error) {
+		switch c := v.(type) {
+		case *java_util.Calendar:
+			return d.decodedTime(c.ToTime()), nil
+		case *calendarHandle:
+			return d.decodedTime(c.Date), nil
+		}
+		return nil, perrors.Errorf("result type %T is not a java calendar", v)
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+// AtomicInteger is java.util.concurrent.atomic.AtomicInteger.
+// A decoded AtomicInteger is unwrapped into its int32 value, so AtomicInteger is only used to encode.
+type AtomicInteger struct {
+	Value int32 `hessian:"value"`
+}
+
+// NewAtomicInteger creates an AtomicInteger of @v.
+func NewAtomicInteger(v int32) *AtomicInteger {
+	return &AtomicInteger{Value: v}
+}
+
+func (AtomicInteger) JavaClassName() string {
+	return "java.util.concurrent.atomic.AtomicInteger"
+}
+
+// AtomicLong is java.util.concurrent.atomic.AtomicLong.
+// A decoded AtomicLong is unwrapped into its int64 value, so AtomicLong is only used to encode.
+type AtomicLong struct {
+	Value int64 `hessian:"value"`
+}
+
+// NewAtomicLong creates an AtomicLong of @v.
+func NewAtomicLong(v int64) *AtomicLong {
+	return &AtomicLong{Value: v}
+}
+
+func (AtomicLong) JavaClassName() string {
+	return "java.util.concurrent.atomic.AtomicLong"
+}
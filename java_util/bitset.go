@@ -0,0 +1,127 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+import (
+	"math/bits"
+)
+
+// BitSet is java.util.BitSet, whose bits are packed into Words the same way as java:
+// bit i is the bit (i % 64) of Words[i / 64], counted from the lowest bit.
+type BitSet struct {
+	Words []int64 `hessian:"words"`
+}
+
+// NewBitSet creates a BitSet with the bits @indexes set.
+func NewBitSet(indexes ...int) *BitSet {
+	b := &BitSet{}
+	for _, i := range indexes {
+		b.Set(i)
+	}
+	return b
+}
+
+func (BitSet) JavaClassName() string {
+	return "java.util.BitSet"
+}
+
+// Get returns true if the bit @i is set. It panics if @i is negative like java.
+func (b BitSet) Get(i int) bool {
+	if i < 0 {
+		panic("java_util: negative BitSet index")
+	}
+	w := i >> 6
+	return w < len(b.Words) && b.Words[w]&(1<<uint(i&63)) != 0
+}
+
+// Set sets the bit @i. It panics if @i is negative like java.
+func (b *BitSet) Set(i int) {
+	if i < 0 {
+		panic("java_util: negative BitSet index")
+	}
+	w := i >> 6
+	for len(b.Words) <= w {
+		b.Words = append(b.Words, 0)
+	}
+	b.Words[w] |= 1 << uint(i&63)
+}
+
+// Clear clears the bit @i. It panics if @i is negative like java.
+func (b *BitSet) Clear(i int) {
+	if i < 0 {
+		panic("java_util: negative BitSet index")
+	}
+	w := i >> 6
+	if w >= len(b.Words) {
+		return
+	}
+	b.Words[w] &^= 1 << uint(i&63)
+	// drop the trailing zero words as java does for the words in use
+	n := len(b.Words)
+	for n > 0 && b.Words[n-1] == 0 {
+		n--
+	}
+	b.Words = b.Words[:n]
+}
+
+// Len returns the index of the highest set bit plus one, which is the java BitSet.length().
+func (b BitSet) Len() int {
+	for w := len(b.Words) - 1; w >= 0; w-- {
+		if b.Words[w] != 0 {
+			return w<<6 + 64 - bits.LeadingZeros64(uint64(b.Words[w]))
+		}
+	}
+	return 0
+}
+
+// Cardinality returns the number of the set bits.
+func (b BitSet) Cardinality() int {
+	n := 0
+	for _, word := range b.Words {
+		n += bits.OnesCount64(uint64(word))
+	}
+	return n
+}
+
+// NextSetBit returns the index of the first set bit from @from on, or -1 if there is none.
+func (b BitSet) NextSetBit(from int) int {
+	if from < 0 {
+		from = 0
+	}
+	w := from >> 6
+	if w >= len(b.Words) {
+		return -1
+	}
+	word := uint64(b.Words[w]) & (^uint64(0) << uint(from&63))
+	for {
+		if word != 0 {
+			return w<<6 + bits.TrailingZeros64(word)
+		}
+		w++
+		if w >= len(b.Words) {
+			return -1
+		}
+		word = uint64(b.Words[w])
+	}
+}
+
+// Indexes returns the indexes of the set bits in ascending order.
+func (b BitSet) Indexes() []int {
+	indexes := make([]int, 0, b.Cardinality())
+	for i := b.NextSetBit(0); i >= 0; i = b.NextSetBit(i + 1) {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
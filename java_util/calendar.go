@@ -0,0 +1,104 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeZone is the sun.util.calendar.ZoneInfo of java, which is the java.util.TimeZone of a
+// region id like Asia/Shanghai. RawOffset is the offset in milliseconds without the daylight saving.
+type TimeZone struct {
+	ID        string `hessian:"ID"`
+	RawOffset int32  `hessian:"rawOffset"`
+}
+
+func (TimeZone) JavaClassName() string {
+	return "sun.util.calendar.ZoneInfo"
+}
+
+// NewTimeZone returns the java time zone of the location @loc in the year of the instant @t. The raw
+// offset is the smaller offset of January and July, which is without the daylight saving in both hemispheres.
+// The id of time.Local or a location without a name is the custom id of the raw offset, like GMT+08:00.
+func NewTimeZone(t time.Time, loc *time.Location) *TimeZone {
+	_, jan := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, loc).Zone()
+	_, jul := time.Date(t.Year(), time.July, 1, 0, 0, 0, 0, loc).Zone()
+	offset := jan
+	if jul < offset {
+		offset = jul
+	}
+
+	id := loc.String()
+	if loc == time.Local || id == "" {
+		id = customZoneID(offset)
+	}
+	return &TimeZone{ID: id, RawOffset: int32(offset * 1000)}
+}
+
+// customZoneID returns the java custom zone id of the offset @seconds, like GMT+08:00
+func customZoneID(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign, seconds = '-', -seconds
+	}
+	return fmt.Sprintf("GMT%c%02d:%02d", sign, seconds/3600, seconds/60%60)
+}
+
+// Location returns the location of the zone id, which is loaded by time.LoadLocation.
+// A zone id unknown to go, such as a custom id like GMT+08:00, is a fixed zone of the raw offset.
+func (z *TimeZone) Location() *time.Location {
+	if z == nil {
+		return time.UTC
+	}
+	if z.ID != "" && z.ID != "Local" {
+		if loc, err := time.LoadLocation(z.ID); err == nil {
+			return loc
+		}
+	}
+	return time.FixedZone(z.ID, int(z.RawOffset/1000))
+}
+
+// Calendar is java.util.GregorianCalendar, which hessian sends as an object of its fields.
+// Time is the instant in epoch milliseconds, and Zone is the time zone of the calendar.
+//
+// A Calendar written by go has the fields computed from the time by java, which is told by
+// IsTimeSet and AreFieldsSet, so the other fields of the java calendar are left to their defaults.
+type Calendar struct {
+	Time         int64     `hessian:"time"`
+	IsTimeSet    bool      `hessian:"isTimeSet"`
+	AreFieldsSet bool      `hessian:"areFieldsSet"`
+	Lenient      bool      `hessian:"lenient"`
+	Zone         *TimeZone `hessian:"zone"`
+}
+
+// NewCalendar returns the java calendar of the instant @t in the location of @t.
+func NewCalendar(t time.Time) *Calendar {
+	return &Calendar{
+		Time:      t.Unix()*1000 + int64(t.Nanosecond()/1e6),
+		IsTimeSet: true,
+		Lenient:   true,
+		Zone:      NewTimeZone(t, t.Location()),
+	}
+}
+
+// ToTime returns the instant of the calendar in the location of its zone.
+func (c Calendar) ToTime() time.Time {
+	return time.Unix(c.Time/1000, c.Time%1000*int64(time.Millisecond)).In(c.Zone.Location())
+}
+
+func (Calendar) JavaClassName() string {
+	return "java.util.GregorianCalendar"
+}
@@ -0,0 +1,34 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+// Currency is java.util.Currency, which is sent as its ISO 4217 code.
+type Currency struct {
+	CurrencyCode string `hessian:"currencyCode"`
+}
+
+// NewCurrency creates the Currency of ISO 4217 code @code, such as "USD".
+func NewCurrency(code string) *Currency {
+	return &Currency{CurrencyCode: code}
+}
+
+func (Currency) JavaClassName() string {
+	return "java.util.Currency"
+}
+
+// String returns the currency code.
+func (c Currency) String() string {
+	return c.CurrencyCode
+}
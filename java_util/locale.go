@@ -0,0 +1,65 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+import (
+	"strings"
+)
+
+// Locale is java.util.Locale, which hessian sends as the string of Locale.toString()
+// in a com.alibaba.com.caucho.hessian.io.LocaleHandle, such as "en_US" or "no_NO_NY".
+type Locale struct {
+	Language string
+	Country  string
+	// Variant is the rest of the locale string after the country, which keeps
+	// the script and extensions of java 7, such as "#Hans" of "zh_CN_#Hans".
+	Variant string
+}
+
+// NewLocale creates a Locale of @language, @country and @variant.
+func NewLocale(language, country, variant string) *Locale {
+	return &Locale{Language: language, Country: country, Variant: variant}
+}
+
+// ParseLocale parses a locale string of java Locale.toString(), such as "en_US".
+func ParseLocale(s string) *Locale {
+	l := &Locale{}
+	parts := strings.SplitN(s, "_", 3)
+	l.Language = parts[0]
+	if len(parts) > 1 {
+		l.Country = parts[1]
+	}
+	if len(parts) > 2 {
+		l.Variant = parts[2]
+	}
+	return l
+}
+
+func (Locale) JavaClassName() string {
+	return "com.alibaba.com.caucho.hessian.io.LocaleHandle"
+}
+
+// String returns the locale string like java Locale.toString(), which
+// omits the empty country and variant at the end, such as "en" and "en_US".
+func (l Locale) String() string {
+	switch {
+	case l.Variant != "":
+		return l.Language + "_" + l.Country + "_" + l.Variant
+	case l.Country != "":
+		return l.Language + "_" + l.Country
+	default:
+		return l.Language
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+// Optional is java.util.Optional, which is empty when its Value is nil.
+// A decoded java.util.Optional is unwrapped into its value, so Optional is only used to encode.
+type Optional struct {
+	Value interface{} `hessian:"value"`
+}
+
+// NewOptional creates an Optional of @v, which is empty if @v is nil.
+func NewOptional(v interface{}) *Optional {
+	return &Optional{Value: v}
+}
+
+// IsPresent returns true if the Optional is not empty.
+func (o Optional) IsPresent() bool {
+	return o.Value != nil
+}
+
+func (Optional) JavaClassName() string {
+	return "java.util.Optional"
+}
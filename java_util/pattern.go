@@ -0,0 +1,270 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// the flags of java.util.regex.Pattern
+const (
+	PatternUnixLines             = 0x01
+	PatternCaseInsensitive       = 0x02
+	PatternComments              = 0x04
+	PatternMultiline             = 0x08
+	PatternLiteral               = 0x10
+	PatternDotAll                = 0x20
+	PatternUnicodeCase           = 0x40
+	PatternCanonEq               = 0x80
+	PatternUnicodeCharacterClass = 0x100
+)
+
+// Pattern is java.util.regex.Pattern, which is sent as its source and flags.
+type Pattern struct {
+	Pattern string `hessian:"pattern"`
+	Flags   int32  `hessian:"flags"`
+}
+
+// NewPattern creates a Pattern of the source @pattern and the java flags @flags.
+func NewPattern(pattern string, flags int32) *Pattern {
+	return &Pattern{Pattern: pattern, Flags: flags}
+}
+
+// Compile compiles the Pattern into a go regexp. The flags are translated as follows:
+//   - CASE_INSENSITIVE alone folds only the ASCII letters like java, so the pattern is rewritten
+//     to match both cases of its ASCII letters. With UNICODE_CASE it is the go flag i, which folds by unicode.
+//   - MULTILINE and DOTALL are the go flags m and s.
+//   - LITERAL quotes the pattern by regexp.QuoteMeta.
+//   - UNIX_LINES changes nothing, since only '\n' ends a line in go.
+//   - COMMENTS, CANON_EQ and UNICODE_CHARACTER_CLASS have no go counterpart, which cause an error.
+//
+// The syntax of java and go regexp differs too, such as go has no lookaround and backreference,
+// which causes an error of regexp.Compile.
+func (p Pattern) Compile() (*regexp.Regexp, error) {
+	if unsupported := p.Flags &^ (PatternUnixLines | PatternCaseInsensitive | PatternMultiline |
+		PatternLiteral | PatternDotAll | PatternUnicodeCase); unsupported != 0 {
+		return nil, perrors.Errorf("java pattern flags %#x can not be translated to go", unsupported)
+	}
+
+	expr := p.Pattern
+	if p.Flags&PatternLiteral != 0 {
+		expr = regexp.QuoteMeta(expr)
+	}
+
+	var flags string
+	if p.Flags&PatternCaseInsensitive != 0 {
+		if p.Flags&PatternUnicodeCase != 0 {
+			flags += "i"
+		} else {
+			expr = foldASCII(expr)
+		}
+	}
+	if p.Flags&PatternMultiline != 0 {
+		flags += "m"
+	}
+	if p.Flags&PatternDotAll != 0 {
+		flags += "s"
+	}
+	if flags != "" {
+		expr = "(?" + flags + ")" + expr
+	}
+
+	return regexp.Compile(expr)
+}
+
+// foldASCII rewrites the go regexp @expr so that its ASCII letters match both cases and the other
+// letters only themselves. The letters of escapes such as \d, of group flags and of group names are kept.
+func foldASCII(expr string) string {
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case strings.HasPrefix(expr[i:], `\Q`):
+			quoted := expr[i+2:]
+			i = len(expr)
+			if end := strings.Index(quoted, `\E`); end >= 0 {
+				i -= len(quoted) - end - 2
+				quoted = quoted[:end]
+			}
+			for _, r := range quoted {
+				if isASCIILetter(r) {
+					writeFoldedLetter(&b, r)
+				} else {
+					b.WriteString(regexp.QuoteMeta(string(r)))
+				}
+			}
+		case c == '\\':
+			n, r, ok := escape(expr, i)
+			if ok && isASCIILetter(r) {
+				writeFoldedLetter(&b, r)
+			} else {
+				b.WriteString(expr[i : i+n])
+			}
+			i += n
+		case c == '[':
+			i = foldClass(&b, expr, i)
+		case strings.HasPrefix(expr[i:], "(?"):
+			n := len(expr) - i
+			if end := strings.IndexAny(expr[i:], ":)>"); end >= 0 {
+				n = end + 1
+			}
+			b.WriteString(expr[i : i+n])
+			i += n
+		case isASCIILetter(rune(c)):
+			writeFoldedLetter(&b, rune(c))
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// foldClass writes the character class at expr[i] to @b with the other case of its ASCII letters added,
+// and returns the index after the class.
+func foldClass(b *strings.Builder, expr string, i int) int {
+	var folded []byte
+	j := i + 1
+	if j < len(expr) && expr[j] == '^' {
+		j++
+	}
+	for first := true; j < len(expr) && (expr[j] != ']' || first); first = false {
+		if strings.HasPrefix(expr[j:], "[:") {
+			if end := strings.Index(expr[j+2:], ":]"); end >= 0 {
+				j += end + 4
+				continue
+			}
+		}
+		n, lo, ok := classChar(expr, j)
+		j += n
+		if !ok {
+			continue
+		}
+		hi := lo
+		if j+1 < len(expr) && expr[j] == '-' && expr[j+1] != ']' {
+			n, hi, ok = classChar(expr, j+1)
+			j += 1 + n
+			if !ok {
+				continue
+			}
+		}
+		folded = appendFoldedRange(folded, lo, hi, 'a', 'z')
+		folded = appendFoldedRange(folded, lo, hi, 'A', 'Z')
+	}
+	b.WriteString(expr[i:j])
+	b.Write(folded)
+	if j < len(expr) {
+		b.WriteByte(']')
+		j++
+	}
+	return j
+}
+
+// appendFoldedRange appends the other case of the letters both in [lo, hi] and in [from, to].
+func appendFoldedRange(folded []byte, lo, hi, from, to rune) []byte {
+	if lo < from {
+		lo = from
+	}
+	if hi > to {
+		hi = to
+	}
+	if lo > hi {
+		return folded
+	}
+	folded = append(folded, byte(lo^0x20))
+	if hi > lo {
+		folded = append(folded, '-', byte(hi^0x20))
+	}
+	return folded
+}
+
+// classChar returns the length of the character at expr[i] in a class, and the rune if it is a single one.
+func classChar(expr string, i int) (int, rune, bool) {
+	if expr[i] == '\\' {
+		return escape(expr, i)
+	}
+	r, n := utf8.DecodeRuneInString(expr[i:])
+	return n, r, true
+}
+
+// escape returns the length of the escape at expr[i], and the rune if it stands for a single one.
+func escape(expr string, i int) (int, rune, bool) {
+	if i+1 == len(expr) {
+		return 1, 0, false
+	}
+	switch c := expr[i+1]; {
+	case c == 'p' || c == 'P' || c == 'x':
+		n := 3
+		if c == 'x' {
+			n = 4
+		}
+		digits := i + 2
+		if i+2 < len(expr) && expr[i+2] == '{' {
+			n = len(expr) - i
+			if end := strings.IndexByte(expr[i:], '}'); end >= 0 {
+				n = end + 1
+			}
+			digits++
+		}
+		if i+n > len(expr) {
+			n = len(expr) - i
+		}
+		if c != 'x' || digits > i+n {
+			return n, 0, false
+		}
+		v, err := strconv.ParseUint(strings.TrimSuffix(expr[digits:i+n], "}"), 16, 32)
+		return n, rune(v), err == nil
+	case c >= '0' && c <= '7':
+		n := 2
+		for n < 4 && i+n < len(expr) && expr[i+n] >= '0' && expr[i+n] <= '7' {
+			n++
+		}
+		v, _ := strconv.ParseUint(expr[i+1:i+n], 8, 32)
+		return n, rune(v), true
+	case strings.IndexByte("aftnrv", c) >= 0:
+		return 2, rune("\a\f\t\n\r\v"[strings.IndexByte("aftnrv", c)]), true
+	case c < utf8.RuneSelf && (isASCIILetter(rune(c)) || c >= '0' && c <= '9'):
+		return 2, 0, false
+	}
+	r, n := utf8.DecodeRuneInString(expr[i+1:])
+	return 1 + n, r, true
+}
+
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func writeFoldedLetter(b *strings.Builder, r rune) {
+	b.WriteByte('[')
+	b.WriteRune(r)
+	b.WriteRune(r ^ 0x20)
+	b.WriteByte(']')
+}
+
+// String returns the source of the Pattern like java Pattern.toString.
+func (p Pattern) String() string {
+	return p.Pattern
+}
+
+func (Pattern) JavaClassName() string {
+	return "java.util.regex.Pattern"
+}
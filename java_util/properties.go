@@ -0,0 +1,27 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+// Properties is java.util.Properties, whose keys and values are strings.
+// It is encoded as a map of the class java.util.Properties, which java decodes into a Properties.
+//
+// The defaults of a java Properties are not written by hessian, which only writes the entries of
+// the Properties itself, so the defaults are not decoded. Flatten them into the Properties before
+// sending it if the go side needs them, such as by copying the result of stringPropertyNames.
+type Properties map[string]string
+
+func (Properties) JavaClassName() string {
+	return "java.util.Properties"
+}
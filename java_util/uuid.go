@@ -0,0 +1,83 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java_util
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// UUID is java.util.UUID, which is made of two big-endian longs like java.
+type UUID struct {
+	MostSigBits  int64 `hessian:"mostSigBits"`
+	LeastSigBits int64 `hessian:"leastSigBits"`
+}
+
+// NewUUID creates an UUID from its 16 bytes.
+func NewUUID(b [16]byte) *UUID {
+	return &UUID{
+		MostSigBits:  int64(binary.BigEndian.Uint64(b[:8])),
+		LeastSigBits: int64(binary.BigEndian.Uint64(b[8:])),
+	}
+}
+
+// ParseUUID parses the canonical 8-4-4-4-12 form of an UUID, such as "123e4567-e89b-12d3-a456-426614174000".
+func ParseUUID(s string) (*UUID, error) {
+	var b [16]byte
+
+	parts := strings.Split(s, "-")
+	if len(parts) != 5 || len(parts[0]) != 8 || len(parts[1]) != 4 || len(parts[2]) != 4 ||
+		len(parts[3]) != 4 || len(parts[4]) != 12 {
+		return nil, perrors.Errorf("invalid UUID string: %s", s)
+	}
+	if _, err := hex.Decode(b[:], []byte(strings.Join(parts, ""))); err != nil {
+		return nil, perrors.Errorf("invalid UUID string: %s, %v", s, err)
+	}
+
+	return NewUUID(b), nil
+}
+
+// Bytes returns the 16 bytes of the UUID.
+func (u UUID) Bytes() [16]byte {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(u.MostSigBits))
+	binary.BigEndian.PutUint64(b[8:], uint64(u.LeastSigBits))
+	return b
+}
+
+// String returns the canonical 8-4-4-4-12 form, which is the same as java UUID.toString.
+func (u UUID) String() string {
+	b := u.Bytes()
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+func (UUID) JavaClassName() string {
+	return "java.util.UUID"
+}
@@ -0,0 +1,456 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java_util"
+)
+
+type optionalHolder struct {
+	Case   *Case   `hessian:"case,optional"`
+	Name   *string `hessian:",optional"`
+	Number int32   `hessian:"number,optional"`
+}
+
+func (optionalHolder) JavaClassName() string {
+	return "test.OptionalHolder"
+}
+
+func TestOptional(t *testing.T) {
+	RegisterPOJO(&Case{})
+	c := &Case{A: "a", B: 1}
+	present := java_util.NewOptional(c)
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(present))
+	assert.Nil(t, e.Encode(java_util.Optional{}))
+	assert.Nil(t, e.Encode([]interface{}{present, present}))
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, c, res)
+
+	var out Case
+	assert.Nil(t, ReflectResponse(res, &out))
+	assert.Equal(t, *c, out)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+
+	// a ref to an Optional refers to its value
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	list := res.([]interface{})
+	assert.Equal(t, 2, len(list))
+	assert.True(t, list[0] == list[1])
+	assert.Equal(t, c, list[0])
+}
+
+func TestOptionalMaxDepth(t *testing.T) {
+	nested := func(n int) []byte {
+		b := encString(encInt32(encString([]byte{BC_OBJECT_DEF}, "java.util.Optional"), 1), "value")
+		for i := 0; i < n; i++ {
+			b = append(b, BC_OBJECT_DIRECT)
+		}
+		return append(b, BC_NULL)
+	}
+
+	d := NewDecoder(nested(10))
+	d.SetMaxDepth(10)
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+
+	d = NewDecoder(nested(200))
+	d.SetMaxDepth(10)
+	_, err = d.Decode()
+	assert.Equal(t, ErrMaxDepthExceeded, perrors.Cause(err))
+
+	// the nesting which would overflow the stack stops at the default max depth
+	_, err = NewDecoder(nested(4 << 20)).Decode()
+	assert.Equal(t, ErrMaxDepthExceeded, perrors.Cause(err))
+}
+
+func TestOptionalField(t *testing.T) {
+	name := "dubbo"
+	for _, holder := range []*optionalHolder{
+		{Case: &Case{A: "a", B: 1}, Name: &name, Number: 3},
+		{},
+	} {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(holder))
+
+		res, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, holder, res)
+
+		// the fields are encoded as java.util.Optional
+		d := NewDecoder(e.Buffer())
+		_, err = d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, "test.OptionalHolder", d.classInfoList[0].javaName)
+		assert.Equal(t, "java.util.Optional", d.classInfoList[1].javaName)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	// java: new UUID(0x123e4567e89b12d3L, 0xa456426614174000L).toString()
+	u := &java_util.UUID{MostSigBits: 0x123e4567e89b12d3, LeastSigBits: -0x5ba9bd99ebe8c000}
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", u.String())
+
+	parsed, err := java_util.ParseUUID("123e4567-e89b-12d3-a456-426614174000")
+	assert.Nil(t, err)
+	assert.Equal(t, u, parsed)
+	assert.Equal(t, u, java_util.NewUUID(u.Bytes()))
+	_, err = java_util.ParseUUID("123e4567e89b12d3a456426614174000")
+	assert.NotNil(t, err)
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(u))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, u, res)
+
+	var s string
+	assert.Nil(t, ReflectResponse(res, &s))
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", s)
+}
+
+func TestBitSet(t *testing.T) {
+	b := java_util.NewBitSet(0, 63, 64, 200)
+	// java: BitSet.toLongArray()
+	assert.Equal(t, []int64{-0x7fffffffffffffff, 1, 0, 0x100}, b.Words)
+	assert.True(t, b.Get(0) && b.Get(63) && b.Get(64) && b.Get(200))
+	assert.False(t, b.Get(1) || b.Get(62) || b.Get(199) || b.Get(1000))
+	assert.Equal(t, 201, b.Len())
+	assert.Equal(t, 4, b.Cardinality())
+	assert.Equal(t, 64, b.NextSetBit(64))
+	assert.Equal(t, 200, b.NextSetBit(65))
+	assert.Equal(t, -1, b.NextSetBit(201))
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(b))
+	assert.Contains(t, string(e.Buffer()), "[long")
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, b, res)
+	assert.Equal(t, []int{0, 63, 64, 200}, res.(*java_util.BitSet).Indexes())
+
+	b.Clear(200)
+	b.Clear(64)
+	assert.Equal(t, []int64{-0x7fffffffffffffff}, b.Words)
+	assert.Equal(t, 64, b.Len())
+}
+
+func TestCurrency(t *testing.T) {
+	c := java_util.NewCurrency("CNY")
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(c))
+	assert.Contains(t, string(e.Buffer()), "currencyCode")
+
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, c, res)
+
+	var out java_util.Currency
+	assert.Nil(t, ReflectResponse(res, &out))
+	assert.Equal(t, *c, out)
+	var code string
+	assert.Nil(t, ReflectResponse(res, &code))
+	assert.Equal(t, "CNY", code)
+}
+
+func TestLocale(t *testing.T) {
+	for _, c := range []struct {
+		locale *java_util.Locale
+		value  string
+	}{
+		{java_util.NewLocale("en", "US", ""), "en_US"},
+		{java_util.NewLocale("no", "NO", "NY"), "no_NO_NY"},
+		{java_util.NewLocale("fr", "", ""), "fr"},
+		{java_util.NewLocale("fr", "", "POSIX"), "fr__POSIX"},
+		{java_util.NewLocale("zh", "CN", "#Hans"), "zh_CN_#Hans"},
+	} {
+		assert.Equal(t, c.value, c.locale.String())
+		assert.Equal(t, c.locale, java_util.ParseLocale(c.value))
+
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(c.locale))
+		assert.Nil(t, e.Encode([]interface{}{*c.locale, c.locale}))
+		// the locale handle of hessian
+		want := encString(encInt32(encString([]byte{BC_OBJECT_DEF}, "com.alibaba.com.caucho.hessian.io.LocaleHandle"), 1), "value")
+		want = encString(append(want, BC_OBJECT_DIRECT), c.value)
+		assert.Equal(t, want, e.Buffer()[:len(want)])
+
+		d := NewDecoder(e.Buffer())
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, c.locale, res)
+
+		var s string
+		assert.Nil(t, ReflectResponse(res, &s))
+		assert.Equal(t, c.value, s)
+
+		res, err = d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{c.locale, c.locale}, res)
+	}
+}
+
+func TestAtomicNumbers(t *testing.T) {
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(java_util.NewAtomicInteger(7)))
+	assert.Nil(t, e.Encode(java_util.NewAtomicLong(math.MaxInt64)))
+	// the second one is a ref to the first one
+	counter := java_util.NewAtomicLong(-1)
+	assert.Nil(t, e.Encode([]interface{}{counter, counter}))
+
+	// sent as java does
+	buf := encTestClassInstance(nil, 0, "java.util.concurrent.atomic.AtomicInteger", []string{"value"}, int32(3))
+	want := encString(encInt32(encString([]byte{BC_OBJECT_DEF}, "java.util.concurrent.atomic.AtomicInteger"), 1), "value")
+	assert.Equal(t, want, e.Buffer()[:len(want)])
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(7), res)
+
+	var i64 int64
+	assert.Nil(t, ReflectResponse(res, &i64))
+	assert.Equal(t, int64(7), i64)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MaxInt64), res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(-1), int64(-1)}, res)
+
+	res, err = NewDecoder(buf).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), res)
+}
+
+func TestPattern(t *testing.T) {
+	p := java_util.NewPattern("^a.b$", java_util.PatternCaseInsensitive|java_util.PatternMultiline|java_util.PatternDotAll)
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(p))
+	want := encString(encString(encInt32(encString([]byte{BC_OBJECT_DEF}, "java.util.regex.Pattern"), 2), "pattern"), "flags")
+	assert.Equal(t, want, e.Buffer()[:len(want)])
+
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, p, res)
+
+	re, err := res.(*java_util.Pattern).Compile()
+	assert.Nil(t, err)
+	assert.True(t, re.MatchString("x\nA\nB\ny"))
+
+	re, err = java_util.NewPattern("a.b", java_util.PatternLiteral|java_util.PatternUnixLines).Compile()
+	assert.Nil(t, err)
+	assert.True(t, re.MatchString("a.b"))
+	assert.False(t, re.MatchString("axb"))
+
+	// CASE_INSENSITIVE folds only the ASCII letters without UNICODE_CASE
+	for _, c := range []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{`a\Qb.\Ec`, []string{"AB.C", "ab.c"}, []string{"abxc"}},
+		{`é\x61\d`, []string{"éA1", "éa1"}, []string{"ÉA1", "éAd"}},
+		{`k+s`, []string{"KkS"}, []string{"\u212as", "k\u017f"}},
+		{`[a-c]`, []string{"B", "b"}, []string{"D", "\u00e9"}},
+		{`[^a-cX]`, []string{"d", "é"}, []string{"A", "b", "x"}},
+		{`[\x41-C[:digit:]]`, []string{"a", "C", "7"}, []string{"d"}},
+		{`(?P<id>id)\pL`, []string{"IDz", "iDÉ"}, []string{"id1"}},
+	} {
+		re, err = java_util.NewPattern("^"+c.pattern+"$", java_util.PatternCaseInsensitive).Compile()
+		assert.Nil(t, err, c.pattern)
+		for _, m := range c.match {
+			assert.True(t, re.MatchString(m), c.pattern+" "+m)
+		}
+		for _, m := range c.noMatch {
+			assert.False(t, re.MatchString(m), c.pattern+" "+m)
+		}
+	}
+
+	// UNICODE_CASE folds by unicode
+	re, err = java_util.NewPattern("^ék$", java_util.PatternCaseInsensitive|java_util.PatternUnicodeCase).Compile()
+	assert.Nil(t, err)
+	assert.True(t, re.MatchString("É\u212a"))
+
+	re, err = java_util.NewPattern("a.b", java_util.PatternLiteral|java_util.PatternCaseInsensitive).Compile()
+	assert.Nil(t, err)
+	assert.True(t, re.MatchString("A.B"))
+	assert.False(t, re.MatchString("AxB"))
+
+	for _, flags := range []int32{java_util.PatternComments, java_util.PatternCanonEq, java_util.PatternUnicodeCharacterClass} {
+		_, err = java_util.NewPattern("a", flags).Compile()
+		assert.NotNil(t, err)
+	}
+}
+
+type propertiesHolder struct {
+	Props java_util.Properties `hessian:"props"`
+}
+
+func (propertiesHolder) JavaClassName() string {
+	return "test.PropertiesHolder"
+}
+
+func TestProperties(t *testing.T) {
+	// new Properties() with db.url and db.user, written by hessian like the other maps
+	data := []byte{BC_MAP, 0x14}
+	data = append(data, "java.util.Properties"...)
+	data = encString(data, "db.url")
+	data = encString(data, "jdbc:mysql://localhost/test")
+	data = encString(data, "db.user")
+	data = encString(data, "root")
+	data = append(data, BC_END)
+
+	res, err := NewDecoder(data).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"db.url": "jdbc:mysql://localhost/test", "db.user": "root"}, res)
+
+	e := NewEncoder()
+	e.SetSortMapKeys(true)
+	assert.Nil(t, e.Encode(java_util.Properties(res.(map[string]string))))
+	assert.Equal(t, data, e.Buffer())
+
+	// the same map is referred to by the ref
+	e = NewEncoder()
+	props := java_util.Properties{"k": "v"}
+	assert.Nil(t, e.Encode([]interface{}{props, props}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{map[string]string{"k": "v"}, map[string]string{"k": "v"}}, res)
+
+	RegisterPOJO(&propertiesHolder{})
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&propertiesHolder{Props: props}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &propertiesHolder{Props: props}, res)
+
+	// a value which is not a string, such as put into the Hashtable, keeps the map as it is
+	data = []byte{BC_MAP, 0x14}
+	data = append(data, "java.util.Properties"...)
+	data = encString(data, "port")
+	data = encInt32(data, 8080)
+	data = append(data, BC_END)
+	res, err = NewDecoder(data).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{"port": int32(8080)}, res)
+}
+
+func TestCalendar(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+	// in the daylight saving time of new york
+	when := time.Date(2023, time.July, 4, 9, 30, 15, 123000000, loc)
+
+	c := java_util.NewCalendar(when)
+	assert.Equal(t, &java_util.TimeZone{ID: "America/New_York", RawOffset: -5 * 3600 * 1000}, c.Zone)
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(c))
+	assert.Nil(t, e.Encode([]interface{}{c, c}))
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	decoded, ok := res.(time.Time)
+	assert.True(t, ok)
+	assert.True(t, when.Equal(decoded))
+	assert.Equal(t, "America/New_York", decoded.Location().String())
+	assert.Equal(t, when.Hour(), decoded.Hour())
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{decoded, decoded}, res)
+
+	// a calendar written by java has all the fields of the calendar and the zone
+	zone := NewGenericObject("sun.util.calendar.ZoneInfo")
+	zone.Fields["ID"] = "Asia/Kolkata"
+	zone.Fields["rawOffset"] = int32(19800000)
+	zone.Fields["dstSavings"] = int32(0)
+	cal := NewGenericObject("java.util.GregorianCalendar")
+	cal.Fields["fields"] = []int32{1, 2023, 6}
+	cal.Fields["time"] = c.Time
+	cal.Fields["isTimeSet"] = true
+	cal.Fields["firstDayOfWeek"] = int32(1)
+	cal.Fields["zone"] = zone
+	cal.Fields["gregorianCutover"] = int64(-12219292800000)
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(cal))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	decoded = res.(time.Time)
+	assert.True(t, when.Equal(decoded))
+	assert.Equal(t, "Asia/Kolkata", decoded.Location().String())
+	assert.Equal(t, 19, decoded.Hour())
+
+	// an unknown zone id is the fixed zone of the raw offset
+	zone.Fields["ID"] = "GMT+05:30"
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(cal))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	_, offset := res.(time.Time).Zone()
+	assert.Equal(t, 19800, offset)
+
+	utc := java_util.NewCalendar(when.UTC())
+	assert.Equal(t, &java_util.TimeZone{ID: "UTC"}, utc.Zone)
+	local := java_util.NewTimeZone(when, time.FixedZone("", 8*3600))
+	assert.Equal(t, &java_util.TimeZone{ID: "GMT+08:00", RawOffset: 8 * 3600 * 1000}, local)
+
+	// the calendar handle written by hessian of java keeps the time only
+	handle := NewGenericObject("com.caucho.hessian.io.CalendarHandle")
+	handle.Fields["type"] = NewJavaClass("java.util.GregorianCalendar")
+	handle.Fields["date"] = when
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(handle))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.True(t, when.Equal(res.(time.Time)))
+}
+
+func TestCalendarJava(t *testing.T) {
+	when := time.Unix(1688477415, 123000000)
+	res, err := decodeJavaResponse("customReplyGregorianCalendar", "")
+	assert.Nil(t, err)
+	assert.True(t, when.Equal(res.(time.Time)))
+	res, err = decodeJavaResponse("customReplyZoneInfo", "")
+	assert.Nil(t, err)
+	assert.Equal(t, &java_util.TimeZone{ID: "Asia/Kolkata", RawOffset: 19800000}, res)
+
+	loc, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+	testJavaDecode(t, "customArgGregorianCalendar", java_util.NewCalendar(when.In(loc)))
+}
@@ -17,7 +17,6 @@ package hessian
 import (
 	"io"
 	"reflect"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -46,6 +45,28 @@ var (
 	}
 )
 
+// jdkListTypes are the java list classes of the jdk, including the internal ones returned
+// by java.util.Arrays and java.util.Collections, which are decoded as ordinary lists.
+var jdkListTypes = map[string]bool{
+	"java.util.List":                                     true,
+	"java.util.ArrayList":                                true,
+	"java.util.LinkedList":                               true,
+	"java.util.Vector":                                   true,
+	"java.util.Stack":                                    true,
+	"java.util.concurrent.CopyOnWriteArrayList":          true,
+	"java.util.Arrays$ArrayList":                         true,
+	"java.util.Collections$SingletonList":                true,
+	"java.util.Collections$EmptyList":                    true,
+	"java.util.Collections$UnmodifiableList":             true,
+	"java.util.Collections$UnmodifiableRandomAccessList": true,
+	"java.util.Collections$SynchronizedList":             true,
+	"java.util.Collections$SynchronizedRandomAccessList": true,
+	"java.util.Collections$CheckedList":                  true,
+	"java.util.Collections$CheckedRandomAccessList":      true,
+	"java.util.ImmutableCollections$List12":              true,
+	"java.util.ImmutableCollections$ListN":               true,
+}
+
 func init() {
 	listTypeNameMapper.Store("string", "[string")
 	listTypeNameMapper.Store("int8", "[short")
@@ -85,6 +106,19 @@ func getListTypeName(gotype string) string {
 	return ""
 }
 
+// listTypeName gets the type of the typed list of the elements of type @elemType, a named type of a basic
+// kind is listed like its basic type, such as []Status of `type Status string` is a list of "[string".
+func listTypeName(elemType reflect.Type) string {
+	elemType = UnpackPtrType(elemType)
+	if name := getListTypeName(elemType.String()); name != "" {
+		return name
+	}
+	if typ, ok := basicKindTypes[elemType.Kind()]; ok {
+		return getListTypeName(typ.String())
+	}
+	return ""
+}
+
 func getListType(javalistname string) reflect.Type {
 	javaname := javalistname
 	if strings.Index(javaname, "[") == 0 {
@@ -98,6 +132,11 @@ func getListType(javalistname string) reflect.Type {
 		return reflect.SliceOf(lt)
 	}
 
+	// the elements of a jdk list can be of any type
+	if jdkListTypes[javaname] {
+		return nil
+	}
+
 	var sliceTy reflect.Type
 	ltm := listTypeMapper[javaname]
 	if ltm != nil {
@@ -118,17 +157,100 @@ func getListType(javalistname string) reflect.Type {
 	return sliceTy
 }
 
+// int64ListType replaces the int32 elements of the list type @typ, which may be nested like [][]int32, with int64.
+func int64ListType(typ reflect.Type) reflect.Type {
+	switch {
+	case typ == nil:
+		return nil
+	case typ == _int32SliceType:
+		return _int64SliceType
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Slice:
+		return reflect.SliceOf(int64ListType(typ.Elem()))
+	}
+	return typ
+}
+
+// epochMillisListType replaces the time.Time elements of the list type @typ, which may be nested like [][]time.Time, with int64.
+func epochMillisListType(typ reflect.Type) reflect.Type {
+	switch {
+	case typ == nil:
+		return nil
+	case typ == _timeSliceType:
+		return _int64SliceType
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Slice:
+		return reflect.SliceOf(epochMillisListType(typ.Elem()))
+	}
+	return typ
+}
+
 // Object is equal to Object of java When encoding
 type Object interface{}
 
+// ListForm is the form of the hessian list which the encoder writes a go slice or array as, see SetListForm.
+//
+//	::= 'V' type int value*   # fixed-length typed list, ListFormTyped
+//	::= x55 type value* 'Z'   # variable-length typed list, ListFormTypedVariable
+//	::= x58 int value*        # fixed-length untyped list, ListFormUntyped
+type ListForm int
+
+const (
+	// ListFormDefault writes a fixed-length typed list for a slice of a known element type, such as []string
+	// or []*Foo of a POJO, and a fixed-length untyped list for a slice of interface{} elements.
+	ListFormDefault ListForm = iota
+	// ListFormTyped writes a fixed-length typed list, and the type of interface{} elements is "[object".
+	ListFormTyped
+	// ListFormTypedVariable writes a variable-length typed list ended by 'Z' like ListFormTyped.
+	ListFormTypedVariable
+	// ListFormUntyped writes a fixed-length untyped list.
+	ListFormUntyped
+)
+
+// SetListForm sets the form of the lists which the encoder writes the go slices and arrays of @sliceTypes as,
+// or all the slices and arrays if no type is given, for a java reader which branches on the form of the list.
+// The form of a type set by its own takes precedence over the form of all. A []byte or a byte array is
+// always written as binary.
+func (e *Encoder) SetListForm(form ListForm, sliceTypes ...reflect.Type) {
+	if len(sliceTypes) == 0 {
+		e.listForm = form
+		return
+	}
+	if e.listForms == nil {
+		e.listForms = make(map[reflect.Type]ListForm, len(sliceTypes))
+	}
+	for _, typ := range sliceTypes {
+		e.listForms[UnpackPtrType(typ)] = form
+	}
+}
+
+// listFormOf gets the form of the list which the slice or array of type @typ is written as.
+func (e *Encoder) listFormOf(typ reflect.Type) ListForm {
+	if form, ok := e.listForms[UnpackPtrType(typ)]; ok {
+		return form
+	}
+	return e.listForm
+}
+
 /////////////////////////////////////////
 // List
 /////////////////////////////////////////
 
 // encList write list
 func (e *Encoder) encList(v interface{}) error {
+	// a byte array is sent as binary like a []byte
+	if value := UnpackPtrValue(reflect.ValueOf(v)); value.Kind() == reflect.Array && value.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, value.Len())
+		reflect.Copy(reflect.ValueOf(b), value)
+		e.buffer = encBinary(e.buffer, b)
+		return nil
+	}
+	switch form := e.listFormOf(reflect.TypeOf(v)); form {
+	case ListFormTyped, ListFormTypedVariable:
+		return e.writeTypedList(v, form)
+	case ListFormUntyped:
+		return e.writeUntypedList(v)
+	}
 	if !strings.Contains(reflect.TypeOf(v).String(), "interface {}") {
-		return e.writeTypedList(v)
+		return e.writeTypedList(v, ListFormTyped)
 	}
 	return e.writeUntypedList(v)
 }
@@ -138,12 +260,17 @@ func (e *Encoder) encList(v interface{}) error {
 // ::= x55 type value* 'Z'   # variable-length list
 // ::= 'V' type int value*   # fixed-length list
 // ::= [x70-77] type value*  # fixed-length typed list
-func (e *Encoder) writeTypedList(v interface{}) error {
+// The variable-length list is written for ListFormTypedVariable, otherwise the fixed-length list 'V'.
+func (e *Encoder) writeTypedList(v interface{}, form ListForm) error {
 	var (
 		err error
 	)
 
 	value := reflect.ValueOf(v)
+	if e.nilCollection == nilCollectionNull && value.Kind() == reflect.Slice && value.IsNil() {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
 
 	// check ref
 	if n, ok := e.checkRefMap(value); ok {
@@ -153,16 +280,57 @@ func (e *Encoder) writeTypedList(v interface{}) error {
 
 	value = UnpackPtrValue(value)
 	totype := UnpackPtrType(value.Type().Elem()).String()
-	var typeName = getListTypeName(totype)
+	var typeName = listTypeName(value.Type().Elem())
+	if typeName == "" && UnpackPtrType(value.Type().Elem()).Kind() == reflect.Interface {
+		// the elements of any type in a typed list, which java reads as Object[]
+		typeName = getListTypeName("hessian.Object")
+	}
 	if typeName == "" {
 		return perrors.New("no this type name: " + totype)
 	}
 
-	e.buffer = encByte(e.buffer, BC_LIST_FIXED) // 'V'
-	e.buffer = encString(e.buffer, typeName)
-	e.buffer = encInt32(e.buffer, int32(value.Len()))
+	if form == ListFormTypedVariable {
+		e.buffer = encByte(e.buffer, BC_LIST_VARIABLE) // x55
+		e.encType(typeName)
+	} else {
+		e.buffer = encByte(e.buffer, BC_LIST_FIXED) // 'V'
+		e.encType(typeName)
+		e.buffer = encInt32(e.buffer, int32(value.Len()))
+	}
+
+	if err = e.encListElements(value); err != nil {
+		return err
+	}
+	if form == ListFormTypedVariable {
+		e.buffer = encByte(e.buffer, BC_END)
+	}
+
+	return nil
+}
+
+// encListElements writes the elements of the slice or array @value.
+func (e *Encoder) encListElements(value reflect.Value) error {
+	// the arrays of primitives are encoded without boxing every element
+	switch ary := value.Interface().(type) {
+	case []int32:
+		for _, v := range ary {
+			e.buffer = encInt32(e.buffer, v)
+		}
+		return nil
+	case []int64:
+		for _, v := range ary {
+			e.buffer = encInt64(e.buffer, v)
+		}
+		return nil
+	case []float64:
+		for _, v := range ary {
+			e.buffer = encFloat(e.buffer, v)
+		}
+		return nil
+	}
+
 	for i := 0; i < value.Len(); i++ {
-		if err = e.Encode(value.Index(i).Interface()); err != nil {
+		if err := e.Encode(value.Index(i).Interface()); err != nil {
 			return err
 		}
 	}
@@ -181,6 +349,10 @@ func (e *Encoder) writeUntypedList(v interface{}) error {
 	)
 
 	value := reflect.ValueOf(v)
+	if e.nilCollection == nilCollectionNull && value.Kind() == reflect.Slice && value.IsNil() {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
 
 	// check ref
 	if n, ok := e.checkRefMap(value); ok {
@@ -192,10 +364,8 @@ func (e *Encoder) writeUntypedList(v interface{}) error {
 
 	e.buffer = encByte(e.buffer, BC_LIST_FIXED_UNTYPED) // x58
 	e.buffer = encInt32(e.buffer, int32(value.Len()))
-	for i := 0; i < value.Len(); i++ {
-		if err = e.Encode(value.Index(i).Interface()); err != nil {
-			return err
-		}
+	if err = e.encListElements(value); err != nil {
+		return err
 	}
 
 	return nil
@@ -219,7 +389,7 @@ func (d *Decoder) readBufByte() (byte, error) {
 		buf [1]byte
 	)
 
-	_, err = io.ReadFull(d.reader, buf[:1])
+	_, err = d.readFull(buf[:1])
 	if err != nil {
 		return 0, perrors.WithStack(err)
 	}
@@ -277,7 +447,7 @@ func (d *Decoder) decList(flag int32) (interface{}, error) {
 	case untypedListTag(tag):
 		return d.readUntypedList(tag)
 	default:
-		return nil, perrors.Errorf("error list tag: 0x%x", tag)
+		return nil, d.tagErrorf("error list tag: 0x%x", tag)
 	}
 }
 
@@ -287,9 +457,9 @@ func (d *Decoder) decList(flag int32) (interface{}, error) {
 //      ::= 'V' type int value*   # fixed-length list
 //      ::= [x70-77] type value*  # fixed-length typed list
 func (d *Decoder) readTypedList(tag byte) (interface{}, error) {
-	listTyp, err := d.decString(TAG_READ)
+	listTyp, err := d.decType()
 	if err != nil {
-		return nil, perrors.Errorf("error to read list type[%s]: %v", listTyp, err)
+		return nil, perrors.Wrapf(err, "error to read list type[%s]", listTyp)
 	}
 
 	isVariableArr := tag == BC_LIST_VARIABLE
@@ -314,23 +484,48 @@ func (d *Decoder) readTypedList(tag byte) (interface{}, error) {
 		return nil, nil
 	}
 
+	if err = d.enterContainer(); err != nil {
+		return nil, err
+	}
+	defer d.leaveContainer()
+	if err = d.addElements(length); err != nil {
+		return nil, err
+	}
+
 	var (
 		aryValue reflect.Value
 		arrType  reflect.Type
 	)
-	t, err := strconv.Atoi(listTyp)
-	if err == nil {
-		arrType = d.typeRefs.Get(t)
-	} else {
-		arrType = getListType(listTyp)
+	if err = d.checkStrictType(listTyp); err != nil {
+		return nil, err
+	}
+	arrType = getListType(listTyp)
+	if d.int64Mode {
+		arrType = int64ListType(arrType)
+	}
+	// the []int64 of the dates is not a java long[], so it is filled element by element
+	primitive := arrType == _int32SliceType || arrType == _int64SliceType || arrType == _float64SliceType
+	if d.epochMillis {
+		arrType = epochMillisListType(arrType)
+	}
+	// the objects are decoded into *GenericObject in generic mode
+	if d.generic && arrType != nil && arrType.Elem().Kind() == reflect.Ptr && arrType.Elem().Elem().Kind() == reflect.Struct {
+		arrType = nil
+	}
+
+	if primitive {
+		ary, err := d.readPrimitiveList(arrType, length, isVariableArr)
+		if err != nil {
+			return nil, err
+		}
+		// the elements have no refs, so the ref of the list can be appended after them
+		return d.appendRefs(reflect.ValueOf(ary)), nil
 	}
 
 	if arrType != nil {
 		aryValue = reflect.MakeSlice(arrType, length, length)
-		d.typeRefs.appendTypeRefs(arrType.String(), arrType)
 	} else {
 		aryValue = reflect.ValueOf(make([]interface{}, length, length))
-		d.typeRefs.appendTypeRefs(strings.Replace(listTyp, "[", "", -1), aryValue.Type())
 	}
 	holder := d.appendRefs(aryValue)
 	for j := 0; j < length || isVariableArr; j++ {
@@ -342,25 +537,136 @@ func (d *Decoder) readTypedList(tag byte) (interface{}, error) {
 			return nil, perrors.WithStack(err)
 		}
 
+		// a null, or a ref to a value not decoded yet
+		var elem reflect.Value
+		if it != nil {
+			elem = EnsureRawValue(it)
+		}
+		if err = checkListElement(aryValue.Type(), elem); err != nil {
+			return nil, err
+		}
 		if isVariableArr {
-			if it != nil {
-				aryValue = reflect.Append(aryValue, EnsureRawValue(it))
+			if err = d.addElements(1); err != nil {
+				return nil, err
+			}
+			if elem.IsValid() {
+				aryValue = reflect.Append(aryValue, elem)
 			} else {
 				aryValue = reflect.Append(aryValue, reflect.Zero(aryValue.Type().Elem()))
 			}
 			holder.change(aryValue)
 		} else {
-			if it != nil {
-				aryValue.Index(j).Set(EnsureRawValue(it))
+			if elem.IsValid() {
+				aryValue.Index(j).Set(elem)
 			} else {
 				SetValue(aryValue.Index(j), EnsureRawValue(it))
 			}
 		}
 	}
 
+	if arrType == nil {
+		// the list type is a java collection class, such as java.util.LinkedList
+		if v, ok := d.narrowPOJOList(aryValue); ok {
+			holder.change(v)
+		} else if v, ok = narrowEnumList(aryValue); ok && enumSetJavaTypes[listTyp] {
+			holder.change(v)
+		}
+	}
+
 	return holder, nil
 }
 
+// checkListElement returns an error if the decoded element @v can't be an element of the list of @listType,
+// such as a string in the list of a registered POJO, which means the input is corrupt or forged.
+func checkListElement(listType reflect.Type, v reflect.Value) error {
+	if v.IsValid() && !v.Type().AssignableTo(listType.Elem()) {
+		return perrors.Errorf("can not set %s to the element of %s", v.Type(), listType)
+	}
+	return nil
+}
+
+var (
+	_int32SliceType   = reflect.TypeOf([]int32{})
+	_int64SliceType   = reflect.TypeOf([]int64{})
+	_float64SliceType = reflect.TypeOf([]float64{})
+	_timeSliceType    = reflect.TypeOf([]time.Time{})
+)
+
+// readPrimitiveList reads the elements of a java array of primitives, such as int[], long[]
+// and double[], into the go slice of @arrType directly, without boxing every element.
+func (d *Decoder) readPrimitiveList(arrType reflect.Type, length int, isVariableArr bool) (interface{}, error) {
+	switch arrType {
+	case _int32SliceType:
+		ary := make([]int32, 0, length)
+		for {
+			tag, ok, err := d.nextPrimitive(len(ary), length, isVariableArr)
+			if !ok {
+				return ary, err
+			}
+			var v int32
+			if tag != BC_NULL {
+				if v, err = d.decInt32(int32(tag)); err != nil {
+					return nil, err
+				}
+			}
+			ary = append(ary, v)
+		}
+
+	case _int64SliceType:
+		ary := make([]int64, 0, length)
+		for {
+			tag, ok, err := d.nextPrimitive(len(ary), length, isVariableArr)
+			if !ok {
+				return ary, err
+			}
+			v, err := d.decInt64(int32(tag))
+			if err != nil {
+				return nil, err
+			}
+			ary = append(ary, v)
+		}
+
+	case _float64SliceType:
+		ary := make([]float64, 0, length)
+		for {
+			tag, ok, err := d.nextPrimitive(len(ary), length, isVariableArr)
+			if !ok {
+				return ary, err
+			}
+			var v float64
+			if tag != BC_NULL {
+				if v, err = d.decFloat64(int32(tag)); err != nil {
+					return nil, err
+				}
+			}
+			ary = append(ary, v)
+		}
+	}
+
+	return nil, perrors.Errorf("%s is not a primitive array", arrType)
+}
+
+// nextPrimitive reads the tag of the element after the @n elements of a primitive array,
+// and returns false at the end of the array.
+func (d *Decoder) nextPrimitive(n, length int, isVariableArr bool) (byte, bool, error) {
+	if !isVariableArr && n >= length {
+		return 0, false, nil
+	}
+	tag, err := d.readByte()
+	if err != nil {
+		return 0, false, perrors.WithStack(err)
+	}
+	if isVariableArr {
+		if tag == BC_END {
+			return 0, false, nil
+		}
+		if err = d.addElements(1); err != nil {
+			return 0, false, err
+		}
+	}
+	return tag, true, nil
+}
+
 //readUntypedList read untyped list
 // Include 3 formats:
 //      ::= x57 value* 'Z'        # variable-length untyped list
@@ -384,6 +690,14 @@ func (d *Decoder) readUntypedList(tag byte) (interface{}, error) {
 		return nil, perrors.Errorf("error untyped list tag: %x", tag)
 	}
 
+	if err := d.enterContainer(); err != nil {
+		return nil, err
+	}
+	defer d.leaveContainer()
+	if err := d.addElements(length); err != nil {
+		return nil, err
+	}
+
 	ary := make([]interface{}, length)
 	aryValue := reflect.ValueOf(ary)
 	holder := d.appendRefs(aryValue)
@@ -398,16 +712,60 @@ func (d *Decoder) readUntypedList(tag byte) (interface{}, error) {
 		}
 
 		if isVariableArr {
-			if it != nil {
-				aryValue = reflect.Append(aryValue, EnsureRawValue(it))
+			if err = d.addElements(1); err != nil {
+				return nil, err
+			}
+			if elem := EnsureRawValue(it); it != nil && elem.IsValid() {
+				aryValue = reflect.Append(aryValue, elem)
 			} else {
 				aryValue = reflect.Append(aryValue, reflect.Zero(aryValue.Type().Elem()))
 			}
 			holder.change(aryValue)
-		} else {
-			ary[j] = it
+		} else if elem := EnsureRawValue(it); it != nil && elem.IsValid() {
+			ary[j] = elem.Interface()
 		}
 	}
 
+	if v, ok := d.narrowPOJOList(aryValue); ok {
+		holder.change(v)
+	}
+
 	return holder, nil
 }
+
+// narrowPOJOList converts a decoded []interface{} whose elements are all pointers to the same
+// registered POJO type, such as a java.util.ArrayList<com.foo.Bar>, to a slice of that
+// pointer type like []*Bar, if SetTypedPOJOLists is on. Java erases the element type of
+// a generic collection, so it is told by the elements.
+func (d *Decoder) narrowPOJOList(v reflect.Value) (reflect.Value, bool) {
+	if !d.typedPOJOLists || v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Interface || v.Len() == 0 {
+		return v, false
+	}
+
+	var elemType reflect.Type
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		if elem.IsNil() {
+			return v, false
+		}
+		t := elem.Elem().Type()
+		if elemType == nil {
+			if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+				return v, false
+			}
+			if _, ok := checkPOJORegistry(t.Elem().String()); !ok {
+				return v, false
+			}
+			elemType = t
+		} else if t != elemType {
+			return v, false
+		}
+	}
+
+	sl := reflect.MakeSlice(reflect.SliceOf(elemType), v.Len(), v.Len())
+	for i := 0; i < v.Len(); i++ {
+		sl.Index(i).Set(v.Index(i).Elem())
+	}
+
+	return sl, true
+}
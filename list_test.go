@@ -16,6 +16,7 @@ package hessian
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"testing"
 	"time"
@@ -188,3 +189,269 @@ type TypedListTest struct {
 func (*TypedListTest) JavaClassName() string {
 	return "test.TypedListTest"
 }
+
+func TestDecodeCollectionOfPOJOs(t *testing.T) {
+	RegisterPOJO(&Case{})
+	c1, c2 := &Case{A: "a", B: 1}, &Case{A: "b", B: 2}
+
+	// java.util.ArrayList is encoded as an untyped list
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{c1, c2}))
+
+	// other collections are encoded as a typed list with the collection class name
+	e.Append([]byte{BC_LIST_FIXED})
+	e.Append(encString(nil, "java.util.LinkedList"))
+	e.Append(encInt32(nil, 2))
+	assert.Nil(t, e.Encode(c1))
+	assert.Nil(t, e.Encode(c2))
+
+	// mixed elements stay []interface{}
+	assert.Nil(t, e.Encode([]interface{}{c1, "c2"}))
+
+	// the lists stay []interface{} by default
+	d := NewDecoder(e.Buffer())
+	for i := 0; i < 2; i++ {
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{c1, c2}, res)
+
+		var out []*Case
+		assert.Nil(t, ReflectResponse(res, &out))
+		assert.Equal(t, []*Case{c1, c2}, out)
+	}
+
+	d = NewDecoder(e.Buffer())
+	d.SetTypedPOJOLists(true)
+	for i := 0; i < 2; i++ {
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, []*Case{c1, c2}, res)
+
+		var out []*Case
+		assert.Nil(t, ReflectResponse(res, &out))
+		assert.Equal(t, []*Case{c1, c2}, out)
+	}
+
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{c1, "c2"}, res)
+}
+
+func TestDecodeJdkInternalLists(t *testing.T) {
+	e := NewEncoder()
+
+	// Arrays.asList(...) is a java.util.Arrays$ArrayList
+	e.Append([]byte{BC_LIST_FIXED})
+	e.Append(encString(nil, "java.util.Arrays$ArrayList"))
+	e.Append(encInt32(nil, 2))
+	assert.Nil(t, e.Encode("a"))
+	assert.Nil(t, e.Encode(int32(1)))
+
+	// Collections.singletonList(...) in the compact fixed-length form
+	e.Append([]byte{BC_LIST_DIRECT + 1})
+	e.Append(encString(nil, "java.util.Collections$SingletonList"))
+	assert.Nil(t, e.Encode("b"))
+
+	// Collections.emptyList()
+	e.Append([]byte{BC_LIST_DIRECT})
+	e.Append(encString(nil, "java.util.Collections$EmptyList"))
+
+	// Collections.unmodifiableList(...) in the variable-length form
+	e.Append([]byte{BC_LIST_VARIABLE})
+	e.Append(encString(nil, "java.util.Collections$UnmodifiableRandomAccessList"))
+	assert.Nil(t, e.Encode("c"))
+	e.Append([]byte{BC_END})
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", int32(1)}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"b"}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"c"}, res)
+
+	var out []string
+	assert.Nil(t, ReflectResponse(res, &out))
+	assert.Equal(t, []string{"c"}, out)
+}
+
+func TestPrimitiveArrays(t *testing.T) {
+	ints := []int32{1, 2}
+	arrays := []interface{}{
+		// the second one is a ref
+		[]interface{}{ints, ints, "a"},
+		[]int32{0, -1, 1 << 20, math.MinInt32, math.MaxInt32},
+		[]int64{0, -1, 1 << 40, math.MinInt64, math.MaxInt64},
+		[]float64{0, 1, -128, 0.5, math.MaxFloat64},
+		[]int32{},
+	}
+	for _, ary := range arrays {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(ary))
+		res, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, ary, res)
+	}
+
+	// a variable-length double[] and an int[] with a null element
+	e := NewEncoder()
+	e.Append([]byte{BC_LIST_VARIABLE})
+	e.Append(encString(nil, "[double"))
+	e.Append(encFloat(nil, 1.5))
+	e.Append(encFloat(nil, 2))
+	e.Append([]byte{BC_END})
+	e.Append([]byte{BC_LIST_FIXED})
+	e.Append(encString(nil, "[int"))
+	e.Append(encInt32(nil, 2))
+	e.Append([]byte{BC_NULL})
+	e.Append(encInt32(nil, 3))
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{1.5, 2}, res)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{0, 3}, res)
+
+	// an element of a wrong type
+	e = NewEncoder()
+	e.Append([]byte{BC_LIST_DIRECT + 1})
+	e.Append(encString(nil, "[double"))
+	e.Append(encString(nil, "1.5"))
+	_, err = NewDecoder(e.Buffer()).Decode()
+	assert.NotNil(t, err)
+}
+
+func BenchmarkDecodeDoubleArray(b *testing.B) {
+	ary := make([]float64, 1<<20)
+	for i := range ary {
+		ary[i] = float64(i) + 0.5
+	}
+	e := NewEncoder()
+	if err := e.Encode(ary); err != nil {
+		b.Fatal(err)
+	}
+	buf := e.Buffer()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		res, err := NewDecoder(buf).Decode()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(res.([]float64)) != len(ary) {
+			b.Fatalf("got %d elements", len(res.([]float64)))
+		}
+	}
+}
+
+type fixedArrays struct {
+	Names [2]string `hessian:"names"`
+	Point [3]int32  `hessian:"point"`
+	Sum   [4]byte   `hessian:"sum"`
+}
+
+func (fixedArrays) JavaClassName() string {
+	return "test.FixedArrays"
+}
+
+func TestFixedArray(t *testing.T) {
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([4]int32{1, 2, 3, 4}))
+	assert.Equal(t, []byte{'V', 0x04, '[', 'i', 'n', 't', 0x94, 0x91, 0x92, 0x93, 0x94}, e.Buffer())
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 2, 3, 4}, res)
+
+	RegisterPOJO(&fixedArrays{})
+	want := &fixedArrays{Names: [2]string{"x", "y"}, Point: [3]int32{1, 2, 3}, Sum: [4]byte{9, 8, 7, 6}}
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(want))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, want, res)
+
+	// the lists of other lengths can't be decoded into the arrays
+	for _, fields := range []map[string]interface{}{
+		{"names": []string{"x"}, "point": []int32{1, 2, 3}, "sum": []byte{1, 2, 3, 4}},
+		{"names": []string{"x", "y"}, "point": []int32{1, 2, 3, 4}, "sum": []byte{1, 2, 3, 4}},
+		{"names": []string{"x", "y"}, "point": []int32{1, 2, 3}, "sum": []byte{1, 2, 3}},
+	} {
+		v := &GenericObject{ClassName: "test.FixedArrays", Fields: fields}
+		e = NewEncoder()
+		assert.Nil(t, e.Encode(v))
+		_, err = NewDecoder(e.Buffer()).Decode()
+		assert.NotNil(t, err)
+	}
+
+	var out [3]int32
+	assert.Nil(t, CopySlice(reflect.ValueOf([]interface{}{int32(1), int32(2), int32(3)}), reflect.ValueOf(&out)))
+	assert.Equal(t, [3]int32{1, 2, 3}, out)
+	err = CopySlice(reflect.ValueOf([]int32{1, 2}), reflect.ValueOf(&out))
+	assert.EqualError(t, err, "in slice of 2 elements can not assign to out array type [[3]int32]")
+	err = CopySlice(reflect.ValueOf([]int32{1, 2, 3, 4}), reflect.ValueOf(&out))
+	assert.EqualError(t, err, "in slice of 4 elements can not assign to out array type [[3]int32]")
+
+	var names [2]string
+	assert.Nil(t, ReflectResponse([]interface{}{"a", "b"}, &names))
+	assert.Equal(t, [2]string{"a", "b"}, names)
+	assert.NotNil(t, ReflectResponse([]interface{}{"a"}, &names))
+}
+
+func TestListForm(t *testing.T) {
+	RegisterPOJO(&A0{})
+
+	encode := func(e *Encoder, v interface{}) []byte {
+		e.Release()
+		assert.Nil(t, e.Encode(v))
+		return e.Buffer()
+	}
+
+	e := NewEncoder()
+	// the default forms
+	assert.Equal(t, []byte{BC_LIST_FIXED, 0x07, '[', 's', 't', 'r', 'i', 'n', 'g', 0x91, 0x01, 'a'}, encode(e, []string{"a"}))
+	assert.Equal(t, []byte{BC_LIST_FIXED_UNTYPED, 0x91, 0x01, 'a'}, encode(e, []interface{}{"a"}))
+
+	e.SetListForm(ListFormTypedVariable)
+	assert.Equal(t, []byte{BC_LIST_VARIABLE, 0x04, '[', 'i', 'n', 't', 0x91, 0x92, BC_END}, encode(e, []int32{1, 2}))
+	assert.Equal(t, []byte{BC_LIST_VARIABLE, 0x07, '[', 'o', 'b', 'j', 'e', 'c', 't', 0x01, 'a', BC_END}, encode(e, []interface{}{"a"}))
+
+	// the form of a type set by its own wins
+	e.SetListForm(ListFormUntyped, reflect.TypeOf([]string{}))
+	assert.Equal(t, []byte{BC_LIST_FIXED_UNTYPED, 0x91, 0x01, 'a'}, encode(e, []string{"a"}))
+	assert.Equal(t, []byte{BC_LIST_FIXED_UNTYPED, 0x91, 0x01, 'a'}, encode(e, &[]string{"a"}))
+	e.SetListForm(ListFormTyped)
+	assert.Equal(t, []byte{BC_LIST_FIXED, 0x07, '[', 'o', 'b', 'j', 'e', 'c', 't', 0x91, 0x01, 'a'}, encode(e, []interface{}{"a"}))
+	assert.Equal(t, []byte{BC_BINARY_DIRECT + 1, 'a'}, encode(e, []byte{'a'}))
+
+	// the lists of every form are decoded
+	values := []interface{}{[]string{"a", "b"}, []int32{1, 2}, []*A0{{}, nil}, []interface{}{"a", int32(1)}, [][]int64{{1}, {2}}}
+	for _, form := range []ListForm{ListFormDefault, ListFormTyped, ListFormTypedVariable, ListFormUntyped} {
+		e = NewEncoder()
+		e.SetListForm(form)
+		for _, v := range values {
+			res, err := NewDecoder(encode(e, v)).Decode()
+			assert.Nil(t, err)
+			switch {
+			case form == ListFormUntyped:
+				assert.Equal(t, reflect.ValueOf(v).Len(), len(res.([]interface{})), form)
+			case form != ListFormDefault && reflect.TypeOf(v) == reflect.TypeOf([]interface{}{}):
+				// a java Object[]
+				assert.Equal(t, []Object{"a", int32(1)}, res, form)
+			default:
+				assert.Equal(t, v, res, form)
+			}
+		}
+	}
+}
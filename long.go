@@ -16,7 +16,7 @@ package hessian
 
 import (
 	"encoding/binary"
-	"io"
+	"math"
 )
 
 import (
@@ -48,6 +48,21 @@ func encInt64(b []byte, v int64) []byte {
 	return encByte(b, 'L', byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
 }
 
+// encUint64 encodes @v as a java long, and a value beyond math.MaxInt64 is an error
+// instead of a negative long.
+func (e *Encoder) encUint64(v uint64) error {
+	if v > math.MaxInt64 {
+		return perrors.Wrapf(ErrUintOverflow, "%d", v)
+	}
+	e.buffer = encInt64(e.buffer, int64(v))
+	return nil
+}
+
+// isLongTag checks whether @tag starts a long.
+func isLongTag(tag byte) bool {
+	return tag >= 0xd8 || (tag >= 0x38 && tag <= 0x3f) || tag == BC_LONG_INT || tag == BC_LONG
+}
+
 /////////////////////////////////////////
 // Int64
 /////////////////////////////////////////
@@ -69,7 +84,10 @@ func (d *Decoder) decInt64(flag int32) (int64, error) {
 	if flag != TAG_READ {
 		tag = byte(flag)
 	} else {
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return 0, perrors.WithStack(err)
+		}
 	}
 
 	switch {
@@ -88,24 +106,27 @@ func (d *Decoder) decInt64(flag int32) (int64, error) {
 
 		// byte int
 	case tag >= 0xc0 && tag <= 0xcf:
-		if _, err = io.ReadFull(d.reader, buf[:1]); err != nil {
+		if _, err = d.readFull(buf[:1]); err != nil {
 			return 0, perrors.WithStack(err)
 		}
 		return int64(tag-BC_INT_BYTE_ZERO)<<8 + int64(buf[0]), nil
 
 		// short int
 	case tag >= 0xd0 && tag <= 0xd7:
-		if _, err = io.ReadFull(d.reader, buf[:2]); err != nil {
+		if _, err = d.readFull(buf[:2]); err != nil {
 			return 0, perrors.WithStack(err)
 		}
 		return int64(tag-BC_INT_SHORT_ZERO)<<16 + int64(buf[0])<<8 + int64(buf[1]), nil
 
 	case tag == BC_DOUBLE_BYTE:
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return 0, perrors.WithStack(err)
+		}
 		return int64(tag), nil
 
 	case tag == BC_DOUBLE_SHORT:
-		if _, err = io.ReadFull(d.reader, buf[:2]); err != nil {
+		if _, err = d.readFull(buf[:2]); err != nil {
 			return 0, perrors.WithStack(err)
 		}
 
@@ -116,9 +137,8 @@ func (d *Decoder) decInt64(flag int32) (int64, error) {
 		return int64(i32), err
 
 	case tag == BC_LONG_INT:
-		var i32 int32
-		err = binary.Read(d.reader, binary.BigEndian, &i32)
-		return int64(i32), perrors.WithStack(err)
+		_, err = d.readFull(buf[:4])
+		return int64(UnpackInt32(buf[:4])), perrors.WithStack(err)
 
 	case tag >= 0xd8 && tag <= 0xef:
 		i8 := int8(tag - BC_LONG_ZERO)
@@ -126,7 +146,7 @@ func (d *Decoder) decInt64(flag int32) (int64, error) {
 
 	case tag >= 0xf0 && tag <= 0xff:
 		buf := []byte{tag - BC_LONG_BYTE_ZERO, 0}
-		_, err = io.ReadFull(d.reader, buf[1:])
+		_, err = d.readFull(buf[1:])
 		if err != nil {
 			return 0, perrors.WithStack(err)
 		}
@@ -140,7 +160,7 @@ func (d *Decoder) decInt64(flag int32) (int64, error) {
 		if buf[1]&0x80 != 0 {
 			buf[0] = 0xff
 		}
-		_, err = io.ReadFull(d.reader, buf[2:])
+		_, err = d.readFull(buf[2:])
 		if err != nil {
 			return 0, perrors.WithStack(err)
 		}
@@ -149,9 +169,8 @@ func (d *Decoder) decInt64(flag int32) (int64, error) {
 		return int64(i32), nil
 
 	case tag == BC_LONG:
-		var i64 int64
-		err = binary.Read(d.reader, binary.BigEndian, &i64)
-		return i64, perrors.WithStack(err)
+		_, err = d.readFull(buf[:8])
+		return UnpackInt64(buf[:8]), perrors.WithStack(err)
 
 	case tag == BC_DOUBLE_ZERO:
 		return int64(0), nil
@@ -164,6 +183,6 @@ func (d *Decoder) decInt64(flag int32) (int64, error) {
 		return int64(i64), perrors.WithStack(err)
 
 	default:
-		return 0, perrors.Errorf("decInt64 long wrong tag:%#x", tag)
+		return 0, d.tagErrorf("decInt64 long wrong tag:%#x", tag)
 	}
 }
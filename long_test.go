@@ -15,9 +15,15 @@
 package hessian
 
 import (
+	"math"
 	"testing"
 )
 
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
 func TestEncInt64Len1BDirect(t *testing.T) {
 	var (
 		v   int64
@@ -162,3 +168,37 @@ func TestLongEncode(t *testing.T) {
 	testJavaDecode(t, "argLong_m8", int64(-8))
 	testJavaDecode(t, "argLong_m9", int64(-9))
 }
+
+func TestEncIntegerKinds(t *testing.T) {
+	cases := []struct {
+		v    interface{}
+		want interface{}
+	}{
+		{int8(math.MinInt8), int32(math.MinInt8)},
+		{int8(math.MaxInt8), int32(math.MaxInt8)},
+		{int16(math.MinInt16), int32(math.MinInt16)},
+		{int16(math.MaxInt16), int32(math.MaxInt16)},
+		{int32(math.MinInt32), int32(math.MinInt32)},
+		{uint8(math.MaxUint8), int32(math.MaxUint8)},
+		{uint16(math.MaxUint16), int32(math.MaxUint16)},
+		{uint32(math.MaxUint32), int64(math.MaxUint32)},
+		{int(math.MinInt64), int64(math.MinInt64)},
+		{int64(math.MaxInt64), int64(math.MaxInt64)},
+		{uint(math.MaxInt64), int64(math.MaxInt64)},
+		{uint64(math.MaxInt64), int64(math.MaxInt64)},
+	}
+	for _, c := range cases {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(c.v))
+		res, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, c.want, res, "%T %v", c.v, c.v)
+	}
+
+	for _, v := range []interface{}{uint64(math.MaxUint64), uint64(math.MaxInt64) + 1, uint(math.MaxUint64)} {
+		e := NewEncoder()
+		err := e.Encode(v)
+		assert.Equal(t, ErrUintOverflow, perrors.Cause(err), "%T %v", v, v)
+		assert.Empty(t, e.Buffer())
+	}
+}
@@ -15,8 +15,12 @@
 package hessian
 
 import (
+	"fmt"
 	"io"
+	"math"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 import (
@@ -27,9 +31,18 @@ import (
 // map/object
 /////////////////////////////////////////
 
+// jdkMapTypes are the java map classes, such as the wrappers of java.util.Collections and the maps of guava,
+// which are decoded into go maps like java.util.HashMap, they are known to a decoder in strict mode.
+var jdkMapTypes = map[string]bool{}
+
 // ::= 'M' type (value value)* 'Z'  # key, value map pairs
 // ::= 'H' (value value)* 'Z'       # untyped key, value
 func (e *Encoder) encUntypedMap(m map[interface{}]interface{}) error {
+	if m == nil && e.nilCollection == nilCollectionNull {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
+
 	// check ref
 	if n, ok := e.checkRefMap(reflect.ValueOf(m)); ok {
 		e.buffer = encRef(e.buffer, n)
@@ -38,12 +51,23 @@ func (e *Encoder) encUntypedMap(m map[interface{}]interface{}) error {
 
 	var err error
 	e.buffer = encByte(e.buffer, BC_MAP_UNTYPED)
-	for k, v := range m {
-		if err = e.Encode(k); err != nil {
-			return err
+	if e.sortMapKeys {
+		for _, entry := range sortedMapEntries(m) {
+			if err = e.Encode(entry.Key); err != nil {
+				return err
+			}
+			if err = e.Encode(entry.Value); err != nil {
+				return err
+			}
 		}
-		if err = e.Encode(v); err != nil {
-			return err
+	} else {
+		for k, v := range m {
+			if err = e.Encode(k); err != nil {
+				return err
+			}
+			if err = e.Encode(v); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -53,6 +77,11 @@ func (e *Encoder) encUntypedMap(m map[interface{}]interface{}) error {
 }
 
 func getMapKey(key reflect.Value, t reflect.Type) (interface{}, error) {
+	// enums and objects are encoded as they are
+	if _, ok := key.Interface().(POJO); ok {
+		return key.Interface(), nil
+	}
+
 	switch t.Kind() {
 	case reflect.Bool:
 		return key.Bool(), nil
@@ -85,11 +114,308 @@ func getMapKey(key reflect.Value, t reflect.Type) (interface{}, error) {
 		return key.UnsafeAddr(), nil
 	case reflect.String:
 		return key.String(), nil
+	case reflect.Interface, reflect.Ptr, reflect.Struct, reflect.Array:
+		return key.Interface(), nil
 	}
 
 	return nil, perrors.Errorf("unsupported map key kind %s", t.Kind().String())
 }
 
+// sortedMapEntries returns the entries of @m in the order of their keys, see SetSortMapKeys.
+func sortedMapEntries(m map[interface{}]interface{}) []MapEntry {
+	keys := reflect.ValueOf(m).MapKeys()
+	sortMapKeys(keys)
+	entries := make([]MapEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = MapEntry{Key: k.Interface(), Value: m[k.Interface()]}
+	}
+	return entries
+}
+
+// sortMapKeys sorts the map keys @keys deterministically, see SetSortMapKeys.
+func sortMapKeys(keys []reflect.Value) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		return compareMapKeys(keys[i], keys[j]) < 0
+	})
+}
+
+// mapKeyRank groups the map keys of different kinds, the nil first.
+func mapKeyRank(k reflect.Value) int {
+	switch {
+	case !k.IsValid():
+		return 0
+	case k.Kind() == reflect.Bool:
+		return 1
+	case isNumberKind(k.Kind()):
+		return 2
+	case k.Kind() == reflect.String:
+		return 3
+	}
+	return 4
+}
+
+// compareMapKeys compares the map keys @a and @b, which returns a negative number if @a is before @b,
+// a positive number if @a is after @b, and 0 if they are in the same order.
+func compareMapKeys(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	ra, rb := mapKeyRank(a), mapKeyRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+
+	var c int
+	switch ra {
+	case 0:
+		return 0
+	case 1:
+		c = compareBool(a.Bool(), b.Bool())
+	case 2:
+		c = compareNumber(a, b)
+	case 3:
+		c = strings.Compare(a.String(), b.String())
+	default:
+		c = strings.Compare(fmt.Sprintf("%+v", UnpackPtrValue(a)), fmt.Sprintf("%+v", UnpackPtrValue(b)))
+	}
+	if c != 0 {
+		return c
+	}
+	// such as int32(1) and int64(1) of map[interface{}]interface{}
+	if c = strings.Compare(a.Type().String(), b.Type().String()); c != 0 {
+		return c
+	}
+
+	if ra != 4 {
+		return 0
+	}
+
+	// the distinct keys printed alike still need an order, as the keys of a map come in a random order
+	if a.CanInterface() && b.CanInterface() {
+		if c = strings.Compare(fmt.Sprintf("%#v", a.Interface()), fmt.Sprintf("%#v", b.Interface())); c != 0 {
+			return c
+		}
+	}
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		// such as the pointers to the equal structs
+		return compareUint64(uint64(a.Pointer()), uint64(b.Pointer()))
+	}
+	return 0
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+// compareNumber compares the numbers @a and @b of any int, uint and float kinds.
+func compareNumber(a, b reflect.Value) int {
+	switch {
+	case validateIntKind(a.Kind()) && validateIntKind(b.Kind()):
+		return compareInt64(a.Int(), b.Int())
+	case validateUintKind(a.Kind()) && validateUintKind(b.Kind()):
+		return compareUint64(a.Uint(), b.Uint())
+	case validateIntKind(a.Kind()) && validateUintKind(b.Kind()):
+		if a.Int() < 0 {
+			return -1
+		}
+		return compareUint64(uint64(a.Int()), b.Uint())
+	case validateUintKind(a.Kind()) && validateIntKind(b.Kind()):
+		return -compareNumber(b, a)
+	}
+	return compareFloat64(numberAsFloat(a), numberAsFloat(b))
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareFloat64 compares the floats @a and @b, a NaN is before the others.
+func compareFloat64(a, b float64) int {
+	aNaN, bNaN := math.IsNaN(a), math.IsNaN(b)
+	switch {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return -1
+	case bNaN:
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func numberAsFloat(v reflect.Value) float64 {
+	switch {
+	case validateIntKind(v.Kind()):
+		return float64(v.Int())
+	case validateUintKind(v.Kind()):
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+// MapEntry is an entry of a java map whose keys can not be the keys of a go map, such as
+// the lists. Such a map is decoded into []MapEntry in place of map[interface{}]interface{}.
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// ::= 'H' (value value)* 'Z'       # untyped key, value
+func (e *Encoder) encMapEntries(entries []MapEntry) error {
+	if entries == nil && e.nilCollection == nilCollectionNull {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
+
+	// check ref
+	if n, ok := e.checkRefMap(reflect.ValueOf(entries)); ok {
+		e.buffer = encRef(e.buffer, n)
+		return nil
+	}
+
+	var err error
+	e.buffer = encByte(e.buffer, BC_MAP_UNTYPED)
+	for i := range entries {
+		if err = e.Encode(entries[i].Key); err != nil {
+			return perrors.Wrapf(err, "failed to encode map key(idx:%d, key:%+v)", i, entries[i].Key)
+		}
+		if err = e.Encode(entries[i].Value); err != nil {
+			return perrors.Wrapf(err, "failed to encode map value(idx:%d, key:%+v, value:%+v)", i, entries[i].Key, entries[i].Value)
+		}
+	}
+	e.buffer = encByte(e.buffer, BC_END) // 'Z'
+
+	return nil
+}
+
+// isNumberKind checks whether k is one of the int, uint and float kinds
+func isNumberKind(k reflect.Kind) bool {
+	return validateIntKind(k) || validateUintKind(k) || validateFloatKind(k)
+}
+
+// sameNumber checks whether the numbers @a and @b of the same kind are equal.
+func sameNumber(a, b reflect.Value) bool {
+	switch {
+	case validateIntKind(a.Kind()):
+		return a.Int() == b.Int()
+	case validateUintKind(a.Kind()):
+		return a.Uint() == b.Uint()
+	case validateFloatKind(a.Kind()):
+		return a.Float() == b.Float()
+	}
+	return false
+}
+
+// convertMapKey converts the decoded map key @key into the key type @typ of a go map. The numbers
+// are converted if it loses nothing, and a key object decoded as a pointer is dereferenced for a
+// struct key type. An enum key can also be converted into its name for a string key type.
+func convertMapKey(key reflect.Value, typ reflect.Type) (reflect.Value, error) {
+	if key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+	if !key.IsValid() || (key.Kind() == reflect.Interface && key.IsNil()) {
+		return reflect.Zero(typ), nil
+	}
+	if key.Type().AssignableTo(typ) {
+		return key, nil
+	}
+
+	switch {
+	case key.Kind() == reflect.Ptr && !key.IsNil() && key.Elem().Type().AssignableTo(typ):
+		return key.Elem(), nil
+	case typ.Kind() == reflect.String:
+		if enum, ok := key.Interface().(POJOEnum); ok {
+			return reflect.ValueOf(enum.String()).Convert(typ), nil
+		}
+	case isNumberKind(key.Kind()) && isNumberKind(typ.Kind()):
+		out := key.Convert(typ)
+		if out.Convert(key.Type()).Interface() == key.Interface() {
+			return out, nil
+		}
+		return key, perrors.Errorf("map key %v overflows %s", key, typ)
+	}
+
+	return key, perrors.Errorf("map key {type:%s, value:%#v} can not be converted to %s", key.Type(), key, typ)
+}
+
+// hashable checks whether the decoded value @v can be the key of a go map.
+func hashable(v interface{}) bool {
+	return v == nil || reflect.TypeOf(v).Comparable()
+}
+
+// readMapEntries reads the entries of map @m until the end flag 'Z', which is the last one of
+// the refs. If any key can not be a go map key, all the entries are returned as []MapEntry,
+// which takes the place of @m in the refs, and the entries read before lose their order.
+func (d *Decoder) readMapEntries(m map[interface{}]interface{}) (interface{}, error) {
+	var entries []MapEntry
+
+	refIndex := len(d.refs) - 1
+	for d.peekByte() != BC_END {
+		if err := d.addElements(1); err != nil {
+			return nil, err
+		}
+		k, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		if entries == nil && !hashable(k) {
+			entries = make([]MapEntry, 0, len(m)+1)
+			for mk, mv := range m {
+				entries = append(entries, MapEntry{Key: mk, Value: mv})
+			}
+		}
+		if entries != nil {
+			entries = append(entries, MapEntry{Key: k, Value: v})
+			continue
+		}
+		m[k] = v
+	}
+	if _, err := d.readByte(); err != nil {
+		return nil, perrors.WithStack(err)
+	}
+
+	if entries != nil {
+		d.refs[refIndex] = entries
+		return entries, nil
+	}
+	return m, nil
+}
+
 func (e *Encoder) encMap(m interface{}) error {
 	var (
 		err   error
@@ -100,6 +426,10 @@ func (e *Encoder) encMap(m interface{}) error {
 	)
 
 	value = reflect.ValueOf(m)
+	if e.nilCollection == nilCollectionNull && value.Kind() == reflect.Map && value.IsNil() {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
 
 	// check ref
 	if n, ok := e.checkRefMap(value); ok {
@@ -115,14 +445,23 @@ func (e *Encoder) encMap(m interface{}) error {
 	}
 
 	keys = value.MapKeys()
-	if len(keys) == 0 {
+	if len(keys) == 0 && e.nilCollection == nilCollectionCompatible {
 		// fix: set nil for empty map
 		e.buffer = encNull(e.buffer)
 		return nil
 	}
+	if e.sortMapKeys {
+		sortMapKeys(keys)
+	}
 
 	typ = value.Type().Key()
-	e.buffer = encByte(e.buffer, BC_MAP_UNTYPED)
+	// a map type of a java class, such as java_util.Properties, is a typed map
+	if p, ok := m.(POJO); ok {
+		e.buffer = encByte(e.buffer, BC_MAP)
+		e.encType(p.JavaClassName())
+	} else {
+		e.buffer = encByte(e.buffer, BC_MAP_UNTYPED)
+	}
 	for i := 0; i < len(keys); i++ {
 		k, err = getMapKey(keys[i], typ)
 		if err != nil {
@@ -141,6 +480,37 @@ func (e *Encoder) encMap(m interface{}) error {
 	return nil
 }
 
+// encStructAsMap encodes the go struct @v as an untyped map of its java field names, see SetStructAsMap.
+func (e *Encoder) encStructAsMap(v interface{}) error {
+	value := reflect.ValueOf(v)
+	// check ref
+	if n, ok := e.checkRefMap(value); ok {
+		e.buffer = encRef(e.buffer, n)
+		return nil
+	}
+
+	value = UnpackPtr(value)
+	names, indexes := structFields(value.Type())
+	e.buffer = encByte(e.buffer, BC_MAP_UNTYPED)
+	for i, index := range indexes {
+		// the field promoted from a nil anonymous struct pointer is left out
+		field, err := fieldByIndexErr(value, index)
+		if err != nil {
+			continue
+		}
+		if field.IsZero() && hasTagOption(value.Type().FieldByIndex(index), tagOptionOmitEmpty) {
+			continue
+		}
+		e.buffer = encString(e.buffer, names[i])
+		if err = e.Encode(field.Interface()); err != nil {
+			return perrors.Wrapf(err, "failed to encode field %s of %s", names[i], value.Type())
+		}
+	}
+	e.buffer = encByte(e.buffer, BC_END)
+
+	return nil
+}
+
 /////////////////////////////////////////
 // Map
 /////////////////////////////////////////
@@ -155,6 +525,21 @@ func (d *Decoder) decMapByValue(value reflect.Value) error {
 		entryValue interface{}
 	)
 
+	if tag = d.peekByte(); tag == BC_OBJECT_DEF || tag == BC_OBJECT ||
+		(BC_OBJECT_DIRECT <= tag && tag <= (BC_OBJECT_DIRECT+OBJECT_DIRECT_MAX)) {
+		// an object decoded into a map, such as a scala map
+		obj, err := d.decObject(TAG_READ)
+		if err != nil {
+			return perrors.WithStack(err)
+		}
+		m, err := convertValue(reflect.ValueOf(obj), UnpackPtrType(value.Type()), "")
+		if err != nil {
+			return perrors.WithStack(err)
+		}
+		SetValue(value, m)
+		return nil
+	}
+
 	//tag, _ = d.readBufByte()
 	tag, err = d.readByte()
 	// check error
@@ -174,13 +559,21 @@ func (d *Decoder) decMapByValue(value reflect.Value) error {
 		SetValue(value, EnsurePackValue(refObj))
 		return nil
 	case BC_MAP:
-		d.decString(TAG_READ) // read map type , ignored
+		// read map type, ignored
+		if _, err = d.decType(); err != nil {
+			return perrors.WithStack(err)
+		}
 	case BC_MAP_UNTYPED:
 		//do nothing
 	default:
-		return perrors.Errorf("expect map header, but get %x", tag)
+		return d.tagErrorf("expect map header, but get %#x", tag)
 	}
 
+	if err = d.enterContainer(); err != nil {
+		return err
+	}
+	defer d.leaveContainer()
+
 	m := reflect.MakeMap(UnpackPtrType(value.Type()))
 	// pack with pointer, so that to ref the same map
 	m = PackPtr(m)
@@ -200,13 +593,33 @@ func (d *Decoder) decMapByValue(value reflect.Value) error {
 		if entryKey == nil {
 			break
 		}
+		if err = d.addElements(1); err != nil {
+			return err
+		}
 		entryValue, err = d.DecodeValue()
 		// fix: check error
 		if err != nil {
 			return perrors.WithStack(err)
 		}
+		key, err := convertMapKey(EnsurePackValue(entryKey), m.Elem().Type().Key())
+		if err != nil {
+			return perrors.WithStack(err)
+		}
+		if entryValue == nil && d.skipNull {
+			continue
+		}
 		// TODO map value may be a ref object
-		m.Elem().SetMapIndex(EnsurePackValue(entryKey), EnsurePackValue(entryValue))
+		val := EnsurePackValue(entryValue)
+		if elemType := m.Elem().Type().Elem(); entryValue == nil {
+			// a null value is kept as the zero value rather than leaving the key out
+			val = reflect.Zero(elemType)
+		} else if _, ok := entryValue.(*_refHolder); !ok && !val.Type().AssignableTo(elemType) {
+			// such as a decoded *Bar for a **Bar value
+			if val, err = convertValue(val, elemType, ""); err != nil {
+				return perrors.WithStack(err)
+			}
+		}
+		m.Elem().SetMapIndex(key, val)
 	}
 
 	SetValue(value, m)
@@ -233,7 +646,17 @@ func (d *Decoder) decMap(flag int32) (interface{}, error) {
 	if flag != TAG_READ {
 		tag = byte(flag)
 	} else {
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
+	}
+
+	if tag == BC_MAP || tag == BC_MAP_UNTYPED {
+		if err = d.enterContainer(); err != nil {
+			return nil, err
+		}
+		defer d.leaveContainer()
 	}
 
 	switch {
@@ -245,6 +668,18 @@ func (d *Decoder) decMap(flag int32) (interface{}, error) {
 		if t, err = d.decType(); err != nil {
 			return nil, err
 		}
+		if err = d.checkStrictType(t); err != nil {
+			return nil, err
+		}
+		if orderedMapJavaTypes[t] {
+			return d.decOrderedMap(t)
+		}
+		if t == enumMapJavaType {
+			return d.decEnumMap()
+		}
+		if t == javaPropertiesType {
+			return d.decProperties()
+		}
 
 		_, ok = checkPOJORegistry(t)
 		if ok {
@@ -252,6 +687,9 @@ func (d *Decoder) decMap(flag int32) (interface{}, error) {
 			instValue = reflect.ValueOf(inst)
 			d.appendRefs(inst)
 			for d.peekByte() != BC_END {
+				if err = d.addElements(1); err != nil {
+					return nil, err
+				}
 				k, err = d.Decode()
 				if err != nil {
 					return nil, err
@@ -278,45 +716,15 @@ func (d *Decoder) decMap(flag int32) (interface{}, error) {
 		} else {
 			m = make(map[interface{}]interface{})
 			d.appendRefs(m)
-			for d.peekByte() != BC_END {
-				k, err = d.Decode()
-				if err != nil {
-					return nil, err
-				}
-				v, err = d.Decode()
-				if err != nil {
-					return nil, err
-				}
-				m[k] = v
-			}
-			_, err = d.readByte()
-			if err != nil {
-				return nil, perrors.WithStack(err)
-			}
-			return m, nil
+			return d.readMapEntries(m)
 		}
 
 	case tag == BC_MAP_UNTYPED:
 		m = make(map[interface{}]interface{})
 		d.appendRefs(m)
-		for d.peekByte() != BC_END {
-			k, err = d.Decode()
-			if err != nil {
-				return nil, err
-			}
-			v, err = d.Decode()
-			if err != nil {
-				return nil, err
-			}
-			m[k] = v
-		}
-		_, err = d.readByte()
-		if err != nil {
-			return nil, perrors.WithStack(err)
-		}
-		return m, nil
+		return d.readMapEntries(m)
 
 	default:
-		return nil, perrors.Errorf("illegal map type tag:%+v", tag)
+		return nil, d.tagErrorf("illegal map type tag:%#x", tag)
 	}
 }
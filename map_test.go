@@ -18,6 +18,10 @@ import (
 	"testing"
 )
 
+import (
+	"github.com/stretchr/testify/assert"
+)
+
 func TestEncUntypedMap(t *testing.T) {
 	var (
 		m   map[interface{}]interface{}
@@ -93,3 +97,245 @@ func TestMapEncode(t *testing.T) {
 	testJavaDecode(t, "argUntypedMap_1", map[interface{}]interface{}{"a": int32(0)})
 	testJavaDecode(t, "argUntypedMap_2", map[interface{}]interface{}{int32(0): "a", int32(1): "b"})
 }
+
+type keyedMaps struct {
+	Longs  map[int64]string
+	Colors map[testColor]int32
+}
+
+func (keyedMaps) JavaClassName() string {
+	return "test.KeyedMaps"
+}
+
+func TestNonStringMapKeys(t *testing.T) {
+	RegisterPOJO(&Case{})
+	RegisterJavaEnum(testColorRed)
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(map[int64]string{1: "a", 1 << 40: "b"}))
+	assert.Nil(t, e.Encode(map[int32]string{1: "a", 2: "b"}))
+	assert.Nil(t, e.Encode(map[testColor]int32{testColorRed: 1, testColorGreen: 2}))
+	assert.Nil(t, e.Encode(map[Case]string{{A: "a", B: 1}: "x"}))
+	assert.Nil(t, e.Encode(&keyedMaps{Longs: map[int64]string{7: "c"}, Colors: map[testColor]int32{testColorGreen: 3}}))
+	d := NewDecoder(e.Buffer())
+
+	// java Map<Long, String>
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{int64(1): "a", int64(1 << 40): "b"}, res)
+	var longs map[int64]string
+	assert.Nil(t, ReflectResponse(res, &longs))
+	assert.Equal(t, map[int64]string{1: "a", 1 << 40: "b"}, longs)
+	var ints map[int32]string
+	assert.NotNil(t, ReflectResponse(res, &ints))
+
+	// java Map<Integer, String> into map[int64]string
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Nil(t, ReflectResponse(res, &longs))
+	assert.Equal(t, map[int64]string{1: "a", 2: "b"}, longs)
+
+	// enum keys
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{testColorRed: int32(1), testColorGreen: int32(2)}, res)
+	var colors map[testColor]int32
+	assert.Nil(t, ReflectResponse(res, &colors))
+	assert.Equal(t, map[testColor]int32{testColorRed: 1, testColorGreen: 2}, colors)
+	var names map[string]int32
+	assert.Nil(t, ReflectResponse(res, &names))
+	assert.Equal(t, map[string]int32{"RED": 1, "GREEN": 2}, names)
+
+	// the key object is decoded as a pointer, and can be received by a struct key
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	var cases map[Case]string
+	assert.Nil(t, ReflectResponse(res, &cases))
+	assert.Equal(t, map[Case]string{{A: "a", B: 1}: "x"}, cases)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &keyedMaps{Longs: map[int64]string{7: "c"}, Colors: map[testColor]int32{testColorGreen: 3}}, res)
+}
+
+func TestUnhashableMapKeys(t *testing.T) {
+	entries := []MapEntry{
+		{Key: []interface{}{"a", int32(1)}, Value: "x"},
+		{Key: []interface{}{"b", int32(2)}, Value: "y"},
+	}
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(entries))
+	assert.Nil(t, e.Encode(map[interface{}]interface{}{"list": entries}))
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, entries, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, entries, res.(map[interface{}]interface{})["list"])
+}
+
+type mapTypeRefHolder struct {
+	First  map[string]int32 `hessian:"first"`
+	Second map[string]int32 `hessian:"second"`
+	Third  []int32          `hessian:"third"`
+	Fourth []int32          `hessian:"fourth"`
+}
+
+func (mapTypeRefHolder) JavaClassName() string {
+	return "test.MapTypeRefHolder"
+}
+
+func TestDecodeMapFieldTypeRef(t *testing.T) {
+	RegisterPOJO(&mapTypeRefHolder{})
+	a, b := NewOrderedMap("java.util.TreeMap"), NewOrderedMap("java.util.TreeMap")
+	a.Put("a", int32(1))
+	b.Put("b", int32(2))
+
+	e := NewEncoder()
+	encTestInstance(e, encTestClassDef(e, "test.MapTypeRefHolder", "first", "second", "third", "fourth"))
+	assert.Nil(t, e.Encode(a))
+	// the types of the second map and the second list are type refs
+	assert.Nil(t, e.Encode(b))
+	assert.Nil(t, e.Encode([]int32{3}))
+	assert.Nil(t, e.Encode([]int32{4}))
+
+	v, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &mapTypeRefHolder{First: map[string]int32{"a": 1}, Second: map[string]int32{"b": 2},
+		Third: []int32{3}, Fourth: []int32{4}}, v)
+}
+
+type structAsMapAddress struct {
+	City string `hessian:"city"`
+}
+
+type structAsMapUser struct {
+	Name     string `hessian:"userName"`
+	Age      int32
+	Nick     string `hessian:"nick,omitempty"`
+	Password string `hessian:"-"`
+	internal string
+	Address  *structAsMapAddress
+}
+
+func TestEncodeStructAsMap(t *testing.T) {
+	user := &structAsMapUser{Name: "tom", Age: 18, Password: "secret", internal: "x", Address: &structAsMapAddress{City: "hz"}}
+
+	e := NewEncoder()
+	assert.Error(t, e.Encode(user))
+
+	e = NewEncoder()
+	e.SetStructAsMap(true)
+	assert.NoError(t, e.Encode(user))
+	assert.Equal(t, byte(BC_MAP_UNTYPED), e.Buffer()[0])
+
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, map[interface{}]interface{}{
+		"userName": "tom",
+		"age":      int32(18),
+		"address":  map[interface{}]interface{}{"city": "hz"},
+	}, res)
+}
+
+func TestEncodeStructAsMapJava(t *testing.T) {
+	e := NewEncoder()
+	e.SetStructAsMap(true)
+	assert.Nil(t, e.Encode(&structAsMapUser{Name: "tom", Age: 18, Password: "secret", Address: &structAsMapAddress{City: "hz"}}))
+	r, err := javaDecodeBytes("customArgStructAsMap", e.Buffer())
+	assert.Nil(t, err)
+	assert.Equal(t, "true", r)
+}
+
+type mixedMapStatus string
+
+func TestEncodeMixedInterfaceMap(t *testing.T) {
+	RegisterPOJO(&Department{})
+
+	name := "ptr"
+	var nilDept *Department
+	in := map[string]interface{}{
+		"string":   "a",
+		"long":     int64(7),
+		"list":     []interface{}{"x", int32(1), nil, &Department{Name: "l"}},
+		"map":      map[string]interface{}{"k": []string{"v"}, "n": map[string]int32{"i": 1}},
+		"pojo":     &Department{Name: "dev"},
+		"nil":      nilDept,
+		"pointer":  &name,
+		"named":    mixedMapStatus("ok"),
+		"statuses": []mixedMapStatus{"on", "off"},
+	}
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(in))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{
+		"string": "a",
+		"long":   int64(7),
+		"list":   []interface{}{"x", int32(1), nil, &Department{Name: "l"}},
+		"map": map[interface{}]interface{}{
+			"k": []string{"v"},
+			"n": map[interface{}]interface{}{"i": int32(1)},
+		},
+		"pojo":     &Department{Name: "dev"},
+		"nil":      nil,
+		"pointer":  "ptr",
+		"named":    "ok",
+		"statuses": []string{"on", "off"},
+	}, res)
+}
+
+func TestSortMapKeys(t *testing.T) {
+	in := map[string]interface{}{
+		"map":  map[int32]string{3: "c", 1: "a", 2: "b"},
+		"list": []interface{}{map[interface{}]interface{}{"y": 1, "x": 2}},
+	}
+	for i := 0; i < 20; i++ {
+		in[string(rune('a'+i))] = i
+	}
+
+	encode := func(v interface{}) []byte {
+		e := NewEncoder()
+		e.SetSortMapKeys(true)
+		assert.Nil(t, e.Encode(v))
+		return e.Buffer()
+	}
+	b := encode(in)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, b, encode(in))
+	}
+	res, err := NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.Len(t, res, len(in))
+
+	// the entries are written like []MapEntry in the order of the keys
+	assert.Equal(t, encode([]MapEntry{{Key: int32(1), Value: "a"}, {Key: int32(2), Value: "b"}, {Key: int32(3), Value: "c"}}),
+		encode(map[int32]string{3: "c", 1: "a", 2: "b"}))
+	assert.Equal(t, encode([]MapEntry{
+		{Key: nil, Value: 0},
+		{Key: false, Value: 1},
+		{Key: true, Value: 2},
+		{Key: int64(-1), Value: 3},
+		{Key: 1.5, Value: 4},
+		{Key: int32(2), Value: 5},
+		{Key: int64(2), Value: 6},
+		{Key: uint64(3), Value: 7},
+		{Key: "a", Value: 8},
+		{Key: "b", Value: 9},
+	}), encode(map[interface{}]interface{}{
+		"b": 9, uint64(3): 7, int64(2): 6, int32(2): 5, 1.5: 4, "a": 8, true: 2, int64(-1): 3, false: 1, nil: 0,
+	}))
+
+	// the distinct keys printed alike are still in a fixed order
+	alike := map[interface{}]interface{}{}
+	for i := 0; i < 8; i++ {
+		alike[&Department{Name: "dev"}] = i
+	}
+	b = encode(alike)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, b, encode(alike))
+	}
+}
@@ -24,6 +24,10 @@ import (
 	perrors "github.com/pkg/errors"
 )
 
+import (
+	"github.com/apache/dubbo-go-hessian2/java_util"
+)
+
 // get @v go struct name
 func typeof(v interface{}) string {
 	return reflect.TypeOf(v).String()
@@ -93,14 +97,15 @@ func typeof(v interface{}) string {
 //  x04 BLUE                # BLUE value
 //
 //x51 x91                   # object ref #1, i.e. Color.GREEN
-func (e *Encoder) encObject(v POJO) error {
+func (e *Encoder) encObject(v interface{}) error {
 	var (
-		ok     bool
-		i      int
-		idx    int
-		num    int
-		err    error
-		clsDef classInfo
+		ok       bool
+		i        int
+		idx      int
+		err      error
+		goName   string
+		javaName string
+		clsDef   classInfo
 	)
 
 	vv := reflect.ValueOf(v)
@@ -117,36 +122,65 @@ func (e *Encoder) encObject(v POJO) error {
 		return nil
 	}
 
+	goName = UnpackPtrType(reflect.TypeOf(v)).String()
+	resolved := false
+	if p, ok := v.(POJO); ok {
+		javaName = p.JavaClassName()
+	} else if javaName, ok = getJavaName(goName); !ok {
+		if e.classNameResolver != nil {
+			clsDef, resolved, err = e.classNameResolver.classInfo(vv.Type())
+			if err != nil {
+				return perrors.WithStack(err)
+			}
+			javaName = clsDef.javaName
+		}
+		if !resolved {
+			return perrors.Errorf("%s is neither a POJO nor registered by RegisterPOJOMapping", goName)
+		}
+	}
+
 	// write object definition
 	idx = -1
 	for i = range e.classInfoList {
-		if v.JavaClassName() == e.classInfoList[i].javaName {
+		if javaName == e.classInfoList[i].javaName && !e.classInfoList[i].partial {
 			idx = i
 			break
 		}
 	}
 
 	if idx == -1 {
-		idx, ok = checkPOJORegistry(typeof(v))
-		if !ok {
-			if reflect.TypeOf(v).Implements(javaEnumType) {
-				idx = RegisterJavaEnum(v.(POJOEnum))
-			} else {
-				idx = RegisterPOJO(v)
+		if !resolved {
+			idx, ok = checkPOJORegistry(goName)
+			if !ok {
+				if reflect.TypeOf(v).Implements(javaEnumType) {
+					idx = RegisterJavaEnum(v.(POJOEnum))
+				} else {
+					idx = RegisterPOJO(v.(POJO))
+				}
+			}
+			_, clsDef, err = getStructDefByIndex(idx)
+			if err != nil {
+				return perrors.WithStack(err)
 			}
 		}
-		_, clsDef, err = getStructDefByIndex(idx)
-		if err != nil {
-			return perrors.WithStack(err)
+
+		idx = -1
+		// the class definition of the fields to encode is written by omitEmptyFields
+		if len(clsDef.omitEmpty) == 0 {
+			idx = len(e.classInfoList)
+			e.classInfoList = append(e.classInfoList, clsDef)
+			e.buffer = append(e.buffer, clsDef.buffer...)
 		}
+	} else {
+		clsDef = e.classInfoList[idx]
+	}
 
-		idx = len(e.classInfoList)
-		e.classInfoList = append(e.classInfoList, clsDef)
-		e.buffer = append(e.buffer, clsDef.buffer...)
+	if len(clsDef.omitEmpty) > 0 {
+		idx = e.omitEmptyFields(vv, clsDef)
 	}
 
 	// write object instance
-	if byte(idx) <= OBJECT_DIRECT_MAX {
+	if idx <= int(OBJECT_DIRECT_MAX) {
 		e.buffer = encByte(e.buffer, byte(idx)+BC_OBJECT_DIRECT)
 	} else {
 		e.buffer = encByte(e.buffer, BC_OBJECT)
@@ -157,14 +191,32 @@ func (e *Encoder) encObject(v POJO) error {
 		e.buffer = encString(e.buffer, v.(POJOEnum).String())
 		return nil
 	}
-	num = vv.NumField()
-	for i = 0; i < num; i++ {
-		// skip unexported anonymous field
-		if vv.Type().Field(i).PkgPath != "" {
+	for _, index := range e.classInfoList[idx].fieldIndexes {
+		structField := vv.Type().FieldByIndex(index)
+		field, err := fieldByIndexErr(vv, index)
+		if err != nil {
+			// the field is promoted from a nil anonymous struct pointer
+			field = reflect.Zero(structField.Type)
+		}
+		if hasTagOption(structField, tagOptionOptional) {
+			if err = e.encOptionalField(field); err != nil {
+				return perrors.Wrapf(err, "failed to encode optional field: %s", structField.Name)
+			}
+			continue
+		}
+		if hasTagOption(structField, tagOptionBytes) {
+			if err = e.encBytesField(field); err != nil {
+				return perrors.Wrapf(err, "failed to encode bytes field: %s", structField.Name)
+			}
+			continue
+		}
+		if c, ok := fieldCodec(field); ok {
+			if err = c.EncodeHessian(e); err != nil {
+				return perrors.Wrapf(err, "failed to encode field: %s", structField.Name)
+			}
 			continue
 		}
 
-		field := vv.Field(i)
 		if err = e.Encode(field.Interface()); err != nil {
 			fieldName := field.Type().String()
 			return perrors.Wrapf(err, "failed to encode field: %s, %+v", fieldName, field.Interface())
@@ -174,6 +226,76 @@ func (e *Encoder) encObject(v POJO) error {
 	return nil
 }
 
+// omittedFieldsKey identifies a class definition written by omitEmptyFields.
+type omittedFieldsKey struct {
+	javaName string
+	omitted  string // the bitmask of the omitted fields in classInfo.omitEmpty
+}
+
+// omitEmptyFields gets the index of the class definition of the fields of @vv to encode, which are
+// the fields of class definition @cls except the zero ones with tagOptionOmitEmpty.
+// The class definition is written if it has not been written by the encoder, which is
+// a partial one if some fields are omitted.
+func (e *Encoder) omitEmptyFields(vv reflect.Value, cls classInfo) int {
+	var (
+		names   []string
+		indexes [][]int
+	)
+	// the bitmask of the omitted fields in cls.omitEmpty
+	omitted := make([]byte, (len(cls.omitEmpty)+7)/8)
+	j := 0
+	for i, index := range cls.fieldIndexes {
+		if j < len(cls.omitEmpty) && cls.omitEmpty[j] == i {
+			j++
+			// the field promoted from a nil anonymous struct pointer is zero too
+			if field, err := fieldByIndexErr(vv, index); err != nil || field.IsZero() {
+				omitted[(j-1)/8] |= 1 << uint((j-1)%8)
+				continue
+			}
+		}
+		names = append(names, cls.fieldNameList[i])
+		indexes = append(indexes, index)
+	}
+	partial := len(names) < len(cls.fieldNameList)
+
+	key := omittedFieldsKey{javaName: cls.javaName, omitted: string(omitted)}
+	if i, ok := e.omittedFieldsDefs[key]; ok {
+		return i
+	}
+	if e.omittedFieldsDefs == nil {
+		e.omittedFieldsDefs = make(map[omittedFieldsKey]int)
+	}
+	e.omittedFieldsDefs[key] = len(e.classInfoList)
+
+	if partial {
+		cls = classInfo{javaName: cls.javaName, fieldNameList: names, fieldIndexes: indexes, partial: true}
+		cls.buffer = encByte(cls.buffer, BC_OBJECT_DEF)
+		cls.buffer = encString(cls.buffer, cls.javaName)
+		cls.buffer = encInt32(cls.buffer, int32(len(names)))
+		for _, name := range names {
+			cls.buffer = encString(cls.buffer, name)
+		}
+	}
+
+	e.classInfoList = append(e.classInfoList, cls)
+	e.buffer = append(e.buffer, cls.buffer...)
+	return len(e.classInfoList) - 1
+}
+
+// encOptionalField encodes the struct field @field as a java.util.Optional,
+// which is empty when the field is a nil pointer.
+func (e *Encoder) encOptionalField(field reflect.Value) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return e.Encode(java_util.Optional{})
+		}
+		if field.Elem().Kind() != reflect.Struct {
+			field = field.Elem()
+		}
+	}
+	return e.Encode(java_util.Optional{Value: field.Interface()})
+}
+
 /////////////////////////////////////////
 // Object
 /////////////////////////////////////////
@@ -260,6 +382,13 @@ func (d *Decoder) decClassDef() (interface{}, error) {
 	if err != nil {
 		return nil, perrors.WithStack(err)
 	}
+	// every field name takes one byte at least
+	if fieldNum < 0 {
+		return nil, perrors.Errorf("illegal field num %d of class %s", fieldNum, clsName)
+	}
+	if err = d.checkRemaining(int(fieldNum)); err != nil {
+		return nil, err
+	}
 	fieldList = make([]string, fieldNum)
 	for i := 0; i < int(fieldNum); i++ {
 		fieldName, err = d.decString(TAG_READ)
@@ -269,67 +398,219 @@ func (d *Decoder) decClassDef() (interface{}, error) {
 		fieldList[i] = fieldName
 	}
 
-	return classInfo{javaName: clsName, fieldNameList: fieldList}, nil
+	return classInfo{javaName: d.rewriteClassName(clsName), fieldNameList: fieldList}, nil
 }
 
-func findField(name string, typ reflect.Type) (int, error) {
-	for i := 0; i < typ.NumField(); i++ {
-		// matching tag first, then lowerCamelCase, SameCase, lowerCase
+// findField gets the index sequence of the field @name of @typ. The fields of anonymous
+// structs are searched after the fields of @typ, so the outer field wins like go does.
+func findField(name string, typ reflect.Type) ([]int, error) {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
 
-		if val, has := typ.Field(i).Tag.Lookup(tagIdentifier); has && strings.Compare(val, name) == 0 {
-			return i, nil
-		}
+	visited := map[reflect.Type]bool{typ: true}
+	current := []embedded{{typ: typ}}
+	for len(current) > 0 {
+		var next []embedded
+		for _, e := range current {
+			for i := 0; i < e.typ.NumField(); i++ {
+				field := e.typ.Field(i)
+				index := append(append(make([]int, 0, len(e.index)+1), e.index...), i)
 
-		fieldName := typ.Field(i).Name
-		switch {
-		case strings.Compare(lowerCamelCase(fieldName), name) == 0:
-			return i, nil
-		case strings.Compare(fieldName, name) == 0:
-			return i, nil
-		case strings.Compare(strings.ToLower(fieldName), name) == 0:
-			return i, nil
+				// matching tag first, then lowerCamelCase, SameCase, lowerCase
+				if skippedField(field) {
+					continue
+				}
+				if val, _, has := lookupTag(field); has && strings.Compare(val, name) == 0 {
+					return index, nil
+				}
+
+				fieldName := field.Name
+				switch {
+				case strings.Compare(lowerCamelCase(fieldName), name) == 0:
+					return index, nil
+				case strings.Compare(fieldName, name) == 0:
+					return index, nil
+				case strings.Compare(strings.ToLower(fieldName), name) == 0:
+					return index, nil
+				}
+
+				if t, ok := promotedStruct(field); ok && !visited[t] {
+					visited[t] = true
+					next = append(next, embedded{typ: t, index: index})
+				}
+			}
 		}
+		current = next
+	}
+
+	return nil, perrors.Errorf("failed to find field %s", name)
+}
 
+// fieldByIndex gets the nested field of the struct @v by @index,
+// and the nil anonymous struct pointers on the way are allocated.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
 	}
+	return v
+}
 
-	return 0, perrors.Errorf("failed to find field %s", name)
+// fieldByIndexErr gets the nested field of the struct @v by @index like reflect.Value.FieldByIndexErr of go1.18,
+// which returns an error rather than panicking if a nil anonymous struct pointer is on the way.
+func fieldByIndexErr(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, perrors.Errorf("nil pointer to embedded struct %s", v.Type().Elem())
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
 }
 
 func (d *Decoder) decInstance(typ reflect.Type, cls classInfo) (interface{}, error) {
+	if err := d.enterInstance(cls); err != nil {
+		return nil, err
+	}
+	defer d.leaveContainer()
+	return d.decInstanceFields(typ, cls)
+}
+
+// decByClassSerializer decodes an object instance of class @cls by the ClassSerializer @c,
+// which is bounded by the max depth and the max elements like the instances decoded by decInstance.
+func (d *Decoder) decByClassSerializer(c ClassSerializer, typ reflect.Type, cls classInfo) (interface{}, error) {
+	if err := d.enterInstance(cls); err != nil {
+		return nil, err
+	}
+	defer d.leaveContainer()
+	return c.DecInstance(d, typ, ClassInfo{cls})
+}
+
+// enterInstance enters an object instance of class @cls as a container, whose fields are counted as elements.
+func (d *Decoder) enterInstance(cls classInfo) error {
+	if err := d.enterContainer(); err != nil {
+		return err
+	}
+	if err := d.addElements(len(cls.fieldNameList)); err != nil {
+		d.leaveContainer()
+		return err
+	}
+	return nil
+}
+
+// decInstanceFields decodes the fields of an object instance of class @cls into a new value of @typ.
+func (d *Decoder) decInstanceFields(typ reflect.Type, cls classInfo) (interface{}, error) {
 	if typ.Kind() != reflect.Struct {
 		return nil, perrors.Errorf("wrong type expect Struct but get:%s", typ.String())
 	}
 
-	vRef := reflect.New(typ)
+	vRef, err := newInstance(typ, cls.javaName, d.skipNull)
+	if err != nil {
+		return nil, err
+	}
 	// add pointer ref so that ref the same object
 	d.appendRefs(vRef.Interface())
 
+	fieldIndexes := cls.fieldIndexes
+	if fieldIndexes == nil {
+		fieldIndexes = bindFieldIndexes(typ, cls.javaName, cls.fieldNameList)
+	}
+
 	vv := vRef.Elem()
+	pathDepth := len(d.path)
+	defer func() { d.path = d.path[:pathDepth] }()
 	for i := 0; i < len(cls.fieldNameList); i++ {
 		fieldName := cls.fieldNameList[i]
-
-		index, err := findField(fieldName, typ)
-		if err != nil {
-			return nil, perrors.Errorf("can not find field %s", fieldName)
+		if d.strict {
+			d.path = append(d.path[:pathDepth], fieldName)
 		}
 
-		// skip unexported anonymous field
-		if vv.Type().Field(index).PkgPath != "" {
+		// the value of a field which the go struct does not declare or can not set, such as a java
+		// field which is transient in go, is read and dropped to keep the following fields aligned
+		index := fieldIndexes[i]
+		if index == nil || typ.FieldByIndex(index).PkgPath != "" {
+			if _, err := d.DecodeValue(); err != nil {
+				return nil, perrors.Wrapf(err, "decInstance->skip field name:%s", fieldName)
+			}
 			continue
 		}
 
-		field := vv.Field(index)
+		field := fieldByIndex(vv, index)
 		if !field.CanSet() {
 			return nil, perrors.Errorf("decInstance CanSet false for field %s", fieldName)
 		}
+		if d.skipNull && d.peekByte() == BC_NULL {
+			if _, err := d.readByte(); err != nil {
+				return nil, perrors.WithStack(err)
+			}
+			continue
+		}
+
+		if hasTagOption(typ.FieldByIndex(index), tagOptionOptional) {
+			// the value of a java.util.Optional is unwrapped by OptionalSerializer
+			s, err := d.Decode()
+			if err != nil {
+				return nil, perrors.Wrapf(err, "decInstance->Decode optional field name:%s", fieldName)
+			}
+			if s != nil {
+				SetValue(field, EnsurePackValue(s))
+			}
+			continue
+		}
+		if hasTagOption(typ.FieldByIndex(index), tagOptionBytes) {
+			if err := d.decBytesField(field); err != nil {
+				return nil, perrors.Wrapf(err, "decInstance->decBytesField field name:%s", fieldName)
+			}
+			continue
+		}
+		if ok, err := d.decCodecField(field); ok {
+			if err != nil {
+				return nil, perrors.Wrapf(err, "decInstance->DecodeHessian field name:%s", fieldName)
+			}
+			continue
+		}
 
 		// get field type from type object, not do that from value
 		fldTyp := UnpackPtrType(field.Type())
 
+		if fldTyp == rawValueType {
+			rv, err := d.decRawValue()
+			if err != nil {
+				return nil, perrors.Wrapf(err, "decInstance->decRawValue field name:%s", fieldName)
+			}
+			SetValue(field, reflect.ValueOf(rv))
+			continue
+		}
+
+		kind := fldTyp.Kind()
+		if field.Kind() == reflect.Ptr && (kind == reflect.String || kind == reflect.Bool || isNumberKind(kind)) {
+			// a nullable field, such as a kotlin String?, is left nil by a null, otherwise it is allocated
+			if d.peekByte() == BC_NULL {
+				if _, err := d.readByte(); err != nil {
+					return nil, perrors.WithStack(err)
+				}
+				continue
+			}
+			for p := field; p.Kind() == reflect.Ptr; p = p.Elem() {
+				if p.IsNil() {
+					p.Set(reflect.New(p.Type().Elem()))
+				}
+			}
+		}
+
 		// unpack pointer to enable value setting
 		fldRawValue := UnpackPtrValue(field)
 
-		kind := fldTyp.Kind()
 		switch kind {
 		case reflect.String:
 			str, err := d.decString(TAG_READ)
@@ -339,39 +620,104 @@ func (d *Decoder) decInstance(typ reflect.Type, cls classInfo) (interface{}, err
 			fldRawValue.SetString(str)
 
 		case reflect.Int32, reflect.Int16, reflect.Int8:
+			if kind == reflect.Int32 && isStringTag(d.peekByte()) {
+				// a java char received as a go rune
+				r, err := d.decChar()
+				if err != nil {
+					return nil, perrors.Wrapf(err, "decInstance->decChar field name:%s", fieldName)
+				}
+				fldRawValue.SetInt(int64(r))
+				break
+			}
+			if isLongTag(d.peekByte()) {
+				// a java Long received as a narrower go int, such as the value of a field declared Object
+				num, err := d.decInt64(TAG_READ)
+				if err != nil {
+					return nil, perrors.Wrapf(err, "decInstance->decInt64, field name:%s", fieldName)
+				}
+				if fldRawValue.OverflowInt(num) {
+					return nil, perrors.Wrapf(ErrIntOverflow, "%d of field %s into %s", num, fieldName, fldTyp)
+				}
+				fldRawValue.SetInt(num)
+				break
+			}
 			num, err := d.decInt32(TAG_READ)
 			if err != nil {
 				// java enum
 				if fldRawValue.Type().Implements(javaEnumType) {
 					d.unreadByte() // Enum parsing, decInt64 above has read a byte, so you need to return a byte here
 					s, err := d.DecodeValue()
-					if err != nil {
+					if err != nil && perrors.Cause(err) != ErrUnknownJavaEnum {
 						return nil, perrors.Wrapf(err, "decInstance->decObject field name:%s", fieldName)
 					}
-					enumValue, _ := s.(JavaEnum)
-					num = int32(enumValue)
+					num = int32(javaEnumValue(s))
 				} else {
 					return nil, perrors.Wrapf(err, "decInstance->decInt32, field name:%s", fieldName)
 				}
 			}
+			if fldRawValue.OverflowInt(int64(num)) {
+				return nil, perrors.Wrapf(ErrIntOverflow, "%d of field %s into %s", num, fieldName, fldTyp)
+			}
 			fldRawValue.SetInt(int64(num))
 		case reflect.Uint16, reflect.Uint8:
-			num, err := d.decInt32(TAG_READ)
+			if kind == reflect.Uint16 && isStringTag(d.peekByte()) {
+				// a java char received as a go uint16, which is a char of the BMP
+				r, err := d.decChar()
+				if err != nil {
+					return nil, perrors.Wrapf(err, "decInstance->decChar field name:%s", fieldName)
+				}
+				if r > 0xffff {
+					return nil, perrors.Errorf("char %q of field %s overflows uint16", r, fieldName)
+				}
+				fldRawValue.SetUint(uint64(r))
+				break
+			}
+			var (
+				num int64
+				err error
+			)
+			if isLongTag(d.peekByte()) {
+				num, err = d.decInt64(TAG_READ)
+			} else {
+				var i32 int32
+				i32, err = d.decInt32(TAG_READ)
+				num = int64(i32)
+			}
 			if err != nil {
 				return nil, perrors.Wrapf(err, "decInstance->decInt32, field name:%s", fieldName)
 			}
+			if num < 0 || fldRawValue.OverflowUint(uint64(num)) {
+				return nil, perrors.Wrapf(ErrIntOverflow, "%d of field %s into %s", num, fieldName, fldTyp)
+			}
 			fldRawValue.SetUint(uint64(num))
 		case reflect.Uint, reflect.Int, reflect.Int64:
+			if fldTyp == durationType {
+				s, err := d.Decode()
+				if err != nil {
+					return nil, perrors.Wrapf(err, "decInstance->Decode duration field name:%s", fieldName)
+				}
+				if s != nil {
+					SetValue(fldRawValue, EnsurePackValue(s))
+				}
+				break
+			}
+			if b := d.peek(1); d.epochMillis && fldTyp.Kind() == reflect.Int64 && len(b) > 0 && (b[0] == BC_DATE || b[0] == BC_DATE_MINUTE) {
+				t, err := d.decDate(TAG_READ)
+				if err != nil {
+					return nil, perrors.Wrapf(err, "decInstance->decDate field name:%s", fieldName)
+				}
+				fldRawValue.SetInt(unixMillis(t))
+				break
+			}
 			num, err := d.decInt64(TAG_READ)
 			if err != nil {
 				if fldTyp.Implements(javaEnumType) {
 					d.unreadByte() // Enum parsing, decInt64 above has read a byte, so you need to return a byte here
 					s, err := d.Decode()
-					if err != nil {
+					if err != nil && perrors.Cause(err) != ErrUnknownJavaEnum {
 						return nil, perrors.Wrapf(err, "decInstance->decObject field name:%s", fieldName)
 					}
-					enumValue, _ := s.(JavaEnum)
-					num = int64(enumValue)
+					num = int64(javaEnumValue(s))
 				} else {
 					return nil, perrors.Wrapf(err, "decInstance->decInt64 field name:%s", fieldName)
 				}
@@ -417,7 +763,21 @@ func (d *Decoder) decInstance(typ reflect.Type, cls classInfo) (interface{}, err
 			}
 
 		case reflect.Slice, reflect.Array:
-			m, err := d.decList(TAG_READ)
+			var (
+				m   interface{}
+				err error
+			)
+			if tag := d.peekByte(); tag == BC_OBJECT_DEF || tag == BC_OBJECT ||
+				(BC_OBJECT_DIRECT <= tag && tag <= (BC_OBJECT_DIRECT+OBJECT_DIRECT_MAX)) {
+				// an object decoded into a slice, such as a java.util.EnumSet
+				m, err = d.decObject(TAG_READ)
+			} else if fldRawValue.Type().Elem().Kind() == reflect.Uint8 && (tag == BC_BINARY || tag == BC_BINARY_CHUNK ||
+				(BC_BINARY_DIRECT <= tag && tag <= 0x2f) || (BC_BINARY_SHORT <= tag && tag <= 0x37)) {
+				// a java byte[] decoded into a []byte or a byte array
+				m, err = d.decBinary(TAG_READ)
+			} else {
+				m, err = d.decList(TAG_READ)
+			}
 			if err != nil {
 				if err == io.EOF {
 					break
@@ -436,25 +796,45 @@ func (d *Decoder) decInstance(typ reflect.Type, cls classInfo) (interface{}, err
 				s   interface{}
 			)
 			typ := UnpackPtrType(fldRawValue.Type())
-			if typ.String() == "time.Time" {
-				s, err = d.decDate(TAG_READ)
+			if typ == orderedMapType {
+				s, err = d.decMap(TAG_READ)
 				if err != nil {
 					return nil, perrors.WithStack(err)
 				}
+				if _, ok := s.(*OrderedMap); ok {
+					SetValue(fldRawValue, EnsurePackValue(s))
+				} else if s != nil {
+					return nil, perrors.Errorf("can not decode %T into OrderedMap field %s", s, fieldName)
+				}
+			} else if typ.String() == "time.Time" {
+				s, err = d.decTime()
+				if err != nil {
+					return nil, perrors.Wrapf(err, "decInstance->decTime field name: %s", fieldName)
+				}
 				SetValue(fldRawValue, EnsurePackValue(s))
 			} else {
-				s, err = d.decObject(TAG_READ)
+				if tag := d.peekByte(); kind == reflect.Interface && tag != BC_NULL && tag != BC_REF && tag != BC_OBJECT_DEF &&
+					tag != BC_OBJECT && !(BC_OBJECT_DIRECT <= tag && tag <= (BC_OBJECT_DIRECT+OBJECT_DIRECT_MAX)) {
+					// an interface field holds any value, such as a string
+					s, err = EnsureInterface(d.DecodeValue())
+				} else {
+					s, err = d.decObject(TAG_READ)
+				}
 				if err != nil {
 					return nil, perrors.WithStack(err)
 				}
-				if s != nil {
+				// a java Throwable without cause refers to itself as its cause, which is left nil
+				if s != nil && !(kind == reflect.Interface && s == vRef.Interface()) {
+					if !canSetValue(fldRawValue.Type(), EnsurePackValue(s)) {
+						return nil, perrors.Errorf("can not decode %T into field %s of %v", s, fieldName, typ)
+					}
 					// set value which accepting pointers
 					SetValue(fldRawValue, EnsurePackValue(s))
 				}
 			}
 
 		default:
-			return nil, perrors.Errorf("unknown struct member type: %v %v", kind, typ.Name()+"."+typ.Field(index).Name)
+			return nil, perrors.Errorf("unknown struct member type: %v %v", kind, typ.Name()+"."+typ.FieldByIndex(index).Name)
 		}
 	} // end for
 
@@ -462,6 +842,7 @@ func (d *Decoder) decInstance(typ reflect.Type, cls classInfo) (interface{}, err
 }
 
 func (d *Decoder) appendClsDef(cd classInfo) {
+	d.checkUnknownClass(cd.javaName)
 	d.classInfoList = append(d.classInfoList, cd)
 }
 
@@ -473,18 +854,39 @@ func (d *Decoder) getStructDefByIndex(idx int) (reflect.Type, classInfo, error)
 	)
 
 	if len(d.classInfoList) <= idx || idx < 0 {
-		return nil, cls, perrors.Errorf("illegal class index @idx %d", idx)
+		return nil, cls, perrors.Errorf("illegal class index @idx %d at offset %d", idx, d.Offset())
 	}
 	cls = d.classInfoList[idx]
+	d.observeClass(cls.javaName)
 	s, ok = getStructInfo(cls.javaName)
 	if !ok {
 		return nil, cls, perrors.Errorf("can not find go type name %s in registry", cls.javaName)
 	}
+	if cls.fieldIndexes == nil && s.typ.Kind() == reflect.Struct {
+		// bind the received fields once for all the instances of the class definition
+		cls.fieldIndexes = bindFieldIndexes(s.typ, cls.javaName, cls.fieldNameList)
+		d.classInfoList[idx] = cls
+	}
 
 	return s.typ, cls, nil
 }
 
-func (d *Decoder) decEnum(javaName string, flag int32) (JavaEnum, error) {
+// getUnregisteredClassHook gets the decode hook of the class definition @idx, whose java class
+// has not been registered.
+func (d *Decoder) getUnregisteredClassHook(idx int) (DecodeHook, bool) {
+	if idx < 0 || idx >= len(d.classInfoList) {
+		return nil, false
+	}
+	if _, ok := getStructInfo(d.classInfoList[idx].javaName); ok {
+		return nil, false
+	}
+	return getDecodeHook(d.classInfoList[idx].javaName)
+}
+
+// decEnum returns the enum value as the registered go type of java class @javaName.
+// If the go type does not know the enum name, the name string is returned with ErrUnknownJavaEnum,
+// which is left out inside a container, see ErrUnknownJavaEnum.
+func (d *Decoder) decEnum(javaName string, flag int32) (interface{}, error) {
 	var (
 		err       error
 		enumName  string
@@ -502,8 +904,40 @@ func (d *Decoder) decEnum(javaName string, flag int32) (JavaEnum, error) {
 	}
 
 	enumValue = info.inst.(POJOEnum).EnumValue(enumName)
-	d.appendRefs(enumValue)
-	return enumValue, nil
+	if enumValue == InvalidJavaEnum {
+		d.appendRefs(enumName)
+		if d.depth > 0 {
+			// the element of a list or a map keeps the name, which does not fail the list or the map
+			return enumName, nil
+		}
+		return enumName, perrors.Wrapf(ErrUnknownJavaEnum, "%s.%s", javaName, enumName)
+	}
+
+	// return the typed constant, such as `type Color JavaEnum`
+	var typedValue interface{} = enumValue
+	if v := reflect.ValueOf(enumValue); v.Type().ConvertibleTo(info.typ) {
+		typedValue = v.Convert(info.typ).Interface()
+	}
+	d.appendRefs(typedValue)
+	return typedValue, nil
+}
+
+// javaEnumValue gets the JavaEnum value of a decoded enum constant of any go enum type.
+// It returns InvalidJavaEnum if @v is not a decoded enum constant.
+func javaEnumValue(v interface{}) JavaEnum {
+	if e, ok := v.(JavaEnum); ok {
+		return e
+	}
+	if _, ok := v.(POJOEnum); !ok {
+		return InvalidJavaEnum
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return JavaEnum(rv.Int())
+	}
+
+	return InvalidJavaEnum
 }
 
 func (d *Decoder) decObject(flag int32) (interface{}, error) {
@@ -518,7 +952,10 @@ func (d *Decoder) decObject(flag int32) (interface{}, error) {
 	if flag != TAG_READ {
 		tag = byte(flag)
 	} else {
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
 	}
 
 	switch {
@@ -532,12 +969,22 @@ func (d *Decoder) decObject(flag int32) (interface{}, error) {
 			return nil, perrors.Wrap(err, "decObject->decClassDef byte double")
 		}
 		cls, _ = clsDef.(classInfo)
+		if err = d.checkStrictClass(cls.javaName); err != nil {
+			return nil, err
+		}
 		//add to slice
 		d.appendClsDef(cls)
 		if c, ok := GetSerializer(cls.javaName); ok {
-			return c.DecObject(d)
+			if _, ok = c.(ClassSerializer); !ok {
+				return c.DecObject(d)
+			}
+		}
+		v, err := d.DecodeValue()
+		if err == io.EOF {
+			// the instance should follow its class definition
+			return nil, d.offsetError(ErrShortBuffer)
 		}
-		return d.DecodeValue()
+		return v, err
 
 	case tag == BC_OBJECT:
 		idx, err = d.decInt32(TAG_READ)
@@ -546,27 +993,51 @@ func (d *Decoder) decObject(flag int32) (interface{}, error) {
 		}
 
 		typ, cls, err = d.getStructDefByIndex(int(idx))
+		if d.generic {
+			if c, ok := getClassSerializer(cls.javaName); ok && err == nil {
+				return d.decByClassSerializer(c, typ, cls)
+			}
+			return d.decGenericObject(int(idx))
+		}
 		if err != nil {
+			if hook, ok := d.getUnregisteredClassHook(int(idx)); ok {
+				return d.decInstanceByHook(hook, d.classInfoList[idx])
+			}
 			return nil, err
 		}
 		if typ.Implements(javaEnumType) {
 			return d.decEnum(cls.javaName, TAG_READ)
 		}
+		if c, ok := getClassSerializer(cls.javaName); ok {
+			return d.decByClassSerializer(c, typ, cls)
+		}
 
 		return d.decInstance(typ, cls)
 
 	case BC_OBJECT_DIRECT <= tag && tag <= (BC_OBJECT_DIRECT+OBJECT_DIRECT_MAX):
 		typ, cls, err = d.getStructDefByIndex(int(tag - BC_OBJECT_DIRECT))
+		if d.generic {
+			if c, ok := getClassSerializer(cls.javaName); ok && err == nil {
+				return d.decByClassSerializer(c, typ, cls)
+			}
+			return d.decGenericObject(int(tag - BC_OBJECT_DIRECT))
+		}
 		if err != nil {
+			if hook, ok := d.getUnregisteredClassHook(int(tag - BC_OBJECT_DIRECT)); ok {
+				return d.decInstanceByHook(hook, d.classInfoList[tag-BC_OBJECT_DIRECT])
+			}
 			return nil, err
 		}
 		if typ.Implements(javaEnumType) {
 			return d.decEnum(cls.javaName, TAG_READ)
 		}
+		if c, ok := getClassSerializer(cls.javaName); ok {
+			return d.decByClassSerializer(c, typ, cls)
+		}
 
 		return d.decInstance(typ, cls)
 
 	default:
-		return nil, perrors.Errorf("decObject illegal object type tag:%+v", tag)
+		return nil, d.tagErrorf("decObject illegal object type tag:%#x", tag)
 	}
 }
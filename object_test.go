@@ -15,11 +15,17 @@
 package hessian
 
 import (
+	"bytes"
 	"math"
 	"reflect"
 	"testing"
 )
 
+import (
+	perrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
 type Department struct {
 	Name string
 }
@@ -456,7 +462,7 @@ func TestEncodeDecodeTuple(t *testing.T) {
 		B:       0xFF,
 		S:       0xFFFF,
 		I:       0xFFFFFFFF,
-		L:       0xFFFFFFFFFFFFFFFF,
+		L:       math.MaxInt64,
 		D:       math.MaxFloat64,
 	})
 }
@@ -525,3 +531,877 @@ func doTestBasePointer(t *testing.T, base *BasePointer, expected *BasePointer) {
 		t.Errorf("expect: %v, but get: %v", base, decObj)
 	}
 }
+
+type legacyOrder struct {
+	ID     int32
+	Amount float64 `hessian:"total"`
+	Buyer  string
+	Remark string
+}
+
+func TestRegisterPOJOMapping(t *testing.T) {
+	idx, err := RegisterPOJOMapping("com.legacy.Order", legacyOrder{}, []string{"buyer", "total", "remark", "iD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx == -1 {
+		t.Fatal("legacyOrder should not have been registered")
+	}
+	idx, err = RegisterPOJOMapping("com.legacy.Order", &legacyOrder{}, []string{"buyer"})
+	if err != nil || idx != -1 {
+		t.Errorf("register again = %d, %v", idx, err)
+	}
+
+	order := &legacyOrder{ID: 7, Amount: 12.5, Buyer: "Alice", Remark: "gift"}
+	e := NewEncoder()
+	if err = e.Encode(order); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, res) {
+		t.Errorf("expect: %+v, but get: %+v", order, res)
+	}
+	if !reflect.DeepEqual([]string{"buyer", "total", "remark", "iD"}, d.classInfoList[0].fieldNameList) {
+		t.Errorf("wrong field order of class definition: %v", d.classInfoList[0].fieldNameList)
+	}
+
+	// the field values follow the pinned order
+	expected := []byte{BC_OBJECT_DEF}
+	expected = encString(expected, "com.legacy.Order")
+	expected = encInt32(expected, 4)
+	for _, name := range []string{"buyer", "total", "remark", "iD"} {
+		expected = encString(expected, name)
+	}
+	expected = encByte(expected, BC_OBJECT_DIRECT)
+	expected = encString(expected, "Alice")
+	expected = encFloat(expected, 12.5)
+	expected = encString(expected, "gift")
+	expected = encInt32(expected, 7)
+	if !reflect.DeepEqual(expected, e.Buffer()) {
+		t.Errorf("expect: %v, but get: %v", expected, e.Buffer())
+	}
+
+	type unknownField struct {
+		Name string
+	}
+	if _, err = RegisterPOJOMapping("com.legacy.Unknown", unknownField{}, []string{"name", "age"}); err == nil {
+		t.Error("register a field which go struct does not have should fail")
+	}
+}
+
+type registeredBase struct {
+	ID int64
+}
+
+type registeredDTO struct {
+	registeredBase
+	Name string `hessian:"fullName"`
+}
+
+func TestRegisteredPOJOs(t *testing.T) {
+	_, err := RegisterPOJOWithAliases(&registeredDTO{}, "test.model.Registered", "test.old.Registered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterJavaEnum(testColorRed)
+
+	infos := RegisteredPOJOs()
+	info, ok := infos["test.model.Registered"]
+	if !ok {
+		t.Fatal("test.model.Registered is not in the snapshot")
+	}
+	expected := POJOInfo{
+		JavaClassName: "test.model.Registered",
+		Aliases:       []string{"test.old.Registered"},
+		GoType:        reflect.TypeOf(registeredDTO{}),
+		Fields: []POJOField{
+			{JavaName: "iD", GoName: "registeredBase.ID", GoType: reflect.TypeOf(int64(0))},
+			{JavaName: "fullName", GoName: "Name", GoType: reflect.TypeOf("")},
+		},
+	}
+	if !reflect.DeepEqual(expected, info) {
+		t.Errorf("expect: %+v, but get: %+v", expected, info)
+	}
+	if _, ok = infos["test.old.Registered"]; ok {
+		t.Error("an alias should not be listed as a java class")
+	}
+	if !infos["test.model.Color"].Enum {
+		t.Error("test.model.Color should be an enum")
+	}
+
+	// the snapshot is a copy of the registry
+	info.Fields[0].JavaName = "changed"
+	if RegisteredPOJOs()["test.model.Registered"].Fields[0].JavaName != "iD" {
+		t.Error("changing the snapshot should not change the registry")
+	}
+}
+
+type classDefItem struct {
+	SKU string
+}
+
+func (classDefItem) JavaClassName() string {
+	return "test.model.ClassDefItem"
+}
+
+type classDefOrder struct {
+	registeredBase
+	Items  []*classDefItem `hessian:"lines"`
+	Counts [][]int32
+	Raw    []byte
+	Any    []interface{}
+	Tags   map[string]string
+	Note   string `hessian:"note,omitempty"`
+	Secret string `hessian:"-"`
+}
+
+func (classDefOrder) JavaClassName() string {
+	return "test.model.ClassDefOrder"
+}
+
+func TestGetClassDef(t *testing.T) {
+	RegisterPOJOs(&classDefItem{}, &classDefOrder{})
+	RegisterJavaEnum(testColorRed)
+
+	def, err := GetClassDef(reflect.TypeOf(&classDefOrder{}))
+	assert.Nil(t, err)
+	assert.Equal(t, POJOInfo{
+		JavaClassName: "test.model.ClassDefOrder",
+		GoType:        reflect.TypeOf(classDefOrder{}),
+		Fields: []POJOField{
+			{JavaName: "iD", GoName: "registeredBase.ID", GoType: reflect.TypeOf(int64(0))},
+			{JavaName: "lines", GoName: "Items", GoType: reflect.TypeOf([]*classDefItem{}), TypeHint: "[test.model.ClassDefItem"},
+			{JavaName: "counts", GoName: "Counts", GoType: reflect.TypeOf([][]int32{}), TypeHint: "[[int"},
+			{JavaName: "raw", GoName: "Raw", GoType: reflect.TypeOf([]byte{})},
+			{JavaName: "any", GoName: "Any", GoType: reflect.TypeOf([]interface{}{})},
+			{JavaName: "tags", GoName: "Tags", GoType: reflect.TypeOf(map[string]string{})},
+			{JavaName: "note", GoName: "Note", GoType: reflect.TypeOf(""), OmitEmpty: true},
+		},
+	}, def)
+
+	// the type hints are the ones the encoder writes
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&classDefOrder{Items: []*classDefItem{{SKU: "a"}}, Counts: [][]int32{{1}}}))
+	assert.True(t, bytes.Contains(e.Buffer(), encString(nil, def.Fields[1].TypeHint)))
+	assert.True(t, bytes.Contains(e.Buffer(), encString(nil, def.Fields[2].TypeHint)))
+
+	def, err = GetClassDef(reflect.TypeOf(testColorRed))
+	assert.Nil(t, err)
+	assert.Equal(t, []POJOField{{JavaName: "name", GoType: reflect.TypeOf("")}}, def.Fields)
+	assert.True(t, def.Enum)
+	_, _ = RegisterPOJOWithAliases(&registeredDTO{}, "test.model.Registered", "test.old.Registered")
+	def, err = GetClassDef(reflect.TypeOf(registeredDTO{}))
+	assert.Nil(t, err)
+	assert.Equal(t, RegisteredPOJOs()["test.model.Registered"], def)
+
+	_, err = GetClassDef(reflect.TypeOf(struct{ A int }{}))
+	assert.NotNil(t, err)
+}
+
+type testColor JavaEnum
+
+const (
+	testColorRed testColor = iota
+	testColorGreen
+)
+
+var testColorNames = map[testColor]string{
+	testColorRed:   "RED",
+	testColorGreen: "GREEN",
+}
+
+func (testColor) JavaClassName() string {
+	return "test.model.Color"
+}
+
+func (c testColor) String() string {
+	return testColorNames[c]
+}
+
+func (testColor) EnumValue(s string) JavaEnum {
+	for k, v := range testColorNames {
+		if v == s {
+			return JavaEnum(k)
+		}
+	}
+	return InvalidJavaEnum
+}
+
+type colorBox struct {
+	Color testColor
+	Label string
+}
+
+func (colorBox) JavaClassName() string {
+	return "test.model.ColorBox"
+}
+
+func TestEncodeDecodeTypedJavaEnum(t *testing.T) {
+	e := NewEncoder()
+	if err := e.Encode(testColorGreen); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Encode(&colorBox{Color: testColorGreen, Label: "box"}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != testColorGreen {
+		t.Errorf("expect: %#v, but get: %#v", testColorGreen, res)
+	}
+	var color testColor
+	if err = ReflectResponse(res, &color); err != nil || color != testColorGreen {
+		t.Errorf("ReflectResponse = %v, %v", color, err)
+	}
+	var name string
+	if err = ReflectResponse(res, &name); err != nil || name != "GREEN" {
+		t.Errorf("ReflectResponse = %v, %v", name, err)
+	}
+
+	res, err = d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&colorBox{Color: testColorGreen, Label: "box"}, res) {
+		t.Errorf("expect: %+v, but get: %+v", colorBox{Color: testColorGreen, Label: "box"}, res)
+	}
+}
+
+func TestDecodeUnknownJavaEnumName(t *testing.T) {
+	RegisterJavaEnum(testColorRed)
+	_, clsDef, err := getStructDefByIndex(checkPOJORegistryIndex(t, "hessian.testColor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := append([]byte{}, clsDef.buffer...)
+	b = encByte(b, BC_OBJECT_DIRECT)
+	b = encString(b, "PURPLE")
+	b = encByte(b, BC_OBJECT_DIRECT)
+	b = encString(b, "RED")
+
+	d := NewDecoder(b)
+	res, err := d.Decode()
+	if perrors.Cause(err) != ErrUnknownJavaEnum {
+		t.Errorf("expect ErrUnknownJavaEnum, but get: %v", err)
+	}
+	if res != "PURPLE" {
+		t.Errorf("expect the enum name, but get: %#v", res)
+	}
+
+	// the decoder can go on after an unknown enum name
+	res, err = d.Decode()
+	if err != nil || res != testColorRed {
+		t.Errorf("Decode() = %#v, %v", res, err)
+	}
+
+	// an unknown name in a list or a map is kept without failing the list or the map
+	b = append([]byte{BC_LIST_DIRECT_UNTYPED + 2}, clsDef.buffer...)
+	b = encString(encByte(b, BC_OBJECT_DIRECT), "RED")
+	b = encString(encByte(b, BC_OBJECT_DIRECT), "PINK")
+	res, err = NewDecoder(b).Decode()
+	if err != nil || !reflect.DeepEqual([]interface{}{testColorRed, "PINK"}, res) {
+		t.Errorf("Decode() = %#v, %v", res, err)
+	}
+
+	b = append(encString([]byte{BC_MAP_UNTYPED}, "a"), clsDef.buffer...)
+	b = encByte(encString(encByte(b, BC_OBJECT_DIRECT), "PINK"), BC_END)
+	res, err = NewDecoder(b).Decode()
+	if err != nil || !reflect.DeepEqual(map[interface{}]interface{}{"a": "PINK"}, res) {
+		t.Errorf("Decode() = %#v, %v", res, err)
+	}
+}
+
+func checkPOJORegistryIndex(t *testing.T, goName string) int {
+	idx, ok := checkPOJORegistry(goName)
+	if !ok {
+		t.Fatalf("%s has not been registered", goName)
+	}
+	return idx
+}
+
+type auditBase struct {
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+type AuditedEntity struct {
+	auditBase
+	ID   int64 `hessian:"id"`
+	Name string
+}
+
+type auditedAccount struct {
+	*AuditedEntity
+	Name  string
+	Owner string
+}
+
+func (auditedAccount) JavaClassName() string {
+	return "test.AuditedAccount"
+}
+
+func TestEmbeddedStructFields(t *testing.T) {
+	RegisterPOJO(&auditedAccount{})
+	idx := checkPOJORegistryIndex(t, "hessian.auditedAccount")
+	_, cls, err := getStructDefByIndex(idx)
+	assert.Nil(t, err)
+	// AuditedEntity.Name is shadowed by auditedAccount.Name
+	assert.Equal(t, []string{"createdAt", "updatedAt", "id", "name", "owner"}, cls.fieldNameList)
+
+	account := &auditedAccount{
+		AuditedEntity: &AuditedEntity{auditBase: auditBase{CreatedAt: 1, UpdatedAt: 2}, ID: 3},
+		Name:          "name",
+		Owner:         "owner",
+	}
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(account))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, account, res)
+
+	// a promoted field encoded from a nil anonymous pointer is the zero value
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&auditedAccount{Name: "name"}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &auditedAccount{AuditedEntity: &AuditedEntity{}, Name: "name"}, res)
+
+	// the java class fields are bound into the anonymous structs
+	buf := encTestClassInstance(nil, 0, "test.AuditedAccount", []string{"id", "createdAt", "name"}, int64(7), int64(8), "outer")
+	res, err = NewDecoder(buf).Decode()
+	assert.Nil(t, err)
+	var out auditedAccount
+	assert.Nil(t, ReflectResponse(res, &out))
+	assert.Equal(t, int64(7), out.ID)
+	assert.Equal(t, int64(8), out.CreatedAt)
+	assert.Equal(t, "outer", out.Name)
+	assert.Equal(t, "", out.AuditedEntity.Name)
+}
+
+type renamedUser struct {
+	Name string
+	Age  int32
+}
+
+func TestRegisterPOJOWithAliases(t *testing.T) {
+	_, err := RegisterPOJOWithAliases(renamedUser{}, "com.company.user.User", "com.company.dto.User", "com.legacy.User")
+	assert.Nil(t, err)
+
+	want := &renamedUser{Name: "tom", Age: 18}
+	b := encTestClassInstance(nil, 0, "com.company.dto.User", []string{"name", "age"}, "tom", int32(18))
+	b = encTestClassInstance(b, 1, "com.legacy.User", []string{"age", "name"}, int32(18), "tom")
+	b = encTestClassInstance(b, 2, "com.company.user.User", []string{"name"}, "tom")
+	d := NewDecoder(b)
+	for _, v := range []*renamedUser{want, want, {Name: "tom"}} {
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, v, res)
+	}
+
+	// the primary name is used to encode
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(want))
+	assert.Contains(t, string(e.Buffer()), "com.company.user.User")
+	assert.NotContains(t, string(e.Buffer()), "com.company.dto.User")
+
+	_, err = RegisterPOJOWithAliases(&Case{}, "com.test.Case")
+	assert.NotNil(t, err)
+	_, err = RegisterPOJOWithAliases(legacyOrder{}, "com.legacy.Order", "com.legacy.User")
+	assert.NotNil(t, err)
+}
+
+type taggedOrder struct {
+	_      struct{} `hessian:"class=com.tagged.Order"`
+	ID     int32
+	Amount float64 `hessian:"total"`
+	Items  []*taggedItem
+}
+
+type taggedItem struct {
+	_    struct{} `hessian:"class=com.tagged.Item"`
+	Name string
+}
+
+type untaggedDTO struct {
+	Name string `hessian:"class=com.untagged.DTO"`
+}
+
+func TestRegisterTaggedPOJOs(t *testing.T) {
+	_, err := RegisterTaggedPOJOs(taggedOrder{}, &untaggedDTO{}, 7, &taggedItem{})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "hessian.untaggedDTO, int")
+	assert.NotContains(t, err.Error(), "taggedItem")
+	// nothing is registered
+	_, ok := getJavaName("hessian.taggedOrder")
+	assert.False(t, ok)
+
+	idx, err := RegisterTaggedPOJOs(taggedOrder{}, &taggedItem{})
+	assert.Nil(t, err)
+	assert.Len(t, idx, 2)
+	assert.NotEqual(t, -1, idx[0])
+	assert.NotEqual(t, -1, idx[1])
+	idx, err = RegisterTaggedPOJOs(&taggedItem{})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{-1}, idx)
+
+	order := &taggedOrder{ID: 7, Amount: 12.5, Items: []*taggedItem{{Name: "book"}, {Name: "pen"}}}
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(order))
+	assert.Contains(t, string(e.Buffer()), "com.tagged.Order")
+	assert.Contains(t, string(e.Buffer()), "com.tagged.Item")
+
+	b := encTestClassInstance(nil, 0, "com.tagged.Item", []string{"name"}, "pen")
+	b = encTestClassInstance(b, 1, "com.tagged.Order", []string{"iD", "total"}, int32(8), 3.5)
+	d := NewDecoder(b)
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &taggedItem{Name: "pen"}, res)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &taggedOrder{ID: 8, Amount: 3.5}, res)
+
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, order, res)
+}
+
+type pooledDTO struct {
+	Name  string
+	Count int32
+	Tags  []string
+}
+
+func (pooledDTO) JavaClassName() string {
+	return "test.PooledDTO"
+}
+
+func TestRegisterPOJOFactory(t *testing.T) {
+	RegisterPOJO(&pooledDTO{})
+	assert.NotNil(t, RegisterPOJOFactory("test.UnknownPooledDTO", func() interface{} { return &pooledDTO{} }))
+
+	// a recycled instance with stale fields
+	recycled := &pooledDTO{Name: "stale", Count: 9, Tags: []string{"stale"}}
+	made := 0
+	assert.Nil(t, RegisterPOJOFactory("test.PooledDTO", func() interface{} {
+		made++
+		return recycled
+	}))
+	defer RegisterPOJOFactory("test.PooledDTO", nil)
+
+	b := encTestClassInstance(nil, 0, "test.PooledDTO", []string{"name", "tags"}, "fresh", nil)
+	res, err := NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.True(t, res == interface{}(recycled))
+	assert.Equal(t, &pooledDTO{Name: "fresh"}, res)
+	assert.Equal(t, 1, made)
+
+	recycled.Count = 3
+	b = encTestClassInstance(nil, 0, "test.PooledDTO", []string{"count"}, int32(5))
+	res, err = NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &pooledDTO{Count: 5}, res)
+
+	assert.Nil(t, RegisterPOJOFactory("test.PooledDTO", func() interface{} { return pooledDTO{} }))
+	_, err = NewDecoder(b).Decode()
+	assert.NotNil(t, err)
+	assert.Nil(t, RegisterPOJOFactory("test.PooledDTO", func() interface{} { return (*pooledDTO)(nil) }))
+	_, err = NewDecoder(b).Decode()
+	assert.NotNil(t, err)
+
+	// the default of reflect.New is restored
+	assert.Nil(t, RegisterPOJOFactory("test.PooledDTO", nil))
+	res, err = NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.False(t, res == interface{}(recycled))
+	assert.Equal(t, &pooledDTO{Count: 5}, res)
+}
+
+type markerDTO struct{}
+
+func (markerDTO) JavaClassName() string {
+	return "test.MarkerDTO"
+}
+
+type markerHolder struct {
+	Name   string
+	Marker *markerDTO
+	Value  markerDTO
+	Age    int32
+}
+
+func (markerHolder) JavaClassName() string {
+	return "test.MarkerHolder"
+}
+
+func TestZeroFieldClass(t *testing.T) {
+	RegisterPOJO(&markerDTO{})
+	RegisterPOJO(&markerHolder{})
+
+	// the class definition declares no field, and the instance has no value
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&markerDTO{}))
+	want := encInt32(encString([]byte{BC_OBJECT_DEF}, "test.MarkerDTO"), 0)
+	want = append(want, BC_OBJECT_DIRECT)
+	assert.Equal(t, want, e.Buffer())
+	assert.Nil(t, e.Encode("after"))
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &markerDTO{}, res)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "after", res)
+
+	holder := &markerHolder{Name: "n", Marker: &markerDTO{}, Age: 7}
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(holder))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, holder, res)
+
+	// the instances of zero-field classes written by java, whose field unknown to go is skipped
+	b := encInt32(encString([]byte{BC_OBJECT_DEF}, "test.MarkerDTO"), 0)
+	b = encInt32(encString(append(b, BC_OBJECT_DEF), "test.MarkerHolder"), 5)
+	for _, name := range []string{"name", "marker", "unknown", "value", "age"} {
+		b = encString(b, name)
+	}
+	b = encString(append(b, BC_OBJECT_DIRECT+1), "n")
+	b = encInt32(append(b, BC_OBJECT_DIRECT, BC_OBJECT_DIRECT, BC_OBJECT_DIRECT), 7)
+	res, err = NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, holder, res)
+
+	// an empty object of an unknown class is an empty generic object
+	b = encInt32(encString([]byte{BC_OBJECT_DEF}, "test.Unknown"), 0)
+	b = append(b, BC_OBJECT_DIRECT)
+	b = encString(append(encInt32(encString(append(b, BC_OBJECT_DEF), "test.Holder"), 2), 1, 'u'), "age")
+	b = encInt32(append(b, BC_OBJECT_DIRECT+1, BC_REF, 0x90), 7)
+	d = NewDecoder(b)
+	d.SetGenericMode(true)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	empty := &GenericObject{ClassName: "test.Unknown", Fields: map[string]interface{}{}, fieldNames: []string{}}
+	assert.Equal(t, empty, res)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	generic := res.(*GenericObject)
+	assert.Equal(t, int32(7), generic.Fields["age"])
+	assert.Equal(t, empty, generic.Fields["u"])
+}
+
+type omitEmptyUser struct {
+	ID    int64    `hessian:"id"`
+	Name  string   `hessian:"name,omitempty"`
+	Age   int32    `hessian:",omitempty"`
+	Tags  []string `hessian:"tags,omitempty"`
+	Admin bool
+}
+
+func (omitEmptyUser) JavaClassName() string {
+	return "test.OmitEmptyUser"
+}
+
+func TestEncodeOmitEmpty(t *testing.T) {
+	RegisterPOJO(&omitEmptyUser{})
+	full := &omitEmptyUser{ID: 1, Name: "a", Age: 2, Tags: []string{"x"}, Admin: true}
+	partial := &omitEmptyUser{ID: 2, Age: 3}
+	empty := &omitEmptyUser{}
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(full))
+	assert.Nil(t, e.Encode(partial))
+	assert.Nil(t, e.Encode(&omitEmptyUser{ID: 3, Age: 4}))
+	assert.Nil(t, e.Encode(empty))
+
+	// a partial class definition for every distinct set of fields
+	assert.Equal(t, 3, len(e.classInfoList))
+	assert.Equal(t, []string{"id", "name", "age", "tags", "admin"}, e.classInfoList[0].fieldNameList)
+	assert.Equal(t, []string{"id", "age", "admin"}, e.classInfoList[1].fieldNameList)
+	assert.Equal(t, []string{"id", "admin"}, e.classInfoList[2].fieldNameList)
+
+	// the missing fields are left zero
+	d := NewDecoder(e.Buffer())
+	for _, want := range []*omitEmptyUser{full, partial, {ID: 3, Age: 4}, empty} {
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, want, res)
+	}
+
+	// only the partial class definition is written if no instance has all the fields
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(partial))
+	want := encTestClassInstance(nil, 0, "test.OmitEmptyUser", []string{"id", "age", "admin"}, int64(2), int32(3), false)
+	assert.Equal(t, want, e.Buffer())
+}
+
+type omitEmptyFlags struct {
+	F0 int32 `hessian:"f0,omitempty"`
+	F1 int32 `hessian:"f1,omitempty"`
+	F2 int32 `hessian:"f2,omitempty"`
+	F3 int32 `hessian:"f3,omitempty"`
+	F4 int32 `hessian:"f4,omitempty"`
+	F5 int32 `hessian:"f5,omitempty"`
+	F6 int32 `hessian:"f6,omitempty"`
+	F7 int32 `hessian:"f7,omitempty"`
+	F8 int32 `hessian:"f8,omitempty"`
+}
+
+func (omitEmptyFlags) JavaClassName() string {
+	return "test.OmitEmptyFlags"
+}
+
+func TestEncodeOmitEmptyManyDefinitions(t *testing.T) {
+	RegisterPOJO(&omitEmptyFlags{})
+
+	// every value has its own set of fields, so the class indexes go beyond a byte
+	var values []*omitEmptyFlags
+	for i := 0; i < 300; i++ {
+		v := &omitEmptyFlags{}
+		fields := reflect.ValueOf(v).Elem()
+		for j := 0; j < fields.NumField(); j++ {
+			if i&(1<<uint(j)) != 0 {
+				fields.Field(j).SetInt(int64(j + 1))
+			}
+		}
+		values = append(values, v)
+	}
+
+	e := NewEncoder()
+	for _, v := range values {
+		assert.Nil(t, e.Encode(v))
+	}
+	// the copies are not refs, but reuse the class definitions
+	for _, v := range values {
+		c := *v
+		assert.Nil(t, e.Encode(&c))
+	}
+	assert.Equal(t, 300, len(e.classInfoList))
+
+	d := NewDecoder(e.Buffer())
+	for i := 0; i < 2*len(values); i++ {
+		res, err := d.Decode()
+		assert.Nil(t, err)
+		assert.Equal(t, values[i%len(values)], res)
+	}
+}
+
+type transientUser struct {
+	ID      int64
+	Name    string
+	Session string `hessian:"-"` // transient in go
+	Note    string // not on the wire
+	secret  string
+}
+
+func (transientUser) JavaClassName() string {
+	return "test.model.TransientUser"
+}
+
+func TestTransientFields(t *testing.T) {
+	RegisterPOJO(&transientUser{})
+
+	// the wire has the fields cache and secret, which the go struct does not bind
+	e := NewEncoder()
+	encTestInstance(e, encTestClassDef(e, "test.model.TransientUser", "iD", "cache", "session", "secret", "name"))
+	dept := &Department{Name: "dev"}
+	for _, v := range []interface{}{int64(7), map[string]interface{}{"dept": dept}, "sid", "pwd", "Alice"} {
+		assert.Nil(t, e.Encode(v))
+	}
+	// a ref to an object inside a dropped field
+	assert.Nil(t, e.Encode([]interface{}{dept, dept}))
+
+	d := NewDecoder(e.Buffer())
+	user, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &transientUser{ID: 7, Name: "Alice"}, user)
+	depts, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{dept, dept}, depts)
+
+	// the fields tagged "-" are not encoded
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&transientUser{ID: 8, Name: "Bob", Session: "sid", Note: "n"}))
+	d = NewDecoder(e.Buffer())
+	user, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &transientUser{ID: 8, Name: "Bob", Note: "n"}, user)
+	assert.Equal(t, []string{"iD", "name", "note"}, d.classInfoList[0].fieldNameList)
+}
+
+type kotlinUser struct {
+	Name   string
+	Nick   *string
+	Age    *int32
+	Score  int64
+	Leader *Department
+	Tags   []string
+	Labels map[string]string
+	Active *bool
+}
+
+func (kotlinUser) JavaClassName() string {
+	return "test.kotlin.User"
+}
+
+func TestKotlinDataClassDefaults(t *testing.T) {
+	RegisterPOJO(&kotlinUser{})
+
+	// the kotlin class declares the fields in another order, leaves out the fields age and score
+	// which have kotlin defaults, and has the nullable fields which are null
+	e := NewEncoder()
+	idx := encTestClassDef(e, "test.kotlin.User", "leader", "nick", "name", "tags", "labels", "active")
+	encTestInstance(e, idx)
+	for _, v := range []interface{}{nil, nil, "Alice", nil, nil, nil} {
+		assert.Nil(t, e.Encode(v))
+	}
+	// the same class with the nullable fields set
+	encTestInstance(e, idx)
+	for _, v := range []interface{}{&Department{Name: "dev"}, "al", "Bob", []string{"x"}, map[string]string{"k": "v"}, true} {
+		assert.Nil(t, e.Encode(v))
+	}
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &kotlinUser{Name: "Alice"}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	nick, active := "al", true
+	assert.Equal(t, &kotlinUser{
+		Name: "Bob", Nick: &nick, Leader: &Department{Name: "dev"},
+		Tags: []string{"x"}, Labels: map[string]string{"k": "v"}, Active: &active,
+	}, res)
+}
+
+type boxedNumbers struct {
+	Total int64
+	Count int32
+	Level int8
+	Port  uint16
+}
+
+func (boxedNumbers) JavaClassName() string {
+	return "test.model.BoxedNumbers"
+}
+
+func TestDecodeBoxedNumberFields(t *testing.T) {
+	RegisterPOJO(&boxedNumbers{})
+
+	decode := func(fields map[string]interface{}) (interface{}, error) {
+		e := NewEncoder()
+		if err := e.Encode(&GenericObject{ClassName: "test.model.BoxedNumbers", Fields: fields}); err != nil {
+			return nil, err
+		}
+		return NewDecoder(e.Buffer()).Decode()
+	}
+
+	// an Integer into the long field, and the small Longs into the narrower fields
+	res, err := decode(map[string]interface{}{"total": int32(7), "count": int64(8), "level": int64(-9), "port": int64(8080)})
+	assert.Nil(t, err)
+	assert.Equal(t, &boxedNumbers{Total: 7, Count: 8, Level: -9, Port: 8080}, res)
+
+	for _, fields := range []map[string]interface{}{
+		{"count": int64(math.MaxInt32 + 1)},
+		{"level": int32(128)},
+		{"port": int64(-1)},
+		{"port": int32(math.MaxUint16 + 1)},
+	} {
+		_, err = decode(fields)
+		assert.Equal(t, ErrIntOverflow, perrors.Cause(err), fields)
+	}
+}
+
+type pointerBar struct {
+	Name string
+}
+
+func (pointerBar) JavaClassName() string {
+	return "test.model.PointerBar"
+}
+
+type pointerFoo struct {
+	Bar      *pointerBar
+	BarPtr   **pointerBar
+	Bars     []*pointerBar
+	BarMap   map[string]*pointerBar
+	BarsPtr  *[]*pointerBar
+	BarMapP  *map[string]*pointerBar
+	BarPtrs  map[string]**pointerBar
+	NamePtr  **string
+	Reserved *[]string
+}
+
+func (pointerFoo) JavaClassName() string {
+	return "test.model.PointerFoo"
+}
+
+func TestEncodePointers(t *testing.T) {
+	RegisterPOJOs(&pointerBar{}, &pointerFoo{})
+
+	bar := &pointerBar{Name: "bar"}
+	var nilBar *pointerBar
+	name := "name"
+	namePtr := &name
+	bars := []*pointerBar{bar, nil, {Name: "other"}, nil}
+	barMap := map[string]*pointerBar{"bar": bar, "nil": nil}
+	foo := &pointerFoo{
+		Bar:     bar,
+		BarPtr:  &bar,
+		Bars:    bars,
+		BarMap:  barMap,
+		BarsPtr: &bars,
+		BarMapP: &barMap,
+		BarPtrs: map[string]**pointerBar{"bar": &bar, "nil": nil, "nilBar": &nilBar},
+		NamePtr: &namePtr,
+	}
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(foo))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	got := res.(*pointerFoo)
+	assert.Equal(t, bar, got.Bar)
+	assert.Equal(t, bar, *got.BarPtr)
+	assert.Equal(t, bars, got.Bars)
+	assert.Equal(t, barMap, got.BarMap)
+	assert.Equal(t, bars, *got.BarsPtr)
+	assert.Equal(t, barMap, *got.BarMapP)
+	assert.Equal(t, bar, *got.BarPtrs["bar"])
+	assert.Nil(t, got.BarPtrs["nil"])
+	assert.Nil(t, got.BarPtrs["nilBar"])
+	assert.Equal(t, "name", **got.NamePtr)
+	assert.Nil(t, got.Reserved)
+	// the pointers to the same object are refs
+	assert.True(t, got.Bar == got.Bars[0] && got.Bar == got.BarMap["bar"])
+
+	// the nil pointers at any depth
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&pointerFoo{BarPtr: &nilBar}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	// a nil slice is an empty list, and a nil map is null
+	assert.Equal(t, &pointerFoo{Bars: []*pointerBar{}}, res)
+
+	barPtr := &bar
+	for _, v := range []interface{}{&barPtr, &nilBar, []**pointerBar{&bar, nil, &nilBar}, map[string]**pointerBar{"bar": &bar}} {
+		e = NewEncoder()
+		assert.Nil(t, e.Encode(v))
+		_, err = NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+	}
+	e = NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{&barPtr, &nilBar, nil}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{bar, nil, nil}, res)
+}
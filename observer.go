@@ -0,0 +1,152 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"io"
+	"reflect"
+	"sync/atomic"
+)
+
+// Observer is notified at the start and the end of every top level Encode and Decode, such as
+// to emit the metrics and the spans of tracing. The values nested in a top level value, such as
+// the fields of an object, are not notified. The methods are called synchronously by the encoders
+// and the decoders of all the goroutines, so they should be fast and safe for concurrent use.
+type Observer interface {
+	OnEncode(event ObserverEvent)
+	OnDecode(event ObserverEvent)
+}
+
+// ObserverEvent is passed to an Observer at the start and the end of a top level Encode or Decode.
+type ObserverEvent struct {
+	Done bool // false at the start of the call, true at its end
+	// the go type of the value encoded, or decoded which is only known at the end, nil for a null
+	Type reflect.Type
+	// the java class of the object, typed list or typed map, such as "com.foo.User" or "[com.foo.User",
+	// which is resolved like the encoder and the decoder do, and empty for the other values.
+	// The class of a decoded value is only known at the end.
+	JavaClassName string
+	Bytes         int   // the bytes written or read by the call, only at the end
+	Err           error // the error of the call, only at the end
+}
+
+// observerHolder makes the observer storable in an atomic.Value, which can not hold nil.
+type observerHolder struct {
+	Observer
+}
+
+var observer atomic.Value
+
+// SetObserver sets the Observer of all the encoders and decoders, and nil removes it.
+// The encoders and decoders cost nothing more than checking it if it is not set.
+func SetObserver(o Observer) {
+	observer.Store(observerHolder{o})
+}
+
+// getObserver gets the Observer set by SetObserver, which is nil if none.
+func getObserver() Observer {
+	h, _ := observer.Load().(observerHolder)
+	return h.Observer
+}
+
+// observeEncode encodes @v like Encode and notifies @o.
+func (e *Encoder) observeEncode(o Observer, v interface{}) error {
+	e.observing = true
+	defer func() { e.observing = false }()
+
+	event := ObserverEvent{Type: reflect.TypeOf(v), JavaClassName: e.encodedJavaClassName(v)}
+	o.OnEncode(event)
+	start := e.flushed + len(e.buffer)
+	err := e.Encode(v)
+	event.Done, event.Bytes, event.Err = true, e.flushed+len(e.buffer)-start, err
+	o.OnEncode(event)
+	return err
+}
+
+// encodedJavaClassName gets the java class which @v is encoded as, which is empty if it is neither
+// an object nor a typed list or map.
+func (e *Encoder) encodedJavaClassName(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case POJO:
+		return val.JavaClassName()
+	case *GenericObject:
+		if val != nil {
+			return val.ClassName
+		}
+		return ""
+	case *OrderedMap:
+		if val != nil && val.JavaType != "" {
+			return val.JavaType
+		}
+		return ""
+	}
+
+	t := UnpackPtrType(reflect.TypeOf(v))
+	switch t.Kind() {
+	case reflect.Struct:
+		if e.classNameResolver != nil {
+			if cls, resolved, err := e.classNameResolver.classInfo(t); err == nil && resolved {
+				return cls.javaName
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if elem := t.Elem(); elem.Kind() != reflect.Uint8 && elem.Kind() != reflect.Interface {
+			return listTypeName(elem)
+		}
+	}
+	return ""
+}
+
+// observeDecodeValue decodes a value like DecodeValue and notifies @o.
+func (d *Decoder) observeDecodeValue(o Observer) (interface{}, error) {
+	d.observing = true
+	d.observedClass = ""
+	defer func() { d.observing = false }()
+
+	o.OnDecode(ObserverEvent{})
+	start := d.Offset()
+	v, err := d.DecodeValue()
+	value, _ := EnsureInterface(v, nil)
+	event := ObserverEvent{
+		Done:          true,
+		Type:          reflect.TypeOf(value),
+		JavaClassName: d.observedClass,
+		Bytes:         d.Offset() - start,
+		Err:           err,
+	}
+	o.OnDecode(event)
+	return v, err
+}
+
+// observeClass records the java class @name of the top level value being observed.
+func (d *Decoder) observeClass(name string) {
+	if d.observing && d.depth == 0 && d.observedClass == "" {
+		d.observedClass = name
+	}
+}
+
+// readCounter counts the bytes read from the source of a decoder.
+type readCounter struct {
+	r io.Reader
+	n int64
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,89 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingObserver struct {
+	encodes []ObserverEvent
+	decodes []ObserverEvent
+}
+
+func (o *recordingObserver) OnEncode(event ObserverEvent) {
+	o.encodes = append(o.encodes, event)
+}
+
+func (o *recordingObserver) OnDecode(event ObserverEvent) {
+	o.decodes = append(o.decodes, event)
+}
+
+func TestObserver(t *testing.T) {
+	RegisterPOJO(&Case{})
+	o := &recordingObserver{}
+	SetObserver(o)
+	defer SetObserver(nil)
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&Case{A: "a", B: 1}))
+	objectBytes := len(e.Buffer())
+	assert.Nil(t, e.Encode([]*Case{{A: "b"}}))
+	assert.Nil(t, e.Encode(int32(1)))
+
+	// only the top level values are notified
+	caseType, listType := reflect.TypeOf(&Case{}), reflect.TypeOf([]*Case{})
+	assert.Equal(t, []ObserverEvent{
+		{Type: caseType, JavaClassName: "com.test.case"},
+		{Done: true, Type: caseType, JavaClassName: "com.test.case", Bytes: objectBytes},
+		{Type: listType, JavaClassName: "[com.test.case"},
+		{Done: true, Type: listType, JavaClassName: "[com.test.case", Bytes: o.encodes[3].Bytes},
+		{Type: reflect.TypeOf(int32(0))},
+		{Done: true, Type: reflect.TypeOf(int32(0)), Bytes: 1},
+	}, o.encodes)
+	assert.Equal(t, len(e.Buffer()), objectBytes+o.encodes[3].Bytes+1)
+
+	d := NewDecoder(e.Buffer())
+	for i := 0; i < 3; i++ {
+		_, err := d.Decode()
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []ObserverEvent{
+		{},
+		{Done: true, Type: caseType, JavaClassName: "com.test.case", Bytes: objectBytes},
+		{},
+		{Done: true, Type: listType, JavaClassName: "[com.test.case", Bytes: o.encodes[3].Bytes},
+		{},
+		{Done: true, Type: reflect.TypeOf(int32(0)), Bytes: 1},
+	}, o.decodes)
+
+	// the error of a truncated value
+	o.decodes = nil
+	_, err := NewDecoder(e.Buffer()[:objectBytes-1]).Decode()
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(o.decodes))
+	assert.Equal(t, err, o.decodes[1].Err)
+
+	// nothing is notified after the observer is removed
+	SetObserver(nil)
+	o.encodes = nil
+	assert.Nil(t, NewEncoder().Encode(int32(1)))
+	assert.Nil(t, o.encodes)
+}
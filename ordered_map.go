@@ -0,0 +1,181 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// java map classes which keep the order of their entries
+var orderedMapJavaTypes = map[string]bool{
+	"java.util.TreeMap":                          true,
+	"java.util.LinkedHashMap":                    true,
+	"java.util.concurrent.ConcurrentSkipListMap": true,
+}
+
+var orderedMapType = reflect.TypeOf(OrderedMap{})
+
+// OrderedMap is a map keeping the order of its entries, which is decoded from
+// java.util.TreeMap and java.util.LinkedHashMap. It is encoded as a typed map of its JavaType,
+// or java.util.LinkedHashMap if JavaType is empty.
+type OrderedMap struct {
+	JavaType string
+	keys     []interface{}
+	values   []interface{}
+	index    map[interface{}]int // key --> index of keys and values
+}
+
+// NewOrderedMap creates an empty OrderedMap of java class @javaType.
+func NewOrderedMap(javaType string) *OrderedMap {
+	return &OrderedMap{JavaType: javaType, index: make(map[interface{}]int)}
+}
+
+// Put sets the value of @key, which must be comparable like a go map key. A new key is appended
+// after all the existing keys.
+func (m *OrderedMap) Put(key, value interface{}) {
+	if m.index == nil {
+		m.index = make(map[interface{}]int)
+	}
+	if i, ok := m.index[key]; ok {
+		m.values[i] = value
+		return
+	}
+	m.index[key] = len(m.keys)
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+// Get gets the value of @key.
+func (m *OrderedMap) Get(key interface{}) (interface{}, bool) {
+	i, ok := m.index[key]
+	if !ok {
+		return nil, false
+	}
+	return m.values[i], true
+}
+
+// Len returns the entry number.
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns all the keys in order.
+func (m *OrderedMap) Keys() []interface{} {
+	return m.keys
+}
+
+// Range calls @f for every entry in order until @f returns false.
+func (m *OrderedMap) Range(f func(key, value interface{}) bool) {
+	for i := range m.keys {
+		if !f(m.keys[i], m.values[i]) {
+			return
+		}
+	}
+}
+
+// ToMap copies all the entries into a go map, which loses the order.
+func (m *OrderedMap) ToMap() map[interface{}]interface{} {
+	gm := make(map[interface{}]interface{}, len(m.keys))
+	for i := range m.keys {
+		gm[m.keys[i]] = m.values[i]
+	}
+	return gm
+}
+
+// ::= 'M' type (value value)* 'Z'  # key, value map pairs
+func (e *Encoder) encOrderedMap(m *OrderedMap) error {
+	if m == nil {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
+
+	// check ref
+	if n, ok := e.checkRefMap(reflect.ValueOf(m)); ok {
+		e.buffer = encRef(e.buffer, n)
+		return nil
+	}
+
+	javaType := m.JavaType
+	if javaType == "" {
+		javaType = "java.util.LinkedHashMap"
+	} else if impl, ok := javaCollectionWrappers[javaType]; ok {
+		javaType = impl
+	}
+
+	var err error
+	e.buffer = encByte(e.buffer, BC_MAP)
+	e.encType(javaType)
+	for i := range m.keys {
+		if err = e.Encode(m.keys[i]); err != nil {
+			return perrors.Wrapf(err, "failed to encode map key(idx:%d, key:%+v)", i, m.keys[i])
+		}
+		if err = e.Encode(m.values[i]); err != nil {
+			return perrors.Wrapf(err, "failed to encode map value(idx:%d, key:%+v, value:%+v)", i, m.keys[i], m.values[i])
+		}
+	}
+	e.buffer = encByte(e.buffer, BC_END) // 'Z'
+
+	return nil
+}
+
+// decOrderedMap reads the entries of a map of java class @javaType, whose type has been read.
+// If any key can not be a go map key, all the entries are returned as []MapEntry in order like
+// readMapEntries does, which takes the place of the OrderedMap in the refs.
+func (d *Decoder) decOrderedMap(javaType string) (interface{}, error) {
+	var entries []MapEntry
+
+	m := NewOrderedMap(javaType)
+	refIndex := len(d.refs)
+	d.appendRefs(m)
+	for d.peekByte() != BC_END {
+		if err := d.addElements(1); err != nil {
+			return nil, err
+		}
+		k, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		if entries == nil && !hashable(k) {
+			entries = make([]MapEntry, 0, m.Len()+1)
+			m.Range(func(key, value interface{}) bool {
+				entries = append(entries, MapEntry{Key: key, Value: value})
+				return true
+			})
+		}
+		if entries != nil {
+			entries = append(entries, MapEntry{Key: k, Value: v})
+			continue
+		}
+		m.Put(k, v)
+	}
+	if _, err := d.readByte(); err != nil {
+		return nil, perrors.WithStack(err)
+	}
+
+	if entries != nil {
+		d.refs[refIndex] = entries
+		return entries, nil
+	}
+	return m, nil
+}
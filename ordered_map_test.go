@@ -0,0 +1,104 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type orderedMapHolder struct {
+	Config *OrderedMap
+}
+
+func (orderedMapHolder) JavaClassName() string {
+	return "test.OrderedMapHolder"
+}
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap("java.util.TreeMap")
+	for _, k := range []string{"c", "a", "b"} {
+		m.Put(k, "v-"+k)
+	}
+	m.Put("a", "v-a2")
+	assert.Equal(t, 3, m.Len())
+	assert.Equal(t, []interface{}{"c", "a", "b"}, m.Keys())
+	v, ok := m.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "v-a2", v)
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(m))
+	assert.Nil(t, e.Encode([]interface{}{m, m}))
+	assert.Nil(t, e.Encode(&orderedMapHolder{Config: m}))
+	// a java.util.HashMap is still decoded into a go map
+	e.Append([]byte{BC_MAP})
+	e.Append(encString(nil, "java.util.HashMap"))
+	assert.Nil(t, e.Encode("k"))
+	assert.Nil(t, e.Encode("v"))
+	e.Append([]byte{BC_END})
+	// the second java.util.TreeMap refers to its type name
+	e.Append([]byte{BC_MAP})
+	e.Append(encInt32(nil, 0))
+	assert.Nil(t, e.Encode("x"))
+	assert.Nil(t, e.Encode(int32(1)))
+	e.Append([]byte{BC_END})
+
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, m, res)
+
+	var out map[string]string
+	assert.Nil(t, ReflectResponse(res, &out))
+	assert.Equal(t, map[string]string{"a": "v-a2", "b": "v-b", "c": "v-c"}, out)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	list := res.([]interface{})
+	assert.Equal(t, m, list[0])
+	assert.True(t, list[0] == list[1])
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &orderedMapHolder{Config: m}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{"k": "v"}, res)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	expected := NewOrderedMap("java.util.TreeMap")
+	expected.Put("x", int32(1))
+	assert.Equal(t, expected, res)
+
+	// a key which can not be a go map key turns the entries into []MapEntry in order, like java.util.HashMap
+	e = NewEncoder()
+	e.Append(encString([]byte{BC_MAP}, "java.util.LinkedHashMap"))
+	assert.Nil(t, e.Encode("a"))
+	assert.Nil(t, e.Encode(int32(1)))
+	assert.Nil(t, e.Encode([]interface{}{"b"}))
+	assert.Nil(t, e.Encode(int32(2)))
+	e.Append([]byte{BC_END})
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	entries, ok := res.([]MapEntry)
+	assert.True(t, ok, "%T", res)
+	assert.Equal(t, []MapEntry{{Key: "a", Value: int32(1)}, {Key: []interface{}{"b"}, Value: int32(2)}}, entries)
+}
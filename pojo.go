@@ -17,6 +17,7 @@ package hessian
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"unicode"
@@ -47,6 +48,59 @@ var tagIdentifier = "hessian"
 // hessian.NewEncoder().Encode(user)
 func SetTagIdentifier(s string) { tagIdentifier = s }
 
+// tag name of a go field which is not a field of the java class, like a java transient field,
+// such as `hessian:"-"`. The field is neither encoded nor bound to a decoded field.
+const tagNameSkip = "-"
+
+// tag option of a field which is a java.util.Optional, like `hessian:"name,optional"`
+const tagOptionOptional = "optional"
+
+// tag option of a string field which is a java byte[] of the UTF-8 bytes of the string,
+// like `hessian:"payload,bytes"`, for the java fields which carry texts in byte[].
+const tagOptionBytes = "bytes"
+
+// tag option of a field which is left out of the encoded object if it is the zero value,
+// like `hessian:"name,omitempty"`.
+// A class definition of hessian fixes the fields of all its instances, so an instance omitting
+// some fields is written with another class definition of the same java class, which only lists
+// the fields present. Every distinct set of present fields costs one more class definition in
+// an encoder. The decoders of both java and go bind the fields by names, and the missing fields
+// are left as the default values, such as the zero values of go.
+const tagOptionOmitEmpty = "omitempty"
+
+// tag name prefix of a blank field naming the java class of its go struct,
+// like `hessian:"class=com.company.Order"`, which is read by RegisterTaggedPOJOs.
+const tagClassPrefix = "class="
+
+// lookupTag gets the field name and the options of the hessian tag of @field,
+// such as `hessian:"name,optional"`. The name is empty if the tag only has options.
+func lookupTag(field reflect.StructField) (string, []string, bool) {
+	val, has := field.Tag.Lookup(tagIdentifier)
+	if !has {
+		return "", nil, false
+	}
+
+	parts := strings.Split(val, ",")
+	return parts[0], parts[1:], true
+}
+
+// hasTagOption checks whether the hessian tag of @field has option @opt.
+func hasTagOption(field reflect.StructField, opt string) bool {
+	_, opts, _ := lookupTag(field)
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// skippedField checks whether @field is tagged as tagNameSkip.
+func skippedField(field reflect.StructField) bool {
+	name, opts, _ := lookupTag(field)
+	return name == tagNameSkip && len(opts) == 0
+}
+
 // POJO interface
 // !!! Pls attention that Every field name should be upper case.
 // Otherwise the app may panic.
@@ -72,7 +126,10 @@ type JavaEnumClass struct {
 type classInfo struct {
 	javaName      string
 	fieldNameList []string
-	buffer        []byte // encoded buffer
+	fieldIndexes  [][]int // go struct field index sequence of every field in fieldNameList
+	buffer        []byte  // encoded buffer
+	omitEmpty     []int   // the positions of the fields in fieldNameList with tagOptionOmitEmpty
+	partial       bool    // the class definition omits some fields of the java class
 }
 
 type structInfo struct {
@@ -112,21 +169,66 @@ func showPOJORegistry() {
 
 // RegisterPOJO Register a POJO instance. The return value is -1 if @o has been registered.
 func RegisterPOJO(o POJO) int {
-	// # definition for an object (compact map)
-	// class-def  ::= 'C' string int string*
 	pojoRegistry.Lock()
 	defer pojoRegistry.Unlock()
 
-	if _, ok := pojoRegistry.registry[o.JavaClassName()]; ok {
-		return -1
+	idx, _ := registerPOJO(o.JavaClassName(), o, nil)
+	return idx
+}
+
+// RegisterPOJOMapping Register a go struct instance @prototype as java class @javaName,
+// whose class definition lists the fields exactly in the order of @fields instead of
+// the declaration order of the go struct. Every name in @fields is bound to an exported
+// go field the same way as decoding does: hessian tag first, then lowerCamelCase, SameCase, lowerCase.
+// The declaration order is used if @fields is nil. @prototype does not need to implement POJO.
+// The return value is -1 if @prototype has been registered.
+func RegisterPOJOMapping(javaName string, prototype interface{}, fields []string) (int, error) {
+	if javaName == "" {
+		return -1, perrors.New("java class name should not be empty")
+	}
+	pojoRegistry.Lock()
+	defer pojoRegistry.Unlock()
+
+	return registerPOJO(javaName, prototype, fields)
+}
+
+// RegisterPOJOWithAliases Register a go struct instance @prototype as java class @names[0], and
+// the other names are aliases of it, such as the old names of a renamed java class. The decoder
+// resolves all the names into the go struct, while the encoder uses the primary name @names[0].
+// If @prototype is a POJO, its JavaClassName should be the primary name.
+// The return value is -1 if @prototype has been registered, and the aliases are still bound to it.
+func RegisterPOJOWithAliases(prototype interface{}, names ...string) (int, error) {
+	if len(names) == 0 || names[0] == "" {
+		return -1, perrors.New("java class name should not be empty")
+	}
+	if p, ok := prototype.(POJO); ok && p.JavaClassName() != names[0] {
+		return -1, perrors.Errorf("the primary name %s is not the java class name %s of the POJO", names[0], p.JavaClassName())
 	}
+	pojoRegistry.Lock()
+	defer pojoRegistry.Unlock()
 
+	idx, err := registerPOJO(names[0], prototype, nil)
+	if err != nil {
+		return -1, err
+	}
+
+	goName := UnpackPtrType(reflect.TypeOf(prototype)).String()
+	for _, alias := range names[1:] {
+		if g, ok := pojoRegistry.j2g[alias]; ok && g != goName {
+			return idx, perrors.Errorf("java class %s has been registered as %s", alias, g)
+		}
+		pojoRegistry.j2g[alias] = goName
+	}
+
+	return idx, nil
+}
+
+// registerPOJO registers @o as java class @javaName. The field order of the class
+// definition follows the go struct declaration if @fields is nil.
+// pojoRegistry should be locked by the caller.
+func registerPOJO(javaName string, o interface{}, fields []string) (int, error) {
 	var (
-		bHeader    []byte
-		bBody      []byte
-		fieldList  []string
 		structInfo structInfo
-		clsDef     classInfo
 		v          reflect.Value
 	)
 
@@ -141,48 +243,88 @@ func RegisterPOJO(o POJO) int {
 	}
 
 	structInfo.goName = structInfo.typ.String()
-	structInfo.javaName = o.JavaClassName()
+	if _, ok := pojoRegistry.registry[structInfo.goName]; ok {
+		return -1, nil
+	}
+	clsDef, err := newClassInfo(javaName, structInfo.typ, fields)
+	if err != nil {
+		return -1, err
+	}
+
+	structInfo.javaName = javaName
 	structInfo.inst = o
 	pojoRegistry.j2g[structInfo.javaName] = structInfo.goName
 	registerTypeName(structInfo.goName, structInfo.javaName)
 
-	// prepare fields info of objectDef
-	for i := 0; i < structInfo.typ.NumField(); i++ {
-		// skip unexported anonymous filed
-		if structInfo.typ.Field(i).PkgPath != "" {
-			continue
-		}
+	structInfo.index = len(pojoRegistry.classInfoList)
+	pojoRegistry.classInfoList = append(pojoRegistry.classInfoList, clsDef)
+	pojoRegistry.registry[structInfo.goName] = structInfo
 
-		var fieldName string
-		if val, has := structInfo.typ.Field(i).Tag.Lookup(tagIdentifier); has {
-			fieldName = val
-		} else {
-			fieldName = lowerCamelCase(structInfo.typ.Field(i).Name)
-		}
+	return structInfo.index, nil
+}
 
-		fieldList = append(fieldList, fieldName)
+// newClassInfo builds the class definition of go struct @typ as java class @javaName. The field order
+// of the class definition follows the go struct declaration if @fields is nil.
+func newClassInfo(javaName string, typ reflect.Type, fields []string) (classInfo, error) {
+	// # definition for an object (compact map)
+	// class-def  ::= 'C' string int string*
+	var (
+		bHeader      []byte
+		bBody        []byte
+		fieldList    []string
+		fieldIndexes [][]int
+		clsDef       classInfo
+	)
+
+	goName := typ.String()
+	if typ.Kind() != reflect.Struct {
+		return clsDef, perrors.Errorf("type %s of java class %s is not a struct", goName, javaName)
+	}
+
+	// prepare fields info of objectDef
+	if fields == nil {
+		fieldList, fieldIndexes = structFields(typ)
+	} else {
+		fieldList = make([]string, 0, len(fields))
+		fieldIndexes = make([][]int, 0, len(fields))
+		for _, fieldName := range fields {
+			index, err := findField(fieldName, typ)
+			if err != nil {
+				return clsDef, perrors.Errorf("can not find field %s of java class %s in %s", fieldName, javaName, goName)
+			}
+			if f := typ.FieldByIndex(index); f.PkgPath != "" {
+				return clsDef, perrors.Errorf("field %s of java class %s is bound to unexported field %s.%s",
+					fieldName, javaName, goName, f.Name)
+			}
+			fieldList = append(fieldList, fieldName)
+			fieldIndexes = append(fieldIndexes, index)
+		}
+	}
+	for _, fieldName := range fieldList {
 		bBody = encString(bBody, fieldName)
 	}
+	var omitEmpty []int
+	for i, index := range fieldIndexes {
+		if hasTagOption(typ.FieldByIndex(index), tagOptionOmitEmpty) {
+			omitEmpty = append(omitEmpty, i)
+		}
+	}
 
 	// prepare header of objectDef
 	bHeader = encByte(bHeader, BC_OBJECT_DEF)
-	bHeader = encString(bHeader, structInfo.javaName)
+	bHeader = encString(bHeader, javaName)
 
 	// write fields length into header of objectDef
 	// note: cause fieldList is a dynamic slice, so one must calculate length only after it being prepared already.
 	bHeader = encInt32(bHeader, int32(len(fieldList)))
 
 	// prepare classDef
-	clsDef = classInfo{javaName: structInfo.javaName, fieldNameList: fieldList}
+	clsDef = classInfo{javaName: javaName, fieldNameList: fieldList, fieldIndexes: fieldIndexes, omitEmpty: omitEmpty}
 
 	// merge header and body of objectDef into buffer of classInfo
 	clsDef.buffer = append(bHeader, bBody...)
 
-	structInfo.index = len(pojoRegistry.classInfoList)
-	pojoRegistry.classInfoList = append(pojoRegistry.classInfoList, clsDef)
-	pojoRegistry.registry[structInfo.goName] = structInfo
-
-	return structInfo.index
+	return clsDef, nil
 }
 
 // RegisterPOJOs register a POJO instance arr @os. The return value is @os's
@@ -196,6 +338,76 @@ func RegisterPOJOs(os ...POJO) []int {
 	return arr
 }
 
+// RegisterTaggedPOJOs Register the go struct instances @prototypes as the java classes named by
+// their class tags, such as the tag of the blank field in
+//
+//	type Order struct {
+//		_     struct{} `hessian:"class=com.company.Order"`
+//		ID    int32
+//		Buyer string
+//	}
+//
+// The fields of the class definitions follow the declarations and the tags of the fields like RegisterPOJO
+// does, and the prototypes do not need to implement POJO. Nothing is registered if any prototype is not a
+// struct with the class tag, and the error lists all of them. The return value is the matching index array,
+// in which "-1" means its matching prototype has been registered.
+func RegisterTaggedPOJOs(prototypes ...interface{}) ([]int, error) {
+	names := make([]string, len(prototypes))
+	var untagged []string
+	for i, o := range prototypes {
+		if o == nil {
+			untagged = append(untagged, "<nil>")
+			continue
+		}
+		typ := UnpackPtrType(reflect.TypeOf(o))
+		name, ok := taggedClassName(typ)
+		if !ok {
+			untagged = append(untagged, typ.String())
+			continue
+		}
+		if p, ok := o.(POJO); ok && p.JavaClassName() != name {
+			return nil, perrors.Errorf("the tagged class name %s of %s is not the java class name %s of the POJO",
+				name, typ, p.JavaClassName())
+		}
+		names[i] = name
+	}
+	if len(untagged) > 0 {
+		return nil, perrors.Errorf("java class tag %q is missing in %s", tagClassPrefix, strings.Join(untagged, ", "))
+	}
+
+	pojoRegistry.Lock()
+	defer pojoRegistry.Unlock()
+
+	arr := make([]int, len(prototypes))
+	for i, o := range prototypes {
+		idx, err := registerPOJO(names[i], o, nil)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = idx
+	}
+
+	return arr, nil
+}
+
+// taggedClassName gets the java class name of the class tag of a blank field of struct @typ.
+func taggedClassName(typ reflect.Type) (string, bool) {
+	if typ.Kind() != reflect.Struct {
+		return "", false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Name != "_" {
+			continue
+		}
+		if val, _, has := lookupTag(field); has && strings.HasPrefix(val, tagClassPrefix) {
+			name := strings.TrimPrefix(val, tagClassPrefix)
+			return name, name != ""
+		}
+	}
+	return "", false
+}
+
 // RegisterJavaEnum Register a value type JavaEnum variable.
 func RegisterJavaEnum(o POJOEnum) int {
 	var (
@@ -210,19 +422,20 @@ func RegisterJavaEnum(o POJOEnum) int {
 		v  reflect.Value
 	)
 
+	v = reflect.ValueOf(o)
+	switch v.Kind() {
+	case reflect.Struct:
+		t.typ = v.Type()
+	case reflect.Ptr:
+		t.typ = v.Elem().Type()
+	default:
+		t.typ = reflect.TypeOf(o)
+	}
+	t.goName = t.typ.String()
+
 	pojoRegistry.Lock()
 	defer pojoRegistry.Unlock()
-	if _, ok = pojoRegistry.registry[o.JavaClassName()]; !ok {
-		v = reflect.ValueOf(o)
-		switch v.Kind() {
-		case reflect.Struct:
-			t.typ = v.Type()
-		case reflect.Ptr:
-			t.typ = v.Elem().Type()
-		default:
-			t.typ = reflect.TypeOf(o)
-		}
-		t.goName = t.typ.String()
+	if _, ok = pojoRegistry.registry[t.goName]; !ok {
 		t.javaName = o.JavaClassName()
 		t.inst = o
 		pojoRegistry.j2g[t.javaName] = t.goName
@@ -281,6 +494,173 @@ func getStructInfo(javaName string) (structInfo, bool) {
 	return s, ok
 }
 
+// get the java class name which go struct name @goName has been registered as.
+func getJavaName(goName string) (string, bool) {
+	pojoRegistry.RLock()
+	s, ok := pojoRegistry.registry[goName]
+	pojoRegistry.RUnlock()
+
+	return s.javaName, ok
+}
+
+// POJOField is a field of the class definition of a registered java class, which the encoder writes for the go struct.
+type POJOField struct {
+	JavaName  string       // field name of the java class
+	GoName    string       // name of the go struct field, promoted fields are dotted like "Base.ID"
+	GoType    reflect.Type // type of the go struct field
+	TypeHint  string       // type of the typed list the value is written as, such as "[int", empty if untyped
+	OmitEmpty bool         // the field is left out of the class definition if it is zero
+}
+
+// POJOInfo is a snapshot of the registration of a java class.
+type POJOInfo struct {
+	JavaClassName string
+	Aliases       []string     // the other java class names resolved into GoType
+	GoType        reflect.Type // the go struct type
+	Fields        []POJOField  // fields in the order of the class definition
+	Enum          bool         // registered by RegisterJavaEnum
+}
+
+// RegisteredPOJOs gets a snapshot of all the registered java classes, keyed by the java class name
+// which the encoder writes. The snapshot is a copy, changing it does not affect the registry.
+func RegisteredPOJOs() map[string]POJOInfo {
+	pojoRegistry.RLock()
+	defer pojoRegistry.RUnlock()
+
+	infos := make(map[string]POJOInfo, len(pojoRegistry.registry))
+	for _, s := range pojoRegistry.registry {
+		infos[s.javaName] = pojoInfo(s)
+	}
+	for javaName, goName := range pojoRegistry.j2g {
+		s, ok := pojoRegistry.registry[goName]
+		if !ok || s.javaName == javaName {
+			continue
+		}
+		info := infos[s.javaName]
+		info.Aliases = append(info.Aliases, javaName)
+		infos[s.javaName] = info
+	}
+	for name, info := range infos {
+		if len(info.Aliases) > 1 {
+			sort.Strings(info.Aliases)
+			infos[name] = info
+		}
+	}
+
+	return infos
+}
+
+// pojoInfo gets the registration of @s without the aliases, the registry should be locked.
+func pojoInfo(s structInfo) POJOInfo {
+	info := POJOInfo{JavaClassName: s.javaName, GoType: s.typ}
+	if s.inst != nil {
+		_, info.Enum = s.inst.(POJOEnum)
+	}
+	if s.index < 0 || s.index >= len(pojoRegistry.classInfoList) {
+		return info
+	}
+
+	cls := pojoRegistry.classInfoList[s.index]
+	info.Fields = make([]POJOField, len(cls.fieldNameList))
+	for i, name := range cls.fieldNameList {
+		info.Fields[i].JavaName = name
+		if i >= len(cls.fieldIndexes) || s.typ.Kind() != reflect.Struct {
+			// the name of an enum
+			info.Fields[i].GoType = reflect.TypeOf("")
+			continue
+		}
+		field := s.typ.FieldByIndex(cls.fieldIndexes[i])
+		info.Fields[i].GoName = fieldIndexName(s.typ, cls.fieldIndexes[i])
+		info.Fields[i].GoType = field.Type
+		info.Fields[i].TypeHint = encodedTypeHint(field.Type)
+		info.Fields[i].OmitEmpty = hasTagOption(field, tagOptionOmitEmpty)
+	}
+	return info
+}
+
+// GetClassDef gets the java class and the class definition which the encoder writes for the registered go type
+// @typ, which can also be a pointer to it, like an entry of RegisteredPOJOs. It changes nothing, a go type which
+// is not registered is an error.
+func GetClassDef(typ reflect.Type) (POJOInfo, error) {
+	typ = UnpackPtrType(typ)
+	pojoRegistry.RLock()
+	defer pojoRegistry.RUnlock()
+
+	s, ok := pojoRegistry.registry[typ.String()]
+	if !ok {
+		return POJOInfo{}, perrors.Errorf("%s is neither a POJO nor registered by RegisterPOJOMapping", typ.String())
+	}
+	info := pojoInfo(s)
+	for javaName, goName := range pojoRegistry.j2g {
+		if goName == s.goName && javaName != s.javaName {
+			info.Aliases = append(info.Aliases, javaName)
+		}
+	}
+	sort.Strings(info.Aliases)
+	return info, nil
+}
+
+// encodedTypeHint gets the type of the typed list which the encoder writes a value of type @typ as.
+func encodedTypeHint(typ reflect.Type) string {
+	typ = UnpackPtrType(typ)
+	if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+		return ""
+	}
+	// the bytes are written as binary, and a list of interface{} is untyped
+	if typ.Elem().Kind() == reflect.Uint8 || typ == reflect.TypeOf([]MapEntry(nil)) ||
+		strings.Contains(typ.String(), "interface {}") {
+		return ""
+	}
+	return listTypeName(typ.Elem())
+}
+
+// fieldIndexName gets the dotted name of the go struct field of index sequence @index.
+func fieldIndexName(typ reflect.Type, index []int) string {
+	names := make([]string, 0, len(index))
+	for _, i := range index {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		f := typ.Field(i)
+		names = append(names, f.Name)
+		typ = f.Type
+	}
+	return strings.Join(names, ".")
+}
+
+// bindFieldIndexes gets the go struct field index sequence of every field of a received class definition
+// of java class @javaName. The field names registered for the java class take precedence,
+// other names are looked up by findField. A field which can not be bound gets a nil index.
+func bindFieldIndexes(typ reflect.Type, javaName string, fieldNames []string) [][]int {
+	var registered classInfo
+
+	pojoRegistry.RLock()
+	if g, ok := pojoRegistry.j2g[javaName]; ok {
+		if s, ok := pojoRegistry.registry[g]; ok && s.typ == typ && s.index < len(pojoRegistry.classInfoList) {
+			registered = pojoRegistry.classInfoList[s.index]
+		}
+	}
+	pojoRegistry.RUnlock()
+
+	indexes := make([][]int, len(fieldNames))
+	for i, fieldName := range fieldNames {
+		for j := range registered.fieldIndexes {
+			if registered.fieldNameList[j] == fieldName {
+				indexes[i] = registered.fieldIndexes[j]
+				break
+			}
+		}
+		if indexes[i] != nil {
+			continue
+		}
+		if index, err := findField(fieldName, typ); err == nil {
+			indexes[i] = index
+		}
+	}
+
+	return indexes
+}
+
 func getStructDefByIndex(idx int) (reflect.Type, classInfo, error) {
 	var (
 		ok      bool
@@ -326,6 +706,142 @@ func createInstance(goName string) interface{} {
 	return reflect.New(s.typ).Interface()
 }
 
+// POJOFactory returns a pointer to an instance of the go struct of a java class, such as
+// one taken from a pool, which the decoder sets the fields of instead of allocating one.
+type POJOFactory func() interface{}
+
+// pojoFactories maps the go struct types to their POJOFactory.
+var pojoFactories sync.Map
+
+// RegisterPOJOFactory sets the factory @factory of the instances of registered java class @javaName
+// for the decoder, which calls the factory instead of reflect.New for every decoded object of the class.
+// The decoder zeroes the instance before setting the fields, so that a recycled instance has no stale
+// field which is absent from the class definition. With SetSkipNull, the instance is not zeroed, so that
+// the fields preset by the factory, such as the defaults, are kept for the absent fields and the nulls.
+// The default of reflect.New is restored if @factory is nil.
+func RegisterPOJOFactory(javaName string, factory POJOFactory) error {
+	s, ok := getStructInfo(javaName)
+	if !ok {
+		return perrors.Errorf("java class %s has not been registered", javaName)
+	}
+	if factory == nil {
+		pojoFactories.Delete(s.typ)
+	} else {
+		pojoFactories.Store(s.typ, factory)
+	}
+	return nil
+}
+
+// newInstance returns a pointer to an instance of struct @typ of java class @javaName, which is made by
+// the factory of @typ if it has one. The instance of the factory is zeroed unless @keep is true.
+func newInstance(typ reflect.Type, javaName string, keep bool) (reflect.Value, error) {
+	factory, ok := pojoFactories.Load(typ)
+	if !ok {
+		return reflect.New(typ), nil
+	}
+	inst := factory.(POJOFactory)()
+	v := reflect.ValueOf(inst)
+	if v.Kind() != reflect.Ptr || v.Type().Elem() != typ || v.IsNil() {
+		return reflect.Value{}, perrors.Errorf("factory of java class %s returns %T but not a non-nil *%s", javaName, inst, typ)
+	}
+	if !keep {
+		v.Elem().Set(reflect.Zero(typ))
+	}
+	return v, nil
+}
+
+// structFields gets the java field names and the go struct field index sequences of @typ.
+// The fields of an untagged anonymous struct which is not a POJO are promoted into @typ,
+// and like go does, a field shadows the promoted fields of the same name at a deeper depth.
+func structFields(typ reflect.Type) ([]string, [][]int) {
+	var (
+		names   []string
+		indexes [][]int
+		walk    func(typ reflect.Type, index []int)
+	)
+
+	visited := make(map[reflect.Type]bool)
+	walk = func(typ reflect.Type, index []int) {
+		if visited[typ] {
+			return
+		}
+		visited[typ] = true
+		defer delete(visited, typ)
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+			if t, ok := promotedStruct(field); ok && !t.Implements(pojoType) && !reflect.PtrTo(t).Implements(pojoType) {
+				walk(t, fieldIndex)
+				continue
+			}
+			// skip unexported field
+			if field.PkgPath != "" {
+				continue
+			}
+
+			if skippedField(field) {
+				continue
+			}
+			if val, _, has := lookupTag(field); has && val != "" {
+				names = append(names, val)
+			} else {
+				names = append(names, lowerCamelCase(field.Name))
+			}
+			indexes = append(indexes, fieldIndex)
+		}
+	}
+	walk(typ, nil)
+
+	// a field is visible only if it is the only one of its name at the shallowest depth
+	depth := make(map[string]int, len(names))
+	count := make(map[string]int, len(names))
+	for i, name := range names {
+		if d, ok := depth[name]; !ok || len(indexes[i]) < d {
+			depth[name] = len(indexes[i])
+			count[name] = 1
+		} else if len(indexes[i]) == d {
+			count[name]++
+		}
+	}
+	var (
+		fieldNames   []string
+		fieldIndexes [][]int
+	)
+	for i, name := range names {
+		if len(indexes[i]) == depth[name] && count[name] == 1 {
+			fieldNames = append(fieldNames, name)
+			fieldIndexes = append(fieldIndexes, indexes[i])
+		}
+	}
+
+	return fieldNames, fieldIndexes
+}
+
+// promotedStruct returns the struct type of the anonymous field @field whose fields can be
+// promoted. An anonymous field given a name by the hessian tag is a normal field.
+func promotedStruct(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous {
+		return nil, false
+	}
+	if val, _, has := lookupTag(field); has && val != "" {
+		return nil, false
+	}
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		// the nil pointer of an unexported anonymous field can not be allocated
+		if field.PkgPath != "" {
+			return nil, false
+		}
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	return typ, true
+}
+
 func lowerCamelCase(s string) string {
 	runes := []rune(s)
 	runes[0] = unicode.ToLower(runes[0])
@@ -0,0 +1,494 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"encoding/binary"
+	"reflect"
+	"unicode/utf8"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// RawValue is a hessian value kept as its bytes without being decoded, like json.RawMessage.
+// A struct field of RawValue receives the bytes of the field value, including the class
+// definitions in it, and DecodeRaw decodes them on demand. The value is still walked to find
+// its end, but no go struct is bound, so the classes in it needn't be registered, even by a
+// strict decoder, whose strictness applies when DecodeRaw decodes the value.
+// A RawValue is encoded by writing its bytes verbatim like AppendRaw.
+type RawValue struct {
+	Data []byte // the bytes of the value
+
+	classInfoList []classInfo // the class definitions received before the value
+	typeRefs      TypeRefs    // the list types received before the value
+	refs          int         // the number of the refs before the value
+	strict        bool        // whether the value was received by a strict decoder
+}
+
+var rawValueType = reflect.TypeOf(RawValue{})
+
+// decRawValue captures the bytes of the next value into a RawValue.
+func (d *Decoder) decRawValue() (RawValue, error) {
+	d.drainBinary()
+	rv := RawValue{
+		classInfoList: append([]classInfo(nil), d.classInfoList...),
+		typeRefs: TypeRefs{
+			typeRefs:  append([]reflect.Type(nil), d.typeRefs.typeRefs...),
+			typeNames: append([]string(nil), d.typeRefs.typeNames...),
+		},
+		refs:   len(d.refs),
+		strict: d.strict,
+	}
+
+	// walk the value without binding it, checking its classes, or streaming its binaries
+	generic, strict, threshold := d.generic, d.strict, d.binaryStreamThreshold
+	d.generic, d.strict, d.binaryStreamThreshold = true, false, 0
+	start := len(d.raw)
+	d.capturing++
+	_, err := d.DecodeValue()
+	d.capturing--
+	d.generic, d.strict, d.binaryStreamThreshold = generic, strict, threshold
+
+	if err == nil {
+		rv.Data = append([]byte(nil), d.raw[start:]...)
+	}
+	if d.capturing == 0 {
+		d.raw = d.raw[:0]
+	}
+	return rv, err
+}
+
+// DecodeRaw decodes the value of @rv into @out like ReflectResponse, which should be a pointer.
+// The refs in @rv to the values out of it are decoded as nil. If @rv was received by a strict
+// decoder, the unknown classes in it are reported as by SetStrict.
+func DecodeRaw(rv RawValue, out interface{}) error {
+	d := NewDecoder(rv.Data)
+	d.SetStrict(rv.strict)
+	d.classInfoList = append(d.classInfoList, rv.classInfoList...)
+	d.refs = make([]interface{}, rv.refs, rv.refs+1)
+	d.typeRefs.typeRefs = append(d.typeRefs.typeRefs, rv.typeRefs.typeRefs...)
+	for _, name := range rv.typeRefs.typeNames {
+		d.typeRefs.typeNames = append(d.typeRefs.typeNames, name)
+		d.typeRefs.records[name] = true
+	}
+
+	v, err := d.Decode()
+	if err != nil {
+		return perrors.WithStack(err)
+	}
+	if v == nil {
+		return nil
+	}
+	return ReflectResponse(v, out)
+}
+
+// AppendRaw writes the pre-serialized hessian value @data verbatim, such as a cached sub-object
+// spliced into an object being encoded, without decoding and encoding it again.
+//
+// The refs, class definitions and list types of hessian are numbered from the beginning of the
+// stream, so @data should be encoded by a fresh encoder and must not refer to the definitions out
+// of it. It is walked to find what it defines and uses, and the encoder counts its refs, class
+// definitions and list types, so that the values encoded after it still number theirs right.
+// An error is returned if @data is not exactly one valid self-contained value, or if its numbers
+// would be shifted by the definitions the encoder has written before, that is if it has back refs
+// after some objects, lists or maps, or class definitions after some class definitions, or list
+// types after some list types. Appending such pieces first, or into a fresh encoder, avoids it.
+func (e *Encoder) AppendRaw(data []byte) error {
+	s := rawScanner{data: data}
+	if err := s.scanValue(); err != nil {
+		return perrors.Wrap(err, "invalid raw hessian value")
+	}
+	if s.pos != len(data) {
+		return perrors.New("invalid raw hessian value: trailing bytes after the value")
+	}
+
+	switch {
+	case s.backref && e.refCount > 0:
+		return perrors.Errorf("ambiguous raw hessian value: its back refs would be shifted by the %d refs before it", e.refCount)
+	case len(s.fields) > 0 && len(e.classInfoList) > 0:
+		return perrors.Errorf("ambiguous raw hessian value: its class definitions would be shifted by the %d ones before it",
+			len(e.classInfoList))
+	case len(s.typeNames) > 0 && len(e.typeRefs) > 0:
+		return perrors.Errorf("ambiguous raw hessian value: its list types would be shifted by the %d ones before it",
+			len(e.typeRefs))
+	}
+
+	e.buffer = append(e.buffer, data...)
+	e.refCount += s.refs
+	// the class definitions of @data can not be reused, since the fields of their go types are unknown
+	for i := 0; i < len(s.fields); i++ {
+		e.classInfoList = append(e.classInfoList, classInfo{})
+	}
+	for _, name := range s.typeNames {
+		if e.typeRefs == nil {
+			e.typeRefs = make(map[string]int, len(s.typeNames))
+		}
+		e.typeRefs[name] = len(e.typeRefs)
+	}
+	return nil
+}
+
+// rawScanner walks the bytes of a raw hessian value for AppendRaw following the grammar of hessian 2,
+// without decoding the value. It only counts what the value defines and uses, so that nothing but the
+// names of the list types defined is allocated, however many times a cached value is appended.
+type rawScanner struct {
+	data  []byte
+	pos   int
+	depth int
+
+	refs      int      // the number of the lists, maps and objects, which are numbered by the refs
+	backref   bool     // whether the value has refs
+	fields    []int32  // the number of the fields of each class definition
+	typeNames []string // the list and map types defined, in order
+}
+
+func (s *rawScanner) errorf(format string, args ...interface{}) error {
+	return perrors.Errorf(format+" at offset %d", append(args, s.pos)...)
+}
+
+func (s *rawScanner) readByte() (byte, error) {
+	if s.pos >= len(s.data) {
+		return 0, perrors.WithStack(ErrShortBuffer)
+	}
+	s.pos++
+	return s.data[s.pos-1], nil
+}
+
+func (s *rawScanner) skip(n int) error {
+	if n > len(s.data)-s.pos {
+		return perrors.WithStack(ErrShortBuffer)
+	}
+	s.pos += n
+	return nil
+}
+
+// scanInt reads an int like Decoder.decInt32, such as a length or an index.
+func (s *rawScanner) scanInt(tag byte) (int32, error) {
+	switch {
+	case tag >= 0x80 && tag <= 0xbf:
+		return int32(int8(tag - BC_INT_ZERO)), nil
+	case tag >= 0xc0 && tag <= 0xcf:
+		if err := s.skip(1); err != nil {
+			return 0, err
+		}
+		return int32(int16(binary.BigEndian.Uint16([]byte{tag - BC_INT_BYTE_ZERO, s.data[s.pos-1]}))), nil
+	case tag >= 0xd0 && tag <= 0xd7:
+		if err := s.skip(2); err != nil {
+			return 0, err
+		}
+		return int32(int8(tag-BC_INT_SHORT_ZERO))<<16 | int32(s.data[s.pos-2])<<8 | int32(s.data[s.pos-1]), nil
+	case tag == BC_INT:
+		if err := s.skip(4); err != nil {
+			return 0, err
+		}
+		return UnpackInt32(s.data[s.pos-4 : s.pos]), nil
+	default:
+		return 0, s.errorf("illegal int tag %#x", tag)
+	}
+}
+
+func (s *rawScanner) readInt() (int32, error) {
+	tag, err := s.readByte()
+	if err != nil {
+		return 0, err
+	}
+	return s.scanInt(tag)
+}
+
+// scanString skips the chunks of a string starting with @tag, whose lengths count the chars like
+// Decoder.decString. The bytes of the string are appended to @buf if it is not nil.
+func (s *rawScanner) scanString(tag byte, buf *[]byte) error {
+	for {
+		var n int
+		switch {
+		case tag <= STRING_DIRECT_MAX:
+			n = int(tag - BC_STRING_DIRECT)
+		case tag >= BC_STRING_SHORT && tag <= 0x33:
+			b, err := s.readByte()
+			if err != nil {
+				return err
+			}
+			n = int(tag-BC_STRING_SHORT)<<8 | int(b)
+		case tag == BC_STRING || tag == BC_STRING_CHUNK:
+			if err := s.skip(2); err != nil {
+				return err
+			}
+			n = int(binary.BigEndian.Uint16(s.data[s.pos-2 : s.pos]))
+		default:
+			return s.errorf("illegal string tag %#x", tag)
+		}
+
+		start := s.pos
+		for i := 0; i < n; i++ {
+			if s.pos >= len(s.data) {
+				return perrors.WithStack(ErrShortBuffer)
+			}
+			// a surrogate encoded in 3 bytes by java, which is not valid utf8
+			if b := s.data[s.pos:]; len(b) >= 3 && b[0] == 0xed && b[1] >= 0xa0 && b[1] <= 0xbf && b[2]&0xc0 == 0x80 {
+				s.pos += 3
+				continue
+			}
+			_, size := utf8.DecodeRune(s.data[s.pos:])
+			s.pos += size
+		}
+		if buf != nil {
+			*buf = append(*buf, s.data[start:s.pos]...)
+		}
+
+		if tag != BC_STRING_CHUNK {
+			return nil
+		}
+		var err error
+		if tag, err = s.readByte(); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *rawScanner) readString() error {
+	tag, err := s.readByte()
+	if err != nil {
+		return err
+	}
+	return s.scanString(tag, nil)
+}
+
+// scanBinary skips the chunks of a binary starting with @tag.
+func (s *rawScanner) scanBinary(tag byte) error {
+	for {
+		var n int
+		switch {
+		case tag >= BC_BINARY_DIRECT && tag <= 0x2f:
+			n = int(tag - BC_BINARY_DIRECT)
+		case tag >= BC_BINARY_SHORT && tag <= 0x37:
+			b, err := s.readByte()
+			if err != nil {
+				return err
+			}
+			n = int(tag-BC_BINARY_SHORT)<<8 | int(b)
+		case tag == BC_BINARY || tag == BC_BINARY_CHUNK:
+			if err := s.skip(2); err != nil {
+				return err
+			}
+			n = int(binary.BigEndian.Uint16(s.data[s.pos-2 : s.pos]))
+		default:
+			return s.errorf("illegal binary tag %#x", tag)
+		}
+		if err := s.skip(n); err != nil {
+			return err
+		}
+
+		if tag != BC_BINARY_CHUNK {
+			return nil
+		}
+		var err error
+		if tag, err = s.readByte(); err != nil {
+			return err
+		}
+	}
+}
+
+// scanType reads the type of a list or a map like Decoder.decType, which is either a type name
+// defined by it or a ref to a type name or a class definition.
+func (s *rawScanner) scanType() error {
+	tag, err := s.readByte()
+	if err != nil {
+		return err
+	}
+	if isStringTag(tag) {
+		var name []byte
+		if err = s.scanString(tag, &name); err != nil {
+			return err
+		}
+		for _, typeName := range s.typeNames {
+			if typeName == string(name) {
+				return nil
+			}
+		}
+		s.typeNames = append(s.typeNames, string(name))
+		return nil
+	}
+
+	idx, err := s.scanInt(tag)
+	if err != nil {
+		return err
+	}
+	if idx < 0 || (int(idx) >= len(s.typeNames) && int(idx) >= len(s.fields)) {
+		return s.errorf("illegal type ref index %d", idx)
+	}
+	return nil
+}
+
+// scanValues skips @n values, or the values until 'Z' if @n is negative.
+func (s *rawScanner) scanValues(n int32) error {
+	for i := int32(0); n < 0 || i < n; i++ {
+		if n < 0 {
+			if s.pos < len(s.data) && s.data[s.pos] == BC_END {
+				s.pos++
+				return nil
+			}
+		}
+		if err := s.scanValue(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanObject skips the fields of an object of the class definition @idx.
+func (s *rawScanner) scanObject(idx int32) error {
+	if idx < 0 || int(idx) >= len(s.fields) {
+		return s.errorf("illegal class index %d", idx)
+	}
+	s.refs++
+	return s.scanValues(s.fields[idx])
+}
+
+// scanValue skips the next value.
+func (s *rawScanner) scanValue() error {
+	tag, err := s.readByte()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case tag == BC_NULL || tag == BC_TRUE || tag == BC_FALSE ||
+		(tag >= 0x80 && tag <= 0xbf) || (tag >= 0xd8 && tag <= 0xef) ||
+		tag == BC_DOUBLE_ZERO || tag == BC_DOUBLE_ONE:
+		return nil
+
+	case (tag >= 0xc0 && tag <= 0xcf) || (tag >= 0xf0 && tag <= 0xff) || tag == BC_DOUBLE_BYTE:
+		return s.skip(1)
+
+	case (tag >= 0xd0 && tag <= 0xd7) || (tag >= 0x38 && tag <= 0x3f) || tag == BC_DOUBLE_SHORT:
+		return s.skip(2)
+
+	case tag == BC_INT || tag == BC_LONG_INT || tag == BC_DATE_MINUTE || tag == BC_DOUBLE_MILL:
+		return s.skip(4)
+
+	case tag == BC_LONG || tag == BC_DATE || tag == BC_DOUBLE:
+		return s.skip(8)
+
+	case isStringTag(tag):
+		return s.scanString(tag, nil)
+
+	case tag == BC_BINARY || tag == BC_BINARY_CHUNK || (tag >= BC_BINARY_DIRECT && tag <= 0x2f) ||
+		(tag >= BC_BINARY_SHORT && tag <= 0x37):
+		return s.scanBinary(tag)
+
+	case tag == BC_REF:
+		idx, err := s.readInt()
+		if err != nil {
+			return err
+		}
+		if idx < 0 || int(idx) >= s.refs {
+			return s.errorf("illegal ref index %d", idx)
+		}
+		s.backref = true
+		return nil
+
+	case tag == BC_OBJECT_DEF:
+		if err = s.readString(); err != nil {
+			return err
+		}
+		n, err := s.readInt()
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return s.errorf("illegal field number %d", n)
+		}
+		for i := int32(0); i < n; i++ {
+			if err = s.readString(); err != nil {
+				return err
+			}
+		}
+		s.fields = append(s.fields, n)
+		return s.scanValue()
+	}
+
+	if s.depth >= DEFAULT_MAX_DECODE_DEPTH {
+		return perrors.Wrapf(ErrMaxDepthExceeded, "max depth %d", DEFAULT_MAX_DECODE_DEPTH)
+	}
+	s.depth++
+	defer func() { s.depth-- }()
+
+	switch {
+	case tag == BC_OBJECT:
+		idx, err := s.readInt()
+		if err != nil {
+			return err
+		}
+		return s.scanObject(idx)
+
+	case tag >= BC_OBJECT_DIRECT && tag <= BC_OBJECT_DIRECT+OBJECT_DIRECT_MAX:
+		return s.scanObject(int32(tag - BC_OBJECT_DIRECT))
+
+	case tag == BC_MAP || tag == BC_MAP_UNTYPED:
+		if tag == BC_MAP {
+			if err = s.scanType(); err != nil {
+				return err
+			}
+		}
+		s.refs++
+		for {
+			if s.pos < len(s.data) && s.data[s.pos] == BC_END {
+				s.pos++
+				return nil
+			}
+			if err = s.scanValue(); err != nil {
+				return err
+			}
+			if err = s.scanValue(); err != nil {
+				return err
+			}
+		}
+
+	case tag == BC_LIST_VARIABLE || tag == BC_LIST_FIXED || (tag >= BC_LIST_DIRECT && tag <= 0x77):
+		if err = s.scanType(); err != nil {
+			return err
+		}
+		return s.scanList(tag)
+
+	case tag == BC_LIST_VARIABLE_UNTYPED || tag == BC_LIST_FIXED_UNTYPED || (tag >= BC_LIST_DIRECT_UNTYPED && tag <= 0x7f):
+		return s.scanList(tag)
+
+	default:
+		return s.errorf("invalid type tag %#x", tag)
+	}
+}
+
+// scanList skips the elements of a list starting with @tag, whose type has been read.
+func (s *rawScanner) scanList(tag byte) error {
+	n := int32(-1)
+	switch {
+	case tag >= BC_LIST_DIRECT && tag <= 0x77:
+		n = int32(tag - BC_LIST_DIRECT)
+	case tag >= BC_LIST_DIRECT_UNTYPED && tag <= 0x7f:
+		n = int32(tag - BC_LIST_DIRECT_UNTYPED)
+	case tag == BC_LIST_FIXED || tag == BC_LIST_FIXED_UNTYPED:
+		var err error
+		if n, err = s.readInt(); err != nil {
+			return err
+		}
+		if n < 0 {
+			return s.errorf("illegal list length %d", n)
+		}
+	}
+	s.refs++
+	return s.scanValues(n)
+}
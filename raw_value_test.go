@@ -0,0 +1,241 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rawLevel struct {
+	Name  string
+	Child *rawLevel
+	Cases []*Case
+}
+
+func (rawLevel) JavaClassName() string {
+	return "test.RawLevel"
+}
+
+type rawEnvelope struct {
+	Head    *Case
+	Payload RawValue
+	Tail    *Case
+}
+
+func (rawEnvelope) JavaClassName() string {
+	return "test.RawEnvelope"
+}
+
+func newRawLevel(name string, child *GenericObject, cases ...*Case) *GenericObject {
+	o := NewGenericObject("test.RawLevel")
+	o.fieldNames = []string{"name", "child", "cases"}
+	o.Fields["name"] = name
+	o.Fields["child"] = child
+	o.Fields["cases"] = cases
+	return o
+}
+
+func TestRawValue(t *testing.T) {
+	RegisterPOJO(&Case{})
+	RegisterPOJO(&rawEnvelope{})
+	head, c, tail := &Case{A: "head"}, &Case{A: "c", B: 1}, &Case{A: "tail", B: 2}
+
+	// a deeply nested payload, whose cases refer to the class definition of the head
+	var payload *GenericObject
+	for i := 4; i >= 0; i-- {
+		if i == 4 {
+			payload = newRawLevel("level4", nil, c, c)
+		} else {
+			payload = newRawLevel("level"+string(rune('0'+i)), payload)
+		}
+	}
+	envelope := NewGenericObject("test.RawEnvelope")
+	envelope.fieldNames = []string{"head", "payload", "tail"}
+	envelope.Fields["head"] = head
+	envelope.Fields["payload"] = payload
+	envelope.Fields["tail"] = tail
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(envelope))
+	assert.Nil(t, e.Encode(tail))
+
+	// the payload is skipped, and the following values are still decoded
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	env := res.(*rawEnvelope)
+	assert.Equal(t, head, env.Head)
+	assert.Equal(t, tail, env.Tail)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, tail, res)
+
+	// the class definition of test.RawLevel is in the raw bytes
+	assert.Equal(t, BC_OBJECT_DEF, env.Payload.Data[0])
+	raw := NewDecoder(env.Payload.Data)
+	raw.SetGenericMode(true)
+	raw.classInfoList = env.Payload.classInfoList
+	raw.refs = make([]interface{}, env.Payload.refs)
+	_, err = raw.Decode()
+	assert.Nil(t, err)
+	_, err = raw.Decode()
+	assert.Equal(t, io.EOF, err)
+
+	// the payload is decoded on demand
+	RegisterPOJO(&rawLevel{})
+	var level *rawLevel
+	assert.Nil(t, DecodeRaw(env.Payload, &level))
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "level"+string(rune('0'+i)), level.Name)
+		level = level.Child
+	}
+	assert.Equal(t, &rawLevel{Name: "level4", Cases: []*Case{c, c}}, level)
+	assert.True(t, level.Cases[0] == level.Cases[1])
+}
+
+func TestRawValueStrict(t *testing.T) {
+	RegisterPOJO(&Case{})
+	RegisterPOJO(&rawEnvelope{})
+	buyer := NewGenericObject("com.mycompany.dto.RawBuyer")
+	buyer.fieldNames = []string{"name"}
+	buyer.Fields["name"] = "Alice"
+	envelope := NewGenericObject("test.RawEnvelope")
+	envelope.fieldNames = []string{"head", "payload", "tail"}
+	envelope.Fields["payload"] = buyer
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(envelope))
+
+	// the unregistered class in the payload is not checked until the payload is decoded
+	d := NewDecoder(e.Buffer())
+	d.SetStrict(true)
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	env := res.(*rawEnvelope)
+	var v interface{}
+	err = DecodeRaw(env.Payload, &v)
+	unknown, ok := perrors.Cause(err).(*UnknownClassError)
+	if assert.True(t, ok, "%v", err) {
+		assert.Equal(t, "com.mycompany.dto.RawBuyer", unknown.ClassName)
+	}
+}
+
+func TestAppendRaw(t *testing.T) {
+	RegisterPOJO(&Case{})
+	RegisterPOJO(&rawEnvelope{})
+	c := &Case{A: "cached", B: 1}
+
+	// a cached object spliced before the other values
+	cache := NewEncoder()
+	assert.Nil(t, cache.Encode([]*Case{c, c}))
+	cached := cache.Buffer()
+
+	e := NewEncoder()
+	assert.Nil(t, e.AppendRaw(cached))
+	assert.Nil(t, e.Encode(map[string]interface{}{"case": &Case{A: "after", B: 2}}))
+	d := NewDecoder(e.Buffer())
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []*Case{c, c}, res)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &Case{A: "after", B: 2}, res.(map[interface{}]interface{})["case"])
+
+	// a cached value without class definitions spliced as a field of an object
+	cache = NewEncoder()
+	assert.Nil(t, cache.Encode(map[string]interface{}{"ids": []int32{1, 2}}))
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&rawEnvelope{Head: c, Payload: RawValue{Data: cache.Buffer()}, Tail: c}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	env := res.(*rawEnvelope)
+	assert.Equal(t, c, env.Head)
+	assert.True(t, env.Head == env.Tail)
+	var payload map[string][]int32
+	assert.Nil(t, DecodeRaw(env.Payload, &payload))
+	assert.Equal(t, map[string][]int32{"ids": {1, 2}}, payload)
+
+	// the ambiguous and the invalid values
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(&Case{A: "before"}))
+	err = e.AppendRaw(cached)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "back refs")
+	cache = NewEncoder()
+	assert.Nil(t, cache.Encode(c))
+	err = e.AppendRaw(cache.Buffer())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "class definitions")
+	assert.NotNil(t, e.AppendRaw(cached[:len(cached)-1]))
+	assert.NotNil(t, e.AppendRaw(append(encInt32(nil, 1), BC_NULL)))
+	assert.Nil(t, e.AppendRaw(encInt32(nil, 1)))
+}
+
+func TestAppendRawScan(t *testing.T) {
+	RegisterPOJO(&Case{})
+	RegisterPOJO(&rawLevel{})
+	c := &Case{A: "cached", B: 1}
+	values := []interface{}{
+		nil, true, int32(-2000), int32(1 << 20), int64(-9), int64(300), int64(1 << 40),
+		0.0, 1.0, 12.5, 3.14159, time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC), time.Unix(0, 1e6),
+		"", "hello", "中文\U0001F600", strings.Repeat("x", 40000), make([]byte, 70000),
+		[]string{"a", "b"}, []int32{1, 2, 3}, []interface{}{c, []*Case{c, c}},
+		map[string]interface{}{"a": []int64{1}, "b": map[int32]string{1: "one"}},
+		&rawLevel{Name: "root", Child: &rawLevel{Name: "child", Cases: []*Case{c}}, Cases: []*Case{c, c}},
+	}
+	for _, v := range values {
+		src := NewEncoder()
+		assert.Nil(t, src.Encode(v))
+		data := src.Buffer()
+
+		d := NewDecoder(data)
+		_, err := d.Decode()
+		assert.Nil(t, err)
+		e := NewEncoder()
+		assert.Nil(t, e.AppendRaw(data), "%v", v)
+		assert.Equal(t, len(d.refs), e.refCount)
+		assert.Equal(t, len(d.classInfoList), len(e.classInfoList))
+		assert.Equal(t, len(d.typeRefs.typeNames), len(e.typeRefs))
+		for n := 0; n < len(data); n += 1 + n/8 {
+			assert.NotNil(t, NewEncoder().AppendRaw(data[:n]), "%v truncated to %d bytes", v, n)
+		}
+	}
+
+	// the refs and the class indexes out of the value
+	assert.NotNil(t, NewEncoder().AppendRaw([]byte{BC_REF, 0x90}))
+	assert.NotNil(t, NewEncoder().AppendRaw([]byte{BC_OBJECT_DIRECT}))
+
+	// a cached value without definitions is walked without allocating
+	src := NewEncoder()
+	assert.Nil(t, src.Encode(map[string]interface{}{"id": int32(1), "name": "cached", "tags": map[string]interface{}{"a": 1.5}}))
+	data := src.Buffer()
+	e := NewEncoder()
+	assert.Nil(t, e.AppendRaw(data))
+	allocs := testing.AllocsPerRun(100, func() {
+		e.buffer = e.buffer[:0]
+		e.refCount = 0
+		assert.Nil(t, e.AppendRaw(data))
+	})
+	assert.Equal(t, 0.0, allocs)
+}
@@ -78,9 +78,25 @@ func (d *Decoder) appendRefs(v interface{}) *_refHolder {
 	}
 
 	d.refs = append(d.refs, v)
+	if d.refListener != nil {
+		value, _ := EnsureInterface(v, nil)
+		d.refListener(RefEvent{ID: len(d.refs) - 1, Value: value})
+	}
 	return holder
 }
 
+// RefEvent is passed to the listener set by Decoder.SetRefListener when a ref is defined or used.
+type RefEvent struct {
+	// ID is the ref id, which is assigned in the same order as the java decoder does,
+	// that is, the objects, lists and maps are numbered in the order they begin.
+	ID int
+	// Value is the decoded value of the ref. When the ref is defined, its elements or fields
+	// may not be decoded yet, and it is nil for the value created by a Serializer or a DecodeHook.
+	Value interface{}
+	// Backref is true if the ref is used by a ref tag, and false if it is defined.
+	Backref bool
+}
+
 //encRef encode ref index
 func encRef(b []byte, index int) []byte {
 	return encInt32(append(b, BC_REF), int32(index))
@@ -96,6 +112,10 @@ func (e *Encoder) checkRefMap(v reflect.Value) (int, bool) {
 	)
 
 	if v.Kind() == reflect.Ptr {
+		// a nil pointer is encoded as null, which is not a ref object
+		if v.IsNil() {
+			return 0, false
+		}
 		for v.Elem().Kind() == reflect.Ptr {
 			v = v.Elem()
 		}
@@ -119,22 +139,22 @@ func (e *Encoder) checkRefMap(v reflect.Value) (int, bool) {
 		}
 	}
 
-	if elem, ok := e.refMap[addr]; ok {
-		if elem.kind == kind {
-			// If kind is not struct, just return the index. Otherwise,
-			// check whether the types are same, because the different
-			// empty struct may share the same address and kind.
-			if elem.kind != reflect.Struct {
-				return elem.index, ok
-			} else if elem.tp == tp {
-				return elem.index, ok
-			}
-		}
+	// a nil slice or map is encoded as an empty list or map, which can not be referred to
+	if addr == nil {
+		e.refCount++
 		return 0, false
 	}
 
-	n := len(e.refMap)
-	e.refMap[addr] = _refElem{kind, tp, n}
+	// different values may share the same address, such as a struct and its first field,
+	// so a value is the same one only if both its kind and type are the same.
+	for _, elem := range e.refMap[addr] {
+		if elem.kind == kind && elem.tp == tp {
+			return elem.index, true
+		}
+	}
+
+	e.refMap[addr] = append(e.refMap[addr], _refElem{kind, tp, e.refCount})
+	e.refCount++
 	return 0, false
 }
 
@@ -154,7 +174,10 @@ func (d *Decoder) decRef(flag int32) (interface{}, error) {
 	if flag != TAG_READ {
 		tag = byte(flag)
 	} else {
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return nil, perrors.WithStack(err)
+		}
 	}
 
 	switch {
@@ -163,14 +186,22 @@ func (d *Decoder) decRef(flag int32) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
-
-		if len(d.refs) <= int(i) {
-			return nil, ErrIllegalRefIndex
-		}
-		// return the exact ref object, which maybe a _refHolder
-		return d.refs[i], nil
+		return d.refAt(i)
 
 	default:
-		return nil, perrors.Errorf("decRef illegal ref type tag:%+v", tag)
+		return nil, d.tagErrorf("decRef illegal ref type tag:%#x", tag)
+	}
+}
+
+// refAt gets the value which the ref @i refers to.
+func (d *Decoder) refAt(i int32) (interface{}, error) {
+	if i < 0 || len(d.refs) <= int(i) {
+		return nil, d.offsetError(ErrIllegalRefIndex)
+	}
+	if d.refListener != nil {
+		value, _ := EnsureInterface(d.refs[i], nil)
+		d.refListener(RefEvent{ID: int(i), Value: value, Backref: true})
 	}
+	// return the exact ref object, which maybe a _refHolder
+	return d.refs[i], nil
 }
@@ -198,3 +198,167 @@ func TestComplexLevelRef(t *testing.T) {
 	assert.True(t, AddrEqual(d3, d5.Tags["man"]))
 	assert.True(t, AddrEqual(d4, d5.Tags["woman"]))
 }
+
+type refOuter struct {
+	Inner refInner
+	Name  string
+}
+
+func (refOuter) JavaClassName() string {
+	return "test.RefOuter"
+}
+
+type refInner struct {
+	Num int32
+}
+
+func (refInner) JavaClassName() string {
+	return "test.RefInner"
+}
+
+func TestEncodeCycle(t *testing.T) {
+	// a two-node cycle
+	a := &circular{Num: 1}
+	b := &circular{Num: 2, Previous: a, Next: a}
+	a.Previous = b
+	a.Next = b
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(a))
+	bytes := e.Buffer()
+	// the second encounter of a is the ref to the first object
+	assert.Equal(t, []byte{BC_REF, BC_INT_ZERO}, bytes[len(bytes)-4:len(bytes)-2])
+
+	res, err := NewDecoder(bytes).Decode()
+	assert.Nil(t, err)
+	a1 := res.(*circular)
+	b1 := a1.Next
+	assert.Equal(t, 1, a1.Num)
+	assert.Equal(t, 2, b1.Num)
+	assert.True(t, a1.Previous == b1)
+	assert.True(t, b1.Previous == a1)
+	assert.True(t, b1.Next == a1)
+
+	// a self-referential node in a list
+	s := &circular{Num: 3}
+	s.Next = s
+	e = NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{s, s}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	nodes := res.([]interface{})
+	assert.True(t, nodes[0].(*circular).Next == nodes[0])
+	assert.True(t, nodes[1] == nodes[0])
+
+	// a struct and its first field share the same address but are different objects
+	o := &refOuter{Name: "outer"}
+	e = NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{o, &o.Inner, &o.Inner, o}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	list := res.([]interface{})
+	o1 := list[0].(*refOuter)
+	assert.Equal(t, "outer", o1.Name)
+	assert.Equal(t, &refInner{}, list[1])
+	assert.True(t, list[2] == list[1])
+	assert.True(t, list[3] == o1)
+}
+
+type refFoo struct {
+	Name string
+}
+
+func (refFoo) JavaClassName() string {
+	return "test.RefFoo"
+}
+
+type refBar struct {
+	Foos  []*refFoo
+	Same  []*refFoo
+	ByKey map[string]*refFoo
+	Any   interface{}
+	First *refFoo
+}
+
+func (refBar) JavaClassName() string {
+	return "test.RefBar"
+}
+
+func TestDecodeSharedRefs(t *testing.T) {
+	RegisterPOJO(&refFoo{})
+	f := &refFoo{Name: "foo"}
+	foos := []*refFoo{f, f}
+
+	// refs within a list
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{f, f}))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	var list []*refFoo
+	assert.Nil(t, ReflectResponse(res, &list))
+	assert.Equal(t, 2, len(list))
+	assert.True(t, list[0] == list[1])
+
+	// refs within a map, and refs to a list
+	e = NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{map[string]interface{}{"a": foos, "b": f}, foos}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	values := res.([]interface{})
+	m := values[0].(map[interface{}]interface{})
+	a := m["a"].([]*refFoo)
+	assert.True(t, a[0] == a[1])
+	assert.True(t, m["b"] == a[0])
+	assert.Equal(t, a, values[1])
+
+	// refs within the fields of an object
+	bar := &refBar{Foos: foos, Same: foos, ByKey: map[string]*refFoo{"a": f, "b": f}, Any: foos, First: f}
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(bar))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	bar1 := res.(*refBar)
+	f1 := bar1.First
+	assert.Equal(t, "foo", f1.Name)
+	assert.Equal(t, []*refFoo{f1, f1}, bar1.Foos)
+	assert.True(t, bar1.Foos[0] == f1 && bar1.Foos[1] == f1)
+	assert.True(t, bar1.Same[0] == f1 && bar1.Same[1] == f1)
+	assert.True(t, bar1.ByKey["a"] == f1 && bar1.ByKey["b"] == f1)
+	assert.Equal(t, bar1.Foos, bar1.Any)
+}
+
+func TestRefListener(t *testing.T) {
+	c := &circular{Num: 1}
+	c.Previous = c
+	c.Next = c
+	m := map[string]interface{}{"a": int32(1)}
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{c, m, m}))
+
+	var events []RefEvent
+	d := NewDecoder(e.Buffer())
+	d.SetRefListener(func(event RefEvent) {
+		events = append(events, event)
+	})
+	res, err := d.Decode()
+	assert.Nil(t, err)
+
+	ids := make([]int, 0, len(events))
+	backrefs := make([]bool, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+		backrefs = append(backrefs, event.Backref)
+	}
+	assert.Equal(t, []int{0, 1, 1, 1, 2, 2}, ids)
+	assert.Equal(t, []bool{false, false, true, true, false, true}, backrefs)
+
+	got := res.([]interface{})
+	assert.True(t, events[2].Value == got[0])
+	assert.Equal(t, got[1], events[5].Value)
+
+	// the decoding is the same without the listener
+	again, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, len(got), len(again.([]interface{})))
+}
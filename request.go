@@ -77,7 +77,7 @@ func getArgType(v interface{}) string {
 	case time.Time:
 		return "java.util.Date"
 	case []time.Time:
-		return "[Ljava.util.Date"
+		return "[Ljava.util.Date;"
 	case float32:
 		return "F"
 	case []float32:
@@ -98,6 +98,9 @@ func getArgType(v interface{}) string {
 
 	//  Serialized tags for complex types
 	default:
+		if name, ok := pojoClassName(reflect.TypeOf(v)); ok {
+			return name
+		}
 		t := reflect.TypeOf(v)
 		if reflect.Ptr == t.Kind() {
 			t = reflect.TypeOf(reflect.ValueOf(v).Elem())
@@ -106,6 +109,9 @@ func getArgType(v interface{}) string {
 		case reflect.Struct:
 			return "java.lang.Object"
 		case reflect.Slice, reflect.Array:
+			if name, ok := pojoClassName(t.Elem()); ok {
+				return "[L" + name + ";"
+			}
 			if t.Elem().Kind() == reflect.Struct {
 				return "[Ljava.lang.Object;"
 			}
@@ -122,25 +128,46 @@ func getArgType(v interface{}) string {
 	// return "java.lang.RuntimeException"
 }
 
-func getArgsTypeList(args []interface{}) (string, error) {
-	var (
-		typ   string
-		types string
-	)
+// pojoClassName returns the java class name of @t if @t or its pointer is a POJO.
+func pojoClassName(t reflect.Type) (string, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if p, ok := reflect.New(t).Interface().(POJO); ok {
+		return p.JavaClassName(), true
+	}
+	return "", false
+}
+
+// GetArgDesc returns the JVM type descriptor of the java parameter type of argument @v,
+// such as "I" for int32, "Ljava/lang/String;" for string and "[Ljava/lang/String;" for []string.
+// A POJO is described by its java class name.
+func GetArgDesc(v interface{}) (string, error) {
+	typ := getArgType(v)
+	if typ == "" {
+		return "", perrors.Errorf("cat not get arg %#v type", v)
+	}
+	if !strings.Contains(typ, ".") {
+		return typ, nil
+	}
+	if strings.Index(typ, "[") == 0 {
+		return strings.Replace(typ, ".", "/", -1), nil
+	}
+	// java.util.List -> Ljava/util/List;
+	return "L" + strings.Replace(typ, ".", "/", -1) + ";", nil
+}
+
+// GetArgsTypeList returns the JVM type descriptors of the parameter types of @args, which
+// is sent in a dubbo request to find the java method. It can be overridden by Request.ParamTypes.
+func GetArgsTypeList(args []interface{}) (string, error) {
+	var types string
 
 	for i := range args {
-		typ = getArgType(args[i])
-		if typ == "" {
-			return types, perrors.Errorf("cat not get arg %#v type", args[i])
-		}
-		if !strings.Contains(typ, ".") {
-			types += typ
-		} else if strings.Index(typ, "[") == 0 {
-			types += strings.Replace(typ, ".", "/", -1)
-		} else {
-			// java.util.List -> Ljava/util/List;
-			types += "L" + strings.Replace(typ, ".", "/", -1) + ";"
+		typ, err := GetArgDesc(args[i])
+		if err != nil {
+			return types, err
 		}
+		types += typ
 	}
 
 	return types, nil
@@ -149,6 +176,9 @@ func getArgsTypeList(args []interface{}) (string, error) {
 type Request struct {
 	Params      interface{}
 	Attachments map[string]string
+	// ParamTypes is the JVM type descriptors of the parameter types of the method, such as
+	// "Ljava/lang/String;I". It is generated from Params by GetArgsTypeList if it is empty.
+	ParamTypes string
 }
 
 // NewRequest create a new Request
@@ -176,7 +206,6 @@ func packRequest(service Service, header DubboHeader, req interface{}) ([]byte,
 		err       error
 		types     string
 		byteArray []byte
-		version   string
 		pkgLen    int
 	)
 
@@ -227,8 +256,11 @@ func packRequest(service Service, header DubboHeader, req interface{}) ([]byte,
 	encoder.Encode(service.Method)
 
 	// args = args type list + args value list
-	if types, err = getArgsTypeList(args); err != nil {
-		return nil, perrors.Wrapf(err, " PackRequest(args:%+v)", args)
+	types = request.ParamTypes
+	if types == "" {
+		if types, err = GetArgsTypeList(args); err != nil {
+			return nil, perrors.Wrapf(err, " PackRequest(args:%+v)", args)
+		}
 	}
 	encoder.Encode(types)
 	for _, v := range args {
@@ -238,8 +270,8 @@ func packRequest(service Service, header DubboHeader, req interface{}) ([]byte,
 	request.Attachments[PATH_KEY] = service.Path
 	request.Attachments[GROUP_KEY] = service.Group
 	request.Attachments[INTERFACE_KEY] = service.Interface
-	if len(version) != 0 {
-		request.Attachments[VERSION_KEY] = version
+	if len(service.Version) != 0 {
+		request.Attachments[VERSION_KEY] = service.Version
 	}
 	if service.Timeout != 0 {
 		request.Attachments[TIMEOUT_KEY] = strconv.Itoa(int(service.Timeout / time.Millisecond))
@@ -250,8 +282,8 @@ func packRequest(service Service, header DubboHeader, req interface{}) ([]byte,
 END:
 	byteArray = encoder.Buffer()
 	pkgLen = len(byteArray)
-	if pkgLen > int(DEFAULT_LEN) { // 8M
-		return nil, perrors.Errorf("Data length %d too large, max payload %d", pkgLen, DEFAULT_LEN)
+	if limit := getMaxPayloadSize(); pkgLen > limit {
+		return nil, perrors.Wrapf(ErrPayloadTooLarge, "Data length %d too large, max payload %d", pkgLen, limit)
 	}
 	// byteArray{body length}
 	binary.BigEndian.PutUint32(byteArray[12:], uint32(pkgLen-HEADER_LENGTH))
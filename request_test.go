@@ -45,7 +45,7 @@ func TestPackRequest(t *testing.T) {
 
 func TestGetArgsTypeList(t *testing.T) {
 	type Test struct{}
-	str, err := getArgsTypeList([]interface{}{nil, 1, []int{2}, true, []bool{false}, "a", []string{"b"}, Test{}, &Test{}, []Test{}, map[string]Test{}})
+	str, err := GetArgsTypeList([]interface{}{nil, 1, []int{2}, true, []bool{false}, "a", []string{"b"}, Test{}, &Test{}, []Test{}, map[string]Test{}})
 	assert.NoError(t, err)
 	assert.Equal(t, "VJ[JZ[ZLjava/lang/String;[Ljava/lang/String;Ljava/lang/Object;Ljava/lang/Object;[Ljava/lang/Object;Ljava/util/Map;", str)
 }
@@ -77,3 +77,58 @@ func TestDescRegex(t *testing.T) {
 	assert.Equal(t, "[Ljava/lang/String;", results[0])
 	assert.Equal(t, "[I", results[1])
 }
+
+func TestGetArgDesc(t *testing.T) {
+	for _, c := range []struct {
+		v    interface{}
+		desc string
+	}{
+		{int32(1), "I"},
+		{"a", "Ljava/lang/String;"},
+		{[]string{"a"}, "[Ljava/lang/String;"},
+		{[]time.Time{}, "[Ljava/util/Date;"},
+		{&Case{}, "Lcom/test/case;"},
+		{[]*Case{}, "[Lcom/test/case;"},
+		{[]Case{}, "[Lcom/test/case;"},
+		{testColorGreen, "Ltest/model/Color;"},
+	} {
+		desc, err := GetArgDesc(c.v)
+		assert.Nil(t, err)
+		assert.Equal(t, c.desc, desc, "%T", c.v)
+	}
+
+	_, err := GetArgDesc(make(chan int))
+	assert.NotNil(t, err)
+}
+
+func TestPackUnpackRequest(t *testing.T) {
+	RegisterPOJO(&Case{})
+	service := Service{Path: "test", Interface: "ITest", Group: "g", Version: "v1.0", Method: "find"}
+	header := DubboHeader{Type: PackageRequest, ID: 1}
+
+	for _, c := range []struct {
+		req   *Request
+		types string
+	}{
+		{NewRequest([]interface{}{"a", int32(1), &Case{A: "c", B: 2}}, nil), "Ljava/lang/String;ILcom/test/case;"},
+		// the parameter types of the java method can be given explicitly
+		{&Request{Params: []interface{}{"a", int32(1), &Case{A: "c", B: 2}}, Attachments: map[string]string{},
+			ParamTypes: "Ljava/lang/CharSequence;Ljava/lang/Integer;Ljava/lang/Object;"}, "Ljava/lang/CharSequence;Ljava/lang/Integer;Ljava/lang/Object;"},
+	} {
+		b, err := packRequest(service, header, c.req)
+		assert.Nil(t, err)
+
+		body := make([]interface{}, 7)
+		assert.Nil(t, unpackRequestBody(b[HEADER_LENGTH:], body))
+		assert.Equal(t, DUBBO_VERSION, body[0])
+		assert.Equal(t, "test", body[1])
+		assert.Equal(t, "v1.0", body[2])
+		assert.Equal(t, "find", body[3])
+		assert.Equal(t, c.types, body[4])
+		assert.Equal(t, []interface{}{"a", int32(1), &Case{A: "c", B: 2}}, body[5])
+		attachments, err := toAttachments(body[6])
+		assert.Nil(t, err)
+		assert.Equal(t, "v1.0", attachments[VERSION_KEY])
+		assert.Equal(t, "ITest", attachments[INTERFACE_KEY])
+	}
+}
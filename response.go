@@ -15,7 +15,9 @@
 package hessian
 
 import (
+	"database/sql"
 	"encoding/binary"
+	"fmt"
 	"math"
 	"reflect"
 	"strconv"
@@ -26,10 +28,6 @@ import (
 	perrors "github.com/pkg/errors"
 )
 
-import (
-	"github.com/apache/dubbo-go-hessian2/java_exception"
-)
-
 type Response struct {
 	RspObj      interface{}
 	Exception   error
@@ -48,6 +46,55 @@ func NewResponse(rspObj interface{}, exception error, attachments map[string]str
 	}
 }
 
+// DubboRemoteError is the exception of a response whose status is not Response_OK,
+// such as Response_SERVICE_ERROR or Response_SERVER_TIMEOUT.
+type DubboRemoteError struct {
+	Status  byte   // response status of the dubbo header
+	Message string // error message sent by the server
+}
+
+func (e *DubboRemoteError) Error() string {
+	return "java exception:" + e.Message
+}
+
+// ReflectError is returned by ReflectResponse, CopySlice, CopyMap and CopyMapToStruct
+// when a value can not be assigned, whose Path locates the value in the object graph,
+// such as "items[3].price", and Err describes the mismatch.
+type ReflectError struct {
+	Path string
+	Err  error
+}
+
+func (e *ReflectError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Cause returns the mismatch for perrors.Cause.
+func (e *ReflectError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the mismatch for errors.Is and errors.As.
+func (e *ReflectError) Unwrap() error {
+	return e.Err
+}
+
+// fieldPath returns the path of the field @name of the value at @path.
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// indexPath returns the path of the element @index of the list or map at @path.
+func indexPath(path string, index interface{}) string {
+	return fmt.Sprintf("%s[%v]", path, index)
+}
+
 func EnsureResponse(body interface{}) *Response {
 	if res, ok := body.(*Response); ok {
 		return res
@@ -87,7 +134,8 @@ func packResponse(header DubboHeader, ret interface{}) ([]byte, error) {
 	binary.BigEndian.PutUint64(byteArray[4:], uint64(header.ID))
 
 	// body
-	encoder := NewEncoder()
+	encoder := NewPooledEncoder()
+	defer encoder.Release()
 	encoder.Append(byteArray[:HEADER_LENGTH])
 
 	if header.ResponseStatus == Response_OK {
@@ -112,11 +160,7 @@ func packResponse(header DubboHeader, ret interface{}) ([]byte, error) {
 
 			if response.Exception != nil { // throw error
 				encoder.Encode(resWithException)
-				if t, ok := response.Exception.(java_exception.Throwabler); ok {
-					encoder.Encode(t)
-				} else {
-					encoder.Encode(java_exception.NewThrowable(response.Exception.Error()))
-				}
+				encoder.Encode(toJavaException(response.Exception))
 			} else {
 				if response.RspObj == nil {
 					encoder.Encode(resNullValue)
@@ -140,11 +184,14 @@ func packResponse(header DubboHeader, ret interface{}) ([]byte, error) {
 		}
 	}
 
-	byteArray = encoder.Buffer()
+	// copy out of the pooled buffer, which is released on return
+	body := encoder.Buffer()
+	byteArray = make([]byte, len(body), len(body)+1)
+	copy(byteArray, body)
 	byteArray = encNull(byteArray) // if not, "java client" will throw exception  "unexpected end of file"
 	pkgLen := len(byteArray)
-	if pkgLen > int(DEFAULT_LEN) { // 8M
-		return nil, perrors.Errorf("Data length %d too large, max payload %d", pkgLen, DEFAULT_LEN)
+	if limit := getMaxPayloadSize(); pkgLen > limit {
+		return nil, perrors.Wrapf(ErrPayloadTooLarge, "Data length %d too large, max payload %d", pkgLen, limit)
 	}
 	// byteArray{body length}
 	binary.BigEndian.PutUint32(byteArray[12:], uint32(pkgLen-HEADER_LENGTH))
@@ -152,6 +199,32 @@ func packResponse(header DubboHeader, ret interface{}) ([]byte, error) {
 
 }
 
+// hessian decode the body of a response whose status is not Response_OK, which is an error message.
+// The error is set as the Exception of @resp if it is a *Response, otherwise it is returned.
+func unpackErrorResponseBody(buf []byte, status byte, resp interface{}) error {
+	decoder := NewDecoder(buf[:])
+	msg, err := decoder.Decode()
+	if err != nil {
+		return perrors.WithStack(err)
+	}
+
+	remoteErr := &DubboRemoteError{Status: status}
+	switch m := msg.(type) {
+	case nil:
+	case string:
+		remoteErr.Message = m
+	default:
+		remoteErr.Message = fmt.Sprintf("%+v", m)
+	}
+
+	response, ok := resp.(*Response)
+	if !ok {
+		return remoteErr
+	}
+	response.Exception = remoteErr
+	return nil
+}
+
 // hessian decode response body
 func unpackResponseBody(buf []byte, resp interface{}) error {
 	// body
@@ -170,16 +243,11 @@ func unpackResponseBody(buf []byte, resp interface{}) error {
 			return perrors.WithStack(err)
 		}
 		if rspType == RESPONSE_WITH_EXCEPTION_WITH_ATTACHMENTS {
-			attachments, err := decoder.Decode()
+			atta, err := decodeAttachments(decoder)
 			if err != nil {
-				return perrors.WithStack(err)
-			}
-			atta, ok := attachments.(map[string]string)
-			if ok {
-				response.Attachments = atta
-			} else {
-				return perrors.Errorf("get wrong attachments: %+v", atta)
+				return err
 			}
+			response.Attachments = atta
 		}
 
 		if e, ok := expt.(error); ok {
@@ -195,32 +263,26 @@ func unpackResponseBody(buf []byte, resp interface{}) error {
 			return perrors.WithStack(err)
 		}
 		if rspType == RESPONSE_VALUE_WITH_ATTACHMENTS {
-			attachments, err := decoder.Decode()
+			atta, err := decodeAttachments(decoder)
 			if err != nil {
-				return perrors.WithStack(err)
-			}
-			atta, ok := attachments.(map[string]string)
-			if ok {
-				response.Attachments = atta
-			} else {
-				return perrors.Errorf("get wrong attachments: %+v", atta)
+				return err
 			}
+			response.Attachments = atta
 		}
 
+		if rsp == nil {
+			// such as an empty java.util.Optional
+			return nil
+		}
 		return perrors.WithStack(ReflectResponse(rsp, response.RspObj))
 
 	case RESPONSE_NULL_VALUE, RESPONSE_NULL_VALUE_WITH_ATTACHMENTS:
 		if rspType == RESPONSE_NULL_VALUE_WITH_ATTACHMENTS {
-			attachments, err := decoder.Decode()
+			atta, err := decodeAttachments(decoder)
 			if err != nil {
-				return perrors.WithStack(err)
-			}
-			atta, ok := attachments.(map[string]string)
-			if ok {
-				response.Attachments = atta
-			} else {
-				return perrors.Errorf("get wrong attachments: %+v", atta)
+				return err
 			}
+			response.Attachments = atta
 		}
 		return nil
 	}
@@ -228,8 +290,42 @@ func unpackResponseBody(buf []byte, resp interface{}) error {
 	return nil
 }
 
-// CopySlice copy from inSlice to outSlice
+// stringFormMap holds the value object types which ReflectResponse assigns to a string by their string form.
+var stringFormMap = make(map[reflect.Type]func(interface{}) string, 8)
+
+// SetStringForm makes ReflectResponse assign a value of the type of @prototype, such as a *java_util.UUID,
+// to a string out parameter by its string form @form. The values of the other types, even a fmt.Stringer,
+// are not converted to a string. Like SetSerializer, it should be called in init.
+func SetStringForm(prototype interface{}, form func(interface{}) string) {
+	stringFormMap[reflect.TypeOf(prototype)] = form
+}
+
+// stringForm returns the string form of @in set by SetStringForm, or the name of a java enum.
+func stringForm(in interface{}) (string, bool) {
+	if enum, ok := in.(POJOEnum); ok {
+		return enum.String(), true
+	}
+	form, ok := stringFormMap[reflect.TypeOf(in)]
+	if !ok {
+		return "", false
+	}
+	if v := reflect.ValueOf(in); v.Kind() == reflect.Ptr && v.IsNil() {
+		return "", false
+	}
+	return form(in), true
+}
+
+// CopySlice copy from inSlice to outSlice.
+// The out slice is reused if it is not nil and its capacity is enough, otherwise a new slice is allocated,
+// so that an empty list is still copied into an empty slice rather than nil.
+// The elements are copied directly if they are assignable to the out element type, otherwise the
+// decoded lists and maps are converted by ReflectResponse, such as a []interface{} of maps into a []*Foo.
 func CopySlice(inSlice, outSlice reflect.Value) error {
+	return copySlice(inSlice, outSlice, "")
+}
+
+// copySlice is CopySlice of the slice at @path, which is used in the errors.
+func copySlice(inSlice, outSlice reflect.Value, path string) error {
 	if inSlice.IsNil() {
 		return perrors.New("@in is nil")
 	}
@@ -241,14 +337,58 @@ func CopySlice(inSlice, outSlice reflect.Value) error {
 		outSlice = outSlice.Elem()
 	}
 
+	// reuse the out slice if it has enough capacity like append
 	size := inSlice.Len()
-	outSlice.Set(reflect.MakeSlice(outSlice.Type(), size, size))
+	if outSlice.Kind() == reflect.Array {
+		// a fixed array receives a list of its length only
+		if outSlice.Len() != size {
+			return &ReflectError{Path: path, Err: perrors.Errorf(
+				"in slice of %d elements can not assign to out array type [%s]", size, outSlice.Type().String())}
+		}
+	} else if outSlice.Cap() >= size && !outSlice.IsNil() {
+		outSlice.SetLen(size)
+	} else {
+		outSlice.Set(reflect.MakeSlice(outSlice.Type(), size, size))
+	}
+
+	if inSlice.Type().Elem() == outSlice.Type().Elem() {
+		reflect.Copy(outSlice, inSlice)
+		return nil
+	}
 
 	for i := 0; i < size; i++ {
 		inSliceValue := inSlice.Index(i)
+		if inSliceValue.Kind() == reflect.Interface && outSlice.Index(i).Kind() != reflect.Interface {
+			// the element of a []interface{} can be assigned by its dynamic type
+			if inSliceValue.IsNil() {
+				outSlice.Index(i).Set(reflect.Zero(outSlice.Index(i).Type()))
+				continue
+			}
+			inSliceValue = inSliceValue.Elem()
+		}
+		if outType := outSlice.Index(i).Type(); isNumberKind(inSliceValue.Kind()) && isNumberKind(outType.Kind()) &&
+			inSliceValue.Type() != outType {
+			// a java Integer received as a go int64, or a small Long as an int32
+			v, err := convertValue(inSliceValue, outType, indexPath(path, i))
+			if err != nil {
+				return err
+			}
+			outSlice.Index(i).Set(v)
+			continue
+		}
 		if !inSliceValue.Type().AssignableTo(outSlice.Index(i).Type()) {
-			return perrors.Errorf("in element type [%s] can not assign to out element type [%s]",
-				inSliceValue.Type().String(), outSlice.Type().String())
+			// a decoded list or map element is converted like a response, such as a map into a *Foo
+			if isReflectResponseElement(inSliceValue) {
+				out := reflect.New(outSlice.Index(i).Type())
+				if err := reflectResponse(inSliceValue.Interface(), out.Interface(), indexPath(path, i), false); err != nil {
+					return err
+				}
+				outSlice.Index(i).Set(out.Elem())
+				continue
+			}
+			return &ReflectError{Path: indexPath(path, i), Err: perrors.Errorf(
+				"in element type [%s] can not assign to out element type [%s]",
+				inSliceValue.Type().String(), outSlice.Type().String())}
 		}
 		outSlice.Index(i).Set(inSliceValue)
 	}
@@ -256,11 +396,42 @@ func CopySlice(inSlice, outSlice reflect.Value) error {
 	return nil
 }
 
-// CopyMap copy from in map to out map
+// isReflectResponseElement returns whether the slice element @v which is not assignable to the out element
+// can be converted by reflectResponse, which are the decoded lists and maps.
+func isReflectResponseElement(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return false
+	}
+	if _, ok := v.Interface().(*OrderedMap); ok {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// CopyMap copy from in map to out map.
+// @inMapValue can be a *OrderedMap, whose order is lost in the out map.
+// The keys are converted to the key type of the out map, such as from int32 to int64,
+// from a decoded object pointer to a struct, or from an enum to its name.
+// The values which are not assignable to the out value type are converted like CopySlice does for the elements,
+// so that a nested generic type like Map<String, List<Foo>> is bound into a map[string][]*Foo at once.
 func CopyMap(inMapValue, outMapValue reflect.Value) error {
+	return copyMap(inMapValue, outMapValue, "")
+}
+
+// copyMap is CopyMap of the map at @path, which is used in the errors.
+func copyMap(inMapValue, outMapValue reflect.Value, path string) error {
 	if inMapValue.IsNil() {
 		return perrors.New("@in is nil")
 	}
+	if inMapValue.CanInterface() {
+		if m, ok := inMapValue.Interface().(*OrderedMap); ok {
+			inMapValue = reflect.ValueOf(m.ToMap())
+		}
+	}
 	if !inMapValue.CanInterface() {
 		return perrors.New("@in's Interface can not be used.")
 	}
@@ -279,23 +450,241 @@ func CopyMap(inMapValue, outMapValue reflect.Value) error {
 	for _, inKey := range inMapValue.MapKeys() {
 		inValue := inMapValue.MapIndex(inKey)
 
-		if !inKey.Type().AssignableTo(outKeyType) {
-			return perrors.Errorf("in Key:{type:%s, value:%#v} can not assign to out Key:{type:%s} ",
-				inKey.Type().String(), inKey, outKeyType.String())
+		// the values of a map[interface{}]interface{} can be assigned by their dynamic types
+		if inValue.Kind() == reflect.Interface && outValueType.Kind() != reflect.Interface {
+			if inValue.IsNil() {
+				inValue = reflect.Zero(outValueType)
+			} else {
+				inValue = inValue.Elem()
+			}
+		}
+
+		outKey, err := convertMapKey(inKey, outKeyType)
+		if err != nil {
+			return &ReflectError{Path: indexPath(path, inKey), Err: perrors.Wrapf(err,
+				"in Key:{type:%s, value:%#v} can not assign to out Key:{type:%s}",
+				inKey.Type().String(), inKey, outKeyType.String())}
+		}
+		if isNumberKind(inValue.Kind()) && isNumberKind(outValueType.Kind()) && inValue.Type() != outValueType {
+			// a java Integer received as a go int64, or a small Long as an int32
+			if inValue, err = convertValue(inValue, outValueType, indexPath(path, inKey)); err != nil {
+				return err
+			}
 		}
 		if !inValue.Type().AssignableTo(outValueType) {
-			return perrors.Errorf("in Value:{type:%s, value:%#v} can not assign to out value:{type:%s}",
-				inValue.Type().String(), inValue, outValueType.String())
+			// a decoded list or map value is converted like a response, such as a []interface{} of maps into a []*Foo
+			if isReflectResponseElement(inValue) {
+				out := reflect.New(outValueType)
+				if err = reflectResponse(inValue.Interface(), out.Interface(), indexPath(path, inKey), false); err != nil {
+					return err
+				}
+				outMapValue.SetMapIndex(outKey, out.Elem())
+				continue
+			}
+			return &ReflectError{Path: indexPath(path, inKey), Err: perrors.Errorf(
+				"in Value:{type:%s, value:%#v} can not assign to out value:{type:%s}",
+				inValue.Type().String(), inValue, outValueType.String())}
 		}
-		outMapValue.SetMapIndex(inKey, inValue)
+		outMapValue.SetMapIndex(outKey, inValue)
 	}
 
 	return nil
 }
 
+// CopyMapToStruct sets the fields of the struct @outStructValue by the entries of the map @inMapValue,
+// whose string keys are matched with the field names like the fields of a class definition.
+// The keys matching no field are ignored, and the fields matching no key are left zero.
+// The values are converted to the field types like MapToStruct.
+func CopyMapToStruct(inMapValue, outStructValue reflect.Value) error {
+	return copyMapToStruct(inMapValue, outStructValue, "", false)
+}
+
+// copyMapToStruct is CopyMapToStruct of the struct at @path, which is used in the errors.
+// The fields which no key sets, or which are set by nulls, keep the values of @outStructValue if @keep is true.
+func copyMapToStruct(inMapValue, outStructValue reflect.Value, path string, keep bool) error {
+	if inMapValue.CanInterface() {
+		if m, ok := inMapValue.Interface().(*OrderedMap); ok {
+			inMapValue = reflect.ValueOf(m.ToMap())
+		}
+	}
+	inMapValue = UnpackPtrValue(inMapValue)
+	if inMapValue.Kind() == reflect.Interface {
+		inMapValue = inMapValue.Elem()
+	}
+	if inMapValue.Kind() != reflect.Map {
+		return perrors.Errorf("@in is not map, but %v", inMapValue.Kind())
+	}
+
+	outStructType := UnpackPtrType(outStructValue.Type())
+	if outStructType.Kind() != reflect.Struct {
+		return perrors.Errorf("@out is not struct, but %v", outStructType.Kind())
+	}
+	outValue := reflect.New(outStructType).Elem()
+	if current := UnpackPtrValue(outStructValue); keep && current.Kind() == reflect.Struct {
+		// bind into a copy of the struct of the caller
+		outValue.Set(current)
+	}
+
+	for _, inKey := range inMapValue.MapKeys() {
+		key, ok := inKey.Interface().(string)
+		if !ok {
+			continue
+		}
+		index, err := findField(key, outStructType)
+		if err != nil {
+			continue
+		}
+		if outStructType.FieldByIndex(index).PkgPath != "" {
+			continue
+		}
+
+		inValue := inMapValue.MapIndex(inKey)
+		if inValue.Kind() == reflect.Interface {
+			if inValue.IsNil() {
+				continue
+			}
+			inValue = inValue.Elem()
+		}
+
+		field := fieldByIndex(outValue, index)
+		v, err := convertValue(inValue, field.Type(), fieldPath(path, key))
+		if err != nil {
+			return err
+		}
+		field.Set(v)
+	}
+
+	SetValue(outStructValue, outValue)
+	return nil
+}
+
+// MapToStruct sets the fields of the struct pointed by @out by the entries of @m, such as the
+// Fields of a *GenericObject decoded from an unregistered java class, like CopyMapToStruct.
+// The values are converted to the types of the fields recursively: a number is converted to
+// another number type if it loses nothing, a map or a *GenericObject is bound into a struct
+// or a struct pointer, and the elements of a list and the entries of a map are converted into
+// the element types of a go slice or map, such as []*Item.
+func MapToStruct(m map[string]interface{}, out interface{}) error {
+	outValue := reflect.ValueOf(out)
+	if outValue.Kind() != reflect.Ptr || outValue.IsNil() {
+		return perrors.Errorf("@out should be a non-nil pointer to struct, but %T", out)
+	}
+	return copyMapToStruct(reflect.ValueOf(m), outValue, "", false)
+}
+
+// convertValue converts the decoded value @in at @path to type @outType for MapToStruct and CopyMapToStruct.
+func convertValue(in reflect.Value, outType reflect.Type, path string) (reflect.Value, error) {
+	for in.Kind() == reflect.Interface && !in.IsNil() {
+		in = in.Elem()
+	}
+	if !in.IsValid() || ((in.Kind() == reflect.Interface || in.Kind() == reflect.Ptr) && in.IsNil()) {
+		return reflect.Zero(outType), nil
+	}
+	if in.Type().AssignableTo(outType) {
+		return in, nil
+	}
+	if in.CanInterface() {
+		switch m := in.Interface().(type) {
+		case *OrderedMap:
+			in = reflect.ValueOf(m.ToMap())
+		case *GenericObject:
+			in = reflect.ValueOf(m.Fields)
+		}
+	}
+
+	switch {
+	case outType.Kind() == reflect.Ptr:
+		v, err := convertValue(in, outType.Elem(), path)
+		if err != nil {
+			return v, err
+		}
+		p := reflect.New(outType.Elem())
+		p.Elem().Set(v)
+		return p, nil
+
+	case in.Kind() == reflect.Ptr:
+		return convertValue(in.Elem(), outType, path)
+
+	case isNumberKind(in.Kind()) && isNumberKind(outType.Kind()):
+		negative := (validateIntKind(in.Kind()) && in.Int() < 0) || (validateFloatKind(in.Kind()) && in.Float() < 0)
+		out := in.Convert(outType)
+		if !(negative && validateUintKind(outType.Kind())) && sameNumber(out.Convert(in.Type()), in) {
+			return out, nil
+		}
+		if !validateFloatKind(in.Kind()) && !validateFloatKind(outType.Kind()) {
+			return reflect.Value{}, &ReflectError{Path: path, Err: perrors.Wrapf(ErrIntOverflow,
+				"in Value:{type:%s, value:%#v} can not assign to out type %s", in.Type().String(), in, outType.String())}
+		}
+
+	case in.Kind() == reflect.String && (outType.Kind() == reflect.Int32 || outType.Kind() == reflect.Uint16):
+		// a java char
+		if r, err := stringChar(in.String()); err == nil && (outType.Kind() == reflect.Int32 || r <= 0xffff) {
+			return reflect.ValueOf(r).Convert(outType), nil
+		}
+
+	case outType.Kind() == reflect.Struct && in.Kind() == reflect.Map:
+		out := reflect.New(outType)
+		if err := copyMapToStruct(in, out, path, false); err != nil {
+			return reflect.Value{}, err
+		}
+		return out.Elem(), nil
+
+	case outType.Kind() == reflect.Slice && (in.Kind() == reflect.Slice || in.Kind() == reflect.Array):
+		out := reflect.MakeSlice(outType, in.Len(), in.Len())
+		for i := 0; i < in.Len(); i++ {
+			v, err := convertValue(in.Index(i), outType.Elem(), indexPath(path, i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(v)
+		}
+		return out, nil
+
+	case outType.Kind() == reflect.Map && in.Kind() == reflect.Map:
+		out := reflect.MakeMapWithSize(outType, in.Len())
+		for _, key := range in.MapKeys() {
+			k, err := convertMapKey(key, outType.Key())
+			if err != nil {
+				return reflect.Value{}, &ReflectError{Path: indexPath(path, key), Err: err}
+			}
+			v, err := convertValue(in.MapIndex(key), outType.Elem(), indexPath(path, key))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(k, v)
+		}
+		return out, nil
+	}
+
+	return reflect.Value{}, &ReflectError{Path: path, Err: perrors.Errorf(
+		"in Value:{type:%s, value:%#v} can not assign to out type %s", in.Type().String(), in, outType.String())}
+}
+
 // ReflectResponse reflect return value
+// An @out implementing sql.Scanner, such as *sql.NullString, *sql.NullInt64, *sql.NullBool and *sql.NullTime,
+// is set by its Scan, so that a null is received as Valid=false and a value as Valid=true with the value.
 // TODO response object should not be copied again to another object, it should be the exact type of the object
 func ReflectResponse(in interface{}, out interface{}) error {
+	return reflectResponse(in, out, "", false)
+}
+
+// ReflectResponse is ReflectResponse with the null policy of the decoder. With SetSkipNull, a map bound into
+// the struct pointed by @out leaves the fields of the null values and of the absent keys as the caller set them,
+// such as the defaults, instead of binding the map into a zero struct.
+func (d *Decoder) ReflectResponse(in interface{}, out interface{}) error {
+	return reflectResponse(in, out, "", d.skipNull)
+}
+
+// reflectResponse is ReflectResponse of the value at @path, which is used in the errors.
+// The struct pointed by @out keeps its fields which are not set by a map if @keep is true.
+func reflectResponse(in interface{}, out interface{}, path string, keep bool) error {
+	if scanner, ok := out.(sql.Scanner); ok {
+		if err := scanner.Scan(in); err != nil {
+			return &ReflectError{Path: path, Err: perrors.WithStack(err)}
+		}
+		return nil
+	}
+
 	if in == nil {
 		return perrors.Errorf("@in is nil")
 	}
@@ -316,11 +705,52 @@ func ReflectResponse(in interface{}, out interface{}) error {
 		return nil
 	}
 
+	// value objects like java.math.BigDecimal can be received as their string form
+	if s, ok := stringForm(in); ok && UnpackPtrType(outValue.Type()).Kind() == reflect.String {
+		SetValue(outValue, reflect.ValueOf(s))
+		return nil
+	}
+
+	// a java char can be received as a go rune
+	if s, ok := in.(string); ok {
+		if outType := UnpackPtrType(outValue.Type()); outType.Kind() == reflect.Int32 || outType.Kind() == reflect.Uint16 {
+			v, err := convertValue(reflect.ValueOf(s), outType, path)
+			if err != nil {
+				return err
+			}
+			SetValue(outValue, v)
+			return nil
+		}
+	}
+
+	// a java Integer can be received as a go int64, and a small Long as an int32
+	if outType := UnpackPtrType(outValue.Type()); isNumberKind(inValue.Kind()) && isNumberKind(outType.Kind()) &&
+		inValue.Type() != outType {
+		v, err := convertValue(inValue, outType, path)
+		if err != nil {
+			return err
+		}
+		SetValue(outValue, v)
+		return nil
+	}
+
+	// an ordered map can be received as a go map
+	if _, ok := in.(*OrderedMap); ok && UnpackPtrType(outValue.Type()).Kind() == reflect.Map {
+		return copyMap(inValue, outValue, path)
+	}
+
+	// a java map can be received as a go struct by its keys
+	if _, ok := in.(*OrderedMap); ok || inValue.Kind() == reflect.Map {
+		if UnpackPtrType(outValue.Type()).Kind() == reflect.Struct {
+			return copyMapToStruct(inValue, outValue, path, keep)
+		}
+	}
+
 	switch inValue.Type().Kind() {
 	case reflect.Slice, reflect.Array:
-		return CopySlice(inValue, outValue)
+		return copySlice(inValue, outValue, path)
 	case reflect.Map:
-		return CopyMap(inValue, outValue)
+		return copyMap(inValue, outValue, path)
 	default:
 		SetValue(outValue, inValue)
 	}
@@ -15,10 +15,16 @@
 package hessian
 
 import (
+	"database/sql"
+	"errors"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 import (
+	perrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -215,3 +221,333 @@ func TestVersion2Int(t *testing.T) {
 	assert.Equal(t, 201030405, v)
 
 }
+
+func TestCopySliceOfInterface(t *testing.T) {
+	c1, c2 := &Case{A: "a", B: 1}, &Case{A: "b", B: 2}
+
+	var out []*Case
+	assert.Nil(t, ReflectResponse([]interface{}{c1, nil, c2}, &out))
+	assert.Equal(t, []*Case{c1, nil, c2}, out)
+
+	var wrong []*Case
+	assert.NotNil(t, ReflectResponse([]interface{}{c1, "c2"}, &wrong))
+}
+
+func TestCopySliceConvertElements(t *testing.T) {
+	// the maps of a generically decoded list
+	in := []interface{}{
+		map[interface{}]interface{}{"name": "a", "user_age": int32(1)},
+		nil,
+		map[interface{}]interface{}{"name": "b"},
+	}
+	var out []*mapDTO
+	assert.Nil(t, CopySlice(reflect.ValueOf(in), reflect.ValueOf(&out)))
+	assert.Equal(t, []*mapDTO{{Name: "a", Age: 1}, nil, {Name: "b"}}, out)
+
+	// the nested lists of maps and ordered maps
+	m := NewOrderedMap("")
+	m.Put("name", "c")
+	nested := []interface{}{[]interface{}{in[0]}, []interface{}{m}}
+	var values [][]mapDTO
+	assert.Nil(t, ReflectResponse(nested, &values))
+	assert.Equal(t, [][]mapDTO{{{Name: "a", Age: 1}}, {{Name: "c"}}}, values)
+
+	var reflectErr *ReflectError
+	err := ReflectResponse([]interface{}{map[string]interface{}{"name": 1}}, &out)
+	assert.True(t, errors.As(err, &reflectErr))
+	assert.Equal(t, "[0].name", reflectErr.Path)
+}
+
+func TestCopyMapNestedGenerics(t *testing.T) {
+	// the Map<String, List<Foo>> and Map<String, Map<String, Foo>> of an unregistered Foo
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(map[string]interface{}{
+		"a": []interface{}{map[string]interface{}{"name": "a1", "user_age": int32(1)}, nil},
+	}))
+	assert.Nil(t, e.Encode(map[string]interface{}{
+		"a": map[string]interface{}{"x": map[string]interface{}{"name": "ax"}, "y": nil},
+	}))
+	d := NewDecoder(e.Buffer())
+
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	var lists map[string][]*mapDTO
+	assert.Nil(t, ReflectResponse(res, &lists))
+	assert.Equal(t, map[string][]*mapDTO{"a": {{Name: "a1", Age: 1}, nil}}, lists)
+
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	var maps map[string]map[string]*mapDTO
+	assert.Nil(t, CopyMap(reflect.ValueOf(res), reflect.ValueOf(&maps)))
+	assert.Equal(t, map[string]map[string]*mapDTO{"a": {"x": {Name: "ax"}, "y": nil}}, maps)
+
+	var reflectErr *ReflectError
+	err = ReflectResponse(map[interface{}]interface{}{
+		"a": []interface{}{nil, map[interface{}]interface{}{"name": 1}},
+	}, &lists)
+	assert.True(t, errors.As(err, &reflectErr))
+	assert.Equal(t, "[a][1].name", reflectErr.Path)
+}
+
+func TestReflectResponseBoxedNumbers(t *testing.T) {
+	var longs map[string]int64
+	assert.Nil(t, CopyMap(reflect.ValueOf(map[interface{}]interface{}{"a": int32(1), "b": int64(2)}), reflect.ValueOf(&longs)))
+	assert.Equal(t, map[string]int64{"a": 1, "b": 2}, longs)
+
+	var ints map[string]int32
+	assert.Nil(t, ReflectResponse(map[interface{}]interface{}{"a": int64(1)}, &ints))
+	assert.Equal(t, map[string]int32{"a": 1}, ints)
+	err := ReflectResponse(map[interface{}]interface{}{"a": int64(math.MaxInt32 + 1)}, &ints)
+	assert.Equal(t, ErrIntOverflow, perrors.Cause(err))
+
+	var list []int32
+	assert.Nil(t, ReflectResponse([]interface{}{int64(1), int32(2)}, &list))
+	assert.Equal(t, []int32{1, 2}, list)
+	assert.Equal(t, ErrIntOverflow, perrors.Cause(ReflectResponse([]int64{math.MinInt64}, &list)))
+
+	var total int64
+	assert.Nil(t, ReflectResponse(int32(3), &total))
+	assert.Equal(t, int64(3), total)
+	var count int32
+	assert.Nil(t, ReflectResponse(int64(4), &count))
+	assert.Equal(t, int32(4), count)
+	assert.Equal(t, ErrIntOverflow, perrors.Cause(ReflectResponse(int64(math.MaxInt64), &count)))
+}
+
+type mapDTO struct {
+	Name    string
+	Age     int32 `hessian:"user_age"`
+	Case    *Case
+	Tags    []string
+	Missing string
+}
+
+func TestReflectResponseMapToStruct(t *testing.T) {
+	c := &Case{A: "a", B: 1}
+	in := map[interface{}]interface{}{
+		"name":     "dto",
+		"user_age": int32(18),
+		"case":     c,
+		"tags":     []string{"x"},
+		"unknown":  "ignored",
+		1:          "not a field",
+	}
+
+	var out mapDTO
+	assert.Nil(t, ReflectResponse(in, &out))
+	assert.Equal(t, mapDTO{Name: "dto", Age: 18, Case: c, Tags: []string{"x"}}, out)
+
+	// an ordered map from java.util.LinkedHashMap
+	m := NewOrderedMap("")
+	m.Put("name", "ordered")
+	var out2 mapDTO
+	assert.Nil(t, ReflectResponse(m, &out2))
+	assert.Equal(t, "ordered", out2.Name)
+
+	// the value is decoded from a java map
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(map[string]interface{}{"name": "java", "user_age": int32(1)}))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	var out3 *mapDTO
+	assert.Nil(t, ReflectResponse(res, &out3))
+	assert.Equal(t, &mapDTO{Name: "java", Age: 1}, out3)
+
+	// a value which is not assignable to the field
+	assert.NotNil(t, ReflectResponse(map[string]interface{}{"name": 1}, &mapDTO{}))
+}
+
+func TestReflectResponseErrorPath(t *testing.T) {
+	var reflectErr *ReflectError
+
+	var cases []*Case
+	err := ReflectResponse([]interface{}{&Case{}, &Case{}, &Case{}, "c"}, &cases)
+	assert.True(t, errors.As(err, &reflectErr))
+	assert.Equal(t, "[3]", reflectErr.Path)
+	assert.True(t, strings.HasPrefix(err.Error(), "[3]: in element type [string] can not assign"), err.Error())
+
+	var m map[string]int32
+	err = ReflectResponse(map[interface{}]interface{}{"price": "1"}, &m)
+	assert.True(t, errors.As(err, &reflectErr))
+	assert.Equal(t, "[price]", reflectErr.Path)
+
+	err = ReflectResponse(map[string]interface{}{"user_age": "18"}, &mapDTO{})
+	assert.True(t, errors.As(err, &reflectErr))
+	assert.Equal(t, "user_age", reflectErr.Path)
+
+	// the path of a value in a nested list of structs
+	items := []interface{}{}
+	for i := 0; i < 3; i++ {
+		items = append(items, map[interface{}]interface{}{"count": int32(i)})
+	}
+	items = append(items, map[interface{}]interface{}{"count": "3"})
+	var holder mapToStructHolder
+	err = ReflectResponse(map[interface{}]interface{}{"order": map[interface{}]interface{}{"items": items}}, &holder)
+	assert.True(t, errors.As(err, &reflectErr))
+	assert.Equal(t, "order.items[3].count", reflectErr.Path)
+}
+
+type mapToStructItem struct {
+	SKU   string
+	Count int
+}
+
+type mapToStructOrder struct {
+	ID      uint64
+	Price   float64
+	Buyer   *mapDTO
+	Items   []*mapToStructItem
+	Lines   []mapToStructItem
+	Counts  map[string]int
+	Ignored string `hessian:"-"`
+}
+
+type mapToStructHolder struct {
+	Order *mapToStructOrder
+}
+
+func TestMapToStruct(t *testing.T) {
+	m := map[string]interface{}{
+		"iD":    int64(7),
+		"price": int32(12),
+		"buyer": map[interface{}]interface{}{"name": "Alice", "user_age": int64(18), "tags": []interface{}{"vip"}},
+		"items": []interface{}{
+			map[interface{}]interface{}{"sKU": "a", "count": int32(1)},
+			nil,
+			map[interface{}]interface{}{"sKU": "b", "count": int64(2)},
+		},
+		"lines":   []interface{}{&GenericObject{ClassName: "com.foo.Line", Fields: map[string]interface{}{"sKU": "c", "count": int32(3)}}},
+		"counts":  map[interface{}]interface{}{"a": int32(1), "b": nil},
+		"ignored": "x",
+		"unknown": "y",
+	}
+	var order mapToStructOrder
+	assert.Nil(t, MapToStruct(m, &order))
+	assert.Equal(t, mapToStructOrder{
+		ID:     7,
+		Price:  12,
+		Buyer:  &mapDTO{Name: "Alice", Age: 18, Tags: []string{"vip"}},
+		Items:  []*mapToStructItem{{SKU: "a", Count: 1}, nil, {SKU: "b", Count: 2}},
+		Lines:  []mapToStructItem{{SKU: "c", Count: 3}},
+		Counts: map[string]int{"a": 1, "b": 0},
+	}, order)
+
+	var reflectErr *ReflectError
+	err := MapToStruct(map[string]interface{}{"items": []interface{}{map[interface{}]interface{}{"count": "1"}}}, &order)
+	assert.True(t, errors.As(err, &reflectErr))
+	assert.Equal(t, "items[0].count", reflectErr.Path)
+
+	// the numbers which can not be converted without loss
+	for _, v := range []interface{}{int64(-1), 1.5} {
+		err = MapToStruct(map[string]interface{}{"iD": v}, &order)
+		assert.True(t, errors.As(err, &reflectErr), "%v", v)
+		assert.Equal(t, "iD", reflectErr.Path)
+	}
+	err = MapToStruct(map[string]interface{}{"user_age": int64(1) << 40}, &mapDTO{})
+	assert.True(t, errors.As(err, &reflectErr))
+
+	assert.NotNil(t, MapToStruct(m, order))
+}
+
+func TestCopySliceReuse(t *testing.T) {
+	out := make([]string, 1, 4)
+	buf := out[:4]
+	assert.Nil(t, ReflectResponse([]interface{}{"a", nil, "c"}, &out))
+	assert.Equal(t, []string{"a", "", "c"}, out)
+	// the out slice is reused
+	assert.True(t, &buf[0] == &out[0])
+
+	// the stale elements are cleared
+	assert.Nil(t, ReflectResponse([]interface{}{nil, "b"}, &out))
+	assert.Equal(t, []string{"", "b"}, out)
+	assert.True(t, &buf[0] == &out[0])
+
+	// grown like append
+	assert.Nil(t, ReflectResponse([]string{"a", "b", "c", "d", "e"}, &out))
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, out)
+	assert.False(t, &buf[0] == &out[0])
+
+	// an empty list is copied into an empty slice rather than nil
+	var empty []string
+	assert.Nil(t, ReflectResponse([]interface{}{}, &empty))
+	assert.NotNil(t, empty)
+	assert.Equal(t, []string{}, empty)
+
+	var lists map[string][]string
+	assert.Nil(t, ReflectResponse(map[interface{}]interface{}{"a": []interface{}{}}, &lists))
+	assert.Equal(t, map[string][]string{"a": {}}, lists)
+}
+
+func benchmarkCopySlice(b *testing.B, reuse bool) {
+	in := make([]interface{}, 1024)
+	for i := range in {
+		in[i] = int64(i)
+	}
+	var out []int64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !reuse {
+			out = nil
+		}
+		if err := ReflectResponse(in, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCopySliceNew(b *testing.B) {
+	benchmarkCopySlice(b, false)
+}
+
+func BenchmarkCopySliceReuse(b *testing.B) {
+	benchmarkCopySlice(b, true)
+}
+
+func TestReflectResponseSqlNull(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, c := range []struct {
+		in       interface{}
+		out      sql.Scanner
+		expected sql.Scanner
+	}{
+		{nil, &sql.NullString{String: "stale", Valid: true}, &sql.NullString{}},
+		{"a", &sql.NullString{}, &sql.NullString{String: "a", Valid: true}},
+		{nil, &sql.NullInt64{Int64: 1, Valid: true}, &sql.NullInt64{}},
+		{int64(7), &sql.NullInt64{}, &sql.NullInt64{Int64: 7, Valid: true}},
+		{int32(8), &sql.NullInt64{}, &sql.NullInt64{Int64: 8, Valid: true}},
+		{nil, &sql.NullBool{Bool: true, Valid: true}, &sql.NullBool{}},
+		{true, &sql.NullBool{}, &sql.NullBool{Bool: true, Valid: true}},
+		{nil, &sql.NullTime{Time: now, Valid: true}, &sql.NullTime{}},
+		{now, &sql.NullTime{}, &sql.NullTime{Time: now, Valid: true}},
+		{3.5, &sql.NullFloat64{}, &sql.NullFloat64{Float64: 3.5, Valid: true}},
+	} {
+		assert.Nil(t, ReflectResponse(c.in, c.out))
+		assert.Equal(t, c.expected, c.out)
+	}
+
+	// the values decoded from hessian
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(nil))
+	assert.Nil(t, e.Encode(int32(9)))
+	d := NewDecoder(e.Buffer())
+	var (
+		absent  sql.NullInt64
+		present sql.NullInt64
+	)
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Nil(t, ReflectResponse(res, &absent))
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Nil(t, ReflectResponse(res, &present))
+	assert.Equal(t, sql.NullInt64{}, absent)
+	assert.Equal(t, sql.NullInt64{Int64: 9, Valid: true}, present)
+
+	var b sql.NullBool
+	err = ReflectResponse("not a bool", &b)
+	assert.NotNil(t, err)
+	var reflectErr *ReflectError
+	assert.True(t, errors.As(err, &reflectErr))
+}
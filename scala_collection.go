@@ -0,0 +1,387 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// Scala collections don't implement java.util.Collection or java.util.Map, so hessian writes them
+// field by field as the objects of their implementation classes. ScalaSerializer decodes the immutable
+// List, Vector and Map of scala 2.12 from these objects into a []interface{} or a map[interface{}]interface{},
+// like the java collections.
+func init() {
+	for _, pojo := range []POJO{
+		&scalaCons{}, &scalaNil{}, &scalaVector{},
+		&scalaEmptyMap{}, &scalaMap1{}, &scalaMap2{}, &scalaMap3{}, &scalaMap4{},
+		&scalaEmptyHashMap{}, &scalaHashMap1{}, &scalaHashTrieMap{},
+	} {
+		RegisterPOJO(pojo)
+		SetSerializer(pojo.JavaClassName(), ScalaSerializer{})
+	}
+}
+
+// scalaCons is a cell of scala.collection.immutable.List, the tail of which is the next cell or Nil.
+// The tail is named tl before scala 2.13 and next since then.
+type scalaCons struct {
+	Head interface{} `hessian:"head"`
+	Tl   interface{} `hessian:"tl"`
+	Next interface{} `hessian:"next"`
+}
+
+func (scalaCons) JavaClassName() string {
+	return "scala.collection.immutable.$colon$colon"
+}
+
+// scalaNil is the empty scala.collection.immutable.List.
+type scalaNil struct{}
+
+func (scalaNil) JavaClassName() string {
+	return "scala.collection.immutable.Nil$"
+}
+
+// scalaVector is a scala.collection.immutable.Vector, the elements of which are the leaves of a trie
+// of arrays of 32 entries. The root is display0 for a vector of depth 1, display1 for depth 2 and so on,
+// and the elements are the indexes startIndex until endIndex of the trie.
+type scalaVector struct {
+	StartIndex int32         `hessian:"startIndex"`
+	EndIndex   int32         `hessian:"endIndex"`
+	Depth      int32         `hessian:"depth"`
+	Display0   []interface{} `hessian:"display0"`
+	Display1   []interface{} `hessian:"display1"`
+	Display2   []interface{} `hessian:"display2"`
+	Display3   []interface{} `hessian:"display3"`
+	Display4   []interface{} `hessian:"display4"`
+	Display5   []interface{} `hessian:"display5"`
+}
+
+func (scalaVector) JavaClassName() string {
+	return "scala.collection.immutable.Vector"
+}
+
+// scalaEmptyMap is the empty scala.collection.immutable.Map.
+type scalaEmptyMap struct{}
+
+func (scalaEmptyMap) JavaClassName() string {
+	return "scala.collection.immutable.Map$EmptyMap$"
+}
+
+// scalaMap1 to scalaMap4 are the scala.collection.immutable.Map of up to 4 entries.
+type scalaMap1 struct {
+	Key1   interface{} `hessian:"key1"`
+	Value1 interface{} `hessian:"value1"`
+}
+
+func (scalaMap1) JavaClassName() string {
+	return "scala.collection.immutable.Map$Map1"
+}
+
+type scalaMap2 struct {
+	Key1   interface{} `hessian:"key1"`
+	Value1 interface{} `hessian:"value1"`
+	Key2   interface{} `hessian:"key2"`
+	Value2 interface{} `hessian:"value2"`
+}
+
+func (scalaMap2) JavaClassName() string {
+	return "scala.collection.immutable.Map$Map2"
+}
+
+type scalaMap3 struct {
+	Key1   interface{} `hessian:"key1"`
+	Value1 interface{} `hessian:"value1"`
+	Key2   interface{} `hessian:"key2"`
+	Value2 interface{} `hessian:"value2"`
+	Key3   interface{} `hessian:"key3"`
+	Value3 interface{} `hessian:"value3"`
+}
+
+func (scalaMap3) JavaClassName() string {
+	return "scala.collection.immutable.Map$Map3"
+}
+
+type scalaMap4 struct {
+	Key1   interface{} `hessian:"key1"`
+	Value1 interface{} `hessian:"value1"`
+	Key2   interface{} `hessian:"key2"`
+	Value2 interface{} `hessian:"value2"`
+	Key3   interface{} `hessian:"key3"`
+	Value3 interface{} `hessian:"value3"`
+	Key4   interface{} `hessian:"key4"`
+	Value4 interface{} `hessian:"value4"`
+}
+
+func (scalaMap4) JavaClassName() string {
+	return "scala.collection.immutable.Map$Map4"
+}
+
+// scalaEmptyHashMap, scalaHashMap1 and scalaHashTrieMap are the nodes of a scala.collection.immutable.HashMap
+// of more than 4 entries, a trie node holds the child nodes in elems.
+type scalaEmptyHashMap struct{}
+
+func (scalaEmptyHashMap) JavaClassName() string {
+	return "scala.collection.immutable.HashMap$EmptyHashMap$"
+}
+
+type scalaHashMap1 struct {
+	Key   interface{} `hessian:"key"`
+	Hash  int32       `hessian:"hash"`
+	Value interface{} `hessian:"value"`
+}
+
+func (scalaHashMap1) JavaClassName() string {
+	return "scala.collection.immutable.HashMap$HashMap1"
+}
+
+type scalaHashTrieMap struct {
+	Bitmap int32         `hessian:"bitmap"`
+	Elems  []interface{} `hessian:"elems"`
+	Size0  int32         `hessian:"size0"`
+}
+
+func (scalaHashTrieMap) JavaClassName() string {
+	return "scala.collection.immutable.HashMap$HashTrieMap"
+}
+
+// ScalaSerializer decodes the scala immutable List and Vector into a []interface{}, and the scala immutable
+// Map into a map[interface{}]interface{}. A List is a chain of cells nested in each other on the wire,
+// which are decoded one after another instead of one in another, so the length of a list is not limited
+// by the max depth of the decoder.
+type ScalaSerializer struct {
+	objectSerializer
+}
+
+func (ScalaSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	if cls.javaName == (scalaCons{}).JavaClassName() {
+		if list, ok, err := d.decScalaList(cls.classInfo); ok {
+			return list, err
+		}
+	}
+	return decInstanceAs(d, typ, cls, scalaCollection)
+}
+
+// decScalaList decodes the cells of a scala list from the one of class definition @cls, whose fields have been
+// read, to the last one, as long as the tail of a cell is the next cell. The ref of a cell refers to the list
+// from the cell on. Nothing is read and false is returned if the tail is not the last field of the cells,
+// since the fields after it would follow the whole tail on the wire.
+func (d *Decoder) decScalaList(cls classInfo) ([]interface{}, bool, error) {
+	n := len(cls.fieldNameList)
+	if n == 0 || (cls.fieldNameList[n-1] != "tl" && cls.fieldNameList[n-1] != "next") {
+		return nil, false, nil
+	}
+
+	var (
+		heads      []interface{}
+		refIndexes []int
+	)
+	for {
+		refIndexes = append(refIndexes, len(d.refs))
+		d.appendRefs(nil)
+
+		var head interface{}
+		for _, fieldName := range cls.fieldNameList[:n-1] {
+			v, err := d.Decode()
+			if err != nil {
+				return nil, true, perrors.Wrapf(err, "failed to decode field %s of a scala list", fieldName)
+			}
+			if fieldName == "head" {
+				head = v
+			}
+		}
+		heads = append(heads, head)
+
+		next, err := d.readInstanceOf(cls)
+		if err != nil {
+			return nil, true, err
+		}
+		if !next {
+			break
+		}
+		// the cells are at the depth of the first one, which has been entered and counted by the decoder
+		if err = d.addElements(n); err != nil {
+			return nil, true, err
+		}
+	}
+
+	tail, err := d.Decode()
+	if err != nil {
+		return nil, true, perrors.Wrapf(err, "failed to decode field %s of a scala list", cls.fieldNameList[n-1])
+	}
+	rest, ok := tail.([]interface{})
+	if tail != nil && !ok {
+		return nil, true, perrors.Errorf("the tail %T of a scala list is not a list", tail)
+	}
+	list := append(heads, rest...)
+	for i, refIndex := range refIndexes {
+		d.refs[refIndex] = list[i:]
+	}
+	return list, true, nil
+}
+
+// readInstanceOf reads the tag and the class index of the next value if it is an object instance of a class
+// definition like @cls, and returns false without reading anything if it is not.
+func (d *Decoder) readInstanceOf(cls classInfo) (bool, error) {
+	var (
+		buf [6]byte
+		idx int32
+		n   = 1
+	)
+	switch tag := d.peekByte(); {
+	case tag >= BC_OBJECT_DIRECT && tag <= BC_OBJECT_DIRECT+OBJECT_DIRECT_MAX:
+		idx = int32(tag - BC_OBJECT_DIRECT)
+	case tag == BC_OBJECT:
+		// the class index is an int like decInt32 reads
+		p := d.peek(2)
+		if len(p) < 2 {
+			return false, nil
+		}
+		switch tag := p[1]; {
+		case tag >= 0x80 && tag <= 0xbf:
+			n = 2
+		case tag >= 0xc0 && tag <= 0xcf:
+			n = 3
+		case tag >= 0xd0 && tag <= 0xd7:
+			n = 4
+		case tag == BC_INT:
+			n = 6
+		default:
+			return false, nil
+		}
+		if p = d.peek(n); len(p) < n {
+			return false, nil
+		}
+		switch n {
+		case 2:
+			idx = int32(int8(p[1] - BC_INT_ZERO))
+		case 3:
+			idx = int32(int8(p[1]-BC_INT_BYTE_ZERO))<<8 | int32(p[2])
+		case 4:
+			idx = int32(int8(p[1]-BC_INT_SHORT_ZERO))<<16 | int32(p[2])<<8 | int32(p[3])
+		default:
+			idx = UnpackInt32(p[2:])
+		}
+	default:
+		return false, nil
+	}
+
+	if idx < 0 || int(idx) >= len(d.classInfoList) || !sameClassDef(d.classInfoList[idx], cls) {
+		return false, nil
+	}
+	_, err := d.readFull(buf[:n])
+	return true, perrors.WithStack(err)
+}
+
+// sameClassDef reports whether the class definitions @a and @b are of the same class and fields.
+func sameClassDef(a, b classInfo) bool {
+	if a.javaName != b.javaName || len(a.fieldNameList) != len(b.fieldNameList) {
+		return false
+	}
+	for i := range a.fieldNameList {
+		if a.fieldNameList[i] != b.fieldNameList[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scalaCollection converts a decoded scala collection @v to a []interface{} or a map[interface{}]interface{}.
+func scalaCollection(v interface{}) (interface{}, error) {
+	var (
+		result interface{}
+		err    error
+	)
+	switch c := v.(type) {
+	case *scalaNil:
+		result = []interface{}{}
+	case *scalaCons:
+		tail := c.Next
+		if tail == nil {
+			tail = c.Tl
+		}
+		rest, ok := tail.([]interface{})
+		if tail != nil && !ok {
+			return nil, perrors.Errorf("the tail %T of a scala list is not a list", tail)
+		}
+		result = append([]interface{}{c.Head}, rest...)
+	case *scalaVector:
+		if result, err = c.elements(); err != nil {
+			return nil, err
+		}
+	case *scalaEmptyMap, *scalaEmptyHashMap:
+		result = map[interface{}]interface{}{}
+	case *scalaMap1:
+		result = map[interface{}]interface{}{c.Key1: c.Value1}
+	case *scalaMap2:
+		result = map[interface{}]interface{}{c.Key1: c.Value1, c.Key2: c.Value2}
+	case *scalaMap3:
+		result = map[interface{}]interface{}{c.Key1: c.Value1, c.Key2: c.Value2, c.Key3: c.Value3}
+	case *scalaMap4:
+		result = map[interface{}]interface{}{c.Key1: c.Value1, c.Key2: c.Value2, c.Key3: c.Value3, c.Key4: c.Value4}
+	case *scalaHashMap1:
+		result = map[interface{}]interface{}{c.Key: c.Value}
+	case *scalaHashTrieMap:
+		m := make(map[interface{}]interface{}, c.Size0)
+		for _, elem := range c.Elems {
+			child, ok := elem.(map[interface{}]interface{})
+			if !ok {
+				return nil, perrors.Errorf("the node %T of a scala hash map is not a map", elem)
+			}
+			for k, v := range child {
+				m[k] = v
+			}
+		}
+		result = m
+	default:
+		return nil, perrors.Errorf("result type %T is not a scala collection", v)
+	}
+	return result, nil
+}
+
+// elements walks the trie of the vector from its root to the leaves of the indexes [startIndex, endIndex).
+func (v *scalaVector) elements() ([]interface{}, error) {
+	if v.EndIndex <= v.StartIndex {
+		return []interface{}{}, nil
+	}
+	displays := [][]interface{}{v.Display0, v.Display1, v.Display2, v.Display3, v.Display4, v.Display5}
+	if v.Depth < 1 || int(v.Depth) > len(displays) {
+		return nil, perrors.Errorf("illegal scala vector depth %d", v.Depth)
+	}
+	root := displays[v.Depth-1]
+
+	result := make([]interface{}, 0, v.EndIndex-v.StartIndex)
+	for i := int(v.StartIndex); i < int(v.EndIndex); i++ {
+		node := root
+		for level := int(v.Depth) - 1; level >= 0; level-- {
+			slot := (i >> uint(5*level)) & 31
+			if slot >= len(node) {
+				return nil, perrors.Errorf("scala vector index %d is out of its trie", i)
+			}
+			if level == 0 {
+				result = append(result, node[slot])
+				break
+			}
+			child, ok := node[slot].([]interface{})
+			if !ok {
+				return nil, perrors.Errorf("scala vector index %d is out of its trie", i)
+			}
+			node = child
+		}
+	}
+	return result, nil
+}
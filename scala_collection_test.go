@@ -0,0 +1,151 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"fmt"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+// scalaObject builds the object of a scala class like a scala provider writes it, with the fields in order.
+func scalaObject(className string, fields ...interface{}) *GenericObject {
+	o := NewGenericObject(className)
+	for i := 0; i < len(fields); i += 2 {
+		name := fields[i].(string)
+		o.Fields[name] = fields[i+1]
+		o.fieldNames = append(o.fieldNames, name)
+	}
+	return o
+}
+
+type scalaHolder struct {
+	Names  []string         `hessian:"names"`
+	Scores map[string]int32 `hessian:"scores"`
+}
+
+func (scalaHolder) JavaClassName() string {
+	return "test.ScalaHolder"
+}
+
+func TestScalaCollections(t *testing.T) {
+	RegisterPOJO(&scalaHolder{})
+	decode := func(v interface{}) interface{} {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(v))
+		res, err := NewDecoder(e.Buffer()).Decode()
+		assert.Nil(t, err)
+		return res
+	}
+	nilList := scalaObject("scala.collection.immutable.Nil$")
+
+	// scala 2.12 names the tail tl, and 2.13 names it next
+	list := scalaObject("scala.collection.immutable.$colon$colon", "head", "a", "tl",
+		scalaObject("scala.collection.immutable.$colon$colon", "head", int32(2), "tl", nilList))
+	assert.Equal(t, []interface{}{"a", int32(2)}, decode(list))
+	list = scalaObject("scala.collection.immutable.$colon$colon", "head", "b", "next", nilList)
+	assert.Equal(t, []interface{}{"b"}, decode(list))
+	assert.Equal(t, []interface{}{}, decode(nilList))
+
+	// a vector of 40 elements has two leaves under its root display1
+	leaf0, leaf1 := make([]interface{}, 32), make([]interface{}, 32)
+	want := make([]interface{}, 0, 40)
+	for i := 0; i < 40; i++ {
+		if i < 32 {
+			leaf0[i] = int32(i)
+		} else {
+			leaf1[i-32] = int32(i)
+		}
+		want = append(want, int32(i))
+	}
+	vector := scalaObject("scala.collection.immutable.Vector", "startIndex", int32(0), "endIndex", int32(40),
+		"focus", int32(32), "dirty", false, "depth", int32(2),
+		"display0", leaf1, "display1", []interface{}{leaf0, leaf1}, "display2", nil,
+		"display3", nil, "display4", nil, "display5", nil)
+	assert.Equal(t, want, decode(vector))
+
+	m := scalaObject("scala.collection.immutable.Map$Map2", "key1", "x", "value1", int32(1), "key2", "y", "value2", int32(2))
+	assert.Equal(t, map[interface{}]interface{}{"x": int32(1), "y": int32(2)}, decode(m))
+	hashMap := scalaObject("scala.collection.immutable.HashMap$HashTrieMap", "bitmap", int32(3), "elems", []interface{}{
+		scalaObject("scala.collection.immutable.HashMap$HashMap1", "key", "p", "hash", int32(1), "value", int32(5), "kv", nil),
+		scalaObject("scala.collection.immutable.HashMap$HashMap1", "key", "q", "hash", int32(2), "value", int32(6), "kv", nil),
+	}, "size0", int32(2))
+	assert.Equal(t, map[interface{}]interface{}{"p": int32(5), "q": int32(6)}, decode(hashMap))
+	assert.Equal(t, map[interface{}]interface{}{}, decode(scalaObject("scala.collection.immutable.Map$EmptyMap$")))
+
+	// the scala collections are bound to the slice and map fields
+	holder := scalaObject("test.ScalaHolder",
+		"names", scalaObject("scala.collection.immutable.$colon$colon", "head", "n", "tl", nilList),
+		"scores", scalaObject("scala.collection.immutable.Map$Map1", "key1", "s", "value1", int32(9)))
+	assert.Equal(t, &scalaHolder{Names: []string{"n"}, Scores: map[string]int32{"s": 9}}, decode(holder))
+}
+
+func TestScalaLongList(t *testing.T) {
+	nilList := scalaObject("scala.collection.immutable.Nil$")
+	cons := func(head, tail interface{}) *GenericObject {
+		return scalaObject("scala.collection.immutable.$colon$colon", "head", head, "tl", tail)
+	}
+
+	// a list longer than the max depth, after more class definitions than the direct class indexes
+	var list interface{} = nilList
+	want := make([]interface{}, 2000)
+	for i := len(want) - 1; i >= 0; i-- {
+		want[i] = int32(i)
+		list = cons(int32(i), list)
+	}
+	classes := make([]interface{}, 20)
+	for i := range classes {
+		classes[i] = scalaObject(fmt.Sprintf("test.Scala%d", i))
+	}
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(classes))
+	assert.Nil(t, e.Encode(list))
+	d := NewDecoder(e.Buffer())
+	d.SetGenericMode(true)
+	_, err := d.Decode()
+	assert.Nil(t, err)
+	res, err := d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, want, res)
+
+	// the refs to a shared tail refer to the list from the tail on
+	tail := cons("b", cons("c", nilList))
+	e = NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{cons("a", tail), tail}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"a", "b", "c"}, []interface{}{"b", "c"}}, res)
+
+	// the tail before the head is decoded cell in cell
+	list = scalaObject("scala.collection.immutable.$colon$colon", "tl",
+		scalaObject("scala.collection.immutable.$colon$colon", "tl", nilList, "head", "y"), "head", "x")
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(list))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"x", "y"}, res)
+}
+
+func TestScalaCollectionsJava(t *testing.T) {
+	res, err := decodeJavaResponse("customReplyScalaList", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int32(1), int32(2), int32(3)}, res)
+	res, err = decodeJavaResponse("customReplyScalaMap", "")
+	assert.Nil(t, err)
+	assert.Equal(t, map[interface{}]interface{}{"a": int32(1)}, res)
+}
@@ -14,20 +14,89 @@
 
 package hessian
 
+import (
+	"reflect"
+)
+
 import (
 	big "github.com/dubbogo/gost/math/big"
+	perrors "github.com/pkg/errors"
 )
 
 func init() {
 	RegisterPOJO(&big.Decimal{})
 	SetSerializer("java.math.BigDecimal", DecimalSerializer{})
+	SetStringForm(&big.Decimal{}, func(v interface{}) string {
+		return decimalValue(v.(*big.Decimal))
+	})
 }
 
+// Serializer encodes and decodes the objects of a java class, see SetSerializer.
+// DecObject is called after the class definition is read, and decodes the value which follows it.
 type Serializer interface {
 	EncObject(*Encoder, POJO) error
 	DecObject(*Decoder) (interface{}, error)
 }
 
+// ClassSerializer is a Serializer which decodes every object instance of its class by DecInstance,
+// instead of DecObject, whether the instance follows the class definition or refers to it by index.
+type ClassSerializer interface {
+	Serializer
+	DecInstance(*Decoder, reflect.Type, ClassInfo) (interface{}, error)
+}
+
+// ClassInfo is the class definition of the object instance a ClassSerializer decodes.
+type ClassInfo struct {
+	classInfo
+}
+
+// JavaName returns the java class name of the class definition.
+func (c ClassInfo) JavaName() string {
+	return c.javaName
+}
+
+// FieldNames returns the field names of the class definition, in the order the fields are encoded.
+func (c ClassInfo) FieldNames() []string {
+	return append([]string(nil), c.fieldNameList...)
+}
+
+// DecodeInstance decodes the fields of an object instance of class @cls into a new value of @typ,
+// as the decoder does for the classes without a Serializer. It is called by ClassSerializer.DecInstance,
+// whose instance has been counted against the max depth and the max elements by the decoder.
+func (d *Decoder) DecodeInstance(typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	return d.decInstanceFields(typ, cls.classInfo)
+}
+
+// objectSerializer implements Serializer for the ClassSerializers embedding it. EncObject encodes the value
+// as an object, for the serializers which only convert the instances they decode, and DecObject decodes
+// the value following the class definition, whose instance is decoded by DecInstance.
+type objectSerializer struct{}
+
+func (objectSerializer) EncObject(e *Encoder, v POJO) error {
+	return e.encObject(v)
+}
+
+func (objectSerializer) DecObject(d *Decoder) (interface{}, error) {
+	return d.DecodeValue()
+}
+
+// decInstanceAs decodes an object instance of class @cls into @typ like DecodeInstance, and converts it
+// by @convert. The ref of the instance refers to the converted value instead of the instance.
+func decInstanceAs(d *Decoder, typ reflect.Type, cls ClassInfo, convert func(interface{}) (interface{}, error)) (interface{}, error) {
+	refIndex := len(d.refs)
+	v, err := d.DecodeInstance(typ, cls)
+	if err != nil {
+		return nil, perrors.WithStack(err)
+	}
+	result, err := convert(v)
+	if err != nil {
+		return nil, err
+	}
+	d.refs[refIndex] = result
+
+	return result, nil
+}
+
 var serializerMap = make(map[string]Serializer, 16)
 
 func SetSerializer(key string, codec Serializer) {
@@ -39,29 +108,88 @@ func GetSerializer(key string) (Serializer, bool) {
 	return codec, ok
 }
 
-type DecimalSerializer struct{}
+// getClassSerializer gets the Serializer of @key if it is a ClassSerializer.
+func getClassSerializer(key string) (ClassSerializer, bool) {
+	codec, ok := serializerMap[key].(ClassSerializer)
+	return codec, ok
+}
+
+type DecimalSerializer struct {
+	objectSerializer
+}
 
 func (DecimalSerializer) EncObject(e *Encoder, v POJO) error {
-	decimal, ok := v.(big.Decimal)
-	if !ok {
+	var decimal big.Decimal
+	switch dec := v.(type) {
+	case big.Decimal:
+		decimal = dec
+	case *big.Decimal:
+		if dec == nil {
+			e.buffer = encNull(e.buffer)
+			return nil
+		}
+		decimal = *dec
+	default:
 		return e.encObject(v)
 	}
-	decimal.Value = decimal.String()
+
+	decimal.Value = decimalValue(&decimal)
 	return e.encObject(decimal)
 }
 
-func (DecimalSerializer) DecObject(d *Decoder) (interface{}, error) {
-	dec, err := d.DecodeValue()
+func (DecimalSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	dec, err := d.DecodeInstance(typ, cls)
 	if err != nil {
-		return nil, err
+		return nil, perrors.WithStack(err)
 	}
 	result, ok := dec.(*big.Decimal)
 	if !ok {
-		panic("result type is not decimal,please check the whether the conversion is ok")
+		return nil, perrors.Errorf("result type %T is not decimal, please check the whether the conversion is ok", dec)
 	}
-	err = result.FromString(result.Value)
+	value := result.Value
+	err = result.FromString(value)
 	if err != nil {
-		return nil, err
+		return nil, perrors.Wrapf(err, "java.math.BigDecimal value %q", value)
 	}
+	// FromString may reset the decimal, keep the original java string
+	result.Value = value
 	return result, nil
 }
+
+// decimalValue returns the string sent as BigDecimal.value. The original java
+// string is kept as long as it still represents the same number, so that the
+// scale of values like "0E-10" survives a round trip.
+func decimalValue(decimal *big.Decimal) string {
+	if decimal.Value != "" {
+		var origin big.Decimal
+		if origin.FromString(decimal.Value) == nil && origin.Compare(decimal) == 0 {
+			return decimal.Value
+		}
+	}
+	return decimal.String()
+}
+
+// OptionalSerializer unwraps a decoded java.util.Optional into its value, which is nil if it is empty.
+type OptionalSerializer struct {
+	objectSerializer
+}
+
+func (OptionalSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	var value interface{}
+
+	// hold the ref index of the Optional, which refers to its value
+	refIndex := len(d.refs)
+	d.appendRefs(nil)
+	for _, fieldName := range cls.fieldNameList {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, perrors.Wrapf(err, "failed to decode field %s of java.util.Optional", fieldName)
+		}
+		if fieldName == "value" {
+			value = v
+		}
+	}
+	d.refs[refIndex] = value
+
+	return value, nil
+}
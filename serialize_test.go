@@ -66,3 +66,128 @@ func doTestDecimal(t *testing.T, method, content string) {
 		assert.Equal(t, content, r.(*big.Decimal).String())
 	})
 }
+
+func TestEncodeDecodeDecimalEdgeCases(t *testing.T) {
+	var values []interface{}
+	for _, s := range []string{
+		"123456789012345678901234567890.123456789",
+		"-98765432109876543210.0001",
+		"0.000",
+		"0E-10",
+	} {
+		var dec big.Decimal
+		assert.Nil(t, dec.FromString(s))
+		dec.Value = s
+		values = append(values, &dec)
+	}
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(values))
+
+	decObj, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	decoded, ok := decObj.([]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, len(values), len(decoded))
+	for i := range values {
+		expected := values[i].(*big.Decimal)
+		got, ok := decoded[i].(*big.Decimal)
+		if !assert.True(t, ok, "element %d is %T", i, decoded[i]) {
+			continue
+		}
+		assert.Equal(t, expected.Value, got.Value)
+		assert.Equal(t, 0, expected.Compare(got))
+	}
+}
+
+func TestReflectResponseDecimal(t *testing.T) {
+	var dec big.Decimal
+	_ = dec.FromString("-100.256")
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&dec))
+	decObj, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+
+	var outDec big.Decimal
+	assert.Nil(t, ReflectResponse(decObj, &outDec))
+	assert.Equal(t, "-100.256", outDec.String())
+
+	var outStr string
+	assert.Nil(t, ReflectResponse(decObj, &outStr))
+	assert.Equal(t, "-100.256", outStr)
+}
+
+type serializedDTO struct {
+	Name  string
+	Class string
+}
+
+func (serializedDTO) JavaClassName() string {
+	return "test.SerializedDTO"
+}
+
+// serializedDTOSerializer tells the decoded dto its class definition
+type serializedDTOSerializer struct {
+	objectSerializer
+}
+
+func (serializedDTOSerializer) DecInstance(d *Decoder, typ reflect.Type, cls ClassInfo) (interface{}, error) {
+	v, err := d.DecodeInstance(typ, cls)
+	if err != nil {
+		return nil, err
+	}
+	dto := v.(*serializedDTO)
+	dto.Class = cls.JavaName() + "." + cls.FieldNames()[0]
+	return dto, nil
+}
+
+func TestSerializerClassInfo(t *testing.T) {
+	RegisterPOJO(&serializedDTO{})
+	SetSerializer(serializedDTO{}.JavaClassName(), serializedDTOSerializer{})
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(&serializedDTO{Name: "a"}))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, &serializedDTO{Name: "a", Class: "test.SerializedDTO.name"}, res)
+}
+
+type legacyDTO struct {
+	Name string
+}
+
+func (legacyDTO) JavaClassName() string {
+	return "test.LegacyDTO"
+}
+
+// legacySerializer decodes the value following its class definition like the serializers
+// written before ClassSerializer
+type legacySerializer struct{}
+
+func (legacySerializer) EncObject(e *Encoder, v POJO) error {
+	return e.encObject(v)
+}
+
+func (legacySerializer) DecObject(d *Decoder) (interface{}, error) {
+	v, err := d.DecodeValue()
+	if err != nil {
+		return nil, err
+	}
+	dto := v.(*legacyDTO)
+	dto.Name += " by serializer"
+	return dto, nil
+}
+
+func TestLegacySerializer(t *testing.T) {
+	RegisterPOJO(&legacyDTO{})
+	SetSerializer(legacyDTO{}.JavaClassName(), legacySerializer{})
+	defer delete(serializerMap, legacyDTO{}.JavaClassName())
+
+	e := NewEncoder()
+	assert.Nil(t, e.Encode([]interface{}{&legacyDTO{Name: "a"}, &legacyDTO{Name: "b"}}))
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	// only the instance following the class definition is passed to DecObject
+	assert.Equal(t, []interface{}{&legacyDTO{Name: "a by serializer"}, &legacyDTO{Name: "b"}}, res)
+}
@@ -0,0 +1,109 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"reflect"
+)
+
+import (
+	perrors "github.com/pkg/errors"
+)
+
+// the java set classes which are decoded into slices like the lists
+func init() {
+	for _, name := range []string{
+		"java.util.Set",
+		"java.util.SortedSet",
+		"java.util.NavigableSet",
+		"java.util.HashSet",
+		"java.util.LinkedHashSet",
+		"java.util.TreeSet",
+		"java.util.concurrent.ConcurrentSkipListSet",
+		"java.util.concurrent.CopyOnWriteArraySet",
+		"java.util.ImmutableCollections$Set12",
+		"java.util.ImmutableCollections$SetN",
+	} {
+		jdkListTypes[name] = true
+	}
+}
+
+// Set is a java set, such as java.util.HashSet, of the elements of Values, which is a slice, an array,
+// or a map whose keys are the elements, such as map[string]struct{}. Go has no set and a slice is encoded
+// as a java list, so a slice is wrapped into a Set for a java service expecting a set.
+//
+// It is encoded as a typed list of the class JavaType, which is java.util.HashSet if empty, and java
+// decodes into an instance of the class. The keys of a map are in the random order of iterating it,
+// unless the encoder sorts the map keys, see SetSortMapKeys.
+//
+// A list of a java set class, such as java.util.HashSet, java.util.LinkedHashSet or java.util.TreeSet,
+// is decoded into a slice like a java list. The elements are in the order of the list, which is the
+// iteration order of the java set, such as the insertion order of a LinkedHashSet.
+type Set struct {
+	JavaType string
+	Values   interface{}
+}
+
+// NewSet creates a java set of class @javaType of the elements of @values, see Set.
+func NewSet(javaType string, values interface{}) *Set {
+	return &Set{JavaType: javaType, Values: values}
+}
+
+// ::= 'V' type int value*   # fixed-length typed list
+func (e *Encoder) encSet(s *Set) error {
+	if s == nil {
+		e.buffer = encNull(e.buffer)
+		return nil
+	}
+
+	var elements []reflect.Value
+	if s.Values != nil {
+		switch values := UnpackPtrValue(reflect.ValueOf(s.Values)); values.Kind() {
+		case reflect.Slice, reflect.Array:
+			elements = make([]reflect.Value, values.Len())
+			for i := range elements {
+				elements[i] = values.Index(i)
+			}
+		case reflect.Map:
+			elements = values.MapKeys()
+			if e.sortMapKeys {
+				sortMapKeys(elements)
+			}
+		default:
+			return perrors.Errorf("the values of a set should be a slice, an array or a map, but %T", s.Values)
+		}
+	}
+
+	// check ref
+	if n, ok := e.checkRefMap(reflect.ValueOf(s)); ok {
+		e.buffer = encRef(e.buffer, n)
+		return nil
+	}
+
+	javaType := s.JavaType
+	if javaType == "" {
+		javaType = "java.util.HashSet"
+	}
+	e.buffer = encByte(e.buffer, BC_LIST_FIXED) // 'V'
+	e.encType(javaType)
+	e.buffer = encInt32(e.buffer, int32(len(elements)))
+	for i, elem := range elements {
+		if err := e.Encode(elem.Interface()); err != nil {
+			return perrors.Wrapf(err, "failed to encode set element(idx:%d, value:%+v)", i, elem.Interface())
+		}
+	}
+
+	return nil
+}
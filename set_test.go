@@ -0,0 +1,62 @@
+// Copyright 2016-2019 Alex Stocks
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hessian
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	e := NewEncoder()
+	assert.Nil(t, e.Encode(NewSet("", []string{"a", "b"})))
+	want := []byte{BC_LIST_FIXED, 0x11}
+	want = append(want, "java.util.HashSet"...)
+	want = append(want, 0x92, 0x01, 'a', 0x01, 'b')
+	assert.Equal(t, want, e.Buffer())
+
+	res, err := NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, res)
+
+	// the order of a LinkedHashSet is kept, and a set of objects is narrowed like a list
+	RegisterPOJO(&Case{})
+	e = NewEncoder()
+	assert.Nil(t, e.Encode(NewSet("java.util.LinkedHashSet", []*Case{{A: "z"}, {A: "y"}})))
+	d := NewDecoder(e.Buffer())
+	d.SetStrict(true)
+	d.SetTypedPOJOLists(true)
+	res, err = d.Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []*Case{{A: "z"}, {A: "y"}}, res)
+
+	// the keys of a map, and the same set is a ref
+	set := &Set{JavaType: "java.util.TreeSet", Values: map[int32]struct{}{3: {}, 1: {}, 2: {}}}
+	e = NewEncoder()
+	e.SetSortMapKeys(true)
+	assert.Nil(t, e.Encode([]interface{}{set, set, Set{}}))
+	res, err = NewDecoder(e.Buffer()).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		[]interface{}{int32(1), int32(2), int32(3)},
+		[]interface{}{int32(1), int32(2), int32(3)},
+		[]interface{}{},
+	}, res)
+
+	assert.Error(t, NewEncoder().Encode(NewSet("", "a")))
+}
@@ -15,10 +15,9 @@
 package hessian
 
 import (
-	"bytes"
-	"io"
 	"reflect"
 	"strconv"
+	"unicode/utf16"
 	"unicode/utf8"
 	"unsafe"
 )
@@ -46,51 +45,92 @@ func Slice(s string) (b []byte) {
 // ::= 'S' b1 b0 <utf8-data>         # string of length 0-65535
 // ::= [x00-x1f] <utf8-data>         # string of length 0-31
 // ::= [x30-x34] <utf8-data>         # string of length 0-1023
+//
+// the length counts 16-bit java chars, so a rune outside the BMP is written as
+// a surrogate pair of two 3-byte sequences, the same as java does.
 func encString(b []byte, v string) []byte {
-	var (
-		vLen int
-
-		vBuf = *bytes.NewBufferString(v)
+	if v == "" {
+		return encByte(b, BC_STRING_DIRECT)
+	}
 
-		vChunk = func(length int) {
-			for i := 0; i < length; i++ {
-				if r, s, err := vBuf.ReadRune(); s > 0 && err == nil {
-					// b = append(b, []byte(string(r))...)
-					b = append(b, Slice(string(r))...) // converts it to []byte in memory space of "r"
-				}
+	for v != "" {
+		// find the end of a chunk of at most CHUNK_SIZE chars, never splitting a surrogate pair
+		var (
+			vLen int
+			end  int
+		)
+		for end < len(v) {
+			r, size := utf8.DecodeRuneInString(v[end:])
+			// a rune outside the BMP is a surrogate pair of two java chars
+			n := 1
+			if r > 0xffff {
+				n = 2
+			}
+			if vLen+n > CHUNK_SIZE {
+				break
 			}
+			vLen += n
+			end += size
 		}
-	)
 
-	if v == "" {
-		return encByte(b, BC_STRING_DIRECT)
+		if end < len(v) {
+			b = encByte(b, BC_STRING_CHUNK)
+			b = encByte(b, PackUint16(uint16(vLen))...)
+		} else if vLen <= int(STRING_DIRECT_MAX) {
+			b = encByte(b, byte(vLen+int(BC_STRING_DIRECT)))
+		} else if vLen <= int(STRING_SHORT_MAX) {
+			b = encByte(b, byte((vLen>>8)+int(BC_STRING_SHORT)), byte(vLen))
+		} else {
+			b = encByte(b, BC_STRING)
+			b = encByte(b, PackUint16(uint16(vLen))...)
+		}
+		b = encUTF16Chars(b, v[:end])
+		v = v[end:]
 	}
 
-	for {
-		vLen = utf8.RuneCount(vBuf.Bytes())
-		if vLen == 0 {
+	return b
+}
+
+// encUTF16Chars appends s as utf-8, with every rune outside the BMP split into a surrogate pair.
+func encUTF16Chars(b []byte, s string) []byte {
+	for len(s) > 0 {
+		i := 0
+		for i < len(s) && s[i] < 0xf0 {
+			i++
+		}
+		b = append(b, s[:i]...)
+		s = s[i:]
+		if len(s) == 0 {
 			break
 		}
-		if vLen > CHUNK_SIZE {
-			b = encByte(b, BC_STRING_CHUNK)
-			b = encByte(b, PackUint16(uint16(CHUNK_SIZE))...)
-			vChunk(CHUNK_SIZE)
+
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+			b = encSurrogate(b, r1)
+			b = encSurrogate(b, r2)
 		} else {
-			if vLen <= int(STRING_DIRECT_MAX) {
-				b = encByte(b, byte(vLen+int(BC_STRING_DIRECT)))
-			} else if vLen <= int(STRING_SHORT_MAX) {
-				b = encByte(b, byte((vLen>>8)+int(BC_STRING_SHORT)), byte(vLen))
-			} else {
-				b = encByte(b, BC_STRING)
-				b = encByte(b, PackUint16(uint16(vLen))...)
-			}
-			vChunk(vLen)
+			b = appendRune(b, r)
 		}
 	}
-
 	return b
 }
 
+// appendRune appends the utf-8 of @r like utf8.AppendRune of go1.18.
+func appendRune(b []byte, r rune) []byte {
+	if r < utf8.RuneSelf {
+		return append(b, byte(r))
+	}
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(b, buf[:n]...)
+}
+
+// encSurrogate writes a surrogate char as a 3-byte sequence, which utf8.EncodeRune refuses to do.
+func encSurrogate(b []byte, r rune) []byte {
+	return append(b, byte(0xe0|r>>12), byte(0x80|(r>>6)&0x3f), byte(0x80|r&0x3f))
+}
+
 /////////////////////////////////////////
 // String
 /////////////////////////////////////////
@@ -112,7 +152,7 @@ func (d *Decoder) getStringLength(tag byte) (int32, error) {
 		return int32(tag - 0x00), nil
 
 	case tag >= 0x30 && tag <= 0x33:
-		_, err = io.ReadFull(d.reader, buf[:1])
+		_, err = d.readFull(buf[:1])
 		if err != nil {
 			return -1, perrors.WithStack(err)
 		}
@@ -121,7 +161,7 @@ func (d *Decoder) getStringLength(tag byte) (int32, error) {
 		return length, nil
 
 	case tag == BC_STRING_CHUNK || tag == BC_STRING:
-		_, err = io.ReadFull(d.reader, buf[:2])
+		_, err = d.readFull(buf[:2])
 		if err != nil {
 			return -1, perrors.WithStack(err)
 		}
@@ -129,13 +169,14 @@ func (d *Decoder) getStringLength(tag byte) (int32, error) {
 		return length, nil
 
 	default:
-		return -1, perrors.WithStack(err)
+		return -1, d.tagErrorf("illegal string tag %#x", tag)
 	}
 }
 
 // hessian-lite/src/main/java/com/alibaba/com/caucho/hessian/io/Hessian2Input.java : readString
 func (d *Decoder) decString(flag int32) (string, error) {
 	var (
+		err    error
 		tag    byte
 		length int32
 		last   bool
@@ -146,7 +187,10 @@ func (d *Decoder) decString(flag int32) (string, error) {
 	if flag != TAG_READ {
 		tag = byte(flag)
 	} else {
-		tag, _ = d.readByte()
+		tag, err = d.readByte()
+		if err != nil {
+			return s, perrors.WithStack(err)
+		}
 	}
 
 	switch {
@@ -189,62 +233,88 @@ func (d *Decoder) decString(flag int32) (string, error) {
 		(tag >= 0x30 && tag <= 0x33) ||
 		(tag == BC_STRING_CHUNK || tag == BC_STRING) {
 
-		if tag == BC_STRING_CHUNK {
-			last = false
-		} else {
-			last = true
-		}
+		var (
+			buf []byte
+			// a high surrogate waiting for its low half, which may start the next chunk
+			high  rune
+			total int
+		)
+		for {
+			last = tag != BC_STRING_CHUNK
+			length, err = d.getStringLength(tag)
+			if err != nil {
+				return s, perrors.WithStack(err)
+			}
+			if total += int(length); d.maxStringLength > 0 && total > d.maxStringLength {
+				return s, perrors.Wrapf(ErrMaxStringLengthExceeded, "string of %d chars, max length %d", total, d.maxStringLength)
+			}
+			// every char takes one byte at least
+			if err = d.checkRemaining(int(length)); err != nil {
+				return s, err
+			}
+			if buf == nil {
+				buf = make([]byte, 0, length)
+			}
 
-		l, err := d.getStringLength(tag)
-		if err != nil {
-			return s, perrors.WithStack(err)
-		}
-		length = l
-		runeDate := make([]rune, length)
-		for i := 0; ; {
-			if int32(i) == length {
-				if last {
-					return string(runeDate), nil
+			for i := int32(0); i < length; i++ {
+				r, err = d.readChar()
+				if err != nil {
+					return s, perrors.WithStack(err)
 				}
-
-				b, _ := d.readByte()
-				switch {
-				case (tag >= BC_STRING_DIRECT && tag <= STRING_DIRECT_MAX) ||
-					(tag >= 0x30 && tag <= 0x33) ||
-					(tag == BC_STRING_CHUNK || tag == BC_STRING):
-
-					if b == BC_STRING_CHUNK {
-						last = false
-					} else {
-						last = true
-					}
-
-					l, err := d.getStringLength(b)
-					if err != nil {
-						return s, perrors.WithStack(err)
+				if high != 0 {
+					if utf16.IsSurrogate(r) && r >= 0xdc00 {
+						buf = appendRune(buf, utf16.DecodeRune(high, r))
+						high = 0
+						continue
 					}
-					length += l
-					bs := make([]rune, length)
-					copy(bs, runeDate)
-					runeDate = bs
-
-				default:
-					return s, perrors.WithStack(err)
+					buf = appendRune(buf, utf8.RuneError)
+					high = 0
+				}
+				if utf16.IsSurrogate(r) && r < 0xdc00 {
+					high = r
+					continue
 				}
+				buf = appendRune(buf, r)
+			}
 
-			} else {
-				r, _, err = d.reader.ReadRune()
-				if err != nil {
-					return s, perrors.WithStack(err)
+			if last {
+				if high != 0 {
+					buf = appendRune(buf, utf8.RuneError)
 				}
-				runeDate[i] = r
-				i++
+				return string(buf), nil
 			}
-		}
 
-		// unreachable
-		// return string(runeDate), nil
+			tag, err = d.readByte()
+			if err != nil {
+				return s, perrors.WithStack(err)
+			}
+			if !((tag >= BC_STRING_DIRECT && tag <= STRING_DIRECT_MAX) ||
+				(tag >= 0x30 && tag <= 0x33) ||
+				(tag == BC_STRING_CHUNK || tag == BC_STRING)) {
+				return s, d.tagErrorf("illegal string chunk tag %#x", tag)
+			}
+		}
 	}
 
 	return s, perrors.Errorf("unknown string tag %#x\n", tag)
 }
+
+// readChar reads one java char of a string. A surrogate written as a 3-byte
+// sequence is returned as is, to be paired up by the caller.
+// The bytes after a lead byte 0xed are peeked only as the surrogate form needs them,
+// so that reading the last char of a frame does not wait for more bytes from a stream.
+func (d *Decoder) readChar() (rune, error) {
+	if b := d.peek(1); len(b) == 1 && b[0] == 0xed {
+		if b = d.peek(2); len(b) == 2 && b[1] >= 0xa0 && b[1] <= 0xbf {
+			if b = d.peek(3); len(b) == 3 && b[2]&0xc0 == 0x80 {
+				var buf [3]byte
+				if _, err := d.readFull(buf[:]); err != nil {
+					return 0, err
+				}
+				return rune(buf[0]&0x0f)<<12 | rune(buf[1]&0x3f)<<6 | rune(buf[2]&0x3f), nil
+			}
+		}
+	}
+	r, _, err := d.readRune()
+	return r, err
+}
@@ -15,8 +15,17 @@
 package hessian
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
 )
 
 func TestEncString(t *testing.T) {
@@ -141,3 +150,109 @@ func TestStringEncode(t *testing.T) {
 	testJavaDecode(t, "argString_32", s32)
 	testJavaDecode(t, "argString_65536", s65560[:65536])
 }
+
+func TestStringChunks(t *testing.T) {
+	// builds a string of n UTF-16 chars with the multi-byte char c ending at each chunk split
+	build := func(n int, c rune) string {
+		units := len(utf16.Encode([]rune{c}))
+		rs := make([]rune, 0, n)
+		for len(rs) < n {
+			rs = append(rs, 'a')
+		}
+		for split := CHUNK_SIZE; split <= n; split += CHUNK_SIZE {
+			if split-units >= 0 {
+				rs[split-units] = c
+				if units == 2 {
+					rs[split-1] = c
+				}
+			}
+		}
+		return string(rs)
+	}
+
+	for _, c := range []rune{'a', 'é', '中', '😀'} {
+		for _, n := range []int{0xffff - 1, 0xffff, 0xffff + 1, 200000} {
+			v := build(n, c)
+			e := NewEncoder()
+			assert.Nil(t, e.Encode(v))
+
+			res, err := NewDecoder(e.Buffer()).Decode()
+			assert.Nil(t, err)
+			assert.Equal(t, v, res, "char %q length %d", c, n)
+		}
+	}
+}
+
+func TestStringSurrogates(t *testing.T) {
+	// java counts chars, so a rune outside the BMP is a surrogate pair of two 3-byte sequences
+	e := NewEncoder()
+	assert.Nil(t, e.Encode("😀"))
+	assert.Equal(t, []byte{0x02, 0xed, 0xa0, 0xbd, 0xed, 0xb8, 0x80}, e.Buffer())
+
+	// java style chunks of 0x8000 chars, with a surrogate pair split between two chunks
+	b := []byte{BC_STRING_CHUNK, 0x80, 0x00}
+	b = append(b, bytes.Repeat([]byte("中"), 0x8000-1)...)
+	b = append(b, 0xed, 0xa0, 0xbd)
+	b = append(b, BC_STRING, 0x00, 0x02, 0xed, 0xb8, 0x80, 'z')
+	res, err := NewDecoder(b).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Repeat("中", 0x8000-1)+"😀z", res)
+
+	// a plain 4-byte utf-8 rune counted as one char is still accepted
+	res, err = NewDecoder([]byte{0x02, 0xf0, 0x9f, 0x98, 0x80, 'z'}).Decode()
+	assert.Nil(t, err)
+	assert.Equal(t, "😀z", res)
+
+	_, err = NewDecoder([]byte{BC_STRING_CHUNK, 0x00, 0x01, 'a', BC_INT}).Decode()
+	assert.NotNil(t, err)
+}
+
+// readOpenStream calls @read on a decoder of a stream which has sent @frame and is kept open,
+// and fails if @read waits for more bytes than the frame.
+func readOpenStream(t *testing.T, frame []byte, read func(d *Decoder) (interface{}, error)) (interface{}, error) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() {
+		_, _ = pw.Write(frame)
+	}()
+
+	type result struct {
+		v   interface{}
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := read(NewDecoderFromReader(pr))
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reading % x waits for more bytes of the open stream", frame)
+		return nil, nil
+	}
+}
+
+func TestReadRuneOpenStream(t *testing.T) {
+	for _, r := range []rune{'a', 'é', '中', '😀'} {
+		v, err := readOpenStream(t, []byte(string(r)), func(d *Decoder) (interface{}, error) {
+			r, _, err := d.readRune()
+			return r, err
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, r, v)
+	}
+}
+
+func TestDecodeStringOpenStream(t *testing.T) {
+	for _, s := range []string{"a", "ab", "é", "中", "😀", "a😀", "\xed\x9f\xbf"} {
+		e := NewEncoder()
+		assert.Nil(t, e.Encode(s))
+		v, err := readOpenStream(t, e.Buffer(), func(d *Decoder) (interface{}, error) {
+			return d.Decode()
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, s, v)
+	}
+}
@@ -23,8 +23,11 @@ import java.io.InputStream;
 import java.lang.reflect.Array;
 import java.util.ArrayList;
 import java.util.Arrays;
+import java.util.Calendar;
 import java.util.Date;
+import java.util.HashMap;
 import java.util.List;
+import java.util.Map;
 import java.math.BigDecimal;
 import test.model.DateDemo;
 
@@ -174,4 +177,29 @@ public class TestCustomDecode {
         DateDemo o = (DateDemo) input.readObject();
         return o.getDate() == null && o.getDate1() == null;
     }
-}
\ No newline at end of file
+
+    public Object customArgGregorianCalendar() throws Exception {
+        Calendar calendar = (Calendar) input.readObject();
+        return calendar.getTimeInMillis() == 1688477415123L
+                && calendar.getTimeZone().getID().equals("America/New_York");
+    }
+
+    public Object customArgStructAsMap() throws Exception {
+        Object o = input.readObject();
+        if (!(o instanceof HashMap)) {
+            return false;
+        }
+        Map<Object, Object> address = new HashMap<>();
+        address.put("city", "hz");
+        Map<Object, Object> user = new HashMap<>();
+        user.put("userName", "tom");
+        user.put("age", 18);
+        user.put("address", address);
+        return user.equals(o);
+    }
+
+    public Object customArgFloat32() throws Exception {
+        Object o = input.readObject();
+        return o instanceof Double && ((Double) o).floatValue() == 0.1f && o.toString().equals("0.1");
+    }
+}
@@ -20,8 +20,11 @@ import com.caucho.hessian.test.A1;
 
 import java.io.OutputStream;
 import java.io.Serializable;
+import java.util.Calendar;
 import java.util.Date;
+import java.util.GregorianCalendar;
 import java.util.HashMap;
+import java.util.TimeZone;
 import java.math.BigDecimal;
 import test.model.DateDemo;
 
@@ -353,6 +356,33 @@ public class TestCustomReply {
         output.flush();
     }
 
+    public void customReplyGregorianCalendar() throws Exception {
+        Calendar calendar = new GregorianCalendar(TimeZone.getTimeZone("Asia/Kolkata"));
+        calendar.setTimeInMillis(1688477415123L);
+        output.writeObject(calendar);
+        output.flush();
+    }
+
+    public void customReplyZoneInfo() throws Exception {
+        output.writeObject(TimeZone.getTimeZone("Asia/Kolkata"));
+        output.flush();
+    }
+
+    public void customReplyScalaList() throws Exception {
+        scala.collection.immutable.List list = scala.collection.immutable.Nil$.MODULE$;
+        for (int i = 3; i > 0; i--) {
+            list = list.$colon$colon(i);
+        }
+        output.writeObject(list);
+        output.flush();
+    }
+
+    public void customReplyScalaMap() throws Exception {
+        scala.collection.immutable.Map map = scala.collection.immutable.Map$.MODULE$.empty();
+        output.writeObject(map.$plus(new scala.Tuple2<>("a", 1)));
+        output.flush();
+    }
+
 }
 
 class TypedListTest implements Serializable {